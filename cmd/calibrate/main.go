@@ -0,0 +1,102 @@
+// Command calibrate fits the complexity rules to reviewer-labelled outcomes and
+// writes a rules file the service can load with COMPLEXITY_RULES_PATH.
+//
+//	calibrate -labels conversion_validation_report.csv -source ./templates -out rules.yaml
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/analyzer"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/calibration"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+func main() {
+	labelsPath := flag.String("labels", "", "CSV of filename, outcome and optional reviewer level")
+	sourceDir := flag.String("source", ".", "Directory holding the labelled files")
+	basePath := flag.String("rules", "", "Rules to start from (default: built-in rules)")
+	outPath := flag.String("out", "complexity-rules.yaml", "Where to write the fitted rules")
+	flag.Parse()
+
+	if *labelsPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	file, err := os.Open(*labelsPath)
+	if err != nil {
+		log.Fatalf("Failed to open labels: %v", err)
+	}
+	labels, err := calibration.ReadLabels(file)
+	file.Close()
+	if err != nil {
+		log.Fatalf("Failed to read labels: %v", err)
+	}
+
+	base := analyzer.DefaultRules()
+	if *basePath != "" {
+		if base, err = analyzer.LoadRules(*basePath); err != nil {
+			log.Fatalf("Failed to load base rules: %v", err)
+		}
+	}
+
+	result, err := calibration.Calibrate(context.Background(), labels, *sourceDir, base)
+	if err != nil {
+		log.Fatalf("Calibration failed: %v", err)
+	}
+	for _, name := range result.Skipped {
+		log.Warnf("Skipped %s: file not found in %s", name, *sourceDir)
+	}
+
+	printEvaluation(os.Stdout, "Base rules", result.Baseline)
+	printEvaluation(os.Stdout, "Fitted rules on their training files", result.Training)
+	printEvaluation(os.Stdout, fmt.Sprintf("Fitted rules on held-out files (%d cross-validation folds)", result.Folds),
+		result.CrossValidated)
+
+	data, err := yaml.Marshal(result.Rules)
+	if err != nil {
+		log.Fatalf("Failed to encode rules: %v", err)
+	}
+	header := fmt.Sprintf("# Complexity rules fitted to %d labelled files from %s on %s\n",
+		result.Training.Files, *labelsPath, time.Now().Format("2006-01-02"))
+	if err := os.WriteFile(*outPath, append([]byte(header), data...), 0o644); err != nil {
+		log.Fatalf("Failed to write rules: %v", err)
+	}
+	log.Infof("Wrote fitted rules to %s", *outPath)
+}
+
+// printEvaluation writes review precision and recall and the level confusion matrix
+func printEvaluation(w io.Writer, title string, e calibration.Evaluation) {
+	fmt.Fprintf(w, "%s (%d files)\n", title, e.Files)
+	fmt.Fprintf(w, "  needs review: precision %.2f, recall %.2f (TP %d, FP %d, FN %d, TN %d)\n",
+		e.Precision(), e.Recall(), e.TruePositives, e.FalsePositives, e.FalseNegatives, e.TrueNegatives)
+	if len(e.Confusion) == 0 {
+		fmt.Fprintln(w)
+		return
+	}
+
+	fmt.Fprintf(w, "  level accuracy %.2f; rows are reviewer levels, columns analyzer levels\n", e.LevelAccuracy())
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(tw, "\t")
+	for _, level := range calibration.Levels() {
+		fmt.Fprintf(tw, "%s\t", level)
+	}
+	fmt.Fprintln(tw)
+	for _, reviewer := range calibration.Levels() {
+		fmt.Fprintf(tw, "%s\t", reviewer)
+		for _, level := range calibration.Levels() {
+			fmt.Fprintf(tw, "%d\t", e.Confusion[reviewer][level])
+		}
+		fmt.Fprintln(tw)
+	}
+	tw.Flush()
+	fmt.Fprintln(w)
+}
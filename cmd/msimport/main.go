@@ -0,0 +1,106 @@
+// Command msimport queues the precedents of MatterSphere exports for conversion
+// on the service's queue, one job per precedent, and writes the reconciliation
+// report mapping each PrecID to its job and output document. It uses the
+// service's REDIS_URL and storage settings.
+//
+//	msimport -root "MatterSphere Export" -dest converted/archive -report reconciliation.csv
+//	msimport -reconcile <batch-id> -report reconciliation.csv
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/config"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/converter"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/mattersphere"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/queue"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/storage"
+	log "github.com/sirupsen/logrus"
+)
+
+func main() {
+	root := flag.String("root", "", "Local directory or storage prefix holding the exports")
+	dest := flag.String("dest", "", "Storage prefix for the converted documents")
+	outputFormat := flag.String("format", "", "Comma-separated output formats (default: docx)")
+	priority := flag.Int("priority", 0, "Job priority")
+	engine := flag.String("engine", "", "Conversion engine (default: the service's routing)")
+	annotate := flag.Bool("annotate", false, "Also produce a DOCX with a review comment per complexity issue")
+	reconcile := flag.String("reconcile", "", "Refresh the report of an earlier import batch instead of importing")
+	reportPath := flag.String("report", "", "Also write the reconciliation report here (.csv or .json)")
+	flag.Parse()
+
+	if *reconcile == "" && (*root == "" || *dest == "") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg := config.Load()
+	q, err := queue.NewRedisQueue(cfg.RedisURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to the queue: %v", err)
+	}
+	defer q.Close()
+
+	var s storage.Storage = storage.NewLocalStorage("/tmp/conversions")
+	if cfg.AzureStorageConnectionString != "" {
+		if s, err = storage.NewAzureStorage(cfg.AzureStorageConnectionString, cfg.AzureStorageContainer); err != nil {
+			log.Fatalf("Failed to initialize Azure Storage: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	importer := mattersphere.NewImporter(q, s)
+	var r *mattersphere.Reconciliation
+	if *reconcile != "" {
+		if r, err = importer.Reconcile(ctx, *reconcile); err != nil {
+			log.Fatalf("Failed to reconcile batch %s: %v", *reconcile, err)
+		}
+	} else {
+		formats, err := converter.ParseOutputFormats(*outputFormat)
+		if err != nil {
+			log.Fatal(err)
+		}
+		r, err = importer.Import(ctx, *root, mattersphere.ImportOptions{
+			Destination: *dest,
+			Formats:     formats,
+			Priority:    *priority,
+			Engine:      *engine,
+			Annotate:    *annotate,
+		})
+		if err != nil {
+			log.Fatalf("Import failed: %v", err)
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, entry := range r.Entries {
+		counts[entry.Status]++
+	}
+	log.Infof("Batch %s: %d precedents, %d queued, by status %v", r.BatchID, len(r.Entries), r.Queued, counts)
+
+	if *reportPath != "" {
+		if err := writeReport(*reportPath, r); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+		log.Infof("Wrote reconciliation report to %s", *reportPath)
+	}
+}
+
+// writeReport writes the reconciliation as CSV or, for .json paths, JSON
+func writeReport(path string, r *mattersphere.Reconciliation) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if strings.HasSuffix(strings.ToLower(path), ".json") {
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	}
+	return r.WriteCSV(f)
+}
@@ -0,0 +1,502 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/field"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/vba"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/wordml"
+)
+
+// Anchor estimation settings
+const (
+	charsPerPage  = 3000 // Characters of text on a page, for parts without rendered page breaks
+	snippetRadius = 40   // Characters of text kept either side of an anchor
+)
+
+// drawingMLNS holds the a:p paragraphs of charts and shapes, which are not
+// paragraphs of the document text
+const drawingMLNS = "http://schemas.openxmlformats.org/drawingml/2006/main"
+
+// Anchor places an issue in the document. Line and column are set for positions
+// in XML parts; paragraph, page and offset refer to the text of the part, with
+// field codes shown in braces and field results left out.
+type Anchor struct {
+	Part      string `json:"part"`                // Part or story, e.g. "word/document.xml" or "main"
+	Paragraph int    `json:"paragraph,omitempty"` // 1-based, within the part
+	Page      int    `json:"page,omitempty"`      // Approximate
+	Offset    int    `json:"offset"`              // Characters of text before the anchor in the part
+	Line      int    `json:"line,omitempty"`
+	Column    int    `json:"column,omitempty"`
+	Snippet   string `json:"snippet,omitempty"` // Text around the anchor
+}
+
+// String formats the anchor as part:line:column for XML parts, otherwise with
+// its paragraph and page
+func (a *Anchor) String() string {
+	switch {
+	case a == nil:
+		return ""
+	case a.Line > 0:
+		return fmt.Sprintf("%s:%d:%d", a.Part, a.Line, a.Column)
+	case a.Paragraph > 0:
+		return fmt.Sprintf("%s, paragraph %d, page %d", a.Part, a.Paragraph, a.Page)
+	}
+	return a.Part
+}
+
+// paragraph is one paragraph of an indexed part
+type paragraph struct {
+	src, end int    // Source offsets of the paragraph
+	text     string // Text with field codes in braces
+	start    int    // Characters of text before the paragraph
+	marks    []mark // Where runs of text start in the source
+
+	// Page breaks before the paragraph, and characters since the last explicit one
+	rendered, explicit, sinceBreak int
+}
+
+// mark maps a run of text back to its source offset
+type mark struct {
+	src  int
+	pos  int    // Characters into the paragraph text
+	text string // The run, to place offsets within it
+}
+
+// textIndex maps source offsets of one part or story to paragraphs, pages and text
+type textIndex struct {
+	part       string
+	xml        bool
+	paragraphs []*paragraph
+	lineStarts []int // Offset of each line, for XML parts
+	rendered   bool  // The part records the page breaks of its last layout
+}
+
+// textBuilder collects the paragraphs of a part as its source is read
+type textBuilder struct {
+	ix         *textIndex
+	current    *paragraph
+	text       strings.Builder
+	runes      int
+	chars      int
+	rendered   int
+	explicit   int
+	sinceBreak int
+	results    []bool // Open fields, true once in their result
+	markEnd    int    // Source offset just past the last written run, or -1
+}
+
+func newTextBuilder(part string, xml bool) *textBuilder {
+	return &textBuilder{ix: &textIndex{part: part, xml: xml}, markEnd: -1}
+}
+
+// begin starts a paragraph at a source offset
+func (b *textBuilder) begin(src int) {
+	b.finish(src)
+	b.current = &paragraph{
+		src: src, start: b.chars,
+		rendered: b.rendered, explicit: b.explicit, sinceBreak: b.sinceBreak,
+	}
+	b.markEnd = -1
+}
+
+// finish closes the open paragraph at a source offset
+func (b *textBuilder) finish(end int) {
+	if b.current == nil {
+		return
+	}
+	b.current.end = end
+	b.current.text = b.text.String()
+	b.ix.paragraphs = append(b.ix.paragraphs, b.current)
+	b.current = nil
+	b.text.Reset()
+	b.runes = 0
+}
+
+// write adds text found at a source offset
+func (b *textBuilder) write(s string, src int) {
+	if s == "" {
+		return
+	}
+	if b.current == nil {
+		b.begin(src)
+	}
+	if src != b.markEnd {
+		b.current.marks = append(b.current.marks, mark{src: src, pos: b.runes, text: s})
+	} else {
+		last := &b.current.marks[len(b.current.marks)-1]
+		last.text += s
+	}
+	b.markEnd = src + len(s)
+	n := utf8.RuneCountInString(s)
+	b.text.WriteString(s)
+	b.runes += n
+	b.chars += n
+	b.sinceBreak += n
+}
+
+// pageBreak records an explicit page break. A break before any text of a
+// paragraph moves the whole paragraph to the next page.
+func (b *textBuilder) pageBreak() {
+	b.explicit++
+	b.sinceBreak = 0
+	if b.current != nil && b.runes == 0 {
+		b.current.explicit, b.current.sinceBreak = b.explicit, 0
+	}
+}
+
+// hidden reports whether text belongs to a field result
+func (b *textBuilder) hidden() bool {
+	for _, result := range b.results {
+		if result {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *textBuilder) fieldBegin(src int) {
+	if !b.hidden() {
+		b.write("{", src)
+	}
+	b.results = append(b.results, false)
+}
+
+func (b *textBuilder) fieldSeparate() {
+	if len(b.results) > 0 {
+		b.results[len(b.results)-1] = true
+	}
+}
+
+func (b *textBuilder) fieldEnd(src int) {
+	if len(b.results) > 0 {
+		b.results = b.results[:len(b.results)-1]
+	}
+	if !b.hidden() {
+		b.write("}", src)
+	}
+}
+
+// index closes the last paragraph and returns the index
+func (b *textBuilder) index(end int) *textIndex {
+	b.finish(end)
+	b.ix.rendered = b.rendered > 0
+	return b.ix
+}
+
+// indexRaw indexes the text of a Word binary or RTF story, where paragraphs end
+// in \r or a cell mark and fields are delimited by 0x13, 0x14 and 0x15, or of
+// plain text, where paragraphs are lines
+func indexRaw(part, raw string, binary bool) *textIndex {
+	b := newTextBuilder(part, false)
+	b.begin(0)
+	for i, r := range raw {
+		switch {
+		case binary && r == '\x13':
+			b.fieldBegin(i)
+		case binary && r == '\x14':
+			b.fieldSeparate()
+		case binary && r == '\x15':
+			b.fieldEnd(i)
+		case binary && (r == '\r' || r == '\x07'), !binary && r == '\n':
+			b.begin(i + 1)
+		case binary && r == '\x0c':
+			b.pageBreak()
+		case b.hidden():
+		case r == '\v' || r == '\t':
+			b.write(" ", i)
+		default:
+			_, size := utf8.DecodeRuneInString(raw[i:])
+			b.write(raw[i:i+size], i)
+		}
+	}
+	return b.index(len(raw))
+}
+
+// indexXML indexes the paragraphs of a WordprocessingML part. Unreadable XML
+// yields the paragraphs read so far.
+func indexXML(part string, data []byte) *textIndex {
+	b := newTextBuilder(part, true)
+	b.ix.lineStarts = []int{0}
+	for i, c := range data {
+		if c == '\n' {
+			b.ix.lineStarts = append(b.ix.lineStarts, i+1)
+		}
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var inText, inInstr bool
+	for {
+		offset := int(decoder.InputOffset())
+		tok, err := decoder.Token()
+		if err != nil {
+			if err != io.EOF {
+				return b.index(offset)
+			}
+			return b.index(len(data))
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				if t.Name.Space != drawingMLNS {
+					b.begin(offset)
+				}
+			case "t":
+				inText = true
+			case "instrText":
+				inInstr = true
+			case "tab":
+				if !b.hidden() {
+					b.write(" ", offset)
+				}
+			case "br":
+				if xmlAttr(t, "type") == "page" {
+					b.pageBreak()
+				} else if !b.hidden() {
+					b.write(" ", offset)
+				}
+			case "lastRenderedPageBreak":
+				b.rendered++
+				if b.current != nil && b.runes == 0 {
+					b.current.rendered = b.rendered
+				}
+			case "pageBreakBefore":
+				if v := xmlAttr(t, "val"); v != "0" && v != "false" {
+					b.pageBreak()
+					if b.current != nil {
+						b.current.explicit, b.current.sinceBreak = b.explicit, 0
+					}
+				}
+			case "fldChar":
+				switch xmlAttr(t, "fldCharType") {
+				case "begin":
+					b.fieldBegin(offset)
+				case "separate":
+					b.fieldSeparate()
+				case "end":
+					b.fieldEnd(offset)
+				}
+			case "fldSimple":
+				if !b.hidden() {
+					b.write("{"+xmlAttr(t, "instr")+"}", offset)
+				}
+				b.results = append(b.results, true)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p":
+				if t.Name.Space != drawingMLNS && b.current != nil {
+					b.finish(int(decoder.InputOffset()))
+				}
+			case "t":
+				inText = false
+			case "instrText":
+				inInstr = false
+			case "fldSimple":
+				if len(b.results) > 0 {
+					b.results = b.results[:len(b.results)-1]
+				}
+			}
+		case xml.CharData:
+			if (inText || inInstr) && !b.hidden() {
+				b.write(string(t), offset)
+			}
+		}
+	}
+}
+
+// xmlAttr returns the value of the attribute with the given local name
+func xmlAttr(e xml.StartElement, local string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// at anchors a source offset to the paragraph holding it, or the next one when
+// the offset lies between paragraphs
+func (ix *textIndex) at(offset int) *Anchor {
+	anchor := &Anchor{Part: ix.part}
+	if len(ix.paragraphs) == 0 {
+		return anchor
+	}
+
+	index := len(ix.paragraphs) - 1
+	for i, p := range ix.paragraphs {
+		if p.end > offset {
+			index = i
+			break
+		}
+	}
+	p := ix.paragraphs[index]
+
+	pos := 0
+	for _, m := range p.marks {
+		if m.src > offset {
+			break
+		}
+		within := offset - m.src
+		if within > len(m.text) {
+			within = len(m.text)
+		}
+		pos = m.pos + utf8.RuneCountInString(m.text[:within])
+	}
+
+	anchor.Paragraph = index + 1
+	anchor.Offset = p.start + pos
+	anchor.Snippet = snippet(p.text, pos)
+	if ix.rendered {
+		anchor.Page = 1 + p.rendered
+	} else {
+		anchor.Page = 1 + p.explicit + p.sinceBreak/charsPerPage
+	}
+	return anchor
+}
+
+// offsetOf converts a 1-based line and column of an XML part to a source offset
+func (ix *textIndex) offsetOf(line, column int) int {
+	if line < 1 || line > len(ix.lineStarts) {
+		return 0
+	}
+	return ix.lineStarts[line-1] + column - 1
+}
+
+// snippet returns the text around a position of a paragraph, with whitespace
+// collapsed and ellipses where it was cut
+func snippet(text string, pos int) string {
+	runes := []rune(text)
+	start, end := pos-snippetRadius, pos+snippetRadius
+	if start < 0 {
+		start = 0
+	}
+	if end > len(runes) {
+		end = len(runes)
+	}
+	if start > end {
+		start = end
+	}
+	s := strings.Join(strings.Fields(string(runes[start:end])), " ")
+	if s == "" {
+		return ""
+	}
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(runes) {
+		s += "…"
+	}
+	return s
+}
+
+// anchorIndex anchors fields and structural elements to the part they were read from
+type anchorIndex struct {
+	parts  []*textIndex
+	fields map[*field.Field]*textIndex
+}
+
+func newAnchorIndex() *anchorIndex {
+	return &anchorIndex{fields: make(map[*field.Field]*textIndex)}
+}
+
+// add records an indexed part and the fields parsed from it
+func (x *anchorIndex) add(ix *textIndex, fields []*field.Field) {
+	x.parts = append(x.parts, ix)
+	for _, f := range field.All(fields) {
+		x.fields[f] = ix
+	}
+}
+
+// field anchors a parsed field; fields of unknown origin have no anchor
+func (x *anchorIndex) field(f *field.Field) *Anchor {
+	if x == nil || f == nil {
+		return nil
+	}
+	ix, ok := x.fields[f]
+	if !ok {
+		return nil
+	}
+	anchor := ix.at(f.Pos.Offset)
+	if ix.xml {
+		anchor.Line, anchor.Column = f.Pos.Line, f.Pos.Column
+	}
+	return anchor
+}
+
+// fieldCode anchors the first field written as code, e.g. "{INCLUDETEXT ...}"
+func (x *anchorIndex) fieldCode(code string) *Anchor {
+	if x == nil {
+		return nil
+	}
+	for _, ix := range x.parts {
+		var found *field.Field
+		for f, fix := range x.fields {
+			if fix == ix && f.String() == code && (found == nil || f.Pos.Offset < found.Pos.Offset) {
+				found = f
+			}
+		}
+		if found != nil {
+			return x.field(found)
+		}
+	}
+	return nil
+}
+
+// location anchors a position in an XML part
+func (x *anchorIndex) location(loc wordml.Location) *Anchor {
+	if x != nil {
+		for _, ix := range x.parts {
+			if ix.part == loc.Part && ix.xml {
+				anchor := ix.at(ix.offsetOf(loc.Line, loc.Column))
+				anchor.Line, anchor.Column = loc.Line, loc.Column
+				return anchor
+			}
+		}
+	}
+	return &Anchor{Part: loc.Part, Line: loc.Line, Column: loc.Column}
+}
+
+// vbaAnchor anchors the declaration of the first procedure of a VBA project,
+// preferring the ones Word runs on its own
+func vbaAnchor(modules []vba.Module) *Anchor {
+	var fallback *Anchor
+	for _, m := range modules {
+		for i, line := range strings.Split(m.Source, "\n") {
+			code := strings.TrimSpace(line)
+			for _, prefix := range []string{"Public ", "Private ", "Static "} {
+				code = strings.TrimPrefix(code, prefix)
+			}
+			var name string
+			for _, keyword := range []string{"Sub ", "Function "} {
+				if strings.HasPrefix(code, keyword) {
+					name = strings.TrimSpace(strings.TrimPrefix(code, keyword))
+				}
+			}
+			if name == "" {
+				continue
+			}
+			if j := strings.IndexByte(name, '('); j >= 0 {
+				name = name[:j]
+			}
+
+			anchor := &Anchor{Part: "VBA/" + m.Name, Line: i + 1, Column: 1, Snippet: strings.TrimSpace(line)}
+			for _, auto := range m.AutoExec {
+				if strings.EqualFold(auto, name) {
+					return anchor
+				}
+			}
+			if fallback == nil {
+				fallback = anchor
+			}
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,104 @@
+package analyzer
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestTextIndexAnchors(t *testing.T) {
+	// Word binary story: \r ends paragraphs, \x0c breaks pages and
+	// \x13 \x14 \x15 delimit a field's code and result
+	story := "Dear client,\rThank you.\r\x0cAmount: \x13 MERGEFIELD Amount \x14«Amount»\x15 due.\r"
+	xmlPart := "<w:document xmlns:w=\"http://schemas.openxmlformats.org/wordprocessingml/2006/main\"><w:body>\n" +
+		"<w:p><w:r><w:t>First page</w:t></w:r></w:p>\n" +
+		"<w:p><w:r><w:lastRenderedPageBreak/><w:t>Second page</w:t></w:r></w:p>\n" +
+		"</w:body></w:document>"
+
+	tests := []struct {
+		name      string
+		ix        *textIndex
+		offset    int
+		paragraph int
+		page      int
+		snippet   string
+	}{
+		{"binary first paragraph", indexRaw("main", story, true), 5, 1, 1, "Dear client,"},
+		{"binary after page break", indexRaw("main", story, true), strings.Index(story, "MERGEFIELD"), 3, 2, "Amount: { MERGEFIELD Amount } due."},
+		{"xml rendered page", indexXML("word/document.xml", []byte(xmlPart)), strings.Index(xmlPart, "Second"), 2, 2, "Second page"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := tt.ix.at(tt.offset)
+			if a == nil {
+				t.Fatal("no anchor")
+			}
+			if a.Paragraph != tt.paragraph || a.Page != tt.page || a.Snippet != tt.snippet {
+				t.Errorf("anchor = %+v, want paragraph %d, page %d, snippet %q", a, tt.paragraph, tt.page, tt.snippet)
+			}
+		})
+	}
+}
+
+func TestIssuesCarryAnchors(t *testing.T) {
+	content := []byte("Opening paragraph of the letter.\n" +
+		"Your matter remains open with { MERGEFIELD Balance \\# \"#,##0.00\" } outstanding.\n" +
+		"Closing paragraph of the letter.\n")
+	report := AnalyzeComplexity(content)
+
+	var anchored int
+	for _, issue := range report.Issues {
+		if issue.Anchor == nil || issue.Location == "" {
+			t.Errorf("issue %s has no location", issue.Type)
+			continue
+		}
+		anchored++
+		if issue.Anchor.Paragraph != 2 {
+			t.Errorf("issue %s anchored to %+v, want paragraph 2", issue.Type, issue.Anchor)
+		}
+	}
+	if anchored == 0 {
+		t.Fatal("no issues reported")
+	}
+
+	// The converted document keeps the field result text, which the comment is matched on
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range map[string]string{
+		"[Content_Types].xml": `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"></Types>`,
+		"word/document.xml": `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+			`<w:p><w:r><w:t>Heading</w:t></w:r></w:p>` +
+			`<w:p><w:r><w:t>Opening paragraph of the letter.</w:t></w:r></w:p>` +
+			`<w:p><w:r><w:t>Your matter remains open with 1,250.00 outstanding.</w:t></w:r></w:p>` +
+			`</w:body></w:document>`,
+	} {
+		w, _ := zw.Create(name)
+		w.Write([]byte(data))
+	}
+	zw.Close()
+
+	annotated, err := Annotate(buf.Bytes(), report)
+	if err != nil {
+		t.Fatalf("Annotate failed: %v", err)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(annotated), int64(len(annotated)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range reader.File {
+		if file.Name != "word/document.xml" {
+			continue
+		}
+		rc, _ := file.Open()
+		document, _ := io.ReadAll(rc)
+		rc.Close()
+		if got := strings.Count(string(document), `<w:commentRangeStart w:id=`); got != len(report.Issues) {
+			t.Errorf("comment ranges = %d, want %d", got, len(report.Issues))
+		}
+		if !strings.Contains(string(document), `<w:commentRangeStart w:id="0"/><w:r><w:t>Your matter`) {
+			t.Errorf("first issue not placed on its paragraph: %s", document)
+		}
+	}
+}
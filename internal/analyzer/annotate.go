@@ -0,0 +1,91 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/wordml"
+)
+
+// Author of the review comments added to annotated documents
+const (
+	annotationAuthor   = "Complexity analyzer"
+	annotationInitials = "CA"
+)
+
+// minSearchRunes is the shortest snippet text worth finding in the converted document
+const minSearchRunes = 12
+
+// mainStories are the parts whose paragraph numbers carry over to the main
+// document of the converted DOCX
+var mainStories = map[string]bool{
+	"word/document.xml": true,
+	"main":              true,
+	"text":              true,
+}
+
+// Annotate returns a copy of a converted DOCX with a Word comment for each
+// issue of the report, on the paragraph its anchor points to. Issues without an
+// anchor are commented on the first paragraph.
+func Annotate(docx []byte, report *ComplexityReport) ([]byte, error) {
+	if report == nil || len(report.Issues) == 0 {
+		return docx, nil
+	}
+
+	comments := make([]wordml.Comment, 0, len(report.Issues))
+	for _, issue := range report.Issues {
+		text := fmt.Sprintf("[%s] %s", issue.Severity, issue.Description)
+		if issue.Location != "" {
+			text += " (source: " + issue.Location + ")"
+		}
+		c := wordml.Comment{Author: annotationAuthor, Initials: annotationInitials, Text: text}
+		if a := issue.Anchor; a != nil {
+			c.Find = searchText(a.Snippet)
+			if mainStories[a.Part] {
+				c.Paragraph = a.Paragraph
+			}
+		}
+		comments = append(comments, c)
+	}
+
+	annotated, err := wordml.Annotate(docx, comments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to annotate document: %w", err)
+	}
+	return annotated, nil
+}
+
+// searchText returns the longest stretch of a snippet outside field codes,
+// which is plain text likely to survive conversion, or "" when it is too short
+// to find reliably
+func searchText(snippet string) string {
+	var best, current []rune
+	depth := 0
+	keep := func() {
+		segment := []rune(strings.TrimSpace(strings.Trim(string(current), "…")))
+		if len(segment) > len(best) {
+			best = segment
+		}
+		current = current[:0]
+	}
+	for _, r := range snippet {
+		switch {
+		case r == '{':
+			if depth == 0 {
+				keep()
+			}
+			depth++
+		case r == '}':
+			if depth > 0 {
+				depth--
+			}
+		case depth == 0:
+			current = append(current, r)
+		}
+	}
+	keep()
+	if len(best) < minSearchRunes {
+		return ""
+	}
+	return string(best)
+}
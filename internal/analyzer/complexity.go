@@ -17,6 +17,11 @@ import (
 	"regexp"
 	"strings"
 	"unicode"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/field"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/inventory"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/vba"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/wordml"
 )
 
 // Constants for complexity thresholds - CALIBRATED BASED ON TESTING
@@ -31,6 +36,9 @@ const (
 	// Merge field thresholds
 	MergeFieldHighCount = 15
 
+	// Table count threshold
+	MultipleTableThreshold = 10
+
 	// Complexity score levels - ADJUSTED FOR BETTER DISTRIBUTION
 	ComplexityScoreCritical = 120
 	ComplexityScoreHigh     = 60
@@ -46,6 +54,15 @@ const (
 	NestedTableWeight       = 20
 	MultipleTableWeight     = 8
 	ActiveXControlWeight    = 35
+	ContentControlWeight    = 10
+	LegacyFormFieldWeight   = 15
+	TrackedChangeWeight     = 10
+	CommentWeight           = 3
+	TextBoxWeight           = 8
+	SectionLayoutWeight     = 5
+	EmbeddedObjectWeight    = 5
+	ExternalPathWeight      = 15
+	PrecedentScriptWeight   = 20
 
 	// Validation constants
 	MinFormulaLength     = 10
@@ -56,18 +73,22 @@ const (
 	DefaultMaxStoredFieldCodes = 20
 )
 
-// PatternRegistry encapsulates all regex patterns used for analysis
-type PatternRegistry struct {
-	// IF statement patterns
-	IFFieldStart *regexp.Regexp
-	IFFieldFull  *regexp.Regexp
-
-	// Merge field patterns
-	MergeFields       []*regexp.Regexp
-	ComplexMergeField *regexp.Regexp
+// Field types grouped by the complexity they add; fields themselves are read
+// with the field package rather than matched by pattern
+var (
+	mergeFieldTypes = []string{
+		field.TypeMergeField, field.TypeDocVariable, field.TypeDocProperty,
+		field.TypeAsk, field.TypeFillIn, field.TypeRef,
+	}
+	formulaFieldTypes = []string{field.TypeFormula, "FORMULA", "EQ", "CALC", "SYMBOL"}
+	specialFieldTypes = []string{"AUTOTEXT", "INCLUDETEXT", "LINK", "EMBED"}
+)
 
-	// Formula patterns
-	Formulas []*regexp.Regexp
+// PatternRegistry encapsulates the regex patterns used for content that is not
+// a Word field
+type PatternRegistry struct {
+	// Merge placeholders outside fields, e.g. «Client» result text
+	MergePlaceholders []*regexp.Regexp
 
 	// Macro patterns
 	Macros []*regexp.Regexp
@@ -78,37 +99,14 @@ type PatternRegistry struct {
 
 	// ActiveX patterns
 	ActiveX []*regexp.Regexp
-
-	// Field code patterns
-	FieldCodes []*regexp.Regexp
 }
 
 // NewPatternRegistry creates and initializes all regex patterns
 func NewPatternRegistry() *PatternRegistry {
 	return &PatternRegistry{
-		// IF statement patterns
-		IFFieldStart: regexp.MustCompile(`(?i)\{[\s]*IF\b`),
-		IFFieldFull:  regexp.MustCompile(`(?i)\{[\s]*IF\b[^}]*\}`),
-
-		// Merge field patterns for DOT files
-		MergeFields: []*regexp.Regexp{
-			regexp.MustCompile(`(?i)\{[\s]*MERGEFIELD\s+([^}]+)\}`),
-			regexp.MustCompile(`(?i)«([^»]+)»`), // Alternative merge field format
-			regexp.MustCompile(`(?i)\{[\s]*DOCVARIABLE\s+([^}]+)\}`),
-			regexp.MustCompile(`(?i)\{[\s]*DOCPROPERTY\s+([^}]+)\}`),
-			regexp.MustCompile(`(?i)\{[\s]*ASK\s+([^}]+)\}`),
-			regexp.MustCompile(`(?i)\{[\s]*FILLIN\s+([^}]+)\}`),
-			regexp.MustCompile(`(?i)\{[\s]*REF\s+([^}]+)\}`),
-		},
-		ComplexMergeField: regexp.MustCompile(`(?i)\{[\s]*MERGEFIELD\s+[^}]*(\*|\\[\w]+|MERGEFORMAT)[^}]*\}`),
-
-		// Formula patterns
-		Formulas: []*regexp.Regexp{
-			regexp.MustCompile(`(?i)\{[\s]*=\s*[^}]+\}`),       // Field calculations
-			regexp.MustCompile(`(?i)\{[\s]*FORMULA\s+[^}]+\}`), // FORMULA fields
-			regexp.MustCompile(`(?i)\{[\s]*EQ\s+[^}]+\}`),      // Equation fields
-			regexp.MustCompile(`(?i)\{[\s]*CALC\s+[^}]+\}`),    // Calculation fields
-			regexp.MustCompile(`(?i)\{[\s]*SYMBOL\s+[^}]+\}`),  // Symbol fields
+		// Merge field results shown as placeholders
+		MergePlaceholders: []*regexp.Regexp{
+			regexp.MustCompile(`(?i)«([^»]+)»`),
 		},
 
 		// Macro patterns
@@ -137,14 +135,6 @@ func NewPatternRegistry() *PatternRegistry {
 			regexp.MustCompile(`(?i)CommandButton\d+`),
 			regexp.MustCompile(`(?i)Forms\.`),
 		},
-
-		// Field code patterns
-		FieldCodes: []*regexp.Regexp{
-			regexp.MustCompile(`(?i)\{[\s]*AUTOTEXT\s+[^}]+\}`),
-			regexp.MustCompile(`(?i)\{[\s]*INCLUDETEXT\s+[^}]+\}`),
-			regexp.MustCompile(`(?i)\{[\s]*LINK\s+[^}]+\}`),
-			regexp.MustCompile(`(?i)\{[\s]*EMBED\s+[^}]+\}`),
-		},
 	}
 }
 
@@ -234,38 +224,58 @@ func (m *PatternMatcher) MatchPatterns(
 	limit int,
 	validate bool,
 ) (matches []string, validCount, invalidCount int) {
+	var found []string
+	for _, pattern := range patterns {
+		found = append(found, pattern.FindAllString(content, -1)...)
+	}
+	return m.collect(found, limit, validate)
+}
+
+// MatchFields collects the codes of the given fields, in braces, the same way
+// MatchPatterns collects pattern matches
+func (m *PatternMatcher) MatchFields(
+	fields []*field.Field,
+	limit int,
+	validate bool,
+) (matches []string, validCount, invalidCount int) {
+	codes := make([]string, 0, len(fields))
+	for _, f := range fields {
+		codes = append(codes, f.String())
+	}
+	return m.collect(codes, limit, validate)
+}
+
+// collect deduplicates, validates and truncates matches
+func (m *PatternMatcher) collect(found []string, limit int, validate bool) (matches []string, validCount, invalidCount int) {
 	matches = make([]string, 0, limit)
 	seen := make(map[string]bool)
 
-	for _, pattern := range patterns {
-		found := pattern.FindAllString(content, -1)
-		for _, match := range found {
-			// Skip duplicates
-			if seen[match] {
-				continue
-			}
-			seen[match] = true
-
-			if validate {
-				if m.validator.IsValid(match) {
-					validCount++
-					if len(matches) < limit {
-						cleanMatch := m.validator.ExtractClean(match)
-						if len(cleanMatch) > 100 {
-							cleanMatch = cleanMatch[:100] + "..."
-						}
-						matches = append(matches, cleanMatch)
+	for _, match := range found {
+		// Skip duplicates
+		if seen[match] {
+			continue
+		}
+		seen[match] = true
+
+		if validate {
+			if m.validator.IsValid(match) {
+				validCount++
+				if len(matches) < limit {
+					cleanMatch := m.validator.ExtractClean(match)
+					if len(cleanMatch) > 100 {
+						cleanMatch = cleanMatch[:100] + "..."
 					}
-				} else {
-					invalidCount++
+					matches = append(matches, cleanMatch)
 				}
 			} else {
-				if len(matches) < limit {
-					if len(match) > 100 {
-						match = match[:100] + "..."
-					}
-					matches = append(matches, match)
+				invalidCount++
+			}
+		} else {
+			if len(matches) < limit {
+				if len(match) > 100 {
+					match = match[:100] + "..."
 				}
+				matches = append(matches, match)
 			}
 		}
 	}
@@ -290,14 +300,30 @@ type ComplexityReport struct {
 	FieldCodes         []string          `json:"field_codes,omitempty"`
 	ValidFormulas      int               `json:"valid_formulas_count"`
 	InvalidFormulas    int               `json:"invalid_formulas_count"`
+	Detections         map[string]int    `json:"detections,omitempty"` // Occurrences scored per detector
+
+	// VBA project contents, read from the macro storage of Word documents
+	VBAModules     []vba.Module `json:"vba_modules,omitempty"`
+	AutoExecMacros []string     `json:"auto_exec_macros,omitempty"` // Entry points as Module.Procedure
+	MacroObjects   []string     `json:"macro_objects,omitempty"`    // COM classes, DLLs and libraries the macros use
+
+	// Structural elements with their locations, read from the parts of ZIP-based documents
+	Structure *wordml.Structure `json:"structure,omitempty"`
+
+	// Images, OLE objects and external links of the document
+	Inventory *inventory.Inventory `json:"inventory,omitempty"`
+
+	// Scripts that run when the template is used, added by AddScripts
+	Scripts []ScriptDependency `json:"precedent_scripts,omitempty"`
 }
 
 // ComplexityIssue represents a specific complexity concern
 type ComplexityIssue struct {
-	Type        string `json:"type"`
-	Description string `json:"description"`
-	Location    string `json:"location,omitempty"`
-	Severity    string `json:"severity"` // low, medium, high
+	Type        string  `json:"type"`
+	Description string  `json:"description"`
+	Location    string  `json:"location,omitempty"`
+	Severity    string  `json:"severity"`         // low, medium, high
+	Anchor      *Anchor `json:"anchor,omitempty"` // Where the first occurrence is, when known
 }
 
 // ComplexityConfig allows customization of thresholds and limits
@@ -310,6 +336,9 @@ type ComplexityConfig struct {
 	// Merge field thresholds
 	MergeFieldHighCount int
 
+	// Table count threshold; zero uses MultipleTableThreshold
+	MultipleTableThreshold int
+
 	// Score thresholds
 	CriticalScore int
 	HighScore     int
@@ -325,6 +354,45 @@ type ComplexityConfig struct {
 
 	// Pattern registry (optional custom patterns)
 	Patterns *PatternRegistry
+
+	// Detector weights and severities by name; detectors missing from the map
+	// use DefaultDetectors
+	Detectors map[string]Detector
+}
+
+// Detector configures the issue one detector reports
+type Detector struct {
+	Enabled  bool
+	Weight   int    // Score added per issue, or per occurrence for counting detectors
+	Severity string // low, medium or high
+}
+
+// DefaultDetectors returns the built-in detectors, keyed by name. Detector names
+// match the issue type they report, except moderate_nested_conditionals, which
+// reports nested_conditionals below the high nesting threshold.
+func DefaultDetectors() map[string]Detector {
+	return map[string]Detector{
+		"nested_conditionals":          {true, NestedIfHighWeight, "high"},     // Per level of nesting
+		"moderate_nested_conditionals": {true, NestedIfMediumWeight, "medium"}, // Per level of nesting
+		"multiple_conditionals":        {true, MultipleIfWeight, "medium"},     // Per IF field
+		"complex_merge_fields":         {true, ComplexMergeFieldWeight, "medium"},
+		"numerous_merge_fields":        {true, 1, "low"}, // Per merge field
+		"vba_macros":                   {true, MacroDetectionWeight, "high"},
+		"formulas":                     {true, FormulaWeight, "medium"}, // Per valid formula
+		"nested_tables":                {true, NestedTableWeight, "medium"},
+		"multiple_tables":              {true, MultipleTableWeight, "low"},
+		"content_controls":             {true, ContentControlWeight, "medium"},
+		"legacy_form_fields":           {true, LegacyFormFieldWeight, "medium"},
+		"tracked_changes":              {true, TrackedChangeWeight, "medium"},
+		"comments":                     {true, CommentWeight, "low"},
+		"text_boxes":                   {true, TextBoxWeight, "low"},
+		"section_layout":               {true, SectionLayoutWeight, "low"},
+		"activex_controls":             {true, ActiveXControlWeight, "high"},
+		"field_codes":                  {true, 2, "low"}, // Per special field
+		"embedded_objects":             {true, EmbeddedObjectWeight, "low"},
+		"external_paths":               {true, ExternalPathWeight, "medium"},
+		"precedent_scripts":            {true, PrecedentScriptWeight, "high"}, // Per script, see AddScripts
+	}
 }
 
 // DefaultConfig returns the default configuration
@@ -334,6 +402,7 @@ func DefaultConfig() *ComplexityConfig {
 		NestedIfMediumThreshold: NestedIfMediumThreshold,
 		IfCountHighThreshold:    IfCountHighThreshold,
 		MergeFieldHighCount:     MergeFieldHighCount,
+		MultipleTableThreshold:  MultipleTableThreshold,
 		CriticalScore:           ComplexityScoreCritical,
 		HighScore:               ComplexityScoreHigh,
 		MediumScore:             ComplexityScoreMedium,
@@ -342,6 +411,7 @@ func DefaultConfig() *ComplexityConfig {
 		ValidateFormulas:        true,
 		ExtractFieldCodes:       true,
 		Patterns:                nil, // Use default patterns
+		Detectors:               DefaultDetectors(),
 	}
 }
 
@@ -354,13 +424,15 @@ func DefaultConfig() *ComplexityConfig {
 //	    log.Fatal(err)
 //	}
 //	report := analyzer.AnalyzeComplexity(content)
+//
+// The active configuration is used; see SetActiveConfig and RulesWatcher.
 func AnalyzeComplexity(content []byte) *ComplexityReport {
-	return AnalyzeComplexityWithConfig(context.Background(), content, DefaultConfig())
+	return AnalyzeComplexityWithConfig(context.Background(), content, ActiveConfig())
 }
 
 // AnalyzeComplexityWithContext analyzes with context support for cancellation
 func AnalyzeComplexityWithContext(ctx context.Context, content []byte) *ComplexityReport {
-	return AnalyzeComplexityWithConfig(ctx, content, DefaultConfig())
+	return AnalyzeComplexityWithConfig(ctx, content, ActiveConfig())
 }
 
 // AnalyzeComplexityWithConfig analyzes with custom configuration and context
@@ -399,13 +471,15 @@ func AnalyzeComplexityWithConfig(ctx context.Context, content []byte, config *Co
 	docInfo, err := extractor.AnalyzeDocument(content)
 
 	var contentStr string
+	var fields []*field.Field
 	if err != nil {
 		// If extraction fails, try to use raw content
 		contentStr = string(content)
 		report.ParseErrors = append(report.ParseErrors, fmt.Sprintf("Document extraction warning: %v", err))
 	} else {
-		// Use extracted text for analysis
+		// Use extracted text and fields for analysis
 		contentStr = docInfo.Text
+		fields = docInfo.Fields
 
 		// Add format information
 		formatName := "Unknown"
@@ -420,62 +494,79 @@ func AnalyzeComplexityWithConfig(ctx context.Context, content []byte, config *Co
 			formatName = "Plain Text"
 		}
 
-		// Check if we extracted meaningful content
-		if len(contentStr) < 100 && docInfo.Format != FormatPlainText {
+		// Check if we extracted meaningful content; Word binary text read from the
+		// piece table is authoritative even when short
+		if len(contentStr) < 100 && docInfo.Format != FormatPlainText && docInfo.Stories == nil {
 			report.ParseErrors = append(report.ParseErrors, fmt.Sprintf("Limited text extraction from %s format (only %d chars)", formatName, len(contentStr)))
 			// Try fallback extraction
 			fallbackText := extractor.extractReadableText(content)
 			if len(fallbackText) > len(contentStr) {
 				contentStr = fallbackText
+				fields = nil
 			}
 		}
 
-		// Add macro detection from document info
-		if docInfo.HasMacros {
-			report.Macros = append(report.Macros, "VBA Project detected in document")
-			report.NeedsReview = true
-		}
-
 		// Add extracted field codes
 		if len(docInfo.FieldCodes) > 0 {
 			report.FieldCodes = append(report.FieldCodes, docInfo.FieldCodes...)
 		}
 	}
 
+	var anchors *anchorIndex
+	if fields == nil {
+		fields = field.ParseText(contentStr)
+		anchors = newAnchorIndex()
+		anchors.add(indexRaw("text", contentStr, false), fields)
+	} else if docInfo != nil {
+		anchors = docInfo.anchors
+	}
+
 	analyzer := &complexityAnalyzer{
 		patterns:       patterns,
 		config:         config,
 		patternMatcher: NewPatternMatcher(),
 		validator:      NewContentValidator(),
+		anchors:        anchors,
+		content:        contentStr,
 	}
 
 	// Run all analyzers with error handling
-	if err := analyzer.analyzeNestedIfs(ctx, contentStr, report); err != nil {
+	if err := analyzer.analyzeNestedIfs(ctx, fields, report); err != nil {
 		report.ParseErrors = append(report.ParseErrors, fmt.Sprintf("IF analysis error: %v", err))
 	}
 
-	if err := analyzer.analyzeMergeFields(ctx, contentStr, report); err != nil {
+	if err := analyzer.analyzeMergeFields(ctx, contentStr, fields, report); err != nil {
 		report.ParseErrors = append(report.ParseErrors, fmt.Sprintf("Merge field analysis error: %v", err))
 	}
 
-	if err := analyzer.detectMacros(ctx, contentStr, report); err != nil {
+	if err := analyzer.detectMacros(ctx, contentStr, docInfo, report); err != nil {
 		report.ParseErrors = append(report.ParseErrors, fmt.Sprintf("Macro detection error: %v", err))
 	}
 
-	if err := analyzer.detectFormulas(ctx, contentStr, report); err != nil {
+	if err := analyzer.detectFormulas(ctx, fields, report); err != nil {
 		report.ParseErrors = append(report.ParseErrors, fmt.Sprintf("Formula detection error: %v", err))
 	}
 
-	if err := analyzer.detectTables(ctx, contentStr, report); err != nil {
+	if err := analyzer.detectTables(ctx, contentStr, docInfo, report); err != nil {
 		report.ParseErrors = append(report.ParseErrors, fmt.Sprintf("Table detection error: %v", err))
 	}
 
+	if docInfo != nil && docInfo.Structure != nil {
+		if err := analyzer.detectStructure(ctx, docInfo.Structure, report); err != nil {
+			report.ParseErrors = append(report.ParseErrors, fmt.Sprintf("Structure analysis error: %v", err))
+		}
+	}
+
+	if docInfo != nil && docInfo.Inventory != nil {
+		analyzer.detectInventory(ctx, docInfo.Inventory, report)
+	}
+
 	if err := analyzer.detectActiveX(ctx, contentStr, report); err != nil {
 		report.ParseErrors = append(report.ParseErrors, fmt.Sprintf("ActiveX detection error: %v", err))
 	}
 
 	if config.ExtractFieldCodes {
-		analyzer.detectFieldCodes(ctx, contentStr, report)
+		analyzer.detectFieldCodes(ctx, fields, report)
 	}
 
 	// Calculate final score and determine review needs
@@ -491,10 +582,32 @@ type complexityAnalyzer struct {
 	config         *ComplexityConfig
 	patternMatcher *PatternMatcher
 	validator      *ContentValidator
+
+	// Issue anchors: fields and structure map to their part, pattern matches to
+	// the extracted text
+	anchors      *anchorIndex
+	content      string
+	contentIndex *textIndex
+}
+
+// textAnchor anchors an offset of the extracted text
+func (a *complexityAnalyzer) textAnchor(offset int) *Anchor {
+	if a.contentIndex == nil {
+		a.contentIndex = indexRaw("text", a.content, false)
+	}
+	return a.contentIndex.at(offset)
+}
+
+// firstField anchors the first of the fields
+func (a *complexityAnalyzer) firstField(fields []*field.Field) *Anchor {
+	if len(fields) == 0 {
+		return nil
+	}
+	return a.anchors.field(fields[0])
 }
 
 // analyzeNestedIfs detects and measures nested IF statement depth
-func (a *complexityAnalyzer) analyzeNestedIfs(ctx context.Context, content string, report *ComplexityReport) error {
+func (a *complexityAnalyzer) analyzeNestedIfs(ctx context.Context, fields []*field.Field, report *ComplexityReport) error {
 	// Check context
 	select {
 	case <-ctx.Done():
@@ -502,83 +615,46 @@ func (a *complexityAnalyzer) analyzeNestedIfs(ctx context.Context, content strin
 	default:
 	}
 
-	// Find all IF field occurrences
-	ifMatches := a.patterns.IFFieldFull.FindAllString(content, -1)
-	report.TotalIfStatements = len(ifMatches)
+	report.TotalIfStatements = len(field.OfType(fields, field.TypeIF))
+	maxDepth := a.calculateIfNestingDepth(fields)
+	report.NestedIfDepth = maxDepth
 
-	// Analyze nesting depth with improved algorithm
-	maxDepth, err := a.calculateIfNestingDepth(content)
-	if err != nil {
-		return fmt.Errorf("failed to calculate IF nesting depth: %w", err)
+	// Anchor nesting at the first top-level field reaching the deepest nesting
+	var deepest *Anchor
+	for _, f := range fields {
+		if field.MaxDepth([]*field.Field{f}, field.TypeIF) == maxDepth {
+			deepest = a.anchors.field(f)
+			break
+		}
 	}
 
-	report.NestedIfDepth = maxDepth
-
 	// Add issue for nested conditionals
 	if maxDepth > a.config.NestedIfHighThreshold {
-		a.addIssue(report, "nested_conditionals",
-			fmt.Sprintf("Deep nesting of IF statements detected (depth: %d)", maxDepth),
-			"high", maxDepth*NestedIfHighWeight)
+		a.addIssueAt(report, "nested_conditionals",
+			fmt.Sprintf("Deep nesting of IF statements detected (depth: %d)", maxDepth), deepest, maxDepth)
 	} else if maxDepth > a.config.NestedIfMediumThreshold {
-		a.addIssue(report, "nested_conditionals",
-			fmt.Sprintf("Moderate nesting of IF statements detected (depth: %d)", maxDepth),
-			"medium", maxDepth*NestedIfMediumWeight)
+		a.addIssueAt(report, "moderate_nested_conditionals",
+			fmt.Sprintf("Moderate nesting of IF statements detected (depth: %d)", maxDepth), deepest, maxDepth)
 	}
 
 	// Check for high number of IF statements
 	if report.TotalIfStatements > a.config.IfCountHighThreshold {
-		a.addIssue(report, "multiple_conditionals",
+		a.addIssueAt(report, "multiple_conditionals",
 			fmt.Sprintf("High number of conditional statements (%d)", report.TotalIfStatements),
-			"medium", report.TotalIfStatements*MultipleIfWeight)
+			a.firstField(field.OfType(fields, field.TypeIF)), report.TotalIfStatements)
 	}
 
 	return nil
 }
 
-// calculateIfNestingDepth calculates IF nesting with improved accuracy
-func (a *complexityAnalyzer) calculateIfNestingDepth(content string) (int, error) {
-	// Find all IF field start positions using proper regex
-	ifStarts := a.patterns.IFFieldStart.FindAllStringIndex(content, -1)
-	if len(ifStarts) == 0 {
-		return 0, nil
-	}
-
-	maxDepth := 0
-	ifNestPattern := a.patterns.IFFieldStart
-
-	for _, ifStart := range ifStarts {
-		depth := 1
-		braceCount := 1
-		position := ifStart[1]
-
-		// Track brace depth to find the end of this IF field
-		for position < len(content) && braceCount > 0 {
-			switch content[position] {
-			case '{':
-				braceCount++
-				// Check if this is another IF field using regex
-				if position+10 < len(content) {
-					testStr := content[position:minInt(position+20, len(content))]
-					if ifNestPattern.MatchString(testStr) {
-						depth++
-					}
-				}
-			case '}':
-				braceCount--
-			}
-			position++
-		}
-
-		if depth > maxDepth {
-			maxDepth = depth
-		}
-	}
-
-	return maxDepth, nil
+// calculateIfNestingDepth returns the deepest nesting of IF fields, counting
+// IFs nested through other fields such as "{ IF a "{ MERGEFIELD { IF b ... } }" }"
+func (a *complexityAnalyzer) calculateIfNestingDepth(fields []*field.Field) int {
+	return field.MaxDepth(fields, field.TypeIF)
 }
 
 // analyzeMergeFields detects and analyzes merge fields
-func (a *complexityAnalyzer) analyzeMergeFields(ctx context.Context, content string, report *ComplexityReport) error {
+func (a *complexityAnalyzer) analyzeMergeFields(ctx context.Context, content string, fields []*field.Field, report *ComplexityReport) error {
 	// Check context
 	select {
 	case <-ctx.Done():
@@ -586,62 +662,88 @@ func (a *complexityAnalyzer) analyzeMergeFields(ctx context.Context, content str
 	default:
 	}
 
-	totalMergeFields := 0
-
-	// Use pattern matcher for merge fields
-	for _, pattern := range a.patterns.MergeFields {
-		matches := pattern.FindAllStringSubmatch(content, -1)
-		for _, match := range matches {
-			totalMergeFields++
-			if len(match) > 1 && a.validator.IsValid(match[1]) {
-				// Field name is valid
-			}
+	mergeFields := field.OfType(fields, mergeFieldTypes...)
+	totalMergeFields := len(mergeFields)
+	firstMerge := a.firstField(mergeFields)
+	for _, pattern := range a.patterns.MergePlaceholders {
+		matches := pattern.FindAllStringIndex(content, -1)
+		totalMergeFields += len(matches)
+		if firstMerge == nil && len(matches) > 0 {
+			firstMerge = a.textAnchor(matches[0][0])
 		}
 	}
-
 	report.TotalMergeFields = totalMergeFields
 
-	// Find complex merge fields
-	complexMatches, _, _ := a.patternMatcher.MatchPatterns(
-		content,
-		[]*regexp.Regexp{a.patterns.ComplexMergeField},
-		a.config.MaxStoredFormulas,
-		true,
-	)
+	// Merge fields with formatting switches or extra arguments need checking
+	var complexFields []*field.Field
+	for _, f := range field.OfType(fields, field.TypeMergeField) {
+		if len(f.Switches) > 0 || len(f.Args) > 1 {
+			complexFields = append(complexFields, f)
+		}
+	}
+	complexMatches, _, _ := a.patternMatcher.MatchFields(complexFields, a.config.MaxStoredFormulas, true)
 	report.ComplexMergeFields = complexMatches
 
 	if len(report.ComplexMergeFields) > 0 {
-		a.addIssue(report, "complex_merge_fields",
+		a.addIssueAt(report, "complex_merge_fields",
 			fmt.Sprintf("Complex merge fields with formatting detected (%d)", len(report.ComplexMergeFields)),
-			"medium", len(report.ComplexMergeFields)*ComplexMergeFieldWeight)
+			a.firstField(complexFields), len(report.ComplexMergeFields))
 	}
 
 	if report.TotalMergeFields > a.config.MergeFieldHighCount {
-		a.addIssue(report, "numerous_merge_fields",
+		a.addIssueAt(report, "numerous_merge_fields",
 			fmt.Sprintf("Large number of merge fields detected (%d)", report.TotalMergeFields),
-			"low", report.TotalMergeFields)
+			firstMerge, report.TotalMergeFields)
 	}
 
 	return nil
 }
 
-// detectMacros detects VBA macros
-func (a *complexityAnalyzer) detectMacros(ctx context.Context, content string, report *ComplexityReport) error {
+// detectMacros reports the VBA project of Word documents. Code patterns are only
+// matched in plain text, where document prose would otherwise pass for macros.
+func (a *complexityAnalyzer) detectMacros(ctx context.Context, content string, docInfo *DocumentInfo, report *ComplexityReport) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
 	}
 
-	macros, _, _ := a.patternMatcher.MatchPatterns(
-		content, a.patterns.Macros, a.config.MaxStoredFormulas, true,
-	)
-	report.Macros = macros
+	var anchor *Anchor
+	switch {
+	case docInfo != nil && docInfo.VBA != nil:
+		anchor = vbaAnchor(docInfo.VBA.Modules)
+		for _, m := range docInfo.VBA.Modules {
+			for _, proc := range m.Procedures {
+				report.Macros = append(report.Macros, m.Name+"."+proc)
+			}
+		}
+		if len(report.Macros) == 0 && len(docInfo.VBA.Modules) > 0 {
+			report.Macros = append(report.Macros, "VBA Project detected in document")
+		}
+		report.VBAModules = docInfo.VBA.Modules
+		report.AutoExecMacros = docInfo.VBA.AutoExec()
+		report.MacroObjects = docInfo.VBA.Objects()
+	case docInfo != nil && docInfo.HasMacros && docInfo.Format != FormatPlainText && docInfo.Format != FormatUnknown:
+		// The macro storage exists but its project could not be read
+		report.Macros = append(report.Macros, "VBA Project detected in document")
+	case docInfo == nil || docInfo.Format == FormatPlainText || docInfo.Format == FormatUnknown:
+		macros, _, _ := a.patternMatcher.MatchPatterns(
+			content, a.patterns.Macros, a.config.MaxStoredFormulas, true,
+		)
+		report.Macros = macros
+		first := -1
+		for _, pattern := range a.patterns.Macros {
+			if loc := pattern.FindStringIndex(content); loc != nil && (first < 0 || loc[0] < first) {
+				first = loc[0]
+			}
+		}
+		if first >= 0 {
+			anchor = a.textAnchor(first)
+		}
+	}
 
-	if len(report.Macros) > 0 {
-		a.addIssue(report, "vba_macros",
-			fmt.Sprintf("VBA macros detected in document (%d unique)", len(report.Macros)),
-			"high", MacroDetectionWeight)
+	if len(report.Macros) > 0 && a.addIssueAt(report, "vba_macros",
+		fmt.Sprintf("VBA macros detected in document (%d unique)", len(report.Macros)), anchor, 1) {
 		report.NeedsReview = true
 	}
 
@@ -649,15 +751,16 @@ func (a *complexityAnalyzer) detectMacros(ctx context.Context, content string, r
 }
 
 // detectFormulas detects formulas with validation
-func (a *complexityAnalyzer) detectFormulas(ctx context.Context, content string, report *ComplexityReport) error {
+func (a *complexityAnalyzer) detectFormulas(ctx context.Context, fields []*field.Field, report *ComplexityReport) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
 	}
 
-	formulas, validCount, invalidCount := a.patternMatcher.MatchPatterns(
-		content, a.patterns.Formulas, a.config.MaxStoredFormulas, a.config.ValidateFormulas,
+	formulaFields := field.OfType(fields, formulaFieldTypes...)
+	formulas, validCount, invalidCount := a.patternMatcher.MatchFields(
+		formulaFields, a.config.MaxStoredFormulas, a.config.ValidateFormulas,
 	)
 
 	report.Formulas = formulas
@@ -665,40 +768,144 @@ func (a *complexityAnalyzer) detectFormulas(ctx context.Context, content string,
 	report.InvalidFormulas = invalidCount
 
 	if validCount > 0 {
-		a.addIssue(report, "formulas",
+		a.addIssueAt(report, "formulas",
 			fmt.Sprintf("Valid formulas and calculations detected (%d valid, %d invalid)", validCount, invalidCount),
-			"medium", validCount*FormulaWeight)
+			a.firstField(formulaFields), validCount)
 	}
 
 	return nil
 }
 
-// detectTables detects complex table structures
-func (a *complexityAnalyzer) detectTables(ctx context.Context, content string, report *ComplexityReport) error {
+// detectTables detects complex table structures. ZIP-based documents are
+// measured from their WordprocessingML and RTF from its rows; other content
+// falls back to markup patterns.
+func (a *complexityAnalyzer) detectTables(ctx context.Context, content string, docInfo *DocumentInfo, report *ComplexityReport) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
 	}
 
-	tables := a.patterns.Table.FindAllString(content, -1)
-	nestedTables := a.patterns.NestedTable.FindAllString(content, -1)
+	var tables, nestedTables int
+	var firstTable, firstNested *Anchor
+	if docInfo != nil && docInfo.Structure != nil {
+		nested := docInfo.Structure.OfKind(wordml.KindNestedTable)
+		nestedTables = len(nested)
+		tables = docInfo.Structure.Count(wordml.KindTable) + nestedTables
+		if nestedTables > 0 {
+			firstNested = a.anchors.location(nested[0].Location)
+		}
+		if top := docInfo.Structure.OfKind(wordml.KindTable); len(top) > 0 {
+			firstTable = a.anchors.location(top[0].Location)
+		}
+	} else if docInfo != nil && docInfo.Format == FormatRTF {
+		tables = docInfo.TableCount
+	} else {
+		matches := a.patterns.Table.FindAllStringIndex(content, -1)
+		tables = len(matches)
+		if tables > 0 {
+			firstTable = a.textAnchor(matches[0][0])
+		}
+		nested := a.patterns.NestedTable.FindAllStringIndex(content, -1)
+		nestedTables = len(nested)
+		if nestedTables > 0 {
+			firstNested = a.textAnchor(nested[0][0])
+		}
+	}
+
+	if nestedTables > 0 {
+		a.addIssueAt(report, "nested_tables",
+			fmt.Sprintf("Nested table structures detected (%d)", nestedTables),
+			firstNested, 1)
+	}
+
+	threshold := a.config.MultipleTableThreshold
+	if threshold == 0 {
+		threshold = MultipleTableThreshold
+	}
+	if tables > threshold {
+		a.addIssueAt(report, "multiple_tables",
+			fmt.Sprintf("Multiple table structures detected (%d)", tables), firstTable, 1)
+	}
+
+	return nil
+}
 
-	if len(nestedTables) > 0 {
-		a.addIssue(report, "nested_tables",
-			fmt.Sprintf("Nested table structures detected (%d)", len(nestedTables)),
-			"medium", NestedTableWeight)
+// detectStructure reports content controls, legacy form fields, tracked changes,
+// comments, text boxes and mixed page layouts found in the document parts
+func (a *complexityAnalyzer) detectStructure(ctx context.Context, structure *wordml.Structure, report *ComplexityReport) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	report.Structure = structure
+
+	kinds := []struct {
+		kind, detector, description string
+	}{
+		{wordml.KindContentControl, "content_controls", "Content controls detected"},
+		{wordml.KindFormField, "legacy_form_fields", "Legacy form fields detected"},
+		{wordml.KindRevision, "tracked_changes", "Unresolved tracked changes detected"},
+		{wordml.KindComment, "comments", "Comments detected"},
+		{wordml.KindTextBox, "text_boxes", "Text boxes detected"},
+	}
+	for _, k := range kinds {
+		elements := structure.OfKind(k.kind)
+		if len(elements) == 0 {
+			continue
+		}
+		a.addIssueAt(report, k.detector,
+			fmt.Sprintf("%s (%d)", k.description, len(elements)),
+			a.anchors.location(elements[0].Location), 1)
 	}
 
-	if len(tables) > 10 {
-		a.addIssue(report, "multiple_tables",
-			fmt.Sprintf("Multiple table structures detected (%d)", len(tables)),
-			"low", MultipleTableWeight)
+	// Sections that change orientation or paper size rarely survive conversion intact
+	for i := 1; i < len(structure.Sections); i++ {
+		first, section := structure.Sections[0], structure.Sections[i]
+		if section.Orientation != first.Orientation || section.PageWidth != first.PageWidth || section.PageHeight != first.PageHeight {
+			a.addIssueAt(report, "section_layout",
+				fmt.Sprintf("Sections with different page layouts detected (%d sections)", len(structure.Sections)),
+				a.anchors.location(section.Location), 1)
+			break
+		}
 	}
 
 	return nil
 }
 
+// detectInventory reports embedded OLE objects and links to network shares or
+// local paths, which break once the template leaves its original environment
+func (a *complexityAnalyzer) detectInventory(ctx context.Context, inv *inventory.Inventory, report *ComplexityReport) {
+	select {
+	case <-ctx.Done():
+		return
+	default:
+	}
+
+	if !inv.Empty() {
+		report.Inventory = inv
+	}
+
+	if len(inv.Objects) > 0 {
+		a.addIssueAt(report, "embedded_objects",
+			fmt.Sprintf("Embedded OLE objects detected (%d)", len(inv.Objects)),
+			&Anchor{Part: inv.Objects[0].Name}, 1)
+	}
+
+	if paths := inv.ExternalPaths(); len(paths) > 0 {
+		// Field links are located by their code, relationships by their part
+		anchor := a.anchors.fieldCode(paths[0].Location)
+		if anchor == nil && paths[0].Location != "" {
+			anchor = &Anchor{Part: paths[0].Location}
+		}
+		a.addIssueAt(report, "external_paths",
+			fmt.Sprintf("Links to network shares or local paths detected (%d), e.g. %s", len(paths), paths[0].Target),
+			anchor, 1)
+	}
+}
+
 // detectActiveX detects ActiveX controls
 func (a *complexityAnalyzer) detectActiveX(ctx context.Context, content string, report *ComplexityReport) error {
 	select {
@@ -707,20 +914,19 @@ func (a *complexityAnalyzer) detectActiveX(ctx context.Context, content string,
 	default:
 	}
 
+	var anchor *Anchor
 	hasActiveX := false
 	for _, pattern := range a.patterns.ActiveX {
-		if matches := pattern.FindAllString(content, 1); len(matches) > 0 {
-			if a.validator.IsValid(matches[0]) {
+		if loc := pattern.FindStringIndex(content); loc != nil {
+			if a.validator.IsValid(content[loc[0]:loc[1]]) {
 				hasActiveX = true
+				anchor = a.textAnchor(loc[0])
 				break
 			}
 		}
 	}
 
-	if hasActiveX {
-		a.addIssue(report, "activex_controls",
-			"ActiveX controls detected",
-			"high", ActiveXControlWeight)
+	if hasActiveX && a.addIssueAt(report, "activex_controls", "ActiveX controls detected", anchor, 1) {
 		report.NeedsReview = true
 	}
 
@@ -728,33 +934,62 @@ func (a *complexityAnalyzer) detectActiveX(ctx context.Context, content string,
 }
 
 // detectFieldCodes detects special field codes
-func (a *complexityAnalyzer) detectFieldCodes(ctx context.Context, content string, report *ComplexityReport) {
+func (a *complexityAnalyzer) detectFieldCodes(ctx context.Context, fields []*field.Field, report *ComplexityReport) {
 	select {
 	case <-ctx.Done():
 		return
 	default:
 	}
 
-	fieldCodes, _, _ := a.patternMatcher.MatchPatterns(
-		content, a.patterns.FieldCodes, a.config.MaxStoredFieldCodes, true,
-	)
+	special := field.OfType(fields, specialFieldTypes...)
+	fieldCodes, _, _ := a.patternMatcher.MatchFields(special, a.config.MaxStoredFieldCodes, true)
 	report.FieldCodes = fieldCodes
 
 	if len(report.FieldCodes) > 0 {
-		a.addIssue(report, "field_codes",
+		a.addIssueAt(report, "field_codes",
 			fmt.Sprintf("Special field codes detected (%d)", len(report.FieldCodes)),
-			"low", len(report.FieldCodes)*2)
+			a.firstField(special), len(report.FieldCodes))
 	}
 }
 
-// addIssue adds an issue to the report and updates the score
-func (a *complexityAnalyzer) addIssue(report *ComplexityReport, issueType, description, severity string, scoreIncrease int) {
+// detectorIssueTypes holds the issue types of detectors not named after theirs
+var detectorIssueTypes = map[string]string{
+	"moderate_nested_conditionals": "nested_conditionals",
+}
+
+// detector returns the configured detector, falling back to the default
+func (a *complexityAnalyzer) detector(name string) Detector {
+	if d, ok := a.config.Detectors[name]; ok {
+		return d
+	}
+	return DefaultDetectors()[name]
+}
+
+// addIssueAt reports an issue of a detector, anchored at its first occurrence,
+// and adds the detector's weight per occurrence to the score. A nil anchor
+// leaves the location empty. It returns false when the detector is disabled.
+func (a *complexityAnalyzer) addIssueAt(report *ComplexityReport, detector, description string, anchor *Anchor, occurrences int) bool {
+	d := a.detector(detector)
+	if !d.Enabled {
+		return false
+	}
+	issueType := detector
+	if t, ok := detectorIssueTypes[detector]; ok {
+		issueType = t
+	}
 	report.Issues = append(report.Issues, ComplexityIssue{
 		Type:        issueType,
 		Description: description,
-		Severity:    severity,
+		Location:    anchor.String(),
+		Severity:    d.Severity,
+		Anchor:      anchor,
 	})
-	report.Score += scoreIncrease
+	if report.Detections == nil {
+		report.Detections = make(map[string]int)
+	}
+	report.Detections[detector] += occurrences
+	report.Score += d.Weight * occurrences
+	return true
 }
 
 // calculateScore determines final score and complexity level
@@ -765,8 +1000,11 @@ func (a *complexityAnalyzer) calculateScore(report *ComplexityReport) {
 			report.Score = report.Score * report.ValidFormulas / divisor
 		}
 	}
+	a.assignLevel(report)
+}
 
-	// Determine complexity level
+// assignLevel sets the complexity level and review need from the score
+func (a *complexityAnalyzer) assignLevel(report *ComplexityReport) {
 	switch {
 	case report.Score >= a.config.CriticalScore:
 		report.Level = "critical"
@@ -787,7 +1025,7 @@ func (a *complexityAnalyzer) calculateScore(report *ComplexityReport) {
 	}
 
 	// Force review for certain conditions
-	if report.NestedIfDepth > a.config.NestedIfHighThreshold || len(report.Macros) > 0 {
+	if report.NestedIfDepth > a.config.NestedIfHighThreshold || (len(report.Macros) > 0 && a.detector("vba_macros").Enabled) {
 		report.NeedsReview = true
 	}
 }
@@ -824,12 +1062,24 @@ func (a *complexityAnalyzer) generateRecommendations(report *ComplexityReport) {
 			"Document contains binary or corrupted formula data - verify source file integrity")
 	}
 
-	// Check for ActiveX controls
+	// Check for ActiveX controls and structural issues
 	for _, issue := range report.Issues {
-		if issue.Type == "activex_controls" {
+		switch issue.Type {
+		case "activex_controls":
 			report.Recommendations = append(report.Recommendations,
 				"ActiveX controls require manual replacement or removal")
-			break
+		case "legacy_form_fields":
+			report.Recommendations = append(report.Recommendations,
+				"Rebuild legacy form fields as content controls")
+		case "tracked_changes":
+			report.Recommendations = append(report.Recommendations,
+				"Accept or reject tracked changes in the template before conversion")
+		case "embedded_objects":
+			report.Recommendations = append(report.Recommendations,
+				"Check that embedded objects open and render after conversion")
+		case "external_paths":
+			report.Recommendations = append(report.Recommendations,
+				"Repoint links to network shares and local paths, including the attached template")
 		}
 	}
 
@@ -848,11 +1098,3 @@ func (a *complexityAnalyzer) generateRecommendations(report *ComplexityReport) {
 			"Some analysis features encountered errors - manual review recommended")
 	}
 }
-
-// Helper function for minimum integer
-func minInt(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
@@ -1,9 +1,14 @@
 package analyzer
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"io"
 	"strings"
 	"testing"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/field"
 )
 
 func TestAnalyzeComplexity(t *testing.T) {
@@ -161,11 +166,7 @@ func TestCalculateIfNestingDepth(t *testing.T) {
 				patterns: NewPatternRegistry(),
 				config:   DefaultConfig(),
 			}
-			depth, err := analyzer.calculateIfNestingDepth(tt.content)
-			if err != nil {
-				t.Fatalf("Unexpected error: %v", err)
-			}
-
+			depth := analyzer.calculateIfNestingDepth(field.ParseText(tt.content))
 			if depth != tt.expected {
 				t.Errorf("Expected depth %d, got %d for content: %s", tt.expected, depth, tt.content)
 			}
@@ -177,16 +178,12 @@ func TestPreCompiledPatterns(t *testing.T) {
 	// Test that patterns are pre-compiled (not nil)
 	patterns := NewPatternRegistry()
 
-	if patterns.IFFieldStart == nil {
-		t.Error("IFFieldStart should be pre-compiled")
-	}
-
-	if len(patterns.MergeFields) == 0 {
-		t.Error("MergeFields should be pre-compiled")
+	if len(patterns.MergePlaceholders) == 0 {
+		t.Error("MergePlaceholders should be pre-compiled")
 	}
 
-	if len(patterns.Formulas) == 0 {
-		t.Error("Formulas should be pre-compiled")
+	if len(patterns.ActiveX) == 0 {
+		t.Error("ActiveX should be pre-compiled")
 	}
 
 	if len(patterns.Macros) == 0 {
@@ -264,6 +261,56 @@ func TestMacroDeduplication(t *testing.T) {
 	}
 }
 
+func TestMacrosIgnoreDocumentProse(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>`+
+		`<w:p><w:r><w:t>The Function clause (see Sub clause (b)) applies to AutoOpen accounts.</w:t></w:r></w:p>`+
+		`</w:body></w:document>`)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	report := AnalyzeComplexity(buf.Bytes())
+	if len(report.Macros) != 0 || len(report.VBAModules) != 0 {
+		t.Errorf("Macros = %q, want none for a document without a VBA project", report.Macros)
+	}
+}
+
+func TestStructuralIssues(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>`+
+		`<w:tbl><w:tr><w:tc><w:tbl><w:tr><w:tc><w:p/></w:tc></w:tr></w:tbl></w:tc></w:tr></w:tbl>`+
+		`<w:p><w:ins w:id="1" w:author="Jane"><w:r><w:t>added</w:t></w:r></w:ins></w:p>`+
+		`</w:body></w:document>`)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	report := AnalyzeComplexity(buf.Bytes())
+	locations := make(map[string]string)
+	for _, issue := range report.Issues {
+		locations[issue.Type] = issue.Location
+	}
+	for _, issueType := range []string{"nested_tables", "tracked_changes"} {
+		if !strings.HasPrefix(locations[issueType], "word/document.xml:1:") {
+			t.Errorf("%s issue location = %q, want a position in word/document.xml", issueType, locations[issueType])
+		}
+	}
+	if report.Structure == nil || report.Structure.MaxTableDepth != 2 {
+		t.Errorf("Structure = %+v, want table depth 2", report.Structure)
+	}
+}
+
 func TestFormulaLimiting(t *testing.T) {
 	// Create content with many formulas
 	var formulas []string
@@ -359,6 +406,6 @@ func BenchmarkIfNestingDepth(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, _ = analyzer.calculateIfNestingDepth(content)
+		_ = analyzer.calculateIfNestingDepth(field.ParseText(content))
 	}
 }
@@ -4,11 +4,19 @@ package analyzer
 import (
 	"archive/zip"
 	"bytes"
-	"encoding/binary"
 	"fmt"
 	"io"
 	"regexp"
 	"strings"
+	"time"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/field"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/inventory"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/msword"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/ole"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/rtf"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/vba"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/wordml"
 )
 
 // DocumentFormat represents the detected document format
@@ -22,18 +30,32 @@ const (
 	FormatRTF      // Rich Text Format
 )
 
+// String returns a short identifier for the format
+func (f DocumentFormat) String() string {
+	switch f {
+	case FormatPlainText:
+		return "text"
+	case FormatZipBased:
+		return "zip"
+	case FormatOLEBased:
+		return "ole"
+	case FormatRTF:
+		return "rtf"
+	default:
+		return "unknown"
+	}
+}
+
 // DocumentExtractor handles document format detection and text extraction
 type DocumentExtractor struct {
 	// Patterns for extracting text from XML
 	xmlTextPattern *regexp.Regexp
-	rtfTextPattern *regexp.Regexp
 }
 
 // NewDocumentExtractor creates a new document extractor
 func NewDocumentExtractor() *DocumentExtractor {
 	return &DocumentExtractor{
 		xmlTextPattern: regexp.MustCompile(`<w:t[^>]*>([^<]+)</w:t>`),
-		rtfTextPattern: regexp.MustCompile(`\\par|\\tab|\\line`),
 	}
 }
 
@@ -85,6 +107,20 @@ func (e *DocumentExtractor) ExtractText(content []byte) (string, error) {
 	}
 }
 
+// contentPartNames returns the content parts of a DOCX/DOTX, discovered through
+// the package relationships
+func contentPartNames(content []byte) map[string]bool {
+	pkg, err := wordml.OpenPackage(content)
+	if err != nil {
+		return nil
+	}
+	names := make(map[string]bool)
+	for _, part := range pkg.Parts() {
+		names[part.Name] = true
+	}
+	return names
+}
+
 // extractFromZip extracts text from ZIP-based Word documents (DOCX/DOTX)
 func (e *DocumentExtractor) extractFromZip(content []byte) (string, error) {
 	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
@@ -94,32 +130,10 @@ func (e *DocumentExtractor) extractFromZip(content []byte) (string, error) {
 
 	var textBuilder strings.Builder
 	documentsProcessed := 0
-
-	// Primary document content files to extract
-	targetFiles := []string{
-		"word/document.xml",
-		"word/header1.xml",
-		"word/header2.xml",
-		"word/header3.xml",
-		"word/footer1.xml",
-		"word/footer2.xml",
-		"word/footer3.xml",
-		"word/footnotes.xml",
-		"word/endnotes.xml",
-		"word/comments.xml",
-	}
+	targets := contentPartNames(content)
 
 	for _, file := range reader.File {
-		// Check if this is one of our target files
-		isTarget := false
-		for _, target := range targetFiles {
-			if file.Name == target {
-				isTarget = true
-				break
-			}
-		}
-
-		if !isTarget {
+		if !targets[file.Name] {
 			continue
 		}
 
@@ -179,6 +193,39 @@ func (e *DocumentExtractor) extractFromZip(content []byte) (string, error) {
 	return result, nil
 }
 
+// extractZipFields parses the fields of the content parts of a DOCX/DOTX,
+// indexing each part for issue anchors
+func (e *DocumentExtractor) extractZipFields(content []byte, anchors *anchorIndex) []*field.Field {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil
+	}
+
+	var fields []*field.Field
+	targets := contentPartNames(content)
+	for _, file := range reader.File {
+		if !targets[file.Name] {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			continue
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			continue
+		}
+
+		if parsed, err := field.ParseXML(data); err == nil {
+			fields = append(fields, parsed...)
+			anchors.add(indexXML(file.Name, data), parsed)
+		}
+	}
+	return fields
+}
+
 // extractTextFromXML extracts text from Word XML content
 func (e *DocumentExtractor) extractTextFromXML(xmlContent string) string {
 	var textBuilder strings.Builder
@@ -197,102 +244,44 @@ func (e *DocumentExtractor) extractTextFromXML(xmlContent string) string {
 		}
 	}
 
-	// Also look for field codes and merge fields
-	fieldPatterns := []*regexp.Regexp{
-		regexp.MustCompile(`<w:instrText[^>]*>([^<]+)</w:instrText>`),
-		regexp.MustCompile(`MERGEFIELD\s+([^\s]+)`),
-		regexp.MustCompile(`IF\s+([^}]+)`),
+	// Append field codes in braces, reassembled from their instruction runs
+	fields, err := field.ParseXML([]byte(xmlContent))
+	if err != nil {
+		return textBuilder.String()
 	}
-
-	for _, pattern := range fieldPatterns {
-		matches := pattern.FindAllStringSubmatch(xmlContent, -1)
-		for _, match := range matches {
-			if len(match) > 0 {
-				if textBuilder.Len() > 0 {
-					textBuilder.WriteString(" ")
-				}
-				// Add field codes with braces to maintain pattern recognition
-				textBuilder.WriteString("{" + match[0] + "}")
-			}
+	for _, f := range fields {
+		if textBuilder.Len() > 0 {
+			textBuilder.WriteString(" ")
 		}
+		textBuilder.WriteString(f.String())
 	}
 
 	return textBuilder.String()
 }
 
-// extractFromOLE extracts text from OLE compound documents
+// extractFromOLE reconstructs the text of a Word binary document from the piece
+// table of its compound file
 func (e *DocumentExtractor) extractFromOLE(content []byte) (string, error) {
-	// OLE compound document structure is complex
-	// We'll extract readable strings and look for Word-specific patterns
-
-	var textBuilder strings.Builder
-
-	// Skip OLE header (512 bytes)
-	if len(content) > 512 {
-		// Look for Word document streams
-		textContent := e.extractReadableText(content[512:])
-
-		// Try to find Word-specific text patterns
-		// Word documents often have readable text interspersed with binary data
-		textBuilder.WriteString(textContent)
-	}
-
-	// Extract any field codes that might be visible
-	fieldPatterns := []string{
-		"MERGEFIELD",
-		"IF ",
-		"DOCPROPERTY",
-		"DOCVARIABLE",
-		"«", "»", // Merge field delimiters
-	}
-
-	contentStr := string(content)
-	for _, pattern := range fieldPatterns {
-		if idx := strings.Index(contentStr, pattern); idx >= 0 {
-			// Extract surrounding context
-			start := max(0, idx-50)
-			end := min(len(contentStr), idx+200)
-			context := e.extractReadableText([]byte(contentStr[start:end]))
-			if len(context) > 0 {
-				if textBuilder.Len() > 0 {
-					textBuilder.WriteString("\n")
-				}
-				textBuilder.WriteString(context)
-			}
-		}
+	cf, err := ole.Open(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to read compound file: %w", err)
 	}
 
-	result := textBuilder.String()
-	if len(result) == 0 {
-		// Fallback: extract any readable text
-		result = e.extractReadableText(content)
+	doc, err := msword.Open(cf)
+	if err != nil {
+		return "", fmt.Errorf("failed to read Word document: %w", err)
 	}
-
-	return result, nil
+	return doc.Text(), nil
 }
 
-// extractFromRTF extracts text from RTF documents
+// extractFromRTF extracts the text of an RTF document, with fields shown as
+// their codes in braces
 func (e *DocumentExtractor) extractFromRTF(content []byte) (string, error) {
-	rtfStr := string(content)
-
-	// Remove RTF control words and groups
-	// This is a simplified extraction - a full RTF parser would be more accurate
-	text := rtfStr
-
-	// Remove RTF groups
-	text = regexp.MustCompile(`\{[^}]*\}`).ReplaceAllString(text, " ")
-
-	// Remove RTF control words
-	text = regexp.MustCompile(`\\[a-z]+[0-9]*\s?`).ReplaceAllString(text, " ")
-
-	// Convert RTF line breaks to spaces
-	text = e.rtfTextPattern.ReplaceAllString(text, " ")
-
-	// Clean up
-	text = strings.TrimSpace(text)
-	text = regexp.MustCompile(`\s+`).ReplaceAllString(text, " ")
-
-	return text, nil
+	doc, err := rtf.Parse(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to read RTF document: %w", err)
+	}
+	return doc.Text(), nil
 }
 
 // extractReadableText extracts readable ASCII/UTF-8 text from binary content
@@ -415,9 +404,10 @@ func (e *DocumentExtractor) ExtractMetadata(content []byte) map[string]string {
 		}
 
 	case FormatOLEBased:
-		// OLE properties are in specific streams
-		// This would require full OLE parsing
 		metadata["format"] = "OLE Compound Document"
+		if cf, err := ole.Open(content); err == nil {
+			e.extractOLEMetadata(cf, metadata)
+		}
 
 	case FormatRTF:
 		metadata["format"] = "Rich Text Format"
@@ -429,6 +419,40 @@ func (e *DocumentExtractor) ExtractMetadata(content []byte) map[string]string {
 	return metadata
 }
 
+// extractOLEMetadata copies the summary property streams into metadata, using the
+// same keys as the docProps parts of ZIP-based documents
+func (e *DocumentExtractor) extractOLEMetadata(cf *ole.File, metadata map[string]string) {
+	set := func(key, value string) {
+		if value != "" {
+			metadata[key] = value
+		}
+	}
+
+	if si, err := cf.SummaryInformation(); err == nil {
+		set("title", si.Title)
+		set("subject", si.Subject)
+		set("creator", si.Author)
+		set("keywords", si.Keywords)
+		set("description", si.Comments)
+		set("template", si.Template)
+		set("lastModifiedBy", si.LastAuthor)
+		set("revision", si.Revision)
+		set("application", si.AppName)
+		if !si.Created.IsZero() {
+			set("created", si.Created.Format(time.RFC3339))
+		}
+		if !si.LastSaved.IsZero() {
+			set("modified", si.LastSaved.Format(time.RFC3339))
+		}
+	}
+
+	if dsi, err := cf.DocumentSummaryInformation(); err == nil {
+		set("category", dsi.Category)
+		set("manager", dsi.Manager)
+		set("company", dsi.Company)
+	}
+}
+
 // extractXMLMetadata extracts metadata from XML content
 func (e *DocumentExtractor) extractXMLMetadata(xmlContent string, metadata map[string]string) {
 	// Extract common metadata fields
@@ -467,93 +491,33 @@ func max(a, b int) int {
 	return b
 }
 
-// Helper type for reading binary data
-type binaryReader struct {
-	data []byte
-	pos  int
-}
-
-func newBinaryReader(data []byte) *binaryReader {
-	return &binaryReader{data: data, pos: 0}
-}
-
-func (r *binaryReader) readUint16() (uint16, error) {
-	if r.pos+2 > len(r.data) {
-		return 0, io.EOF
-	}
-	val := binary.LittleEndian.Uint16(r.data[r.pos:])
-	r.pos += 2
-	return val, nil
-}
-
-func (r *binaryReader) readUint32() (uint32, error) {
-	if r.pos+4 > len(r.data) {
-		return 0, io.EOF
-	}
-	val := binary.LittleEndian.Uint32(r.data[r.pos:])
-	r.pos += 4
-	return val, nil
-}
-
-// WordDocumentStream represents the main document stream in OLE files
-type WordDocumentStream struct {
-	Text       string
-	FieldCodes []string
-	Tables     int
-}
-
-// parseWordDocumentStream attempts to parse the Word document stream from OLE
-func (e *DocumentExtractor) parseWordDocumentStream(content []byte) (*WordDocumentStream, error) {
-	doc := &WordDocumentStream{
-		FieldCodes: make([]string, 0),
-	}
-
-	// This is a simplified parser - full implementation would need complete OLE/DOC spec
-	// For now, we extract readable text and look for patterns
-
-	doc.Text = e.extractReadableText(content)
-
-	// Look for field code patterns
-	fieldPatterns := []string{
-		"MERGEFIELD",
-		"IF ",
-		"DOCPROPERTY",
-		"DOCVARIABLE",
-		"INCLUDETEXT",
-		"REF ",
-		"=",
-	}
-
-	for _, pattern := range fieldPatterns {
-		if strings.Contains(doc.Text, pattern) {
-			// Extract the field code context
-			idx := strings.Index(doc.Text, pattern)
-			if idx >= 0 {
-				end := idx + 100
-				if end > len(doc.Text) {
-					end = len(doc.Text)
-				}
-				fieldCode := doc.Text[idx:end]
-				// Clean it up
-				fieldCode = strings.Split(fieldCode, "\x00")[0]
-				if len(fieldCode) > 0 {
-					doc.FieldCodes = append(doc.FieldCodes, fieldCode)
-				}
-			}
-		}
-	}
-
-	return doc, nil
-}
-
 // DocumentInfo contains extracted document information
 type DocumentInfo struct {
 	Format     DocumentFormat
 	Text       string
 	FieldCodes []string
+	Fields     []*field.Field // Parsed field tree
 	Metadata   map[string]string
 	HasMacros  bool
 	TableCount int
+
+	// Compound file details, set for OLE-based documents; RTF documents also
+	// report their stories and \object groups
+	Streams         []string          // Stream paths, e.g. "WordDocument", "Macros/VBA/dir"
+	EmbeddedObjects int               // Storages under ObjectPool
+	Stories         map[string]string // Text per story of Word binary documents, e.g. "main", "headers"
+
+	// VBA project with module source, set when the document carries readable macros
+	VBA *vba.Project
+
+	// Structural elements of ZIP-based documents, read from their WordprocessingML parts
+	Structure *wordml.Structure
+
+	// Images, OLE objects and external links, including the targets of link fields
+	Inventory *inventory.Inventory
+
+	// Text of each part or story, for anchoring issues
+	anchors *anchorIndex
 }
 
 // AnalyzeDocument performs complete document analysis with text extraction
@@ -562,20 +526,59 @@ func (e *DocumentExtractor) AnalyzeDocument(content []byte) (*DocumentInfo, erro
 		Format:     e.DetectFormat(content),
 		FieldCodes: make([]string, 0),
 		Metadata:   make(map[string]string),
+		anchors:    newAnchorIndex(),
 	}
 
-	// Extract text based on format
-	text, err := e.ExtractText(content)
-	if err != nil {
+	// Legacy documents are inspected through their real streams rather than bytes
+	var cf *ole.File
+	if info.Format == FormatOLEBased {
+		if parsed, err := ole.Open(content); err == nil {
+			cf = parsed
+			e.inspectOLE(cf, info)
+		}
+	}
+
+	// Extract text based on format; Word binary text comes from the piece table
+	// and RTF text from its tokens, both split into stories
+	var text string
+	var err error
+	switch {
+	case cf != nil:
+		text, err = e.analyzeWordBinary(cf, info)
+	case info.Format == FormatRTF:
+		text, err = e.analyzeRTF(content, info)
+	default:
+		text, err = e.ExtractText(content)
+	}
+	if err != nil && cf == nil {
 		// Even if extraction fails, try to get something
 		text = e.extractReadableText(content)
 	}
 	info.Text = text
 
+	// Binary Word and RTF fields were read from the stories; the others come from
+	// the document XML or the brace text
+	switch {
+	case info.Stories != nil:
+	case info.Format == FormatZipBased:
+		info.Fields = e.extractZipFields(content, info.anchors)
+	default:
+		info.Fields = field.ParseText(text)
+		info.anchors.add(indexRaw("text", text, false), info.Fields)
+	}
+	for _, f := range field.All(info.Fields) {
+		info.FieldCodes = append(info.FieldCodes, f.Code)
+	}
+
 	// Extract metadata
 	info.Metadata = e.ExtractMetadata(content)
 
-	// Check for macros
+	// Word documents carry macros in a VBA project; only loose text is checked for
+	// code by substring
+	if info.Format == FormatZipBased {
+		info.VBA, info.HasMacros = e.extractZipVBA(content)
+	}
+
 	macroIndicators := []string{
 		"VBAProject",
 		"Macros",
@@ -587,14 +590,171 @@ func (e *DocumentExtractor) AnalyzeDocument(content []byte) (*DocumentInfo, erro
 
 	contentStr := string(content)
 	for _, indicator := range macroIndicators {
-		if strings.Contains(contentStr, indicator) {
+		if (info.Format == FormatPlainText || info.Format == FormatUnknown) && strings.Contains(contentStr, indicator) {
 			info.HasMacros = true
 			break
 		}
 	}
 
-	// Count tables (simplified)
-	info.TableCount = strings.Count(text, "<table") + strings.Count(text, "\\trowd")
+	// Count tables from the document structure where there is one
+	if info.Format == FormatZipBased {
+		if pkg, err := wordml.OpenPackage(content); err == nil {
+			if structure, err := wordml.Analyze(pkg); err == nil {
+				info.Structure = structure
+			}
+			if inv, err := inventory.FromPackage(pkg); err == nil {
+				info.Inventory = inv
+				info.EmbeddedObjects = len(inv.Objects)
+			}
+		}
+	}
+	if info.Inventory == nil {
+		info.Inventory = &inventory.Inventory{}
+	}
+	info.Inventory.AddFields(info.Fields)
+	if info.Structure != nil {
+		info.TableCount = info.Structure.Count(wordml.KindTable) + info.Structure.Count(wordml.KindNestedTable)
+	} else if info.Format != FormatRTF {
+		info.TableCount = strings.Count(text, "<table") + strings.Count(text, "\\trowd")
+	}
 
 	return info, nil
 }
+
+// vbaProjectPart is the VBA project of macro-enabled DOCM/DOTM packages, itself a
+// compound file
+const vbaProjectPart = "word/vbaProject.bin"
+
+// ExtractVBA reads the VBA project of a Word binary document or a macro-enabled
+// DOCM/DOTM package
+func (e *DocumentExtractor) ExtractVBA(content []byte) (*vba.Project, error) {
+	switch e.DetectFormat(content) {
+	case FormatOLEBased:
+		cf, err := ole.Open(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read compound file: %w", err)
+		}
+		return vba.Open(cf)
+	case FormatZipBased:
+		project, found := e.extractZipVBA(content)
+		if project == nil {
+			if found {
+				return nil, fmt.Errorf("failed to read %s", vbaProjectPart)
+			}
+			return nil, vba.ErrNoProject
+		}
+		return project, nil
+	default:
+		return nil, vba.ErrNoProject
+	}
+}
+
+// extractZipVBA reads the VBA project part of a ZIP-based document. found is
+// true when the package has the part, even if it could not be parsed.
+func (e *DocumentExtractor) extractZipVBA(content []byte) (project *vba.Project, found bool) {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, false
+	}
+
+	for _, file := range reader.File {
+		if !strings.EqualFold(file.Name, vbaProjectPart) {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return nil, true
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, true
+		}
+
+		cf, err := ole.Open(data)
+		if err != nil {
+			return nil, true
+		}
+		project, err := vba.Open(cf)
+		if err != nil {
+			return nil, true
+		}
+		return project, true
+	}
+	return nil, false
+}
+
+// Well-known streams and storages of Word compound files
+const (
+	macrosStorage     = "Macros"           // VBA project of a Word document
+	vbaProjectStorage = "_VBA_PROJECT_CUR" // VBA project of Excel and PowerPoint files
+	objectPoolStorage = "ObjectPool"       // Embedded OLE objects
+)
+
+// inspectOLE records the streams of a compound file and derives macro and
+// embedded object information from its storages
+func (e *DocumentExtractor) inspectOLE(cf *ole.File, info *DocumentInfo) {
+	info.Streams = cf.Streams()
+
+	for _, name := range []string{macrosStorage, vbaProjectStorage} {
+		if entry, ok := cf.Find(name); ok && len(entry.Children) > 0 {
+			info.HasMacros = true
+		}
+	}
+
+	if project, err := vba.Open(cf); err == nil {
+		info.VBA = project
+	}
+
+	if pool, ok := cf.Find(objectPoolStorage); ok {
+		for _, child := range pool.Children {
+			if child.Type == ole.EntryStorage {
+				info.EmbeddedObjects++
+			}
+		}
+	}
+
+	info.Inventory = inventory.FromCompoundFile(cf)
+}
+
+// analyzeWordBinary reads the stories and fields of a Word binary document.
+// Other compound files (and encrypted or pre-97 documents) yield no text rather
+// than scraped bytes.
+func (e *DocumentExtractor) analyzeWordBinary(cf *ole.File, info *DocumentInfo) (string, error) {
+	doc, err := msword.Open(cf)
+	if err != nil {
+		return "", err
+	}
+
+	info.Stories = make(map[string]string, len(doc.Stories))
+	for _, story := range doc.Stories {
+		info.Stories[story.Name] = doc.Story(story.Name)
+		fields := field.ParseBinary(story.Raw)
+		info.Fields = append(info.Fields, fields...)
+		info.anchors.add(indexRaw(story.Name, story.Raw, true), fields)
+	}
+	info.Inventory.AddLink(inventory.LinkAttachedTemplate, doc.AttachedTemplate, "SttbfAssoc")
+	return doc.Text(), nil
+}
+
+// analyzeRTF reads the stories, fields, tables and embedded objects of an RTF
+// document
+func (e *DocumentExtractor) analyzeRTF(content []byte, info *DocumentInfo) (string, error) {
+	doc, err := rtf.Parse(content)
+	if err != nil {
+		return "", err
+	}
+
+	info.Stories = make(map[string]string, len(doc.Stories))
+	for _, story := range doc.Stories {
+		info.Stories[story.Name] = doc.Story(story.Name)
+		fields := field.ParseBinary(story.Raw)
+		info.Fields = append(info.Fields, fields...)
+		info.anchors.add(indexRaw(story.Name, story.Raw, true), fields)
+	}
+	info.TableCount = doc.Tables
+	info.EmbeddedObjects = len(doc.Objects)
+	info.Inventory = inventory.FromRTF(doc)
+	return doc.Text(), nil
+}
@@ -0,0 +1,121 @@
+package analyzer
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/field"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/ole/oletest"
+)
+
+func TestAnalyzeDocumentOLEStreams(t *testing.T) {
+	tests := []struct {
+		name            string
+		streams         map[string][]byte
+		expectedMacros  bool
+		expectedObjects int
+	}{
+		{
+			name: "Plain template",
+			streams: map[string][]byte{
+				"WordDocument": []byte("Sub Main is just text here"),
+				"1Table":       make([]byte, 16),
+			},
+		},
+		{
+			name: "Template with VBA and embedded objects",
+			streams: map[string][]byte{
+				"WordDocument":             make([]byte, 16),
+				"1Table":                   make([]byte, 16),
+				"Macros/VBA/dir":           []byte{0x01},
+				"ObjectPool/_1001/\x01Ole": make([]byte, 20),
+				"ObjectPool/_1002/\x01Ole": make([]byte, 20),
+			},
+			expectedMacros:  true,
+			expectedObjects: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := oletest.Build(tt.streams)
+			if err != nil {
+				t.Fatalf("Build failed: %v", err)
+			}
+
+			info, err := NewDocumentExtractor().AnalyzeDocument(content)
+			if err != nil {
+				t.Fatalf("AnalyzeDocument failed: %v", err)
+			}
+
+			if info.Format != FormatOLEBased {
+				t.Errorf("Format = %v, want ole", info.Format)
+			}
+			if len(info.Streams) != len(tt.streams) {
+				t.Errorf("Streams = %q, want %d streams", info.Streams, len(tt.streams))
+			}
+			if info.HasMacros != tt.expectedMacros {
+				t.Errorf("HasMacros = %v, want %v", info.HasMacros, tt.expectedMacros)
+			}
+			if info.EmbeddedObjects != tt.expectedObjects {
+				t.Errorf("EmbeddedObjects = %d, want %d", info.EmbeddedObjects, tt.expectedObjects)
+			}
+		})
+	}
+}
+
+func TestAnalyzeDocumentDOCXFields(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The instruction is split across runs, as Word often writes it
+	io.WriteString(w, `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body><w:p>`+
+		`<w:r><w:fldChar w:fldCharType="begin"/></w:r>`+
+		`<w:r><w:instrText> MERGE</w:instrText></w:r><w:r><w:instrText>FIELD Client \* MERGEFORMAT </w:instrText></w:r>`+
+		`<w:r><w:fldChar w:fldCharType="separate"/></w:r><w:r><w:t>«Client»</w:t></w:r>`+
+		`<w:r><w:fldChar w:fldCharType="end"/></w:r></w:p></w:body></w:document>`)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := NewDocumentExtractor().AnalyzeDocument(buf.Bytes())
+	if err != nil {
+		t.Fatalf("AnalyzeDocument failed: %v", err)
+	}
+	if len(info.Fields) != 1 || info.Fields[0].Name != "Client" {
+		t.Fatalf("Fields = %+v, want one MERGEFIELD Client", info.Fields)
+	}
+	if !strings.Contains(info.Text, `{ MERGEFIELD Client \* MERGEFORMAT }`) {
+		t.Errorf("Text = %q, want the reassembled field code", info.Text)
+	}
+}
+
+func TestAnalyzeDocumentRTF(t *testing.T) {
+	content := `{\rtf1\ansi{\fonttbl{\f0 Arial;}}{\footer\pard Page {\field{\*\fldinst PAGE}{\fldrslt 1}}\par}` +
+		`\pard Dear {\field{\*\fldinst MERGEFIELD Client \\* MERGEFORMAT}{\fldrslt \'abClient\'bb}},\par` +
+		`\trowd\cellx1000\pard\intbl A\cell\row\pard\par}`
+
+	info, err := NewDocumentExtractor().AnalyzeDocument([]byte(content))
+	if err != nil {
+		t.Fatalf("AnalyzeDocument failed: %v", err)
+	}
+	if info.Format != FormatRTF || info.TableCount != 1 {
+		t.Errorf("Format = %v, TableCount = %d", info.Format, info.TableCount)
+	}
+	if info.Stories["main"] != "Dear {MERGEFIELD Client \\* MERGEFORMAT},\nA\t\n\n" {
+		t.Errorf("main story = %q", info.Stories["main"])
+	}
+	if want := []string{`MERGEFIELD Client \* MERGEFORMAT`, "PAGE"}; !reflect.DeepEqual(info.FieldCodes, want) {
+		t.Errorf("FieldCodes = %q, want %q", info.FieldCodes, want)
+	}
+	if merge := field.OfType(info.Fields, field.TypeMergeField); len(merge) != 1 || merge[0].Result != "«Client»" {
+		t.Errorf("MERGEFIELD = %+v", merge)
+	}
+}
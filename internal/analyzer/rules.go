@@ -0,0 +1,343 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrInvalidRules is returned when a rules file fails validation
+	ErrInvalidRules = errors.New("invalid complexity rules")
+
+	// ErrRulesNotFound is returned when a named rule set does not exist
+	ErrRulesNotFound = errors.New("complexity rules not found")
+)
+
+// ruleSetName restricts named rule sets to plain file names
+var ruleSetName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ruleExtensions are the file extensions tried for a named rule set
+var ruleExtensions = []string{".yaml", ".yml", ".json"}
+
+// Rules is the file form of a ComplexityConfig. Rules files are YAML or JSON;
+// settings they leave out keep their defaults.
+//
+//	levels: {critical: 150, high: 70, medium: 30}
+//	detectors:
+//	  comments: {enabled: false}
+//	  nested_conditionals: {weight: 20, severity: high}
+//	patterns:
+//	  macros: ['(?i)Document_Open']
+type Rules struct {
+	Thresholds RuleThresholds          `yaml:"thresholds" json:"thresholds"`
+	Levels     RuleLevels              `yaml:"levels" json:"levels"`
+	Detectors  map[string]DetectorRule `yaml:"detectors" json:"detectors"`
+	Patterns   RulePatterns            `yaml:"patterns" json:"patterns"`
+}
+
+// RuleThresholds are the counts above which detectors report an issue
+type RuleThresholds struct {
+	NestedIfHigh   int `yaml:"nested_if_high" json:"nested_if_high"`
+	NestedIfMedium int `yaml:"nested_if_medium" json:"nested_if_medium"`
+	IfCountHigh    int `yaml:"if_count_high" json:"if_count_high"`
+	MergeFieldHigh int `yaml:"merge_field_high" json:"merge_field_high"`
+	MultipleTables int `yaml:"multiple_tables" json:"multiple_tables"`
+}
+
+// RuleLevels are the minimum scores of each complexity level
+type RuleLevels struct {
+	Critical int `yaml:"critical" json:"critical"`
+	High     int `yaml:"high" json:"high"`
+	Medium   int `yaml:"medium" json:"medium"`
+}
+
+// DetectorRule overrides a detector; unset fields keep the default
+type DetectorRule struct {
+	Enabled  *bool  `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	Weight   *int   `yaml:"weight,omitempty" json:"weight,omitempty"`
+	Severity string `yaml:"severity,omitempty" json:"severity,omitempty"`
+}
+
+// RulePatterns are the regular expressions of the PatternRegistry
+type RulePatterns struct {
+	MergePlaceholders []string `yaml:"merge_placeholders" json:"merge_placeholders"`
+	Macros            []string `yaml:"macros" json:"macros"`
+	Table             string   `yaml:"table" json:"table"`
+	NestedTable       string   `yaml:"nested_table" json:"nested_table"`
+	ActiveX           []string `yaml:"activex" json:"activex"`
+}
+
+// DefaultRules returns the built-in configuration in file form
+func DefaultRules() *Rules {
+	patterns := NewPatternRegistry()
+	rules := &Rules{
+		Thresholds: RuleThresholds{
+			NestedIfHigh:   NestedIfHighThreshold,
+			NestedIfMedium: NestedIfMediumThreshold,
+			IfCountHigh:    IfCountHighThreshold,
+			MergeFieldHigh: MergeFieldHighCount,
+			MultipleTables: MultipleTableThreshold,
+		},
+		Levels: RuleLevels{
+			Critical: ComplexityScoreCritical,
+			High:     ComplexityScoreHigh,
+			Medium:   ComplexityScoreMedium,
+		},
+		Detectors: make(map[string]DetectorRule),
+		Patterns: RulePatterns{
+			MergePlaceholders: patternSources(patterns.MergePlaceholders),
+			Macros:            patternSources(patterns.Macros),
+			Table:             patterns.Table.String(),
+			NestedTable:       patterns.NestedTable.String(),
+			ActiveX:           patternSources(patterns.ActiveX),
+		},
+	}
+	for name, d := range DefaultDetectors() {
+		enabled, weight := d.Enabled, d.Weight
+		rules.Detectors[name] = DetectorRule{Enabled: &enabled, Weight: &weight, Severity: d.Severity}
+	}
+	return rules
+}
+
+func patternSources(patterns []*regexp.Regexp) []string {
+	sources := make([]string, len(patterns))
+	for i, p := range patterns {
+		sources[i] = p.String()
+	}
+	return sources
+}
+
+// ParseRules reads and validates YAML or JSON rules
+func ParseRules(data []byte) (*Rules, error) {
+	rules := DefaultRules()
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(rules); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRules, err)
+	}
+	if _, err := rules.Config(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// LoadRules reads and validates a rules file
+func LoadRules(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+	rules, err := ParseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// LoadNamedRules loads the rule set called name from dir, e.g. "strict" for
+// dir/strict.yaml
+func LoadNamedRules(dir, name string) (*ComplexityConfig, error) {
+	if dir == "" || !ruleSetName.MatchString(name) {
+		return nil, fmt.Errorf("%w: %s", ErrRulesNotFound, name)
+	}
+	for _, ext := range ruleExtensions {
+		path := filepath.Join(dir, name+ext)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		rules, err := LoadRules(path)
+		if err != nil {
+			return nil, err
+		}
+		return rules.Config()
+	}
+	return nil, fmt.Errorf("%w: %s", ErrRulesNotFound, name)
+}
+
+// Config validates the rules and builds the configuration they describe
+func (r *Rules) Config() (*ComplexityConfig, error) {
+	t, l := r.Thresholds, r.Levels
+	switch {
+	case t.NestedIfHigh < 0 || t.NestedIfMedium < 0 || t.IfCountHigh < 0 || t.MergeFieldHigh < 0 || t.MultipleTables < 0:
+		return nil, fmt.Errorf("%w: thresholds must not be negative", ErrInvalidRules)
+	case t.NestedIfMedium > t.NestedIfHigh:
+		return nil, fmt.Errorf("%w: nested_if_medium (%d) exceeds nested_if_high (%d)", ErrInvalidRules, t.NestedIfMedium, t.NestedIfHigh)
+	case l.Medium <= 0 || l.Medium >= l.High || l.High >= l.Critical:
+		return nil, fmt.Errorf("%w: levels must satisfy 0 < medium < high < critical", ErrInvalidRules)
+	}
+
+	config := DefaultConfig()
+	config.NestedIfHighThreshold = t.NestedIfHigh
+	config.NestedIfMediumThreshold = t.NestedIfMedium
+	config.IfCountHighThreshold = t.IfCountHigh
+	config.MergeFieldHighCount = t.MergeFieldHigh
+	config.MultipleTableThreshold = t.MultipleTables
+	config.CriticalScore = l.Critical
+	config.HighScore = l.High
+	config.MediumScore = l.Medium
+
+	names := make([]string, 0, len(r.Detectors))
+	for name := range r.Detectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		d, ok := config.Detectors[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown detector %q", ErrInvalidRules, name)
+		}
+		rule := r.Detectors[name]
+		if rule.Enabled != nil {
+			d.Enabled = *rule.Enabled
+		}
+		if rule.Weight != nil {
+			if *rule.Weight < 0 {
+				return nil, fmt.Errorf("%w: detector %s has a negative weight", ErrInvalidRules, name)
+			}
+			d.Weight = *rule.Weight
+		}
+		if rule.Severity != "" {
+			if rule.Severity != "low" && rule.Severity != "medium" && rule.Severity != "high" {
+				return nil, fmt.Errorf("%w: detector %s has unknown severity %q", ErrInvalidRules, name, rule.Severity)
+			}
+			d.Severity = rule.Severity
+		}
+		config.Detectors[name] = d
+	}
+
+	patterns, err := r.Patterns.registry()
+	if err != nil {
+		return nil, err
+	}
+	config.Patterns = patterns
+	return config, nil
+}
+
+// registry compiles the patterns
+func (p RulePatterns) registry() (*PatternRegistry, error) {
+	compileAll := func(name string, sources []string) ([]*regexp.Regexp, error) {
+		compiled := make([]*regexp.Regexp, 0, len(sources))
+		for i, source := range sources {
+			re, err := regexp.Compile(source)
+			if err != nil {
+				return nil, fmt.Errorf("%w: patterns.%s[%d]: %v", ErrInvalidRules, name, i, err)
+			}
+			compiled = append(compiled, re)
+		}
+		return compiled, nil
+	}
+
+	registry := &PatternRegistry{}
+	var err error
+	if registry.MergePlaceholders, err = compileAll("merge_placeholders", p.MergePlaceholders); err != nil {
+		return nil, err
+	}
+	if registry.Macros, err = compileAll("macros", p.Macros); err != nil {
+		return nil, err
+	}
+	if registry.ActiveX, err = compileAll("activex", p.ActiveX); err != nil {
+		return nil, err
+	}
+	if registry.Table, err = regexp.Compile(p.Table); err != nil {
+		return nil, fmt.Errorf("%w: patterns.table: %v", ErrInvalidRules, err)
+	}
+	if registry.NestedTable, err = regexp.Compile(p.NestedTable); err != nil {
+		return nil, fmt.Errorf("%w: patterns.nested_table: %v", ErrInvalidRules, err)
+	}
+	return registry, nil
+}
+
+// activeConfig is the configuration AnalyzeComplexity uses; nil means the default
+var activeConfig atomic.Pointer[ComplexityConfig]
+
+// ActiveConfig returns the configuration used by AnalyzeComplexity
+func ActiveConfig() *ComplexityConfig {
+	if config := activeConfig.Load(); config != nil {
+		return config
+	}
+	return DefaultConfig()
+}
+
+// SetActiveConfig replaces the configuration used by AnalyzeComplexity; nil
+// restores the default
+func SetActiveConfig(config *ComplexityConfig) {
+	activeConfig.Store(config)
+}
+
+// RulesWatcher keeps the active configuration in step with a rules file
+type RulesWatcher struct {
+	path     string
+	interval time.Duration
+	modTime  time.Time
+	size     int64
+}
+
+// NewRulesWatcher loads the rules file and makes it the active configuration.
+// It fails if the file cannot be read or is invalid.
+func NewRulesWatcher(path string, interval time.Duration) (*RulesWatcher, error) {
+	w := &RulesWatcher{path: path, interval: interval}
+	if err := w.Reload(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Reload reads the rules file and activates it. The active configuration is
+// left unchanged if the file is invalid.
+func (w *RulesWatcher) Reload() error {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return fmt.Errorf("failed to read rules file: %w", err)
+	}
+	rules, err := LoadRules(w.path)
+	if err != nil {
+		return err
+	}
+	config, err := rules.Config()
+	if err != nil {
+		return err
+	}
+	SetActiveConfig(config)
+	w.modTime, w.size = info.ModTime(), info.Size()
+	return nil
+}
+
+// Start polls the rules file until the context is cancelled, reloading it
+// whenever it changes
+func (w *RulesWatcher) Start(ctx context.Context) {
+	if w.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(w.path)
+			if err != nil || (info.ModTime().Equal(w.modTime) && info.Size() == w.size) {
+				continue
+			}
+			if err := w.Reload(); err != nil {
+				log.Warnf("Keeping previous complexity rules: %v", err)
+				w.modTime, w.size = info.ModTime(), info.Size() // Retry once the file changes again
+				continue
+			}
+			log.Infof("Reloaded complexity rules from %s", w.path)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules([]byte(`
+thresholds:
+  nested_if_high: 5
+levels: {critical: 200, high: 100, medium: 40}
+detectors:
+  nested_conditionals: {weight: 25}
+  moderate_nested_conditionals: {enabled: false}
+  formulas: {severity: high}
+patterns:
+  activex: ['(?i)Forms\.']
+`))
+	if err != nil {
+		t.Fatalf("ParseRules failed: %v", err)
+	}
+	config, err := rules.Config()
+	if err != nil {
+		t.Fatalf("Config failed: %v", err)
+	}
+
+	if config.NestedIfHighThreshold != 5 || config.NestedIfMediumThreshold != NestedIfMediumThreshold {
+		t.Errorf("thresholds = %d, %d", config.NestedIfHighThreshold, config.NestedIfMediumThreshold)
+	}
+	if config.CriticalScore != 200 || config.MediumScore != 40 {
+		t.Errorf("levels = %d, %d, %d", config.CriticalScore, config.HighScore, config.MediumScore)
+	}
+	if d := config.Detectors["nested_conditionals"]; d != (Detector{true, 25, "high"}) {
+		t.Errorf("nested_conditionals = %+v", d)
+	}
+	if config.Detectors["moderate_nested_conditionals"].Enabled {
+		t.Error("moderate_nested_conditionals should be disabled")
+	}
+	if d := config.Detectors["formulas"]; d.Severity != "high" || d.Weight != FormulaWeight {
+		t.Errorf("formulas = %+v", d)
+	}
+	if len(config.Patterns.ActiveX) != 1 || len(config.Patterns.Macros) != len(NewPatternRegistry().Macros) {
+		t.Errorf("patterns = %d activex, %d macros", len(config.Patterns.ActiveX), len(config.Patterns.Macros))
+	}
+
+	// JSON is read the same way
+	if _, err := ParseRules([]byte(`{"detectors": {"comments": {"enabled": false}}}`)); err != nil {
+		t.Errorf("JSON rules failed: %v", err)
+	}
+
+	invalid := []string{
+		`levels: {critical: 50, high: 60, medium: 30}`,
+		`thresholds: {nested_if_high: 1, nested_if_medium: 2}`,
+		`detectors: {unknown: {weight: 1}}`,
+		`detectors: {comments: {severity: urgent}}`,
+		`detectors: {comments: {weight: -1}}`,
+		`patterns: {macros: ['(unclosed']}`,
+		`threshold: {nested_if_high: 1}`,
+	}
+	for _, data := range invalid {
+		if _, err := ParseRules([]byte(data)); !errors.Is(err, ErrInvalidRules) {
+			t.Errorf("ParseRules(%q) = %v, want ErrInvalidRules", data, err)
+		}
+	}
+}
+
+func TestRulesChangeAnalysis(t *testing.T) {
+	content := []byte(`{IF a "{IF b "yes" "no"}" "c"} {= 1 + 2 + 3 + 4} text`)
+
+	defaults := AnalyzeComplexityWithConfig(context.Background(), content, DefaultConfig())
+	if len(defaults.Issues) != 2 {
+		t.Fatalf("default issues = %+v", defaults.Issues)
+	}
+
+	rules, err := ParseRules([]byte(`
+detectors:
+  moderate_nested_conditionals: {enabled: false}
+  formulas: {weight: 100, severity: high}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, _ := rules.Config()
+	report := AnalyzeComplexityWithConfig(context.Background(), content, config)
+
+	if len(report.Issues) != 1 || report.Issues[0].Type != "formulas" || report.Issues[0].Severity != "high" {
+		t.Errorf("issues = %+v", report.Issues)
+	}
+	if report.Score != 100 || report.Level != "high" {
+		t.Errorf("score = %d, level = %s", report.Score, report.Level)
+	}
+}
+
+func TestRulesWatcher(t *testing.T) {
+	defer SetActiveConfig(nil)
+
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte("levels: {critical: 300, high: 200, medium: 100}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewRulesWatcher(path, time.Hour)
+	if err != nil {
+		t.Fatalf("NewRulesWatcher failed: %v", err)
+	}
+	if ActiveConfig().CriticalScore != 300 {
+		t.Errorf("active critical score = %d, want 300", ActiveConfig().CriticalScore)
+	}
+
+	// An invalid file leaves the active rules in place
+	os.WriteFile(path, []byte("levels: {critical: 1, high: 2, medium: 3}\n"), 0o644)
+	if err := w.Reload(); !errors.Is(err, ErrInvalidRules) {
+		t.Errorf("Reload = %v, want ErrInvalidRules", err)
+	}
+	if ActiveConfig().CriticalScore != 300 {
+		t.Errorf("active critical score = %d after invalid reload", ActiveConfig().CriticalScore)
+	}
+
+	if _, err := LoadNamedRules(filepath.Dir(path), "rules"); err == nil {
+		t.Error("expected the invalid named rule set to fail")
+	}
+	if _, err := LoadNamedRules(filepath.Dir(path), "../rules"); !errors.Is(err, ErrRulesNotFound) {
+		t.Errorf("LoadNamedRules with a path = %v, want ErrRulesNotFound", err)
+	}
+}
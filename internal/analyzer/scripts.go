@@ -0,0 +1,49 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ScriptDependency is a script that runs when a template is used, such as a
+// MatterSphere precedent script, with the data it reads and how hard it is to
+// rebuild outside the source system
+type ScriptDependency struct {
+	Code       string   `json:"code"`
+	Difficulty string   `json:"difficulty"` // low, medium or high
+	Score      int      `json:"difficulty_score"`
+	Reasons    []string `json:"reasons,omitempty"`
+	Wizards    []string `json:"wizards,omitempty"` // Forms prompting the user
+	Fields     []string `json:"fields,omitempty"`
+	Tables     []string `json:"tables,omitempty"`
+	Params     []string `json:"params,omitempty"` // Merge parameters the script provides
+}
+
+// AddScripts adds the scripts a template depends on to its report: an issue
+// per script, scored by the precedent_scripts detector, and a recommendation
+// to rebuild each one. The level and review need are updated for the new score.
+func AddScripts(report *ComplexityReport, scripts []ScriptDependency) {
+	if report == nil || len(scripts) == 0 {
+		return
+	}
+	a := &complexityAnalyzer{config: ActiveConfig()}
+	reviewed := report.NeedsReview
+
+	for _, s := range scripts {
+		report.Scripts = append(report.Scripts, s)
+		description := fmt.Sprintf("Precedent script %s runs with the template (migration difficulty: %s)", s.Code, s.Difficulty)
+		if len(s.Reasons) > 0 {
+			description += ": " + strings.Join(s.Reasons, "; ")
+		}
+		if !a.addIssueAt(report, "precedent_scripts", description, &Anchor{Part: "Scripts/" + s.Code}, 1) {
+			continue
+		}
+		report.Recommendations = append(report.Recommendations,
+			fmt.Sprintf("Rebuild the prompts and data lookups of script %s, which conversion does not preserve", s.Code))
+	}
+
+	a.assignLevel(report)
+	if !reviewed && report.NeedsReview {
+		report.Recommendations = append([]string{"This document requires human review after conversion"}, report.Recommendations...)
+	}
+}
@@ -0,0 +1,28 @@
+package analyzer
+
+import "testing"
+
+func TestAddScripts(t *testing.T) {
+	report := AnalyzeComplexity([]byte("Dear { MERGEFIELD ClientName }, please find enclosed our advice."))
+	score := report.Score
+	if report.NeedsReview {
+		t.Fatalf("report needs review before scripts were added: %+v", report)
+	}
+
+	AddScripts(report, []ScriptDependency{
+		{Code: "_2605", Difficulty: "medium", Reasons: []string{"prompts with wizard forms udScrWiz0087Sel"}},
+		{Code: "_30425", Difficulty: "high"},
+	})
+	if len(report.Scripts) != 2 || report.Detections["precedent_scripts"] != 2 {
+		t.Errorf("scripts = %+v, detections = %v, want both scripts", report.Scripts, report.Detections)
+	}
+	if report.Score != score+2*PrecedentScriptWeight {
+		t.Errorf("score = %d, want %d", report.Score, score+2*PrecedentScriptWeight)
+	}
+	if report.Level != "medium" || !report.NeedsReview {
+		t.Errorf("level = %s, needs review = %v, want medium with review for the high severity issues", report.Level, report.NeedsReview)
+	}
+	if issue := report.Issues[len(report.Issues)-1]; issue.Location != "Scripts/_30425" || issue.Severity != "high" {
+		t.Errorf("issue = %+v, want one anchored at the script", issue)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"io"
 	"net/http"
 	"path/filepath"
@@ -10,8 +11,52 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-// AnalyzeHandler handles document complexity analysis without conversion
-func AnalyzeHandler() gin.HandlerFunc {
+// requestRules returns the complexity rules an analyze request selects: a rules
+// file uploaded as the "rules" form field, the rule set named by the rules query
+// parameter, or the server's active rules. It writes an error response and
+// returns false if the selected rules cannot be loaded.
+func requestRules(c *gin.Context, rulesDir string) (*analyzer.ComplexityConfig, string, bool) {
+	if header, err := c.FormFile("rules"); err == nil {
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read rules file"})
+			return nil, "", false
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read rules file"})
+			return nil, "", false
+		}
+		rules, err := analyzer.ParseRules(data)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, "", false
+		}
+		config, _ := rules.Config() // Validated by ParseRules
+		return config, header.Filename, true
+	}
+
+	name := c.Query("rules")
+	if name == "" {
+		return analyzer.ActiveConfig(), "default", true
+	}
+	config, err := analyzer.LoadNamedRules(rulesDir, name)
+	switch {
+	case errors.Is(err, analyzer.ErrRulesNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "rule set not found: " + name})
+		return nil, "", false
+	case err != nil:
+		log.Warnf("Rule set %s is invalid: %v", name, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, "", false
+	}
+	return config, name, true
+}
+
+// AnalyzeHandler handles document complexity analysis without conversion. The
+// rules query parameter or an uploaded rules file trials other complexity rules.
+func AnalyzeHandler(rulesDir string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Parse multipart form
 		file, header, err := c.Request.FormFile("file")
@@ -35,8 +80,13 @@ func AnalyzeHandler() gin.HandlerFunc {
 			return
 		}
 
+		rules, rulesName, ok := requestRules(c, rulesDir)
+		if !ok {
+			return
+		}
+
 		// Analyze document complexity
-		complexityReport := analyzer.AnalyzeComplexity(fileData)
+		complexityReport := analyzer.AnalyzeComplexityWithConfig(c.Request.Context(), fileData, rules)
 
 		log.Infof("Document analysis for %s: Level=%s, Score=%d, NeedsReview=%v",
 			header.Filename, complexityReport.Level, complexityReport.Score, complexityReport.NeedsReview)
@@ -45,6 +95,7 @@ func AnalyzeHandler() gin.HandlerFunc {
 		c.JSON(http.StatusOK, gin.H{
 			"filename":          header.Filename,
 			"size":              header.Size,
+			"rules":             rulesName,
 			"complexity_report": complexityReport,
 		})
 	}
@@ -62,8 +113,9 @@ type AnalyzeBatchResponse struct {
 	Report   *analyzer.ComplexityReport `json:"complexity_report,omitempty"`
 }
 
-// AnalyzeBatchHandler analyzes multiple files for complexity
-func AnalyzeBatchHandler() gin.HandlerFunc {
+// AnalyzeBatchHandler analyzes multiple files for complexity, with rules selected
+// as for AnalyzeHandler
+func AnalyzeBatchHandler(rulesDir string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Parse the request
 		form, err := c.MultipartForm()
@@ -83,6 +135,11 @@ func AnalyzeBatchHandler() gin.HandlerFunc {
 			return
 		}
 
+		rules, rulesName, ok := requestRules(c, rulesDir)
+		if !ok {
+			return
+		}
+
 		results := make([]AnalyzeBatchResponse, 0, len(files))
 
 		// Process each file
@@ -117,7 +174,7 @@ func AnalyzeBatchHandler() gin.HandlerFunc {
 			}
 
 			// Analyze complexity
-			result.Report = analyzer.AnalyzeComplexity(fileData)
+			result.Report = analyzer.AnalyzeComplexityWithConfig(c.Request.Context(), fileData, rules)
 			results = append(results, result)
 
 			log.Infof("Batch analysis for %s: Level=%s, Score=%d, NeedsReview=%v",
@@ -152,6 +209,7 @@ func AnalyzeBatchHandler() gin.HandlerFunc {
 
 		c.JSON(http.StatusOK, gin.H{
 			"results": results,
+			"rules":   rulesName,
 			"summary": gin.H{
 				"total_files":  totalFiles,
 				"needs_review": needsReview,
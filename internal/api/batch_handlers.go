@@ -0,0 +1,207 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/converter"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/queue"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/storage"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/webhook"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetBatch returns the aggregate status of a batch
+func GetBatch(q queue.Queue) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		batch, err := q.GetBatch(c, c.Param("id"))
+		if err != nil {
+			if err == queue.ErrBatchNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "batch not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, batch)
+	}
+}
+
+// ListBatches lists batches, newest first
+func ListBatches(q queue.Queue) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := 100 // Default limit
+		if value, err := strconv.Atoi(c.Query("limit")); err == nil && value > 0 {
+			limit = value
+		}
+
+		batches, err := q.ListBatches(c, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"batches": batches,
+			"count":   len(batches),
+		})
+	}
+}
+
+// CancelBatch cancels every job of a batch that has not started yet
+func CancelBatch(q queue.Queue, d *webhook.Dispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		batch, err := q.GetBatch(c, c.Param("id"))
+		if err != nil {
+			if err == queue.ErrBatchNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "batch not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		cancelled := 0
+		for _, id := range batch.JobIDs {
+			job, err := q.GetJob(c, id)
+			if err != nil || (job.Status != queue.StatusPending && job.Status != queue.StatusRetrying) {
+				continue
+			}
+			if err := q.CancelJob(c, id); err != nil {
+				log.Warnf("Failed to cancel job %s of batch %s: %v", id, batch.ID, err)
+				continue
+			}
+			if job, err := q.GetJob(c, id); err == nil {
+				PublishJobEvent(JobEventCancelled, job)
+				d.JobFinished(c, job)
+			}
+			cancelled++
+		}
+
+		// Return the refreshed aggregate
+		if refreshed, err := q.GetBatch(c, batch.ID); err == nil {
+			batch = refreshed
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":   "batch cancelled",
+			"cancelled": cancelled,
+			"batch":     batch,
+		})
+	}
+}
+
+// DownloadBatch streams a ZIP of every converted output in the batch together with
+// manifest.csv listing the jobs that produced no output
+func DownloadBatch(q queue.Queue, s storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		batch, err := q.GetBatch(c, c.Param("id"))
+		if err != nil {
+			if err == queue.ErrBatchNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "batch not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Header("Content-Type", "application/zip")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "batch-"+batch.ID+".zip"))
+		c.Status(http.StatusOK)
+
+		archive := zip.NewWriter(c.Writer)
+		var manifestBuf bytes.Buffer
+		manifest := csv.NewWriter(&manifestBuf)
+		manifest.Write([]string{"job_id", "filename", "status", "attempts", "error"})
+
+		used := make(map[string]bool)
+		written := 0
+		for _, id := range batch.JobIDs {
+			job, err := q.GetJob(c, id)
+			if err != nil {
+				manifest.Write([]string{id, "", "missing", "0", err.Error()})
+				continue
+			}
+
+			filename := job.Metadata["filename"]
+			if job.Status != queue.StatusCompleted {
+				manifest.Write([]string{job.ID, filename, job.Status, strconv.Itoa(job.Attempts), job.Error})
+				continue
+			}
+
+			outputs := job.Outputs
+			if len(outputs) == 0 {
+				outputs = map[string]string{converter.OutputFormatForPath(job.OutputPath): job.OutputPath}
+			}
+
+			formats := make([]string, 0, len(outputs))
+			for format := range outputs {
+				formats = append(formats, format)
+			}
+			sort.Strings(formats)
+
+			for _, format := range formats {
+				data, err := s.ReadFile(c, outputs[format])
+				if err != nil {
+					manifest.Write([]string{job.ID, filename, "missing_output", strconv.Itoa(job.Attempts),
+						fmt.Sprintf("%s output unavailable: %v", format, err)})
+					continue
+				}
+
+				name := archiveName(job, outputs[format], format)
+				if used[name] {
+					name = job.ID + "/" + name
+				}
+				used[name] = true
+
+				w, err := archive.Create(name)
+				if err != nil {
+					log.Errorf("Failed to add %s to batch %s archive: %v", name, batch.ID, err)
+					return
+				}
+				if _, err := w.Write(data); err != nil {
+					log.Errorf("Failed to stream batch %s archive: %v", batch.ID, err)
+					return
+				}
+				written++
+			}
+		}
+
+		manifest.Flush()
+		if w, err := archive.Create("manifest.csv"); err == nil {
+			w.Write(manifestBuf.Bytes())
+		}
+
+		if err := archive.Close(); err != nil {
+			log.Errorf("Failed to finalize batch %s archive: %v", batch.ID, err)
+			return
+		}
+
+		log.Infof("Streamed batch %s archive with %d outputs", batch.ID, written)
+	}
+}
+
+// archiveName returns a safe relative name for an output inside the batch ZIP,
+// preserving the submitted file's folder structure
+func archiveName(job *queue.Job, outputPath, format string) string {
+	name := filepath.Base(outputPath)
+	if filename := job.Metadata["filename"]; filename != "" {
+		name = converter.WithOutputExtension(filepath.ToSlash(filename), format)
+	}
+
+	name = strings.TrimLeft(path.Clean("/"+name), "/")
+	if name == "" || name == "." {
+		name = job.ID + "." + format
+	}
+	return name
+}
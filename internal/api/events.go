@@ -0,0 +1,296 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/queue"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/websocket"
+)
+
+// Job lifecycle event types pushed to stream subscribers
+const (
+	JobEventSnapshot  = "snapshot" // Current state sent when a client starts following a job
+	JobEventQueued    = "queued"
+	JobEventStarted   = "started"
+	JobEventCompleted = "completed"
+	JobEventFailed    = "failed"
+	JobEventRetrying  = "retrying"
+	JobEventCancelled = "cancelled"
+)
+
+// Stream message types
+const (
+	StreamMetrics = "metrics"
+	StreamJob     = "job"
+)
+
+// subscriberBuffer is how many events a slow client may fall behind before
+// further events are dropped for it
+const subscriberBuffer = 64
+
+// JobEvent describes a change in a job's lifecycle
+type JobEvent struct {
+	Type      string    `json:"type"`
+	JobID     string    `json:"job_id"`
+	BatchID   string    `json:"batch_id,omitempty"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Engine    string    `json:"engine,omitempty"`
+	Attempts  int       `json:"attempts,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// StreamMessage is one message on the live stream: a metrics snapshot or a job event
+type StreamMessage struct {
+	Type    string           `json:"type"`
+	Metrics *MetricsResponse `json:"metrics,omitempty"`
+	Job     *JobEvent        `json:"job,omitempty"`
+}
+
+// eventHub fans job events out to stream subscribers. It is in-process, so a
+// stream only carries events published by its own instance: behind a load
+// balancer, each replica streams the jobs its workers and handlers touch.
+type eventHub struct {
+	mu          sync.RWMutex
+	subscribers map[*subscriber]struct{}
+}
+
+// subscriber receives the job events matching its filter
+type subscriber struct {
+	jobID   string
+	batchID string
+	events  chan JobEvent
+}
+
+var jobEvents = &eventHub{
+	subscribers: make(map[*subscriber]struct{}),
+}
+
+// matches reports whether the subscriber asked for the event
+func (s *subscriber) matches(event JobEvent) bool {
+	return (s.jobID == "" || s.jobID == event.JobID) &&
+		(s.batchID == "" || s.batchID == event.BatchID)
+}
+
+func (h *eventHub) subscribe(jobID, batchID string) *subscriber {
+	sub := &subscriber{
+		jobID:   jobID,
+		batchID: batchID,
+		events:  make(chan JobEvent, subscriberBuffer),
+	}
+
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+func (h *eventHub) unsubscribe(sub *subscriber) {
+	h.mu.Lock()
+	delete(h.subscribers, sub)
+	h.mu.Unlock()
+}
+
+func (h *eventHub) publish(event JobEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subscribers {
+		if !sub.matches(event) {
+			continue
+		}
+		// Never let a slow client hold up the worker that published the event
+		select {
+		case sub.events <- event:
+		default:
+			log.Debugf("Dropping %s event for job %s: stream subscriber is too slow", event.Type, event.JobID)
+		}
+	}
+}
+
+// PublishJobEvent pushes a job lifecycle event to live stream subscribers
+func PublishJobEvent(eventType string, job *queue.Job) {
+	jobEvents.publish(newJobEvent(eventType, job))
+}
+
+func newJobEvent(eventType string, job *queue.Job) JobEvent {
+	return JobEvent{
+		Type:      eventType,
+		JobID:     job.ID,
+		BatchID:   job.Metadata["batch_id"],
+		Status:    job.Status,
+		Error:     job.Error,
+		Engine:    job.Engine,
+		Attempts:  job.Attempts,
+		Timestamp: time.Now(),
+	}
+}
+
+// streamOptions are the query parameters accepted by the live stream
+type streamOptions struct {
+	jobID    string
+	batchID  string
+	metrics  bool
+	interval time.Duration
+}
+
+// parseStreamOptions reads ?job_id=, ?batch_id=, ?metrics=false and ?interval=<seconds>
+func parseStreamOptions(c *gin.Context) streamOptions {
+	opts := streamOptions{
+		jobID:    c.Query("job_id"),
+		batchID:  c.Query("batch_id"),
+		metrics:  true,
+		interval: 2 * time.Second,
+	}
+	if value, err := strconv.ParseBool(c.Query("metrics")); err == nil {
+		opts.metrics = value
+	}
+	if value, err := strconv.Atoi(c.Query("interval")); err == nil && value > 0 {
+		opts.interval = time.Duration(value) * time.Second
+	}
+	return opts
+}
+
+// WebSocketMetricsHandler streams metrics snapshots and job lifecycle events. Clients
+// that do not request a WebSocket upgrade get the same stream as Server-Sent Events.
+// Browsers may open the WebSocket from the server's own origin or one of
+// allowedOrigins; clients that send no Origin header are not browsers and are
+// always accepted.
+func WebSocketMetricsHandler(q queue.Queue, allowedOrigins []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		opts := parseStreamOptions(c)
+
+		if !strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+			streamSSE(c, q, opts)
+			return
+		}
+
+		server := websocket.Server{
+			Handshake: func(_ *websocket.Config, r *http.Request) error {
+				return checkOrigin(r, allowedOrigins)
+			},
+			Handler: func(ws *websocket.Conn) {
+				streamWebSocket(ws, q, opts)
+			},
+		}
+		server.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// checkOrigin accepts WebSocket handshakes without an Origin header, from the
+// server's own host or from an allowed origin
+func checkOrigin(r *http.Request, allowed []string) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return nil
+	}
+	for _, a := range allowed {
+		if a == "*" || strings.EqualFold(strings.TrimSuffix(a, "/"), origin) {
+			return nil
+		}
+	}
+	return fmt.Errorf("origin %s is not allowed", origin)
+}
+
+// streamWebSocket sends stream messages as JSON text frames until the client disconnects
+func streamWebSocket(ws *websocket.Conn, q queue.Queue, opts streamOptions) {
+	defer ws.Close()
+
+	ctx, cancel := context.WithCancel(ws.Request().Context())
+	defer cancel()
+
+	// The stream is one-way; reading only detects the client going away
+	go func() {
+		defer cancel()
+		var discard string
+		for {
+			if err := websocket.Message.Receive(ws, &discard); err != nil {
+				return
+			}
+		}
+	}()
+
+	runStream(ctx, q, opts, func(msg StreamMessage) error {
+		return websocket.JSON.Send(ws, msg)
+	})
+}
+
+// streamSSE sends stream messages as Server-Sent Events until the client disconnects
+func streamSSE(c *gin.Context, q queue.Queue, opts streamOptions) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Disable proxy buffering
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	runStream(c.Request.Context(), q, opts, func(msg StreamMessage) error {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", msg.Type, data); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+}
+
+// runStream pushes metrics on a ticker and job events as they happen until ctx is
+// done or send fails
+func runStream(ctx context.Context, q queue.Queue, opts streamOptions, send func(StreamMessage) error) {
+	sub := jobEvents.subscribe(opts.jobID, opts.batchID)
+	defer jobEvents.unsubscribe(sub)
+
+	// Subscribe before reading the job so no transition is missed in between
+	if opts.jobID != "" && q != nil {
+		if job, err := q.GetJob(ctx, opts.jobID); err == nil {
+			event := newJobEvent(JobEventSnapshot, job)
+			if err := send(StreamMessage{Type: StreamJob, Job: &event}); err != nil {
+				return
+			}
+		}
+	}
+
+	var ticks <-chan time.Time
+	if opts.metrics {
+		metrics := snapshotMetrics(ctx, q)
+		if err := send(StreamMessage{Type: StreamMetrics, Metrics: &metrics}); err != nil {
+			return
+		}
+
+		ticker := time.NewTicker(opts.interval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticks:
+			metrics := snapshotMetrics(ctx, q)
+			if err := send(StreamMessage{Type: StreamMetrics, Metrics: &metrics}); err != nil {
+				return
+			}
+		case event := <-sub.events:
+			if err := send(StreamMessage{Type: StreamJob, Job: &event}); err != nil {
+				return
+			}
+		}
+	}
+}
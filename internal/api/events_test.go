@@ -0,0 +1,189 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/queue"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// openStream starts the event stream handler and opens the stream at query
+func openStream(t *testing.T, q queue.Queue, query string) *bufio.Reader {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/events", WebSocketMetricsHandler(q, nil))
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/events?"+query, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+// readEvent reads one "event:" and "data:" frame ended by a blank line
+func readEvent(t *testing.T, r *bufio.Reader) (string, StreamMessage) {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream ended after %q: %v", lines, err)
+		}
+		if line == "\n" {
+			break
+		}
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "event: ") || !strings.HasPrefix(lines[1], "data: ") {
+		t.Fatalf("malformed frame %q", lines)
+	}
+	var msg StreamMessage
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(lines[1], "data: ")), &msg); err != nil {
+		t.Fatalf("frame data: %v", err)
+	}
+	if event := strings.TrimPrefix(lines[0], "event: "); event != msg.Type {
+		t.Errorf("event %q carries a %q message", event, msg.Type)
+	}
+	return msg.Type, msg
+}
+
+// waitForSubscribers waits until n streams are subscribed to job events
+func waitForSubscribers(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		jobEvents.mu.RLock()
+		count := len(jobEvents.subscribers)
+		jobEvents.mu.RUnlock()
+		if count == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d stream subscribers, want %d", count, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStreamSSEFollowsJob(t *testing.T) {
+	q := queue.NewMemoryQueue()
+	if err := q.Enqueue(context.Background(), &queue.Job{ID: "job-1", Status: queue.StatusPending}); err != nil {
+		t.Fatal(err)
+	}
+	stream := openStream(t, q, "job_id=job-1")
+
+	// The job's current state comes first, then the first metrics snapshot
+	if typ, msg := readEvent(t, stream); typ != StreamJob || msg.Job.Type != JobEventSnapshot || msg.Job.JobID != "job-1" {
+		t.Fatalf("first message = %s %+v, want the job snapshot", typ, msg.Job)
+	}
+	if typ, msg := readEvent(t, stream); typ != StreamMetrics || msg.Metrics == nil {
+		t.Fatalf("second message = %s, want metrics", typ)
+	}
+
+	PublishJobEvent(JobEventStarted, &queue.Job{ID: "job-2", Status: queue.StatusProcessing})
+	PublishJobEvent(JobEventStarted, &queue.Job{ID: "job-1", Status: queue.StatusProcessing})
+	if _, msg := readEvent(t, stream); msg.Job == nil || msg.Job.JobID != "job-1" || msg.Job.Type != JobEventStarted {
+		t.Errorf("job event = %+v, want job-1 started", msg.Job)
+	}
+}
+
+func TestStreamSSEFiltersBatch(t *testing.T) {
+	waitForSubscribers(t, 0) // Streams of earlier tests close asynchronously
+	stream := openStream(t, queue.NewMemoryQueue(), "metrics=false&batch_id=batch-1")
+	waitForSubscribers(t, 1)
+
+	other := &queue.Job{ID: "job-1", Status: queue.StatusCompleted, Metadata: map[string]string{"batch_id": "batch-2"}}
+	mine := &queue.Job{ID: "job-2", Status: queue.StatusCompleted, Metadata: map[string]string{"batch_id": "batch-1"}}
+	PublishJobEvent(JobEventCompleted, other)
+	PublishJobEvent(JobEventCompleted, mine)
+
+	if _, msg := readEvent(t, stream); msg.Job == nil || msg.Job.JobID != "job-2" || msg.Job.BatchID != "batch-1" {
+		t.Errorf("job event = %+v, want job-2 of batch-1", msg.Job)
+	}
+}
+
+func TestEventHubDropsForSlowSubscriber(t *testing.T) {
+	hub := &eventHub{subscribers: make(map[*subscriber]struct{})}
+	slow := hub.subscribe("", "")
+	filtered := hub.subscribe("job-other", "")
+
+	published := make(chan struct{})
+	go func() {
+		for i := 0; i < subscriberBuffer+10; i++ {
+			hub.publish(JobEvent{Type: JobEventQueued, JobID: "job-1"})
+		}
+		close(published)
+	}()
+	select {
+	case <-published:
+	case <-time.After(2 * time.Second):
+		t.Fatal("publishing blocked on a subscriber that does not read")
+	}
+
+	if len(slow.events) != subscriberBuffer {
+		t.Errorf("slow subscriber holds %d events, want %d", len(slow.events), subscriberBuffer)
+	}
+	if len(filtered.events) != 0 {
+		t.Errorf("filtered subscriber received %d events", len(filtered.events))
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	allowed := []string{"https://app.example/"}
+	tests := []struct {
+		origin string
+		ok     bool
+	}{
+		{"", true},
+		{"http://converter.internal:8080", true}, // The server's own host
+		{"https://app.example", true},
+		{"https://evil.example", false},
+		{"https://converter.internal:8080.evil.example", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "http://converter.internal:8080/ws/metrics", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if err := checkOrigin(r, allowed); (err == nil) != tt.ok {
+			t.Errorf("checkOrigin(%q) = %v, want allowed %v", tt.origin, err, tt.ok)
+		}
+	}
+}
+
+func TestWebSocketRejectsForeignOrigin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/ws/metrics", WebSocketMetricsHandler(queue.NewMemoryQueue(), []string{"https://app.example"}))
+	server := httptest.NewServer(router)
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/metrics?metrics=false"
+
+	for origin, ok := range map[string]bool{"https://app.example": true, "https://evil.example": false} {
+		ws, err := websocket.Dial(wsURL, "", origin)
+		if (err == nil) != ok {
+			t.Errorf("dial from %s: %v, want allowed %v", origin, err, ok)
+		}
+		if ws != nil {
+			ws.Close()
+		}
+	}
+}
@@ -5,11 +5,15 @@ import (
 	"io"
 	"net/http"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/alterspective-engine/dot-to-docx-converter/internal/analyzer"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/converter"
 	"github.com/alterspective-engine/dot-to-docx-converter/internal/queue"
 	"github.com/alterspective-engine/dot-to-docx-converter/internal/storage"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/webhook"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
@@ -17,16 +21,66 @@ import (
 
 // ConvertRequest represents a single file conversion request
 type ConvertRequest struct {
-	Priority int               `json:"priority" form:"priority"`
-	Metadata map[string]string `json:"metadata" form:"metadata"`
+	Priority     int               `json:"priority" form:"priority"`
+	Engine       string            `json:"engine" form:"engine"`               // Conversion engine: libreoffice (default) or native
+	OutputFormat string            `json:"output_format" form:"output_format"` // Comma-separated: docx (default), dotx, pdf, odt
+	CallbackURL  string            `json:"callback_url" form:"callback_url"`   // Receives a signed event when the job finishes
+	Tenant       string            `json:"tenant" form:"tenant"`               // Selects the tenant's default callback URL
+	Annotate     bool              `json:"annotate" form:"annotate"`           // Also produce a DOCX with a review comment per complexity issue
+	Metadata     map[string]string `json:"metadata" form:"metadata"`
 }
 
 // BatchConvertRequest represents a batch conversion request
 type BatchConvertRequest struct {
-	Source      string   `json:"source" binding:"required"`
-	Destination string   `json:"destination" binding:"required"`
-	Files       []string `json:"files" binding:"required"`
-	Priority    int      `json:"priority"`
+	Source       string   `json:"source" binding:"required"`
+	Destination  string   `json:"destination" binding:"required"`
+	Files        []string `json:"files" binding:"required"`
+	Priority     int      `json:"priority"`
+	Engine       string   `json:"engine"`
+	OutputFormat string   `json:"output_format"`
+	CallbackURL  string   `json:"callback_url"`
+	Tenant       string   `json:"tenant"`
+	Annotate     bool     `json:"annotate"`
+}
+
+// supportedExtensions lists the template extensions accepted for conversion
+var supportedExtensions = map[string]bool{
+	".dot":  true,
+	".dotx": true,
+	".dotm": true,
+}
+
+// isSupportedTemplate reports whether the filename has a supported template extension
+func isSupportedTemplate(filename string) bool {
+	return supportedExtensions[strings.ToLower(filepath.Ext(filename))]
+}
+
+// checkEngine rejects a preferred engine the registry does not know; an empty
+// engine leaves the choice to the format routing
+func checkEngine(r *converter.Registry, engine string) error {
+	if engine != "" && !r.Has(engine) {
+		return fmt.Errorf("unknown conversion engine %q", engine)
+	}
+	return nil
+}
+
+// withCallback validates the callback options and records them in the metadata
+func withCallback(metadata map[string]string, callbackURL, tenant string) (map[string]string, error) {
+	if callbackURL != "" {
+		if err := webhook.ValidateURL(callbackURL); err != nil {
+			return nil, err
+		}
+	}
+	if metadata == nil {
+		metadata = make(map[string]string)
+	}
+	if callbackURL != "" {
+		metadata[webhook.MetadataCallbackURL] = callbackURL
+	}
+	if tenant != "" {
+		metadata[webhook.MetadataTenant] = tenant
+	}
+	return metadata, nil
 }
 
 // JobResponse represents a job response
@@ -40,12 +94,19 @@ type JobResponse struct {
 	CompletedAt      *time.Time                 `json:"completed_at,omitempty"`
 	Duration         string                     `json:"duration,omitempty"`
 	Error            string                     `json:"error,omitempty"`
+	Attempts         int                        `json:"attempts,omitempty"`
+	MaxAttempts      int                        `json:"max_attempts,omitempty"`
+	NextAttemptAt    *time.Time                 `json:"next_attempt_at,omitempty"`
+	Engine           string                     `json:"engine,omitempty"`
+	OutputFormats    []string                   `json:"output_formats,omitempty"`
 	DownloadURL      string                     `json:"download_url,omitempty"`
+	Downloads        map[string]string          `json:"downloads,omitempty"` // Download URL per output format
+	Artifacts        map[string]string          `json:"artifacts,omitempty"` // URL per analysis sidecar, e.g. "macros"
 	ComplexityReport *analyzer.ComplexityReport `json:"complexity_report,omitempty"`
 }
 
 // ConvertHandler handles single file conversion
-func ConvertHandler(q queue.Queue, s storage.Storage) gin.HandlerFunc {
+func ConvertHandler(q queue.Queue, s storage.Storage, r *converter.Registry) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Parse multipart form
 		file, header, err := c.Request.FormFile("file")
@@ -57,8 +118,8 @@ func ConvertHandler(q queue.Queue, s storage.Storage) gin.HandlerFunc {
 
 		// Validate file extension
 		ext := filepath.Ext(header.Filename)
-		if ext != ".dot" && ext != ".DOT" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "only .dot files are supported"})
+		if !isSupportedTemplate(header.Filename) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "only .dot, .dotx and .dotm files are supported"})
 			return
 		}
 
@@ -68,6 +129,22 @@ func ConvertHandler(q queue.Queue, s storage.Storage) gin.HandlerFunc {
 			log.Warnf("Failed to parse request options: %v", err)
 		}
 
+		formats, err := converter.ParseOutputFormats(req.OutputFormat)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := checkEngine(r, req.Engine); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		metadata, err := withCallback(req.Metadata, req.CallbackURL, req.Tenant)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
 		// Generate job ID
 		jobID := uuid.New().String()
 
@@ -93,8 +170,8 @@ func ConvertHandler(q queue.Queue, s storage.Storage) gin.HandlerFunc {
 			return
 		}
 
-		// Create output path
-		outputFilename := header.Filename[:len(header.Filename)-len(ext)] + ".docx"
+		// Create output path for the primary format; other formats swap the extension
+		outputFilename := converter.WithOutputExtension(header.Filename[:len(header.Filename)-len(ext)], formats[0])
 		outputPath := fmt.Sprintf("outputs/%s/%s", jobID, outputFilename)
 
 		// Create job
@@ -105,7 +182,15 @@ func ConvertHandler(q queue.Queue, s storage.Storage) gin.HandlerFunc {
 			Status:     queue.StatusPending,
 			Priority:   req.Priority,
 			CreatedAt:  time.Now(),
-			Metadata:   req.Metadata,
+			Formats:    formats,
+			Metadata:   metadata,
+		}
+
+		if req.Engine != "" {
+			job.Metadata["engine"] = req.Engine
+		}
+		if req.Annotate {
+			job.Metadata["annotate"] = "true"
 		}
 
 		// Add to queue
@@ -113,6 +198,7 @@ func ConvertHandler(q queue.Queue, s storage.Storage) gin.HandlerFunc {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to queue job"})
 			return
 		}
+		PublishJobEvent(JobEventQueued, job)
 
 		// Store complexity report in job metadata
 		if job.Metadata == nil {
@@ -131,6 +217,7 @@ func ConvertHandler(q queue.Queue, s storage.Storage) gin.HandlerFunc {
 			InputPath:        job.InputPath,
 			OutputPath:       job.OutputPath,
 			CreatedAt:        job.CreatedAt,
+			OutputFormats:    job.Formats,
 			ComplexityReport: complexityReport,
 		}
 
@@ -139,7 +226,7 @@ func ConvertHandler(q queue.Queue, s storage.Storage) gin.HandlerFunc {
 }
 
 // BatchConvertHandler handles batch conversion requests
-func BatchConvertHandler(q queue.Queue, s storage.Storage) gin.HandlerFunc {
+func BatchConvertHandler(q queue.Queue, s storage.Storage, r *converter.Registry) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req BatchConvertRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -158,16 +245,34 @@ func BatchConvertHandler(q queue.Queue, s storage.Storage) gin.HandlerFunc {
 			return
 		}
 
+		formats, err := converter.ParseOutputFormats(req.OutputFormat)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := checkEngine(r, req.Engine); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Batch jobs share the batch's callback so each job and the batch report to it
+		callback, err := withCallback(nil, req.CallbackURL, req.Tenant)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
 		// Create batch ID
 		batchID := uuid.New().String()
 		jobs := make([]JobResponse, 0, len(req.Files))
+		jobIDs := make([]string, 0, len(req.Files))
 
 		// Create jobs for each file
 		for _, file := range req.Files {
 			// Validate extension
 			ext := filepath.Ext(file)
-			if ext != ".dot" && ext != ".DOT" {
-				log.Warnf("Skipping non-.dot file: %s", file)
+			if !isSupportedTemplate(file) {
+				log.Warnf("Skipping unsupported file: %s", file)
 				continue
 			}
 
@@ -176,7 +281,7 @@ func BatchConvertHandler(q queue.Queue, s storage.Storage) gin.HandlerFunc {
 
 			// Create paths
 			inputPath := filepath.Join(req.Source, file)
-			outputFile := file[:len(file)-len(ext)] + ".docx"
+			outputFile := converter.WithOutputExtension(file[:len(file)-len(ext)], formats[0])
 			outputPath := filepath.Join(req.Destination, outputFile)
 
 			// Create job
@@ -187,31 +292,64 @@ func BatchConvertHandler(q queue.Queue, s storage.Storage) gin.HandlerFunc {
 				Status:     queue.StatusPending,
 				Priority:   req.Priority,
 				CreatedAt:  time.Now(),
+				Formats:    formats,
 				Metadata: map[string]string{
 					"batch_id": batchID,
 					"filename": file,
 				},
 			}
+			if req.Engine != "" {
+				job.Metadata["engine"] = req.Engine
+			}
+			if req.Annotate {
+				job.Metadata["annotate"] = "true"
+			}
+			for key, value := range callback {
+				job.Metadata[key] = value
+			}
 
 			// Add to queue
 			if err := q.Enqueue(c, job); err != nil {
 				log.Errorf("Failed to queue job for %s: %v", file, err)
 				continue
 			}
+			PublishJobEvent(JobEventQueued, job)
 
+			jobIDs = append(jobIDs, job.ID)
 			jobs = append(jobs, JobResponse{
-				JobID:      job.ID,
-				Status:     job.Status,
-				InputPath:  job.InputPath,
-				OutputPath: job.OutputPath,
-				CreatedAt:  job.CreatedAt,
+				JobID:         job.ID,
+				Status:        job.Status,
+				InputPath:     job.InputPath,
+				OutputPath:    job.OutputPath,
+				CreatedAt:     job.CreatedAt,
+				OutputFormats: job.Formats,
 			})
 		}
 
+		batch := &queue.Batch{
+			ID:        batchID,
+			Status:    queue.BatchStatusPending,
+			JobIDs:    jobIDs,
+			Total:     len(jobIDs),
+			Pending:   len(jobIDs),
+			CreatedAt: time.Now(),
+			Metadata: map[string]string{
+				"source":      req.Source,
+				"destination": req.Destination,
+			},
+		}
+		for key, value := range callback {
+			batch.Metadata[key] = value
+		}
+		if err := q.CreateBatch(c, batch); err != nil {
+			log.Errorf("Failed to store batch %s: %v", batchID, err)
+		}
+
 		c.JSON(http.StatusAccepted, gin.H{
-			"batch_id": batchID,
-			"jobs":     jobs,
-			"count":    len(jobs),
+			"batch_id":   batchID,
+			"status_url": fmt.Sprintf("/api/v1/batches/%s", batchID),
+			"jobs":       jobs,
+			"count":      len(jobs),
 		})
 	}
 }
@@ -232,14 +370,19 @@ func GetJobStatus(q queue.Queue) gin.HandlerFunc {
 		}
 
 		response := JobResponse{
-			JobID:       job.ID,
-			Status:      job.Status,
-			InputPath:   job.InputPath,
-			OutputPath:  job.OutputPath,
-			CreatedAt:   job.CreatedAt,
-			StartedAt:   job.StartedAt,
-			CompletedAt: job.CompletedAt,
-			Error:       job.Error,
+			JobID:         job.ID,
+			Status:        job.Status,
+			InputPath:     job.InputPath,
+			OutputPath:    job.OutputPath,
+			CreatedAt:     job.CreatedAt,
+			StartedAt:     job.StartedAt,
+			CompletedAt:   job.CompletedAt,
+			Error:         job.Error,
+			Attempts:      job.Attempts,
+			MaxAttempts:   job.MaxAttempts,
+			NextAttemptAt: job.NextAttemptAt,
+			Engine:        job.Engine,
+			OutputFormats: job.Formats,
 		}
 
 		if job.Duration > 0 {
@@ -248,6 +391,21 @@ func GetJobStatus(q queue.Queue) gin.HandlerFunc {
 
 		if job.Status == queue.StatusCompleted {
 			response.DownloadURL = fmt.Sprintf("/api/v1/download/%s", job.ID)
+			if len(job.Outputs) > 0 {
+				response.Downloads = make(map[string]string, len(job.Outputs))
+				for format := range job.Outputs {
+					response.Downloads[format] = fmt.Sprintf("/api/v1/download/%s?format=%s", job.ID, format)
+				}
+			}
+			for _, artifact := range []string{queue.ArtifactMacros, queue.ArtifactAnnotated} {
+				if _, ok := job.Artifacts[artifact]; !ok {
+					continue
+				}
+				if response.Artifacts == nil {
+					response.Artifacts = make(map[string]string)
+				}
+				response.Artifacts[artifact] = fmt.Sprintf("/api/v1/jobs/%s/%s", job.ID, artifact)
+			}
 		}
 
 		c.JSON(http.StatusOK, response)
@@ -269,6 +427,52 @@ func ListJobs(q queue.Queue) gin.HandlerFunc {
 		responses := make([]JobResponse, 0, len(jobs))
 		for _, job := range jobs {
 			response := JobResponse{
+				JobID:         job.ID,
+				Status:        job.Status,
+				InputPath:     job.InputPath,
+				OutputPath:    job.OutputPath,
+				CreatedAt:     job.CreatedAt,
+				StartedAt:     job.StartedAt,
+				CompletedAt:   job.CompletedAt,
+				Error:         job.Error,
+				Attempts:      job.Attempts,
+				MaxAttempts:   job.MaxAttempts,
+				NextAttemptAt: job.NextAttemptAt,
+				Engine:        job.Engine,
+				OutputFormats: job.Formats,
+			}
+
+			if job.Duration > 0 {
+				response.Duration = job.Duration.String()
+			}
+
+			responses = append(responses, response)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"jobs":  responses,
+			"count": len(responses),
+		})
+	}
+}
+
+// ListDeadLetterJobs lists jobs that exhausted their retries
+func ListDeadLetterJobs(q queue.Queue) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := 100 // Default limit
+		if value, err := strconv.Atoi(c.Query("limit")); err == nil && value > 0 {
+			limit = value
+		}
+
+		jobs, err := q.ListDeadLetter(c, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		responses := make([]JobResponse, 0, len(jobs))
+		for _, job := range jobs {
+			responses = append(responses, JobResponse{
 				JobID:       job.ID,
 				Status:      job.Status,
 				InputPath:   job.InputPath,
@@ -277,24 +481,72 @@ func ListJobs(q queue.Queue) gin.HandlerFunc {
 				StartedAt:   job.StartedAt,
 				CompletedAt: job.CompletedAt,
 				Error:       job.Error,
-			}
+				Attempts:    job.Attempts,
+				MaxAttempts: job.MaxAttempts,
+			})
+		}
 
-			if job.Duration > 0 {
-				response.Duration = job.Duration.String()
+		c.JSON(http.StatusOK, gin.H{
+			"jobs":  responses,
+			"count": len(responses),
+		})
+	}
+}
+
+// RedriveJob moves a dead-lettered job back to the queue
+func RedriveJob(q queue.Queue) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, err := q.Redrive(c, c.Param("id"))
+		if err != nil {
+			switch err {
+			case queue.ErrJobNotFound:
+				c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+			case queue.ErrNotDeadLettered:
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			}
+			return
+		}
+		PublishJobEvent(JobEventQueued, job)
 
-			responses = append(responses, response)
+		c.JSON(http.StatusOK, gin.H{
+			"message": "job re-driven",
+			"job_id":  job.ID,
+			"status":  job.Status,
+		})
+	}
+}
+
+// RedriveAllJobs moves every dead-lettered job back to the queue
+func RedriveAllJobs(q queue.Queue) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jobs, err := q.ListDeadLetter(c, 0)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		redriven := make([]string, 0, len(jobs))
+		for _, job := range jobs {
+			redrivenJob, err := q.Redrive(c, job.ID)
+			if err != nil {
+				log.Warnf("Failed to re-drive job %s: %v", job.ID, err)
+				continue
+			}
+			PublishJobEvent(JobEventQueued, redrivenJob)
+			redriven = append(redriven, job.ID)
 		}
 
 		c.JSON(http.StatusOK, gin.H{
-			"jobs":  responses,
-			"count": len(responses),
+			"job_ids": redriven,
+			"count":   len(redriven),
 		})
 	}
 }
 
 // CancelJob cancels a pending job
-func CancelJob(q queue.Queue) gin.HandlerFunc {
+func CancelJob(q queue.Queue, d *webhook.Dispatcher) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		jobID := c.Param("id")
 
@@ -307,42 +559,130 @@ func CancelJob(q queue.Queue) gin.HandlerFunc {
 			return
 		}
 
+		if job, err := q.GetJob(c, jobID); err == nil {
+			PublishJobEvent(JobEventCancelled, job)
+			d.JobFinished(c, job)
+		}
+
 		c.JSON(http.StatusOK, gin.H{"message": "job cancelled"})
 	}
 }
 
-// DownloadFile downloads the converted file
-func DownloadFile(s storage.Storage) gin.HandlerFunc {
+// DownloadFile downloads the converted file of a job; ?format= selects one of
+// several outputs
+func DownloadFile(q queue.Queue, s storage.Storage) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		jobID := c.Param("id")
+		job, err := q.GetJob(c, c.Param("id"))
+		if err != nil {
+			if err == queue.ErrJobNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 
-		// For now, construct the path based on job ID
-		// In production, you'd look up the job to get the actual output path
-		outputPath := fmt.Sprintf("outputs/%s/", jobID)
+		// Default to DOCX when produced, otherwise the job's first format
+		format := c.Query("format")
+		if format != "" {
+			output, ok := converter.LookupOutputFormat(format)
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("unsupported output format %q", format)})
+				return
+			}
+			format = output.Name
+		} else if _, ok := job.Outputs[converter.OutputDOCX]; ok || len(job.Formats) == 0 {
+			format = converter.OutputDOCX
+		} else {
+			format = job.Formats[0]
+		}
 
-		// Find the file
-		files, err := s.List(c, outputPath)
-		if err != nil || len(files) == 0 {
-			c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+		selected, ok := job.Outputs[format]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "converted file not found"})
 			return
 		}
 
-		// Download the first DOCX file found
-		for _, file := range files {
-			if filepath.Ext(file) == ".docx" {
-				data, err := s.ReadFile(c, file)
-				if err != nil {
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read file"})
-					return
-				}
+		data, err := s.ReadFile(c, selected)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read file"})
+			return
+		}
+
+		filename := filepath.Base(selected)
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Data(http.StatusOK, converter.ContentTypeForPath(selected), data)
+	}
+}
+
+// GetJobMacros returns the VBA modules extracted from a job's template
+func GetJobMacros(q queue.Queue, s storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, err := q.GetJob(c, c.Param("id"))
+		if err != nil {
+			if err == queue.ErrJobNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		path, ok := job.Artifacts[queue.ArtifactMacros]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no macros extracted for this job"})
+			return
+		}
+
+		data, err := s.ReadFile(c, path)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read macros"})
+			return
+		}
+		c.Data(http.StatusOK, "application/json", data)
+	}
+}
 
-				filename := filepath.Base(file)
-				c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
-				c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.wordprocessingml.document", data)
+// GetJobAnnotated downloads the converted DOCX of a job with a review comment
+// on each complexity issue
+func GetJobAnnotated(q queue.Queue, s storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, err := q.GetJob(c, c.Param("id"))
+		if err != nil {
+			if err == queue.ErrJobNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
 				return
 			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		path, ok := job.Artifacts[queue.ArtifactAnnotated]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no annotated document for this job"})
+			return
 		}
 
-		c.JSON(http.StatusNotFound, gin.H{"error": "converted file not found"})
+		data, err := s.ReadFile(c, path)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to read annotated document"})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(path)))
+		c.Data(http.StatusOK, converter.ContentTypeForPath(path), data)
+	}
+}
+
+// ListEngines lists the available conversion engines and format routing
+func ListEngines(r *converter.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		engines := r.Engines()
+
+		c.JSON(http.StatusOK, gin.H{
+			"engines":        engines,
+			"routing":        r.Routing(),
+			"output_formats": converter.OutputFormats(),
+			"count":          len(engines),
+		})
 	}
 }
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/converter"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/queue"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+func TestDownloadAnnotatedJob(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	q := queue.NewMemoryQueue()
+	s := storage.NewLocalStorage(t.TempDir())
+
+	job := &queue.Job{ID: "job-1", Status: queue.StatusPending, Formats: []string{converter.OutputDOCX, converter.OutputPDF}}
+	if err := q.Enqueue(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"outputs/job-1/letter.docx":             "converted",
+		"outputs/job-1/letter.pdf":              "pdf",
+		"artifacts/job-1/letter.annotated.docx": "annotated",
+	}
+	for path, content := range files {
+		if err := s.WriteFile(path, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	job.Status = queue.StatusCompleted
+	job.Outputs = map[string]string{converter.OutputDOCX: "outputs/job-1/letter.docx", converter.OutputPDF: "outputs/job-1/letter.pdf"}
+	job.Artifacts = map[string]string{queue.ArtifactAnnotated: "artifacts/job-1/letter.annotated.docx"}
+	if err := q.UpdateJob(job); err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.GET("/download/:id", DownloadFile(q, s))
+	router.GET("/jobs/:id/annotated", GetJobAnnotated(q, s))
+
+	tests := []struct {
+		url    string
+		status int
+		body   string
+	}{
+		{"/download/job-1", http.StatusOK, "converted"},
+		{"/download/job-1?format=pdf", http.StatusOK, "pdf"},
+		{"/download/job-1?format=odt", http.StatusNotFound, ""},
+		{"/download/job-1?format=exe", http.StatusBadRequest, ""},
+		{"/download/job-2", http.StatusNotFound, ""},
+		{"/jobs/job-1/annotated", http.StatusOK, "annotated"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+		if w.Code != tt.status {
+			t.Errorf("GET %s = %d, want %d: %s", tt.url, w.Code, tt.status, w.Body)
+			continue
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("GET %s served %q, want %q", tt.url, w.Body, tt.body)
+		}
+	}
+}
+
+func TestBatchConvertRejectsUnknownEngine(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	q := queue.NewMemoryQueue()
+	registry := converter.NewRegistry()
+	registry.Register(converter.EngineNative, converter.NewNativeConverter(converter.NativeOptions{}, nil))
+
+	router := gin.New()
+	router.POST("/batch", BatchConvertHandler(q, storage.NewLocalStorage(t.TempDir()), registry))
+
+	tests := []struct {
+		engine string
+		status int
+	}{
+		{"", http.StatusAccepted},
+		{converter.EngineNative, http.StatusAccepted},
+		{"nativ", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		body := `{"source": "in", "destination": "out", "files": ["letter.dot"], "engine": "` + tt.engine + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("engine %q = %d, want %d: %s", tt.engine, w.Code, tt.status, w.Body)
+		}
+	}
+	if size, _ := q.Size(); size != 2 {
+		t.Errorf("queued %d jobs, want 2", size)
+	}
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"net/http"
 
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/converter"
 	"github.com/alterspective-engine/dot-to-docx-converter/internal/queue"
 	"github.com/gin-gonic/gin"
 )
@@ -27,8 +28,9 @@ func LivenessCheck() gin.HandlerFunc {
 	}
 }
 
-// ReadinessCheck checks if the service is ready to accept requests
-func ReadinessCheck(q queue.Queue) gin.HandlerFunc {
+// ReadinessCheck checks if the service is ready to accept requests.
+// When an office pool is configured its health is included in the response.
+func ReadinessCheck(q queue.Queue, pool *converter.OfficePool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Check queue connectivity
 		if _, err := q.Size(); err != nil {
@@ -39,6 +41,24 @@ func ReadinessCheck(q queue.Queue) gin.HandlerFunc {
 			return
 		}
 
+		if pool != nil {
+			health := pool.Health()
+			if !health.Ready() {
+				c.JSON(http.StatusServiceUnavailable, gin.H{
+					"status":      "not ready",
+					"error":       "no office instances available",
+					"office_pool": health,
+				})
+				return
+			}
+
+			c.JSON(http.StatusOK, gin.H{
+				"status":      "ready",
+				"office_pool": health,
+			})
+			return
+		}
+
 		c.JSON(http.StatusOK, gin.H{
 			"status": "ready",
 		})
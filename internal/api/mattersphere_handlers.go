@@ -0,0 +1,127 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/converter"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/mattersphere"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/queue"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/storage"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// MatterSphereImportRequest queues the precedents of MatterSphere exports
+type MatterSphereImportRequest struct {
+	Root         string `json:"root" binding:"required"`        // Storage prefix, or directory under the import directory, holding the exports
+	Destination  string `json:"destination" binding:"required"` // Storage prefix of the converted documents
+	Priority     int    `json:"priority"`
+	Engine       string `json:"engine"`
+	OutputFormat string `json:"output_format"`
+	CallbackURL  string `json:"callback_url"`
+	Tenant       string `json:"tenant"`
+	Annotate     bool   `json:"annotate"`
+}
+
+// ImportMatterSphereHandler queues one conversion job per precedent of the
+// MatterSphere exports under a root as a batch. Roots are storage prefixes, or
+// directories under importDir, the only local directory callers may name.
+func ImportMatterSphereHandler(q queue.Queue, s storage.Storage, registry *converter.Registry, importDir string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req MatterSphereImportRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		root, local, err := mattersphere.ResolveRoot(importDir, req.Root)
+		if err != nil {
+			if errors.Is(err, mattersphere.ErrRootNotAllowed) || errors.Is(err, mattersphere.ErrInvalidPrefix) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			log.Errorf("Failed to resolve MatterSphere import root %s: %v", req.Root, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := mattersphere.ValidPrefix(req.Destination); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		formats, err := converter.ParseOutputFormats(req.OutputFormat)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := checkEngine(registry, req.Engine); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		callback, err := withCallback(nil, req.CallbackURL, req.Tenant)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		r, err := mattersphere.NewImporter(q, s).Import(c, root, mattersphere.ImportOptions{
+			Destination: req.Destination,
+			Formats:     formats,
+			Priority:    req.Priority,
+			Engine:      req.Engine,
+			Annotate:    req.Annotate,
+			Metadata:    callback,
+			StorageOnly: !local,
+		})
+		if err != nil {
+			if errors.Is(err, mattersphere.ErrNoManifest) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			log.Errorf("Failed to import MatterSphere exports from %s: %v", req.Root, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		log.Infof("Queued %d of %d MatterSphere precedents from %s as batch %s",
+			r.Queued, len(r.Entries), req.Root, r.BatchID)
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"batch_id":           r.BatchID,
+			"status_url":         fmt.Sprintf("/api/v1/batches/%s", r.BatchID),
+			"reconciliation_url": fmt.Sprintf("/api/v1/batches/%s/reconciliation", r.BatchID),
+			"count":              r.Queued,
+			"precedents":         len(r.Entries),
+		})
+	}
+}
+
+// GetBatchReconciliation returns the reconciliation report of an import batch,
+// refreshed with the status of its jobs, as JSON or, with format=csv, as CSV
+func GetBatchReconciliation(q queue.Queue, s storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		r, err := mattersphere.NewImporter(q, s).Reconcile(c, c.Param("id"))
+		if err != nil {
+			switch {
+			case errors.Is(err, queue.ErrBatchNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": "batch not found"})
+			case errors.Is(err, mattersphere.ErrNoReconciliation):
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+
+		if c.Query("format") == "csv" {
+			c.Header("Content-Type", "text/csv")
+			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "reconciliation-"+r.BatchID+".csv"))
+			if err := r.WriteCSV(c.Writer); err != nil {
+				log.Errorf("Failed to write reconciliation of batch %s: %v", r.BatchID, err)
+			}
+			return
+		}
+		c.JSON(http.StatusOK, r)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/http"
 	"runtime"
 	"sync"
@@ -161,78 +162,64 @@ func GetSystemMetrics() SystemMetrics {
 // MetricsHandler returns current metrics for the dashboard
 func MetricsHandler(q queue.Queue) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		metricsCollector.mu.RLock()
-		defer metricsCollector.mu.RUnlock()
-
-		// Calculate success rate
-		total := metricsCollector.totalProcessed + metricsCollector.totalFailed
-		successRate := 0.0 // Default to 0 if no data
-		if total > 0 {
-			successRate = float64(metricsCollector.totalProcessed) / float64(total)
-		}
-
-		// Get queue status if available
-		if q != nil {
-			// Try to get real queue metrics
-			ctx := c.Request.Context()
-			jobs, err := q.ListJobs(ctx, "", 100)
-			if err == nil {
-				pending := 0
-				processing := 0
-				completed := 0
-				failed := 0
-
-				for _, job := range jobs {
-					switch job.Status {
-					case "pending":
-						pending++
-					case "processing":
-						processing++
-					case "completed":
-						completed++
-					case "failed":
-						failed++
-					}
-				}
+		c.JSON(http.StatusOK, snapshotMetrics(c.Request.Context(), q))
+	}
+}
 
-				metricsCollector.queueStatus = QueueStatus{
-					Pending:    pending,
-					Processing: processing,
-					Completed:  completed,
-					Failed:     failed,
+// snapshotMetrics refreshes the queue status and returns the current metrics
+func snapshotMetrics(ctx context.Context, q queue.Queue) MetricsResponse {
+	// Get queue status if available
+	var queueStatus *QueueStatus
+	if q != nil {
+		// Try to get real queue metrics
+		jobs, err := q.ListJobs(ctx, "", 100)
+		if err == nil {
+			status := QueueStatus{}
+			for _, job := range jobs {
+				switch job.Status {
+				case "pending":
+					status.Pending++
+				case "processing":
+					status.Processing++
+				case "completed":
+					status.Completed++
+				case "failed":
+					status.Failed++
 				}
 			}
+			queueStatus = &status
 		}
+	}
 
-		// Prepare timeline data (last 24 data points for hourly view)
-		timeline := metricsCollector.timeline
-		if len(timeline) > 24 {
-			timeline = timeline[len(timeline)-24:]
-		}
+	metricsCollector.mu.Lock()
+	defer metricsCollector.mu.Unlock()
 
-		response := MetricsResponse{
-			ProcessingNow:     metricsCollector.processingNow,
-			TotalProcessed:    metricsCollector.totalProcessed,
-			TotalFailed:       metricsCollector.totalFailed,
-			SuccessRate:       successRate,
-			AvgProcessingTime: metricsCollector.avgProcessingTime,
-			Timeline:          timeline,
-			QueueStatus:       metricsCollector.queueStatus,
-			SystemMetrics:     GetSystemMetrics(),
-			LastUpdated:       metricsCollector.lastUpdated,
-		}
+	if queueStatus != nil {
+		metricsCollector.queueStatus = *queueStatus
+	}
 
-		c.JSON(http.StatusOK, response)
+	// Calculate success rate
+	total := metricsCollector.totalProcessed + metricsCollector.totalFailed
+	successRate := 0.0 // Default to 0 if no data
+	if total > 0 {
+		successRate = float64(metricsCollector.totalProcessed) / float64(total)
 	}
-}
 
-// WebSocketMetricsHandler provides real-time metrics updates via WebSocket
-func WebSocketMetricsHandler() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// WebSocket implementation would go here
-		// For now, return a simple message
-		c.JSON(http.StatusOK, gin.H{
-			"message": "WebSocket endpoint for real-time metrics (not yet implemented)",
-		})
+	// Prepare timeline data (last 24 data points for hourly view)
+	timeline := metricsCollector.timeline
+	if len(timeline) > 24 {
+		timeline = timeline[len(timeline)-24:]
+	}
+
+	return MetricsResponse{
+		ProcessingNow:     metricsCollector.processingNow,
+		TotalProcessed:    metricsCollector.totalProcessed,
+		TotalFailed:       metricsCollector.totalFailed,
+		SuccessRate:       successRate,
+		AvgProcessingTime: metricsCollector.avgProcessingTime,
+		Timeline:          timeline,
+		QueueStatus:       metricsCollector.queueStatus,
+		SystemMetrics:     GetSystemMetrics(),
+		LastUpdated:       metricsCollector.lastUpdated,
 	}
 }
@@ -3,7 +3,6 @@ package api
 import (
 	"fmt"
 	"net/http"
-	"path/filepath"
 
 	"github.com/alterspective-engine/dot-to-docx-converter/internal/cataloger"
 	"github.com/alterspective-engine/dot-to-docx-converter/internal/migration"
@@ -182,20 +181,10 @@ func ContentBlockHandler() gin.HandlerFunc {
 		}
 
 		generator := migration.NewContentBlockGenerator()
-		// Note: MinFrequency configuration would be added to generator if needed
+		generator.SetMiningOptions(req.Options.MinFrequency, req.Options.MinSimilarity)
 
-		// Generate blocks
-		analysis := generator.AnalyzeContent(documents)
-		blocks := make([]*migration.SharedoContentBlock, 0)
-
-		for _, common := range analysis.CommonBlocks {
-			blockName := filepath.Base(common.Content)
-			if blockName == "" {
-				blockName = "Block"
-			}
-			block := generator.GenerateContentBlock(common, blockName)
-			blocks = append(blocks, block)
-		}
+		// Generate a block per clause reused across the documents
+		blocks := generator.MineBlocks(documents)
 
 		c.JSON(http.StatusOK, ContentBlockResponse{
 			Success: true,
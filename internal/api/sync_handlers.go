@@ -18,13 +18,29 @@ import (
 
 // SyncConvertRequest represents a synchronous conversion request
 type SyncConvertRequest struct {
-	WaitTimeout int `form:"timeout" json:"timeout"` // Max wait time in seconds (default: 30)
+	WaitTimeout  int    `form:"timeout" json:"timeout"`             // Max wait time in seconds (default: 30)
+	Engine       string `form:"engine" json:"engine"`               // Preferred conversion engine (default: format routing)
+	OutputFormat string `form:"output_format" json:"output_format"` // Single output format: docx (default), dotx, pdf, odt
+}
+
+// parseSyncOutputFormat validates the output format of a synchronous request,
+// which can only return one artifact
+func parseSyncOutputFormat(value string) (converter.OutputFormat, error) {
+	formats, err := converter.ParseOutputFormats(value)
+	if err != nil {
+		return converter.OutputFormat{}, err
+	}
+	if len(formats) > 1 {
+		return converter.OutputFormat{}, fmt.Errorf("synchronous conversion supports a single output_format; use /api/v1/convert for multiple formats")
+	}
+	format, _ := converter.LookupOutputFormat(formats[0])
+	return format, nil
 }
 
 // ConvertSyncHandler handles synchronous file conversion
 // This endpoint converts the file immediately and returns the result
 // Suitable for smaller files and when immediate results are needed
-func ConvertSyncHandler(conv converter.Converter, maxFileSize int64, syncTimeout time.Duration) gin.HandlerFunc {
+func ConvertSyncHandler(registry *converter.Registry, maxFileSize int64, syncTimeout time.Duration) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 
@@ -38,8 +54,8 @@ func ConvertSyncHandler(conv converter.Converter, maxFileSize int64, syncTimeout
 
 		// Validate file extension
 		ext := filepath.Ext(header.Filename)
-		if ext != ".dot" && ext != ".DOT" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "only .dot files are supported"})
+		if !isSupportedTemplate(header.Filename) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "only .dot, .dotx and .dotm files are supported"})
 			return
 		}
 
@@ -57,6 +73,16 @@ func ConvertSyncHandler(conv converter.Converter, maxFileSize int64, syncTimeout
 		if err := c.ShouldBind(&req); err != nil {
 			log.Warnf("Failed to parse sync request options: %v", err)
 		}
+		if err := checkEngine(registry, req.Engine); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		outputFormat, err := parseSyncOutputFormat(req.OutputFormat)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
 		// Set default timeout if not specified
 		timeout := syncTimeout
@@ -104,8 +130,8 @@ func ConvertSyncHandler(conv converter.Converter, maxFileSize int64, syncTimeout
 			conversionID, complexityReport.Level, complexityReport.Score, complexityReport.NeedsReview)
 
 		// Set up output path
-		outputFilename := header.Filename[:len(header.Filename)-len(ext)] + ".docx"
-		outputPath := filepath.Join(tempDir, outputFilename)
+		outputFilename := header.Filename[:len(header.Filename)-len(ext)] + outputFormat.Extension
+		outputPath := filepath.Join(tempDir, "output", outputFilename) // separate dir so .dotx output never overwrites the input
 
 		// Create context with timeout for conversion
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
@@ -114,7 +140,7 @@ func ConvertSyncHandler(conv converter.Converter, maxFileSize int64, syncTimeout
 		// Perform synchronous conversion
 		log.Infof("Starting synchronous conversion for file: %s (ID: %s)", header.Filename, conversionID)
 
-		conversionErr := conv.Convert(ctx, inputPath, outputPath)
+		engine, conversionErr := registry.ConvertWith(ctx, inputPath, outputPath, req.Engine)
 		if conversionErr != nil {
 			// Check if it was a timeout
 			if ctx.Err() == context.DeadlineExceeded {
@@ -146,10 +172,11 @@ func ConvertSyncHandler(conv converter.Converter, maxFileSize int64, syncTimeout
 		log.Infof("Synchronous conversion completed in %v: %s -> %s", duration, header.Filename, outputFilename)
 
 		// Set response headers
-		c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.wordprocessingml.document")
+		c.Header("Content-Type", outputFormat.ContentType)
 		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", outputFilename))
 		c.Header("X-Conversion-Time", duration.String())
 		c.Header("X-Conversion-ID", conversionID)
+		c.Header("X-Conversion-Engine", engine)
 		c.Header("X-Complexity-Level", complexityReport.Level)
 		c.Header("X-Complexity-Score", fmt.Sprintf("%d", complexityReport.Score))
 		if complexityReport.NeedsReview {
@@ -157,13 +184,13 @@ func ConvertSyncHandler(conv converter.Converter, maxFileSize int64, syncTimeout
 		}
 
 		// Return the converted file directly
-		c.Data(http.StatusOK, "application/vnd.openxmlformats-officedocument.wordprocessingml.document", convertedData)
+		c.Data(http.StatusOK, outputFormat.ContentType, convertedData)
 	}
 }
 
 // ConvertSyncJSONHandler handles synchronous conversion with base64 encoded response
 // This is useful for API clients that prefer JSON responses
-func ConvertSyncJSONHandler(conv converter.Converter, maxFileSize int64, syncTimeout time.Duration) gin.HandlerFunc {
+func ConvertSyncJSONHandler(registry *converter.Registry, maxFileSize int64, syncTimeout time.Duration) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 
@@ -177,8 +204,8 @@ func ConvertSyncJSONHandler(conv converter.Converter, maxFileSize int64, syncTim
 
 		// Validate file extension
 		ext := filepath.Ext(header.Filename)
-		if ext != ".dot" && ext != ".DOT" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "only .dot files are supported"})
+		if !isSupportedTemplate(header.Filename) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "only .dot, .dotx and .dotm files are supported"})
 			return
 		}
 
@@ -190,6 +217,22 @@ func ConvertSyncJSONHandler(conv converter.Converter, maxFileSize int64, syncTim
 			return
 		}
 
+		// Parse request options
+		var req SyncConvertRequest
+		if err := c.ShouldBind(&req); err != nil {
+			log.Warnf("Failed to parse sync request options: %v", err)
+		}
+		if err := checkEngine(registry, req.Engine); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		outputFormat, err := parseSyncOutputFormat(req.OutputFormat)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
 		// Generate unique ID
 		conversionID := uuid.New().String()
 
@@ -231,15 +274,16 @@ func ConvertSyncJSONHandler(conv converter.Converter, maxFileSize int64, syncTim
 			conversionID, complexityReport.Level, complexityReport.Score, complexityReport.NeedsReview)
 
 		// Set up output
-		outputFilename := header.Filename[:len(header.Filename)-len(ext)] + ".docx"
-		outputPath := filepath.Join(tempDir, outputFilename)
+		outputFilename := header.Filename[:len(header.Filename)-len(ext)] + outputFormat.Extension
+		outputPath := filepath.Join(tempDir, "output", outputFilename) // separate dir so .dotx output never overwrites the input
 
 		// Create context with timeout
 		ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
 		defer cancel()
 
 		// Perform conversion
-		if err := conv.Convert(ctx, inputPath, outputPath); err != nil {
+		engine, err := registry.ConvertWith(ctx, inputPath, outputPath, req.Engine)
+		if err != nil {
 			if ctx.Err() == context.DeadlineExceeded {
 				c.JSON(http.StatusRequestTimeout, gin.H{"error": "conversion timeout"})
 				return
@@ -262,8 +306,11 @@ func ConvertSyncJSONHandler(conv converter.Converter, maxFileSize int64, syncTim
 			"success":           true,
 			"conversion_id":     conversionID,
 			"filename":          outputFilename,
+			"output_format":     outputFormat.Name,
+			"content_type":      outputFormat.ContentType,
 			"size":              len(convertedData),
 			"duration":          duration.String(),
+			"engine":            engine,
 			"download_url":      fmt.Sprintf("/api/v1/sync/download/%s", conversionID),
 			"complexity_report": complexityReport,
 		})
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+// ListWebhookDeliveries returns the webhook delivery log, filterable by job_id,
+// batch_id and status
+func ListWebhookDeliveries(d *webhook.Dispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := 100 // Default limit
+		if value, err := strconv.Atoi(c.Query("limit")); err == nil && value > 0 {
+			limit = value
+		}
+
+		filter := webhook.Filter{
+			JobID:   c.Query("job_id"),
+			BatchID: c.Query("batch_id"),
+			Status:  c.Query("status"),
+		}
+
+		deliveries, err := d.Deliveries(c, filter, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"deliveries": deliveries,
+			"count":      len(deliveries),
+		})
+	}
+}
+
+// GetWebhookDelivery returns one delivery with its payload and last error
+func GetWebhookDelivery(d *webhook.Dispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		delivery, err := d.Delivery(c, c.Param("id"))
+		if err != nil {
+			if err == webhook.ErrDeliveryNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "delivery not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, delivery)
+	}
+}
+
+// RedeliverWebhook resends a delivery, e.g. after a receiver outage exhausted its retries
+func RedeliverWebhook(d *webhook.Dispatcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		delivery, err := d.Redeliver(c, c.Param("id"))
+		if err != nil {
+			if err == webhook.ErrDeliveryNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "delivery not found"})
+				return
+			}
+			if err == webhook.ErrDisabled {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":  "delivery scheduled",
+			"delivery": delivery,
+		})
+	}
+}
@@ -0,0 +1,264 @@
+// Package calibration fits the complexity rules of the analyzer to outcomes
+// recorded by reviewers. Each labelled file is analysed with every detector
+// enabled; a logistic regression of the review outcome on detector occurrences
+// gives the weights, and the level thresholds are chosen to match the levels
+// reviewers assigned. The result is a rules file the analyzer can load.
+package calibration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/analyzer"
+)
+
+// Evaluation measures how well a configuration predicts the labels
+type Evaluation struct {
+	Files          int
+	TruePositives  int // Flagged for review and needed it
+	FalsePositives int
+	FalseNegatives int
+	TrueNegatives  int
+
+	// Confusion counts files by reviewer level, then by analyzer level
+	Confusion map[string]map[string]int
+}
+
+// Precision is the share of files flagged for review that needed it
+func (e Evaluation) Precision() float64 {
+	return ratio(e.TruePositives, e.TruePositives+e.FalsePositives)
+}
+
+// Recall is the share of files needing review that were flagged
+func (e Evaluation) Recall() float64 {
+	return ratio(e.TruePositives, e.TruePositives+e.FalseNegatives)
+}
+
+// LevelAccuracy is the share of files with a reviewer level that the analyzer
+// placed at the same level
+func (e Evaluation) LevelAccuracy() float64 {
+	var matched, total int
+	for reviewer, row := range e.Confusion {
+		for level, n := range row {
+			total += n
+			if level == reviewer {
+				matched += n
+			}
+		}
+	}
+	return ratio(matched, total)
+}
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
+
+// Levels returns the complexity levels in increasing order, for printing the
+// confusion matrix
+func Levels() []string {
+	return append([]string(nil), levels...)
+}
+
+// Evaluate compares reports with the labels of the same files
+func Evaluate(labels []Label, reports []*analyzer.ComplexityReport) Evaluation {
+	e := Evaluation{Confusion: make(map[string]map[string]int)}
+	for i, label := range labels {
+		report := reports[i]
+		e.Files++
+		switch needs := label.NeedsReview(); {
+		case needs && report.NeedsReview:
+			e.TruePositives++
+		case report.NeedsReview:
+			e.FalsePositives++
+		case needs:
+			e.FalseNegatives++
+		default:
+			e.TrueNegatives++
+		}
+		if label.Level != "" {
+			if e.Confusion[label.Level] == nil {
+				e.Confusion[label.Level] = make(map[string]int)
+			}
+			e.Confusion[label.Level][report.Level]++
+		}
+	}
+	return e
+}
+
+// crossValidationFolds is how many folds the labelled files are split into to
+// measure the fitted rules on files they were not fitted to
+const crossValidationFolds = 5
+
+// Result is the outcome of a calibration
+type Result struct {
+	Rules    *analyzer.Rules // Base rules with fitted weights and levels
+	Baseline Evaluation      // The base rules against the labels
+	Training Evaluation      // The fitted rules against the files they were fitted to
+	// CrossValidated evaluates each file with rules fitted to the other folds,
+	// estimating how the fitted rules do on new files; folds whose other files
+	// all have one outcome are left out
+	CrossValidated Evaluation
+	Folds          int
+	Skipped        []string // Labelled files that could not be read
+}
+
+// Calibrate analyses the labelled files found in sourceDir and fits base to
+// their labels. Files that cannot be read are skipped.
+func Calibrate(ctx context.Context, labels []Label, sourceDir string, base *analyzer.Rules) (*Result, error) {
+	baseConfig, err := base.Config()
+	if err != nil {
+		return nil, err
+	}
+	probe, _ := base.Config()
+	for name, d := range probe.Detectors {
+		d.Enabled = true
+		probe.Detectors[name] = d
+	}
+
+	result := &Result{}
+	var used []Label
+	var samples []Sample
+	var baseline []*analyzer.ComplexityReport
+	for _, label := range labels {
+		content, err := os.ReadFile(filepath.Join(sourceDir, label.Filename))
+		if err != nil {
+			result.Skipped = append(result.Skipped, label.Filename)
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		features := analyzer.AnalyzeComplexityWithConfig(ctx, content, probe)
+		samples = append(samples, Sample{Label: label, Detections: features.Detections})
+		baseline = append(baseline, analyzer.AnalyzeComplexityWithConfig(ctx, content, baseConfig))
+		used = append(used, label)
+	}
+	if len(used) == 0 {
+		return nil, fmt.Errorf("none of the %d labelled files were found in %s", len(labels), sourceDir)
+	}
+	result.Baseline = Evaluate(used, baseline)
+
+	fitted, err := fit(samples, base, baseConfig)
+	if err != nil {
+		return nil, err
+	}
+	reports, err := analyze(ctx, used, sourceDir, fitted)
+	if err != nil {
+		return nil, err
+	}
+	result.Training = Evaluate(used, reports)
+	result.Rules = fitted
+
+	// Each fold is evaluated with rules fitted to the others
+	folds := assignFolds(used, crossValidationFolds)
+	var heldOut []Label
+	var heldOutReports []*analyzer.ComplexityReport
+	for fold := 0; fold < crossValidationFolds; fold++ {
+		var training []Sample
+		var test []Label
+		for i, f := range folds {
+			if f == fold {
+				test = append(test, used[i])
+			} else {
+				training = append(training, samples[i])
+			}
+		}
+		if len(test) == 0 {
+			continue
+		}
+		rules, err := fit(training, base, baseConfig)
+		if errors.Is(err, ErrOneClass) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		reports, err := analyze(ctx, test, sourceDir, rules)
+		if err != nil {
+			return nil, err
+		}
+		heldOut = append(heldOut, test...)
+		heldOutReports = append(heldOutReports, reports...)
+		result.Folds++
+	}
+	result.CrossValidated = Evaluate(heldOut, heldOutReports)
+	return result, nil
+}
+
+// fit fits the weights of base to the samples, then its level thresholds
+func fit(samples []Sample, base *analyzer.Rules, baseConfig *analyzer.ComplexityConfig) (*analyzer.Rules, error) {
+	baseWeights := make(map[string]int, len(baseConfig.Detectors))
+	for name, d := range baseConfig.Detectors {
+		baseWeights[name] = d.Weight
+	}
+	weights, err := FitWeights(samples, baseWeights, base.Levels.High)
+	if err != nil {
+		return nil, err
+	}
+
+	fitted := *base
+	fitted.Detectors = make(map[string]analyzer.DetectorRule, len(base.Detectors))
+	for name, rule := range base.Detectors {
+		fitted.Detectors[name] = rule
+	}
+	for name, w := range weights {
+		rule := fitted.Detectors[name]
+		weight := w
+		rule.Weight = &weight
+		fitted.Detectors[name] = rule
+	}
+
+	// Score the samples under the new weights to place the level thresholds
+	scores := make([]int, len(samples))
+	reviewed := make([]string, len(samples))
+	for i, s := range samples {
+		for name, n := range s.Detections {
+			if baseConfig.Detectors[name].Enabled {
+				scores[i] += weights[name] * n
+			}
+		}
+		reviewed[i] = s.Label.Level
+	}
+	fitted.Levels.Medium, fitted.Levels.Critical = FitLevels(scores, reviewed,
+		[3]int{base.Levels.Medium, base.Levels.High, base.Levels.Critical})
+	return &fitted, nil
+}
+
+// analyze reports on the labelled files under rules
+func analyze(ctx context.Context, labels []Label, sourceDir string, rules *analyzer.Rules) ([]*analyzer.ComplexityReport, error) {
+	config, err := rules.Config()
+	if err != nil {
+		return nil, fmt.Errorf("fitted rules are invalid: %w", err)
+	}
+	reports := make([]*analyzer.ComplexityReport, 0, len(labels))
+	for _, label := range labels {
+		content, err := os.ReadFile(filepath.Join(sourceDir, label.Filename))
+		if err != nil {
+			return nil, fmt.Errorf("failed to re-read %s: %w", label.Filename, err)
+		}
+		reports = append(reports, analyzer.AnalyzeComplexityWithConfig(ctx, content, config))
+	}
+	return reports, nil
+}
+
+// assignFolds deals the files needing review and the clean files round-robin
+// into k folds, so every fold holds both outcomes where possible
+func assignFolds(labels []Label, k int) []int {
+	folds := make([]int, len(labels))
+	var next [2]int
+	for i, label := range labels {
+		class := 0
+		if label.NeedsReview() {
+			class = 1
+		}
+		folds[i] = next[class] % k
+		next[class]++
+	}
+	return folds
+}
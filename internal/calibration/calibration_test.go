@@ -0,0 +1,95 @@
+package calibration
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/analyzer"
+)
+
+func TestReadLabels(t *testing.T) {
+	data := "\ufeffFilename,FileSize,Conversion Status,Reviewer Level\n" +
+		"a.dot,10,success,low\n" +
+		"b.dot,20,needs_review,\n" +
+		",30,failed,\n" +
+		"c.dot,40,Success,High\n"
+
+	labels, err := ReadLabels(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadLabels failed: %v", err)
+	}
+	want := []Label{
+		{Filename: "a.dot", Outcome: "success", Level: "low"},
+		{Filename: "b.dot", Outcome: "needs_review"},
+		{Filename: "c.dot", Outcome: "success", Level: "high"},
+	}
+	if !reflect.DeepEqual(labels, want) {
+		t.Errorf("labels = %+v, want %+v", labels, want)
+	}
+	if labels[0].NeedsReview() || !labels[1].NeedsReview() || !labels[2].NeedsReview() {
+		t.Error("NeedsReview should follow the outcome and high levels")
+	}
+
+	if _, err := ReadLabels(strings.NewReader("Filename,Level\na.dot,low\n")); !errors.Is(err, ErrMissingColumn) {
+		t.Errorf("expected ErrMissingColumn, got %v", err)
+	}
+	if _, err := ReadLabels(strings.NewReader("file,status,level\na.dot,ok,extreme\n")); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	dir := t.TempDir()
+	formula := `Total {= SUM(ABOVE) * 1.1 } and {MERGEFIELD Client}`
+	plain := `Dear {MERGEFIELD Client}, your matter {MERGEFIELD Matter} is attached.`
+
+	var labels []Label
+	for i := 0; i < 6; i++ {
+		name, content, outcome, level := "plain"+string(rune('a'+i))+".txt", plain, "success", "low"
+		if i%2 == 0 {
+			name, content, outcome, level = "formula"+string(rune('a'+i))+".txt", formula, "needs_review", "high"
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		labels = append(labels, Label{Filename: name, Outcome: outcome, Level: level})
+	}
+	labels = append(labels, Label{Filename: "missing.txt", Outcome: "success"})
+
+	result, err := Calibrate(context.Background(), labels, dir, analyzer.DefaultRules())
+	if err != nil {
+		t.Fatalf("Calibrate failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(result.Skipped, []string{"missing.txt"}) {
+		t.Errorf("skipped = %v", result.Skipped)
+	}
+	// A single formula scores far below high with the built-in weights
+	if result.Baseline.Recall() != 0 {
+		t.Errorf("baseline recall = %.2f, want 0", result.Baseline.Recall())
+	}
+	if result.Training.Precision() != 1 || result.Training.Recall() != 1 {
+		t.Errorf("training precision = %.2f, recall = %.2f", result.Training.Precision(), result.Training.Recall())
+	}
+	if result.Training.Confusion["high"]["high"] != 3 || result.Training.Confusion["low"]["low"] != 3 {
+		t.Errorf("training confusion = %v", result.Training.Confusion)
+	}
+	// Three folds hold one file of each outcome; the other two are empty
+	if cv := result.CrossValidated; result.Folds != 3 || cv.Files != 6 || cv.Precision() != 1 || cv.Recall() != 1 {
+		t.Errorf("cross-validated over %d folds: %d files, precision %.2f, recall %.2f",
+			result.Folds, cv.Files, cv.Precision(), cv.Recall())
+	}
+
+	// The fitted rules load like any other rules file
+	if _, err := result.Rules.Config(); err != nil {
+		t.Errorf("fitted rules are invalid: %v", err)
+	}
+	if w := *result.Rules.Detectors["formulas"].Weight; w < analyzer.ComplexityScoreHigh {
+		t.Errorf("formulas weight = %d, want at least %d", w, analyzer.ComplexityScoreHigh)
+	}
+}
@@ -0,0 +1,183 @@
+package calibration
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// ErrOneClass is returned when every sample has the same review outcome, which
+// leaves nothing to separate
+var ErrOneClass = errors.New("labels need both reviewed and clean files")
+
+// Logistic regression settings; features are scaled to [0, 1] before fitting
+const (
+	fitIterations   = 5000
+	fitLearningRate = 0.5
+	fitL2           = 0.01
+)
+
+// Sample is the analysis of one labelled file
+type Sample struct {
+	Label      Label
+	Detections map[string]int // Occurrences per detector, with every detector enabled
+}
+
+// FitWeights fits a logistic regression of NeedsReview on detector occurrences
+// and scales the coefficients to integer weights, so that a file scores
+// reviewScore exactly where the model gives it even odds of needing review.
+// Detectors that never occur keep their base weight; weights are never negative.
+func FitWeights(samples []Sample, base map[string]int, reviewScore int) (map[string]int, error) {
+	var positives int
+	for _, s := range samples {
+		if s.Label.NeedsReview() {
+			positives++
+		}
+	}
+	if positives == 0 || positives == len(samples) {
+		return nil, ErrOneClass
+	}
+
+	// Features in a fixed order, scaled by their largest value
+	var names []string
+	scale := make(map[string]float64)
+	for _, s := range samples {
+		for name, n := range s.Detections {
+			if n <= 0 {
+				continue
+			}
+			if _, ok := scale[name]; !ok {
+				names = append(names, name)
+			}
+			scale[name] = math.Max(scale[name], float64(n))
+		}
+	}
+	sort.Strings(names)
+
+	x := make([][]float64, len(samples))
+	y := make([]float64, len(samples))
+	for i, s := range samples {
+		x[i] = make([]float64, len(names))
+		for j, name := range names {
+			x[i][j] = float64(s.Detections[name]) / scale[name]
+		}
+		if s.Label.NeedsReview() {
+			y[i] = 1
+		}
+	}
+
+	beta, intercept := logistic(x, y)
+
+	weights := make(map[string]int, len(base))
+	for name, w := range base {
+		weights[name] = w
+	}
+	if intercept >= 0 {
+		// Even files without detections are more likely than not to need
+		// review; the detectors cannot explain the labels
+		return weights, nil
+	}
+	for j, name := range names {
+		w := beta[j] / scale[name] * float64(reviewScore) / -intercept
+		weights[name] = int(math.Max(0, math.Round(w)))
+	}
+	return weights, nil
+}
+
+// logistic fits coefficients and an intercept by batch gradient descent with
+// L2 regularisation of the coefficients
+func logistic(x [][]float64, y []float64) (beta []float64, intercept float64) {
+	if len(x) == 0 {
+		return nil, 0
+	}
+	n, k := float64(len(x)), len(x[0])
+	beta = make([]float64, k)
+	gradient := make([]float64, k)
+
+	for iter := 0; iter < fitIterations; iter++ {
+		for j := range gradient {
+			gradient[j] = fitL2 * beta[j]
+		}
+		var interceptGradient float64
+		for i, row := range x {
+			z := intercept
+			for j, v := range row {
+				z += beta[j] * v
+			}
+			residual := 1/(1+math.Exp(-z)) - y[i]
+			interceptGradient += residual / n
+			for j, v := range row {
+				gradient[j] += residual * v / n
+			}
+		}
+		intercept -= fitLearningRate * interceptGradient
+		for j := range beta {
+			beta[j] -= fitLearningRate * gradient[j]
+		}
+	}
+	return beta, intercept
+}
+
+// FitLevels chooses the medium and critical score thresholds that best match
+// the reviewer-assigned levels of the scored files, keeping the high threshold
+// fixed. Ties, and samples without levels, keep the base thresholds.
+func FitLevels(scores []int, labelled []string, base [3]int) (medium, critical int) {
+	medium, high, critical := base[0], base[1], base[2]
+
+	// Candidate thresholds place each labelled score just at or just above one
+	mediums := []int{medium}
+	criticals := []int{critical}
+	for i, s := range scores {
+		if labelled[i] == "" {
+			continue
+		}
+		for _, t := range []int{s, s + 1} {
+			if t > 0 && t < high {
+				mediums = append(mediums, t)
+			}
+			if t > high {
+				criticals = append(criticals, t)
+			}
+		}
+	}
+
+	// Medium only separates files scoring below high and critical only those
+	// above, so each is chosen on its own
+	best := agreement(scores, labelled, medium, high, critical)
+	for _, m := range mediums {
+		if n := agreement(scores, labelled, m, high, critical); n > best {
+			best, medium = n, m
+		}
+	}
+	for _, c := range criticals {
+		if n := agreement(scores, labelled, medium, high, c); n > best {
+			best, critical = n, c
+		}
+	}
+	return medium, critical
+}
+
+// agreement counts the files whose level under the thresholds matches the label
+func agreement(scores []int, labelled []string, medium, high, critical int) int {
+	n := 0
+	for i, s := range scores {
+		if labelled[i] != "" && LevelOf(s, medium, high, critical) == labelled[i] {
+			n++
+		}
+	}
+	return n
+}
+
+// LevelOf returns the complexity level of a score
+func LevelOf(score, medium, high, critical int) string {
+	switch {
+	case score >= critical:
+		return "critical"
+	case score >= high:
+		return "high"
+	case score >= medium:
+		return "medium"
+	default:
+		return "low"
+	}
+}
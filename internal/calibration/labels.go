@@ -0,0 +1,122 @@
+package calibration
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrMissingColumn is returned when a labels file lacks a required column
+var ErrMissingColumn = errors.New("labels file is missing a column")
+
+// columnAliases map the accepted header names, lower case without spaces or
+// underscores, to the label field they fill
+var columnAliases = map[string]string{
+	"filename":              "filename",
+	"file":                  "filename",
+	"outcome":               "outcome",
+	"actualoutcome":         "outcome",
+	"conversionstatus":      "outcome",
+	"status":                "outcome",
+	"level":                 "level",
+	"reviewerlevel":         "level",
+	"reviewedlevel":         "level",
+	"reviewerassignedlevel": "level",
+}
+
+// cleanOutcomes are the outcomes of conversions that needed no reviewer
+var cleanOutcomes = map[string]bool{
+	"success":   true,
+	"ok":        true,
+	"converted": true,
+	"passed":    true,
+}
+
+// levels are the complexity levels in increasing order
+var levels = []string{"low", "medium", "high", "critical"}
+
+// Label is a reviewer's verdict on one converted file
+type Label struct {
+	Filename string
+	Outcome  string // Actual conversion outcome, e.g. success, needs_review or failed
+	Level    string // Reviewer-assigned complexity level; empty if not assessed
+}
+
+// NeedsReview reports whether the file should have been flagged for review:
+// its conversion did not succeed cleanly or the reviewer rated it high or critical
+func (l Label) NeedsReview() bool {
+	return !cleanOutcomes[l.Outcome] || l.Level == "high" || l.Level == "critical"
+}
+
+// ReadLabels reads a labelled CSV with a header row. It needs a filename and
+// an outcome column; a reviewer level column is optional. The validation
+// report written by the converter (Filename, ..., ConversionStatus) can be
+// used as it is.
+func ReadLabels(r io.Reader) ([]Label, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read labels header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		key := strings.ToLower(strings.NewReplacer(" ", "", "_", "", "\ufeff", "").Replace(name))
+		if field, ok := columnAliases[key]; ok {
+			if _, seen := columns[field]; !seen {
+				columns[field] = i
+			}
+		}
+	}
+	for _, required := range []string{"filename", "outcome"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMissingColumn, required)
+		}
+	}
+
+	value := func(record []string, field string) string {
+		i, ok := columns[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.ToLower(strings.TrimSpace(record[i]))
+	}
+
+	var labels []Label
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read labels: %w", err)
+		}
+		i := columns["filename"]
+		if i >= len(record) || strings.TrimSpace(record[i]) == "" {
+			continue
+		}
+		label := Label{
+			Filename: strings.TrimSpace(record[i]),
+			Outcome:  value(record, "outcome"),
+			Level:    value(record, "level"),
+		}
+		if label.Level != "" && levelIndex(label.Level) < 0 {
+			return nil, fmt.Errorf("line %d: unknown level %q", line, label.Level)
+		}
+		labels = append(labels, label)
+	}
+	return labels, nil
+}
+
+// levelIndex returns the position of a level in increasing order, or -1
+func levelIndex(level string) int {
+	for i, l := range levels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
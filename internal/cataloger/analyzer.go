@@ -9,6 +9,10 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/analyzer"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/field"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/inventory"
 )
 
 // FieldCategory represents the type of field
@@ -81,6 +85,14 @@ type DocumentCatalog struct {
 	Statistics       CatalogStatistics         `json:"statistics"`
 	Recommendations  []string                  `json:"recommendations"`
 	QualityMetrics   QualityMetrics            `json:"qualityMetrics"`
+	Scripts          map[string]*ScriptUsage   `json:"scripts,omitempty"` // Scripts templates depend on, by code
+	Families         []TemplateFamily          `json:"templateFamilies"`  // Near-identical templates, largest family first
+}
+
+// ScriptUsage is a script of the source system and the documents that use it
+type ScriptUsage struct {
+	analyzer.ScriptDependency
+	Documents []string `json:"documents"`
 }
 
 // DocumentProfile represents individual document analysis
@@ -102,6 +114,7 @@ type DocumentProfile struct {
 	ReviewRequired  bool              `json:"reviewRequired"`
 	ReviewReasons   []string          `json:"reviewReasons"`
 	Metadata        map[string]string `json:"metadata"`
+	Scripts         []string          `json:"scripts,omitempty"` // Codes of the scripts the template depends on
 	Warnings        []string          `json:"warnings"`
 }
 
@@ -123,6 +136,8 @@ type CatalogStatistics struct {
 	AutomationPotential float64        `json:"automationPotential"`
 	EstimatedSavings    string         `json:"estimatedSavings"`
 	MostCommonFields    []string       `json:"mostCommonFields"`
+	TemplateFamilies    int            `json:"templateFamilies"`
+	FamilyVariants      int            `json:"familyVariants"` // Non-canonical family members
 	ComplexityBreakdown map[string]int `json:"complexityBreakdown"`
 }
 
@@ -142,6 +157,7 @@ type DocumentAnalyzer struct {
 	fieldNormalizer  *FieldNormalizer
 	complexityScorer *ComplexityScorer
 	contentDetector  *ContentBlockDetector
+	familyDetector   *FamilyDetector
 	aiEnhancer       *AIEnhancer
 }
 
@@ -152,6 +168,7 @@ func NewDocumentAnalyzer(useAI bool) *DocumentAnalyzer {
 		fieldNormalizer:  NewFieldNormalizer(),
 		complexityScorer: NewComplexityScorer(),
 		contentDetector:  NewContentBlockDetector(),
+		familyDetector:   NewFamilyDetector(),
 	}
 
 	if useAI {
@@ -175,6 +192,8 @@ func (a *DocumentAnalyzer) AnalyzeDocuments(documents []DocumentData) (*Document
 		ComplexityDist:   make(map[ComplexityLevel]int),
 		DocumentProfiles: make([]DocumentProfile, 0, len(documents)),
 		MergeGroups:      make([]FieldMergeGroup, 0),
+		Scripts:          make(map[string]*ScriptUsage),
+		Families:         make([]TemplateFamily, 0),
 	}
 
 	// Process each document
@@ -185,6 +204,7 @@ func (a *DocumentAnalyzer) AnalyzeDocuments(documents []DocumentData) (*Document
 
 	// Post-processing analysis
 	a.detectContentBlocks(catalog)
+	a.detectFamilies(documents, catalog)
 	a.identifyFieldMergeGroups(catalog)
 	a.calculateStatistics(catalog)
 	a.generateRecommendations(catalog)
@@ -207,6 +227,9 @@ func (a *DocumentAnalyzer) analyzeDocument(doc DocumentData, catalog *DocumentCa
 		Metadata: make(map[string]string),
 		Warnings: make([]string, 0),
 	}
+	for key, value := range doc.Metadata {
+		profile.Metadata[key] = value
+	}
 
 	// Extract basic metrics
 	profile.PageCount = a.estimatePageCount(doc.Content)
@@ -229,9 +252,20 @@ func (a *DocumentAnalyzer) analyzeDocument(doc DocumentData, catalog *DocumentCa
 		profile.Fields = append(profile.Fields, field.Name)
 	}
 
+	// Scripts the source system runs with the template
+	for _, script := range doc.Scripts {
+		a.catalogScript(script, doc.Filename, catalog)
+		profile.Scripts = append(profile.Scripts, script.Code)
+	}
+
 	// Detect jurisdiction and matter type
 	profile.Jurisdiction = a.detectJurisdiction(doc.ExtractedText)
-	profile.MatterType = a.detectMatterType(doc.ExtractedText)
+	// Categories recorded by the source system outrank keyword detection
+	if category := doc.Metadata["category"]; category != "" {
+		profile.MatterType = category
+	} else {
+		profile.MatterType = a.detectMatterType(doc.ExtractedText)
+	}
 
 	// Calculate complexity
 	complexityScore := a.complexityScorer.Calculate(doc, fields)
@@ -253,15 +287,26 @@ func (a *DocumentAnalyzer) analyzeDocument(doc DocumentData, catalog *DocumentCa
 	return profile
 }
 
+// catalogScript records a document's use of a script
+func (a *DocumentAnalyzer) catalogScript(script analyzer.ScriptDependency, filename string, catalog *DocumentCatalog) {
+	usage, ok := catalog.Scripts[script.Code]
+	if !ok {
+		usage = &ScriptUsage{ScriptDependency: script}
+		catalog.Scripts[script.Code] = usage
+	}
+	usage.Documents = append(usage.Documents, filename)
+}
+
 // extractFields identifies and categorizes all fields
 func (a *DocumentAnalyzer) extractFields(text string) []*EnhancedField {
 	fields := make([]*EnhancedField, 0)
 	seen := make(map[string]bool)
 
-	// Extract different field types
-	basicFields := a.extractBasicFields(text)
-	calculatedFields := a.extractCalculatedFields(text)
-	conditionalFields := a.extractConditionalFields(text)
+	// Extract different field types from the parsed field tree
+	tree := field.ParseText(text)
+	basicFields := a.extractBasicFields(text, tree)
+	calculatedFields := a.extractCalculatedFields(tree)
+	conditionalFields := a.extractConditionalFields(tree)
 
 	// Combine and deduplicate
 	allFields := append(append(basicFields, calculatedFields...), conditionalFields...)
@@ -339,6 +384,15 @@ func (a *DocumentAnalyzer) detectContentBlocks(catalog *DocumentCatalog) {
 	}
 }
 
+// detectFamilies groups near-identical templates into families
+func (a *DocumentAnalyzer) detectFamilies(documents []DocumentData, catalog *DocumentCatalog) {
+	fields := make([][]string, len(catalog.DocumentProfiles))
+	for i, profile := range catalog.DocumentProfiles {
+		fields[i] = profile.Fields
+	}
+	catalog.Families = append(catalog.Families, a.familyDetector.Detect(documents, fields)...)
+}
+
 // identifyFieldMergeGroups finds similar fields that could be merged
 func (a *DocumentAnalyzer) identifyFieldMergeGroups(catalog *DocumentCatalog) {
 	fields := make([]string, 0, len(catalog.Fields))
@@ -414,20 +468,10 @@ func (a *DocumentAnalyzer) detectTables(text string) bool {
 }
 
 func (a *DocumentAnalyzer) detectImages(content []byte) bool {
-	imagePatterns := [][]byte{
-		[]byte("PNG"),
-		[]byte("JFIF"),
-		[]byte("JPEG"),
-		[]byte("GIF89"),
-		[]byte("BM"), // BMP
-	}
-
-	for _, pattern := range imagePatterns {
-		if strings.Contains(string(content), string(pattern)) {
-			return true
-		}
-	}
-	return false
+	// Signatures are checked on the document's pictures, as short markers such
+	// as "BM" turn up in any binary content
+	inv, err := inventory.Read(content)
+	return err == nil && len(inv.Images) > 0
 }
 
 func (a *DocumentAnalyzer) extractHeaderFooter(text string) (string, string) {
@@ -526,25 +570,42 @@ func (a *DocumentAnalyzer) assessReviewNeeds(profile DocumentProfile, fields []*
 		reasons = append(reasons, fmt.Sprintf("%d deeply nested fields detected", nestedCount))
 	}
 
+	if len(profile.Scripts) > 0 {
+		reasons = append(reasons, fmt.Sprintf("Depends on scripts %s that conversion does not preserve", strings.Join(profile.Scripts, ", ")))
+	}
+
 	return len(reasons) > 0, reasons
 }
 
-// Initialize regex patterns
+// Initialize regex patterns for placeholders that are not Word fields; Word
+// fields are read with the field package
 func initializePatterns() map[string]*regexp.Regexp {
 	return map[string]*regexp.Regexp{
-		"mergefield":  regexp.MustCompile(`(?i)«([^»]+)»|{{\s*([^}]+)\s*}}|\bMERGEFIELD\s+(\w+)`),
-		"formula":     regexp.MustCompile(`(?i)=\s*(?:SUM|AVG|COUNT|MAX|MIN|IF)\s*\([^)]+\)`),
-		"ifstatement": regexp.MustCompile(`(?i)(?:IF\s+[^{]+\s*{|{{\s*#if\s+[^}]+}})`),
+		"placeholder": regexp.MustCompile(`(?i)«([^»]+)»|{{\s*([^}]+)\s*}}`),
 		"date":        regexp.MustCompile(`(?i)\b(?:DATE|TODAY|NOW)\b`),
 		"lookup":      regexp.MustCompile(`(?i)\b(?:VLOOKUP|HLOOKUP|INDEX|MATCH|REF)\b`),
 	}
 }
 
 // Extract basic fields
-func (a *DocumentAnalyzer) extractBasicFields(text string) []*EnhancedField {
+func (a *DocumentAnalyzer) extractBasicFields(text string, tree []*field.Field) []*EnhancedField {
 	fields := make([]*EnhancedField, 0)
 
-	if pattern, exists := a.patterns["mergefield"]; exists {
+	for _, f := range field.OfType(tree, field.TypeMergeField) {
+		if f.Name == "" {
+			continue
+		}
+		fields = append(fields, &EnhancedField{
+			Name:           f.Name,
+			Category:       FieldCategoryBasic,
+			OriginalSyntax: f.String(),
+			DataType:       a.inferDataType(f.Name),
+			NestingLevel:   f.Depth,
+			Confidence:     0.95,
+		})
+	}
+
+	if pattern, exists := a.patterns["placeholder"]; exists {
 		matches := pattern.FindAllStringSubmatch(text, -1)
 		for _, match := range matches {
 			fieldName := ""
@@ -556,14 +617,14 @@ func (a *DocumentAnalyzer) extractBasicFields(text string) []*EnhancedField {
 			}
 
 			if fieldName != "" {
-				field := &EnhancedField{
+				placeholder := &EnhancedField{
 					Name:           fieldName,
 					Category:       FieldCategoryBasic,
 					OriginalSyntax: match[0],
 					DataType:       a.inferDataType(fieldName),
 					Confidence:     0.95,
 				}
-				fields = append(fields, field)
+				fields = append(fields, placeholder)
 			}
 		}
 	}
@@ -572,68 +633,56 @@ func (a *DocumentAnalyzer) extractBasicFields(text string) []*EnhancedField {
 }
 
 // Extract calculated fields
-func (a *DocumentAnalyzer) extractCalculatedFields(text string) []*EnhancedField {
+func (a *DocumentAnalyzer) extractCalculatedFields(tree []*field.Field) []*EnhancedField {
 	fields := make([]*EnhancedField, 0)
 
-	if pattern, exists := a.patterns["formula"]; exists {
-		matches := pattern.FindAllString(text, -1)
-		for _, match := range matches {
-			field := &EnhancedField{
-				Name:           fmt.Sprintf("formula_%d", len(fields)),
-				Category:       FieldCategoryCalculated,
-				OriginalSyntax: match,
-				DataType:       "numeric",
-				Confidence:     0.85,
-			}
-			fields = append(fields, field)
-		}
+	for _, f := range field.OfType(tree, field.TypeFormula) {
+		fields = append(fields, &EnhancedField{
+			Name:           fmt.Sprintf("formula_%d", len(fields)),
+			Category:       FieldCategoryCalculated,
+			OriginalSyntax: f.String(),
+			DataType:       "numeric",
+			NestingLevel:   f.Depth,
+			Confidence:     0.85,
+		})
 	}
 
 	return fields
 }
 
 // Extract conditional fields
-func (a *DocumentAnalyzer) extractConditionalFields(text string) []*EnhancedField {
+func (a *DocumentAnalyzer) extractConditionalFields(tree []*field.Field) []*EnhancedField {
 	fields := make([]*EnhancedField, 0)
 
-	if pattern, exists := a.patterns["ifstatement"]; exists {
-		matches := pattern.FindAllString(text, -1)
-		for _, match := range matches {
-			nestingLevel := a.calculateNestingLevel(match, text)
-			category := FieldCategoryConditional
-			if nestingLevel > 1 {
-				category = FieldCategoryNested
-			}
+	for _, f := range field.OfType(tree, field.TypeIF) {
+		// Nesting counts this IF and every IF inside it
+		nestingLevel := field.MaxDepth([]*field.Field{f}, field.TypeIF)
+		category := FieldCategoryConditional
+		if nestingLevel > 1 {
+			category = FieldCategoryNested
+		}
 
-			field := &EnhancedField{
-				Name:           fmt.Sprintf("condition_%d", len(fields)),
-				Category:       category,
-				OriginalSyntax: match,
-				NestingLevel:   nestingLevel,
-				DataType:       "boolean",
-				Confidence:     0.80,
+		var dependencies []string
+		for _, ref := range field.OfType(f.Children, field.TypeMergeField, field.TypeRef, field.TypeDocVariable) {
+			if ref.Name != "" {
+				dependencies = append(dependencies, ref.Name)
 			}
-			fields = append(fields, field)
 		}
+
+		fields = append(fields, &EnhancedField{
+			Name:           fmt.Sprintf("condition_%d", len(fields)),
+			Category:       category,
+			OriginalSyntax: f.String(),
+			NestingLevel:   nestingLevel,
+			Dependencies:   dependencies,
+			DataType:       "boolean",
+			Confidence:     0.80,
+		})
 	}
 
 	return fields
 }
 
-// Calculate nesting level of conditionals
-func (a *DocumentAnalyzer) calculateNestingLevel(condition string, fullText string) int {
-	// Simple nesting detection - count IF keywords
-	level := 1
-	index := strings.Index(fullText, condition)
-	if index > 0 {
-		// Look for nested IFs within this condition's scope
-		endIndex := index + len(condition)
-		scope := fullText[index:endIndex]
-		level = strings.Count(scope, "IF ")
-	}
-	return level
-}
-
 // Infer data type from field name
 func (a *DocumentAnalyzer) inferDataType(fieldName string) string {
 	lower := strings.ToLower(fieldName)
@@ -696,6 +745,12 @@ func (a *DocumentAnalyzer) calculateStatistics(catalog *DocumentCatalog) {
 		stats.MostCommonFields = append(stats.MostCommonFields, fieldList[i].name)
 	}
 
+	// Template families
+	stats.TemplateFamilies = len(catalog.Families)
+	for _, family := range catalog.Families {
+		stats.FamilyVariants += len(family.Members) - 1
+	}
+
 	catalog.Statistics = stats
 }
 
@@ -719,12 +774,31 @@ func (a *DocumentAnalyzer) generateRecommendations(catalog *DocumentCatalog) {
 			fmt.Sprintf("Standardize %d field variants into %d primary fields", totalVariants, len(catalog.MergeGroups)))
 	}
 
+	// Template family recommendations
+	if catalog.Statistics.TemplateFamilies > 0 {
+		recommendations = append(recommendations,
+			fmt.Sprintf("Convert %d canonical templates in place of %d near-identical variants",
+				catalog.Statistics.TemplateFamilies, catalog.Statistics.FamilyVariants))
+	}
+
 	// Complexity recommendations
 	if catalog.Statistics.AutomationPotential < 80 {
 		recommendations = append(recommendations,
 			"Consider simplifying complex templates to increase automation potential")
 	}
 
+	// Script recommendations
+	if len(catalog.Scripts) > 0 {
+		high := 0
+		for _, usage := range catalog.Scripts {
+			if usage.Difficulty == "high" {
+				high++
+			}
+		}
+		recommendations = append(recommendations,
+			fmt.Sprintf("Rebuild %d scripts templates depend on (%d rated high difficulty)", len(catalog.Scripts), high))
+	}
+
 	// Review recommendations
 	reviewCount := 0
 	for _, profile := range catalog.DocumentProfiles {
@@ -789,4 +863,5 @@ type DocumentData struct {
 	Content       []byte
 	ExtractedText string
 	Metadata      map[string]string
+	Scripts       []analyzer.ScriptDependency // Scripts run with the template, if any
 }
@@ -0,0 +1,290 @@
+package cataloger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/minhash"
+)
+
+// Family detection defaults
+const (
+	familyShingleWords = 5   // Words per shingle
+	familyHashes       = 128 // MinHash functions per signature
+	familyThreshold    = 0.8 // Shingle similarity for two templates to be variants
+	familySeed         = 1
+	diffSampleSize     = 3 // Paragraphs quoted per side of a diff summary
+)
+
+// TemplateFamily is a group of near-identical templates; migrating the canonical
+// member covers the others
+type TemplateFamily struct {
+	ID                string         `json:"id"`
+	Canonical         string         `json:"canonical"` // Member most similar to the rest
+	Members           []FamilyMember `json:"members"`   // Canonical first
+	AverageSimilarity float64        `json:"averageSimilarity"`
+}
+
+// FamilyMember is a template of a family with how it differs from the canonical one
+type FamilyMember struct {
+	Filename   string      `json:"filename"`
+	Similarity float64     `json:"similarity"` // Shingle similarity to the canonical member
+	Diff       DiffSummary `json:"diff"`
+}
+
+// DiffSummary outlines how a member differs from its family's canonical template
+type DiffSummary struct {
+	Summary           string   `json:"summary"`
+	AddedParagraphs   int      `json:"addedParagraphs"` // Paragraphs only in the member
+	RemovedParagraphs int      `json:"removedParagraphs"`
+	Added             []string `json:"added,omitempty"` // Sample of the added paragraphs
+	Removed           []string `json:"removed,omitempty"`
+	FieldsAdded       []string `json:"fieldsAdded,omitempty"`
+	FieldsRemoved     []string `json:"fieldsRemoved,omitempty"`
+}
+
+// FamilyDetector groups near-duplicate templates with MinHash signatures,
+// bucketed by LSH and confirmed on their shingles
+type FamilyDetector struct {
+	shingleWords int
+	threshold    float64
+	hasher       *minhash.Hasher
+	bands, rows  int
+}
+
+// NewFamilyDetector creates detector instance
+func NewFamilyDetector() *FamilyDetector {
+	bands, rows := minhash.Bands(familyHashes, familyThreshold)
+	return &FamilyDetector{
+		shingleWords: familyShingleWords,
+		threshold:    familyThreshold,
+		hasher:       minhash.NewHasher(familyHashes, familySeed),
+		bands:        bands,
+		rows:         rows,
+	}
+}
+
+// familyDocument is a document being grouped
+type familyDocument struct {
+	filename string
+	text     string
+	fields   []string
+	shingles []uint64
+}
+
+// Detect returns the families of two or more documents, largest first. Fields
+// holds the field names of each document, by position.
+func (d *FamilyDetector) Detect(documents []DocumentData, fields [][]string) []TemplateFamily {
+	docs := make([]*familyDocument, len(documents))
+	ix := minhash.NewIndex(d.bands, d.rows)
+	for i, doc := range documents {
+		docs[i] = &familyDocument{filename: doc.Filename, text: doc.ExtractedText}
+		if i < len(fields) {
+			docs[i].fields = fields[i]
+		}
+		docs[i].shingles = minhash.Shingles(doc.ExtractedText, d.shingleWords)
+		if len(docs[i].shingles) > 0 {
+			ix.Add(i, d.hasher.Signature(docs[i].shingles))
+		}
+	}
+
+	// Join candidates whose shingles are similar enough
+	parent := make([]int, len(docs))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	similarity := make(map[minhash.Pair]float64)
+	for _, pair := range ix.Candidates() {
+		s := minhash.Jaccard(docs[pair[0]].shingles, docs[pair[1]].shingles)
+		if s < d.threshold {
+			continue
+		}
+		similarity[pair] = s
+		if a, b := find(pair[0]), find(pair[1]); a != b {
+			parent[b] = a
+		}
+	}
+
+	groups := make(map[int][]int)
+	for i := range docs {
+		root := find(i)
+		groups[root] = append(groups[root], i)
+	}
+	var families []TemplateFamily
+	for _, members := range groups {
+		// Chaining can join templates far from each other; members too far from
+		// the canonical one after chaining form families of their own
+		for len(members) > 1 {
+			canonical := d.canonical(docs, members, similarity)
+			kept, rest := []int{canonical}, []int{}
+			for _, m := range members {
+				if m == canonical {
+					continue
+				}
+				if pairSimilarity(docs, similarity, canonical, m) >= d.threshold {
+					kept = append(kept, m)
+				} else {
+					rest = append(rest, m)
+				}
+			}
+			if len(kept) > 1 {
+				families = append(families, d.family(docs, kept, similarity))
+			}
+			members = rest
+		}
+	}
+	sort.Slice(families, func(i, j int) bool {
+		if len(families[i].Members) != len(families[j].Members) {
+			return len(families[i].Members) > len(families[j].Members)
+		}
+		return families[i].Canonical < families[j].Canonical
+	})
+	for i := range families {
+		families[i].ID = fmt.Sprintf("family_%03d", i+1)
+	}
+	return families
+}
+
+// pairSimilarity returns the shingle similarity of two documents, caching it
+// in known
+func pairSimilarity(docs []*familyDocument, known map[minhash.Pair]float64, a, b int) float64 {
+	if a > b {
+		a, b = b, a
+	}
+	s, ok := known[minhash.Pair{a, b}]
+	if !ok {
+		s = minhash.Jaccard(docs[a].shingles, docs[b].shingles)
+		known[minhash.Pair{a, b}] = s
+	}
+	return s
+}
+
+// canonical returns the member with the highest total similarity to the others
+func (d *FamilyDetector) canonical(docs []*familyDocument, members []int, known map[minhash.Pair]float64) int {
+	canonical, best := members[0], -1.0
+	for _, m := range members {
+		total := 0.0
+		for _, other := range members {
+			if other != m {
+				total += pairSimilarity(docs, known, m, other)
+			}
+		}
+		// Ties go to the shorter name, which is usually the unversioned original
+		if total > best || (total == best && len(docs[m].filename) < len(docs[canonical].filename)) {
+			canonical, best = m, total
+		}
+	}
+	return canonical
+}
+
+// family describes a group whose first member is the canonical one
+func (d *FamilyDetector) family(docs []*familyDocument, members []int, known map[minhash.Pair]float64) TemplateFamily {
+	canonical := members[0]
+	family := TemplateFamily{
+		Canonical: docs[canonical].filename,
+		Members:   []FamilyMember{{Filename: docs[canonical].filename, Similarity: 1, Diff: DiffSummary{Summary: "canonical"}}},
+	}
+	total := 0.0
+	for _, m := range members {
+		if m == canonical {
+			continue
+		}
+		s := pairSimilarity(docs, known, canonical, m)
+		total += s
+		family.Members = append(family.Members, FamilyMember{
+			Filename:   docs[m].filename,
+			Similarity: s,
+			Diff:       diffDocuments(docs[canonical], docs[m]),
+		})
+	}
+	sort.SliceStable(family.Members[1:], func(i, j int) bool {
+		return family.Members[1+i].Similarity > family.Members[1+j].Similarity
+	})
+	family.AverageSimilarity = total / float64(len(members)-1)
+	return family
+}
+
+// diffDocuments summarizes the paragraphs and fields a member adds to or drops
+// from the canonical template
+func diffDocuments(canonical, member *familyDocument) DiffSummary {
+	var diff DiffSummary
+	base, variant := paragraphSet(canonical.text), paragraphSet(member.text)
+	for _, p := range paragraphs(member.text) {
+		if !base[normalizeParagraph(p)] {
+			diff.AddedParagraphs++
+			if len(diff.Added) < diffSampleSize {
+				diff.Added = append(diff.Added, p)
+			}
+		}
+	}
+	for _, p := range paragraphs(canonical.text) {
+		if !variant[normalizeParagraph(p)] {
+			diff.RemovedParagraphs++
+			if len(diff.Removed) < diffSampleSize {
+				diff.Removed = append(diff.Removed, p)
+			}
+		}
+	}
+	diff.FieldsAdded = difference(member.fields, canonical.fields)
+	diff.FieldsRemoved = difference(canonical.fields, member.fields)
+
+	diff.Summary = fmt.Sprintf("+%d/-%d paragraphs", diff.AddedParagraphs, diff.RemovedParagraphs)
+	if len(diff.FieldsAdded) > 0 {
+		diff.Summary += ", adds fields " + strings.Join(diff.FieldsAdded, ", ")
+	}
+	if len(diff.FieldsRemoved) > 0 {
+		diff.Summary += ", drops fields " + strings.Join(diff.FieldsRemoved, ", ")
+	}
+	return diff
+}
+
+// paragraphs splits text into its non-blank lines
+func paragraphs(text string) []string {
+	var result []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r", "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+// paragraphSet returns the normalized paragraphs of text
+func paragraphSet(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, p := range paragraphs(text) {
+		set[normalizeParagraph(p)] = true
+	}
+	return set
+}
+
+// normalizeParagraph ignores case and spacing when comparing paragraphs
+func normalizeParagraph(p string) string {
+	return strings.ToLower(strings.Join(strings.Fields(p), " "))
+}
+
+// difference returns the sorted values of a missing from b
+func difference(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, v := range b {
+		in[v] = true
+	}
+	seen := make(map[string]bool)
+	var result []string
+	for _, v := range a {
+		if !in[v] && !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,54 @@
+package cataloger
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// chain returns n templates of 300 words, each changing 4 more words of the
+// one before, so neighbours are about 0.87 similar and templates two apart
+// about 0.76
+func chain(n int) []DocumentData {
+	words := make([]string, 300)
+	for i := range words {
+		words[i] = fmt.Sprintf("w%d", i)
+	}
+	docs := make([]DocumentData, n)
+	for d := range docs {
+		if d > 0 {
+			for c := 0; c < 4; c++ {
+				i := (d-1)*4 + c
+				words[i*18+9] = fmt.Sprintf("changed%d", i)
+			}
+		}
+		docs[d] = DocumentData{Filename: fmt.Sprintf("template%d.dot", d), ExtractedText: strings.Join(words, " ")}
+	}
+	return docs
+}
+
+func TestDetectSplitsChainedFamilies(t *testing.T) {
+	tests := []struct {
+		name    string
+		docs    int
+		members int
+	}{
+		{"chained triple", 3, 3}, // The middle template is canonical and close to both ends
+		{"chain of four", 4, 3},  // The far end is split off and left on its own
+	}
+	for _, tt := range tests {
+		families := NewFamilyDetector().Detect(chain(tt.docs), nil)
+		if len(families) != 1 {
+			t.Fatalf("%s: detected %d families, want 1", tt.name, len(families))
+		}
+		family := families[0]
+		if len(family.Members) != tt.members {
+			t.Errorf("%s: family has %d members, want %d", tt.name, len(family.Members), tt.members)
+		}
+		for _, m := range family.Members {
+			if m.Similarity < familyThreshold {
+				t.Errorf("%s: %s is %.2f similar to canonical %s", tt.name, m.Filename, m.Similarity, family.Canonical)
+			}
+		}
+	}
+}
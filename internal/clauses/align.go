@@ -0,0 +1,393 @@
+package clauses
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// fieldKey stands for every merge field when comparing paragraphs, so the same
+// clause matches whichever fields it holds
+const fieldKey = "{field}"
+
+// maxAlignCells bounds the alignment table of two paragraphs; longer pairs are
+// not aligned
+const maxAlignCells = 1 << 22
+
+var (
+	datePattern = regexp.MustCompile(`(?i)^(\d{1,2}[/.-]\d{1,2}[/.-]\d{2,4}|\d{4}-\d{2}-\d{2}|` +
+		`(\d{1,2}(st|nd|rd|th)?\s+)?(jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.?(\s+\d{1,2}(st|nd|rd|th)?,?)?\s+\d{4})$`)
+	numberPattern = regexp.MustCompile(`^[$£€]?\s?\d[\d,]*(\.\d+)?%?$`)
+
+	// lostMark finds paragraph marks dropped from manifest previews: a sentence
+	// end or list colon running straight into the next word
+	lostMark  = regexp.MustCompile(`[\p{Ll})\]"”’](?:[.!?]\p{Lu}|[:;]\pL)`)
+	nameChars = regexp.MustCompile(`[^\pL\pN._]+`)
+)
+
+// keptMarks are words whose punctuation runs into the next word without
+// ending a paragraph: titles and URI schemes
+var keptMarks = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "messrs": true, "dr": true, "prof": true, "st": true, "no": true,
+	"mailto": true, "http": true, "https": true, "ftp": true, "file": true, "tel": true,
+}
+
+// token is a word or merge field of a paragraph
+type token struct {
+	key        string // Lower-cased word without surrounding punctuation, or fieldKey
+	field      string // Field name, for merge fields
+	start, end int    // Byte range in the paragraph text
+}
+
+// span is a range of a group representative's tokens that varies; an empty
+// span marks text some members insert before its start
+type span struct {
+	start, end int
+}
+
+// segment splits text into its non-blank paragraphs. Preview text has lost its
+// paragraph marks, so lostMark also ends a paragraph there.
+func segment(doc int, text string, preview bool) []*paragraph {
+	var paragraphs []*paragraph
+	var lines [][2]int
+	start := 0
+	for i := 0; i <= len(text); i++ {
+		if i < len(text) && text[i] != '\n' && text[i] != '\r' && text[i] != '\f' {
+			continue
+		}
+		from := start
+		for _, m := range lostMarks(text[start:i], preview) {
+			end := start + m
+			lines = append(lines, [2]int{from, end})
+			from = end
+		}
+		lines = append(lines, [2]int{from, i})
+		start = i + 1
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimLeftFunc(text[line[0]:line[1]], unicode.IsSpace)
+		offset := line[0] + (line[1] - line[0] - len(trimmed))
+		trimmed = strings.TrimRightFunc(trimmed, unicode.IsSpace)
+		if trimmed == "" {
+			continue
+		}
+		p := &paragraph{
+			doc:        doc,
+			index:      len(paragraphs),
+			offset:     offset,
+			length:     len(trimmed),
+			text:       trimmed,
+			tokens:     tokenize(trimmed),
+			group:      -1,
+			similarity: 1,
+		}
+		keys := make([]string, len(p.tokens))
+		for k, t := range p.tokens {
+			keys[k] = t.key
+		}
+		p.key = strings.Join(keys, " ")
+		paragraphs = append(paragraphs, p)
+	}
+	return paragraphs
+}
+
+// lostMarks returns the positions in a line where preview text lost a
+// paragraph mark. Fields and placeholders keep their punctuation.
+func lostMarks(line string, preview bool) []int {
+	if !preview {
+		return nil
+	}
+	var marks []int
+	for _, m := range lostMark.FindAllStringIndex(line, -1) {
+		before := line[:m[0]]
+		if strings.Count(before, "{") > strings.Count(before, "}") ||
+			strings.Count(before, "«") > strings.Count(before, "»") {
+			continue
+		}
+		// The mark follows the punctuation, one byte before the next word
+		_, size := utf8.DecodeRuneInString(line[m[0]:])
+		word := before + line[m[0]:m[0]+size]
+		if k := strings.LastIndexFunc(word, func(r rune) bool { return !unicode.IsLetter(r) }); k >= 0 {
+			_, width := utf8.DecodeRuneInString(word[k:])
+			word = word[k+width:]
+		}
+		if keptMarks[strings.ToLower(word)] {
+			continue
+		}
+		marks = append(marks, m[0]+size+1)
+	}
+	return marks
+}
+
+// tokenize splits a paragraph into words and merge fields. Fields are field
+// codes in braces as Word shows them, «Name» placeholders and {{Name}}
+// tokens; words made only of punctuation are dropped.
+func tokenize(text string) []token {
+	var tokens []token
+	for i := 0; i < len(text); {
+		end := -1
+		switch {
+		case text[i] == '{':
+			end = closingBrace(text, i)
+		case strings.HasPrefix(text[i:], "«"):
+			if j := strings.Index(text[i:], "»"); j > 0 {
+				end = i + j + len("»")
+			}
+		}
+		if end > 0 {
+			tokens = append(tokens, token{key: fieldKey, field: fieldName(text[i:end]), start: i, end: end})
+			i = end
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if unicode.IsSpace(r) {
+			i += size
+			continue
+		}
+		start := i
+		for i < len(text) {
+			r, size := utf8.DecodeRuneInString(text[i:])
+			if unicode.IsSpace(r) || (i > start && (r == '{' || r == '«')) {
+				break
+			}
+			i += size
+		}
+		if key := strings.ToLower(strings.TrimFunc(text[start:i], unicode.IsPunct)); key != "" {
+			tokens = append(tokens, token{key: key, start: start, end: i})
+		}
+	}
+	return tokens
+}
+
+// closingBrace returns the end of the balanced braces starting at i, or -1
+func closingBrace(text string, i int) int {
+	depth := 0
+	for j := i; j < len(text); j++ {
+		switch text[j] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return -1
+}
+
+// fieldName returns the name a field refers to: the argument of a field code
+// such as MERGEFIELD or DOCVARIABLE, or the placeholder text
+func fieldName(code string) string {
+	words := strings.Fields(strings.Trim(code, "{}«» \t"))
+	name := ""
+	switch {
+	case len(words) >= 2 && strings.ToUpper(words[0]) == words[0]:
+		name = words[1]
+	case len(words) > 0:
+		name = words[0]
+	}
+	if name = strings.Trim(name, `"'{}`); name == "" {
+		return "field"
+	}
+	return name
+}
+
+// lcs aligns two token sequences on their longest common subsequence,
+// returning for each token of a the index of its match in b or -1
+func lcs(a, b []token) []int {
+	align := make([]int, len(a))
+	for i := range align {
+		align[i] = -1
+	}
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 || (n+1)*(m+1) > maxAlignCells {
+		return align
+	}
+	table := make([]int32, (n+1)*(m+1))
+	at := func(i, j int) *int32 { return &table[i*(m+1)+j] }
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case a[i].key == b[j].key:
+				*at(i, j) = *at(i+1, j+1) + 1
+			case *at(i+1, j) >= *at(i, j+1):
+				*at(i, j) = *at(i+1, j)
+			default:
+				*at(i, j) = *at(i, j+1)
+			}
+		}
+	}
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case a[i].key == b[j].key:
+			align[i] = j
+			i++
+			j++
+		case *at(i+1, j) >= *at(i, j+1):
+			i++
+		default:
+			j++
+		}
+	}
+	return align
+}
+
+// similarity is the share of the tokens of a and b in their alignment
+func similarity(a, b []token, align []int) float64 {
+	if len(a)+len(b) == 0 {
+		return 1
+	}
+	matched := 0
+	for _, j := range align {
+		if j >= 0 {
+			matched++
+		}
+	}
+	return float64(2*matched) / float64(len(a)+len(b))
+}
+
+// variableSpans returns the spans of the representative's tokens that are
+// merge fields or differ in a member, and whether the members only differ in
+// their fields
+func variableSpans(g *group) ([]span, bool) {
+	n := len(g.rep.tokens)
+	// Slot 2k is the gap before token k, slot 2k+1 the token itself
+	flagged := make([]bool, 2*n+1)
+	for k, t := range g.rep.tokens {
+		if t.key == fieldKey {
+			flagged[2*k+1] = true
+		}
+	}
+	exact := true
+	for _, p := range g.members {
+		exact = exact && p.key == g.rep.key
+		prev := -1
+		for k := 0; k <= n; k++ {
+			next := len(p.tokens)
+			if k < n {
+				if next = p.align[k]; next < 0 {
+					flagged[2*k+1] = true
+					continue
+				}
+			}
+			if next > prev+1 {
+				flagged[2*k] = true
+			}
+			prev = next
+		}
+	}
+
+	var spans []span
+	for s := 0; s < len(flagged); s++ {
+		if !flagged[s] {
+			continue
+		}
+		// Neighbouring tokens that vary form one span, whether or not text is
+		// inserted between them
+		e := s
+		for e+1 < len(flagged) && (flagged[e+1] || (e%2 == 1 && e+2 < len(flagged) && flagged[e+2])) {
+			e++
+		}
+		spans = append(spans, span{start: s / 2, end: (e + 1) / 2})
+		s = e
+	}
+	return spans, exact
+}
+
+// value returns the text of a member aligned with the span
+func (s span) value(p *paragraph) string {
+	lo, hi := 0, len(p.tokens)
+	for k := s.start - 1; k >= 0; k-- {
+		if p.align[k] >= 0 {
+			lo = p.align[k] + 1
+			break
+		}
+	}
+	for k := s.end; k < len(p.align); k++ {
+		if p.align[k] >= 0 {
+			hi = p.align[k]
+			break
+		}
+	}
+	if lo >= hi {
+		return ""
+	}
+	return p.text[p.tokens[lo].start:p.tokens[hi-1].end]
+}
+
+// template returns the representative's text with its spans replaced by
+// {{name}} placeholders, and the variables. Names already used by the clause
+// are counted in names.
+func template(g *group, names map[string]int) (string, []Variable) {
+	rep := g.rep
+	var out strings.Builder
+	var vars []Variable
+	cursor := 0
+	for _, s := range g.spans {
+		v := Variable{Name: "value", Type: "text"}
+		for _, t := range rep.tokens[s.start:s.end] {
+			if t.field != "" {
+				v.Name, v.Type, v.Field = variableName(t.field), "field", t.field
+				break
+			}
+		}
+		if names[v.Name]++; names[v.Name] > 1 || v.Field == "" {
+			v.Name = fmt.Sprintf("%s_%d", v.Name, names[v.Name])
+		}
+		placeholder := "{{" + v.Name + "}}"
+
+		switch {
+		case s.start < s.end:
+			out.WriteString(rep.text[cursor:rep.tokens[s.start].start])
+			v.Offset = out.Len()
+			out.WriteString(placeholder)
+			cursor = rep.tokens[s.end-1].end
+		case s.start < len(rep.tokens):
+			out.WriteString(rep.text[cursor:rep.tokens[s.start].start])
+			v.Offset = out.Len()
+			out.WriteString(placeholder + " ")
+			cursor = rep.tokens[s.start].start
+		default:
+			out.WriteString(rep.text[cursor:])
+			out.WriteString(" ")
+			v.Offset = out.Len()
+			out.WriteString(placeholder)
+			cursor = len(rep.text)
+		}
+		vars = append(vars, v)
+	}
+	out.WriteString(rep.text[cursor:])
+	return out.String(), vars
+}
+
+// variableName turns a field name into a placeholder name
+func variableName(field string) string {
+	if name := strings.Trim(nameChars.ReplaceAllString(field, "_"), "_."); name != "" {
+		return name
+	}
+	return "field"
+}
+
+// valueType infers the type of a variable from its values
+func valueType(values []string) string {
+	if len(values) == 0 {
+		return "text"
+	}
+	dates, numbers := true, true
+	for _, v := range values {
+		dates = dates && datePattern.MatchString(v)
+		numbers = numbers && numberPattern.MatchString(v)
+	}
+	switch {
+	case dates:
+		return "date"
+	case numbers:
+		return "number"
+	default:
+		return "text"
+	}
+}
@@ -0,0 +1,437 @@
+// Package clauses mines reusable clauses from a template library. Templates are
+// segmented into paragraphs; paragraphs repeated across documents, exactly or
+// with a few differing words, are grouped and aligned so the differing spans
+// become clause variables, and runs of consecutive repeated paragraphs are
+// reported as multi-paragraph clauses.
+package clauses
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/minhash"
+)
+
+// Mining defaults
+const (
+	DefaultMinDocuments = 2    // Documents a clause must appear in
+	DefaultMinWords     = 8    // Words of a clause, and of a paragraph to be fuzzily matched
+	DefaultSimilarity   = 0.75 // Token similarity for two paragraphs to be variants
+	DefaultMaxRun       = 25   // Paragraphs of the longest run considered
+
+	shingleWords = 2
+	hashes       = 64
+	seed         = 3
+)
+
+// Document is a template to mine
+type Document struct {
+	Name    string
+	Text    string
+	Preview bool // Text lost its paragraph marks, as MatterSphere manifest previews do
+}
+
+// Clause is a paragraph or run of paragraphs reused across documents
+type Clause struct {
+	ID          string       `json:"id"`
+	Type        string       `json:"type"` // header, footer, clause or section
+	Text        string       `json:"text"` // Variables shown as {{name}}
+	Paragraphs  int          `json:"paragraphs"`
+	Words       int          `json:"words"`
+	Documents   []string     `json:"documents"` // Distinct source documents, by name
+	Reuse       int          `json:"reuse"`     // Number of documents
+	Variables   []Variable   `json:"variables,omitempty"`
+	Occurrences []Occurrence `json:"occurrences"`
+	Exact       bool         `json:"exact"` // Every occurrence matches apart from merge fields
+}
+
+// Variable is a span of a clause that differs between occurrences or holds a
+// merge field
+type Variable struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`            // text, date, number or field
+	Field  string   `json:"field,omitempty"` // Merge field the span holds
+	Offset int      `json:"offset"`          // Position of the placeholder in the clause text
+	Values []string `json:"values,omitempty"`
+}
+
+// Occurrence locates a clause in a document
+type Occurrence struct {
+	Document   string            `json:"document"`
+	Paragraph  int               `json:"paragraph"` // Index of the first paragraph
+	Offset     int               `json:"offset"`    // Byte offset in the document text
+	Length     int               `json:"length"`
+	Similarity float64           `json:"similarity"`
+	Values     map[string]string `json:"values,omitempty"` // Text of each variable
+}
+
+// Miner finds reusable clauses
+type Miner struct {
+	MinDocuments int
+	MinWords     int
+	Similarity   float64
+	MaxRun       int
+}
+
+// NewMiner creates a miner with the default settings
+func NewMiner() *Miner {
+	return &Miner{
+		MinDocuments: DefaultMinDocuments,
+		MinWords:     DefaultMinWords,
+		Similarity:   DefaultSimilarity,
+		MaxRun:       DefaultMaxRun,
+	}
+}
+
+// paragraph is a paragraph of a document with its tokens
+type paragraph struct {
+	doc, index     int
+	offset, length int
+	text           string
+	tokens         []token
+	key            string
+	group          int // Index of the paragraph's group, -1 if none
+	align          []int
+	similarity     float64
+}
+
+// group is a set of paragraphs matching a representative, with the spans of
+// the representative that vary
+type group struct {
+	rep     *paragraph
+	members []*paragraph
+	spans   []span
+	exact   bool
+}
+
+// Mine returns the clauses reused by at least MinDocuments documents, ranked
+// by reuse count
+func (m *Miner) Mine(documents []Document) []Clause {
+	minDocs := m.MinDocuments
+	if minDocs < 2 {
+		minDocs = 2
+	}
+
+	docs := make([][]*paragraph, len(documents))
+	for i, doc := range documents {
+		docs[i] = segment(i, doc.Text, doc.Preview)
+	}
+	groups := m.groupParagraphs(docs)
+
+	// Runs of grouped paragraphs, grown one paragraph at a time while enough
+	// documents share them
+	type run struct {
+		groups []int
+		starts []*paragraph
+		docs   int
+	}
+	var level []*run
+	byKey := make(map[string]*run)
+	for _, paragraphs := range docs {
+		for _, p := range paragraphs {
+			if p.group < 0 {
+				continue
+			}
+			key := fmt.Sprint(p.group)
+			r, ok := byKey[key]
+			if !ok {
+				r = &run{groups: []int{p.group}}
+				byKey[key] = r
+				level = append(level, r)
+			}
+			r.starts = append(r.starts, p)
+		}
+	}
+	countDocs := func(r *run) {
+		seen := make(map[int]bool)
+		for _, p := range r.starts {
+			seen[p.doc] = true
+		}
+		r.docs = len(seen)
+	}
+	frequent := func(runs []*run) []*run {
+		var kept []*run
+		for _, r := range runs {
+			if countDocs(r); r.docs >= minDocs {
+				kept = append(kept, r)
+			}
+		}
+		return kept
+	}
+
+	level = frequent(level)
+	var found []*run
+	absorbed := make(map[*run]bool)
+	for length := 1; len(level) > 0; length++ {
+		found = append(found, level...)
+		if length >= m.MaxRun {
+			break
+		}
+		byKey = make(map[string]*run)
+		var next []*run
+		parents := make(map[*run][2]*run)
+		index := make(map[string]*run, len(level))
+		for _, r := range level {
+			index[fmt.Sprint(r.groups)] = r
+		}
+		for _, r := range level {
+			for _, start := range r.starts {
+				end := start.index + length
+				if end >= len(docs[start.doc]) || docs[start.doc][end].group < 0 {
+					continue
+				}
+				groupsOf := append(append([]int{}, r.groups...), docs[start.doc][end].group)
+				key := fmt.Sprint(groupsOf)
+				n, ok := byKey[key]
+				if !ok {
+					n = &run{groups: groupsOf}
+					byKey[key] = n
+					next = append(next, n)
+					parents[n] = [2]*run{r, index[fmt.Sprint(groupsOf[1:])]}
+				}
+				n.starts = append(n.starts, start)
+			}
+		}
+		next = frequent(next)
+		// A run shared by as many documents as a longer run holding it adds nothing
+		for _, n := range next {
+			for _, parent := range parents[n] {
+				if parent != nil && parent.docs == n.docs {
+					absorbed[parent] = true
+				}
+			}
+		}
+		level = next
+	}
+
+	var clauses []Clause
+	for _, r := range found {
+		if absorbed[r] {
+			continue
+		}
+		if c, ok := m.clause(documents, docs, groups, r.groups, r.starts); ok {
+			clauses = append(clauses, c)
+		}
+	}
+	sort.SliceStable(clauses, func(i, j int) bool {
+		a, b := clauses[i], clauses[j]
+		if a.Reuse != b.Reuse {
+			return a.Reuse > b.Reuse
+		}
+		if len(a.Occurrences) != len(b.Occurrences) {
+			return len(a.Occurrences) > len(b.Occurrences)
+		}
+		if a.Words != b.Words {
+			return a.Words > b.Words
+		}
+		return a.Text < b.Text
+	})
+	for i := range clauses {
+		clauses[i].ID = fmt.Sprintf("clause_%03d", i+1)
+	}
+	return clauses
+}
+
+// groupParagraphs groups the paragraphs of all documents and returns the
+// groups. Paragraphs with the same tokens are grouped; long enough ones are
+// also grouped with similar paragraphs found by LSH and confirmed by alignment.
+func (m *Miner) groupParagraphs(docs [][]*paragraph) []*group {
+	var keys []string
+	byKey := make(map[string][]*paragraph)
+	for _, paragraphs := range docs {
+		for _, p := range paragraphs {
+			if _, ok := byKey[p.key]; !ok {
+				keys = append(keys, p.key)
+			}
+			byKey[p.key] = append(byKey[p.key], p)
+		}
+	}
+
+	parent := make([]int, len(keys))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	bands, rows := minhash.Bands(hashes, m.Similarity*0.7)
+	hasher := minhash.NewHasher(hashes, seed)
+	ix := minhash.NewIndex(bands, rows)
+	for i, key := range keys {
+		if p := byKey[key][0]; len(p.tokens) >= m.MinWords {
+			ix.Add(i, hasher.Signature(minhash.Shingles(key, shingleWords)))
+		}
+	}
+	for _, pair := range ix.Candidates() {
+		a, b := byKey[keys[pair[0]]][0], byKey[keys[pair[1]]][0]
+		if similarity(a.tokens, b.tokens, lcs(a.tokens, b.tokens)) >= m.Similarity {
+			if x, y := find(pair[0]), find(pair[1]); x != y {
+				parent[y] = x
+			}
+		}
+	}
+
+	sets := make(map[int][]int)
+	var roots []int
+	for i := range keys {
+		root := find(i)
+		if _, ok := sets[root]; !ok {
+			roots = append(roots, root)
+		}
+		sets[root] = append(sets[root], i)
+	}
+
+	var groups []*group
+	for _, root := range roots {
+		set := sets[root]
+		// The variant in most documents represents the set; variants too far
+		// from it after chaining form groups of their own
+		sort.SliceStable(set, func(i, j int) bool {
+			return documentCount(byKey[keys[set[i]]]) > documentCount(byKey[keys[set[j]]])
+		})
+		for len(set) > 0 {
+			rep := byKey[keys[set[0]]][0]
+			g := &group{rep: rep}
+			var rest []int
+			for n, k := range set {
+				variants := byKey[keys[k]]
+				align := identity(len(rep.tokens))
+				s := 1.0
+				if n > 0 {
+					align = lcs(rep.tokens, variants[0].tokens)
+					if s = similarity(rep.tokens, variants[0].tokens, align); s < m.Similarity {
+						rest = append(rest, k)
+						continue
+					}
+				}
+				for _, p := range variants {
+					p.align, p.similarity = align, s
+					g.members = append(g.members, p)
+				}
+			}
+			set = rest
+			g.spans, g.exact = variableSpans(g)
+			for _, p := range g.members {
+				p.group = len(groups)
+			}
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// clause builds the clause of a run of groups starting at each of the given
+// paragraphs, if it is long enough
+func (m *Miner) clause(documents []Document, docs [][]*paragraph, groups []*group, run []int, starts []*paragraph) (Clause, bool) {
+	c := Clause{Paragraphs: len(run), Exact: true}
+	var texts []string
+	var offsets []int
+	names := make(map[string]int)
+	var spanNames [][]string
+	for _, gi := range run {
+		g := groups[gi]
+		c.Words += len(g.rep.tokens)
+		c.Exact = c.Exact && g.exact
+		text, vars := template(g, names)
+		base := 0
+		for _, t := range texts {
+			base += len(t) + 1
+		}
+		var spanned []string
+		for _, v := range vars {
+			v.Offset += base
+			c.Variables = append(c.Variables, v)
+			spanned = append(spanned, v.Name)
+		}
+		spanNames = append(spanNames, spanned)
+		texts = append(texts, text)
+		offsets = append(offsets, base)
+	}
+	if c.Words < m.MinWords {
+		return c, false
+	}
+	c.Text = strings.Join(texts, "\n")
+
+	seen := make(map[int]bool)
+	header, footer := true, true
+	values := make([]map[string]bool, len(c.Variables))
+	for _, start := range starts {
+		paragraphs := docs[start.doc][start.index : start.index+len(run)]
+		last := paragraphs[len(paragraphs)-1]
+		occ := Occurrence{
+			Paragraph: start.index,
+			Offset:    start.offset,
+			Length:    last.offset + last.length - start.offset,
+		}
+		v := 0
+		for i, p := range paragraphs {
+			occ.Similarity += p.similarity / float64(len(paragraphs))
+			for n, s := range groups[run[i]].spans {
+				value := s.value(p)
+				if occ.Values == nil {
+					occ.Values = make(map[string]string)
+				}
+				occ.Values[spanNames[i][n]] = value
+				if values[v] == nil {
+					values[v] = make(map[string]bool)
+				}
+				if value != "" && !values[v][value] {
+					values[v][value] = true
+					c.Variables[v].Values = append(c.Variables[v].Values, value)
+				}
+				v++
+			}
+		}
+		header = header && start.index == 0
+		footer = footer && last.index == len(docs[start.doc])-1
+		if !seen[start.doc] {
+			seen[start.doc] = true
+			c.Documents = append(c.Documents, documents[start.doc].Name)
+		}
+		occ.Document = documents[start.doc].Name
+		c.Occurrences = append(c.Occurrences, occ)
+	}
+	for i := range c.Variables {
+		sort.Strings(c.Variables[i].Values)
+		if c.Variables[i].Field == "" {
+			c.Variables[i].Type = valueType(c.Variables[i].Values)
+		}
+	}
+	c.Reuse = len(c.Documents)
+
+	// A clause that is every document it occurs in is neither header nor footer
+	switch {
+	case header && !footer:
+		c.Type = "header"
+	case footer && !header:
+		c.Type = "footer"
+	case len(run) > 1:
+		c.Type = "section"
+	default:
+		c.Type = "clause"
+	}
+	return c, true
+}
+
+// documentCount returns the number of distinct documents of paragraphs
+func documentCount(paragraphs []*paragraph) int {
+	seen := make(map[int]bool)
+	for _, p := range paragraphs {
+		seen[p.doc] = true
+	}
+	return len(seen)
+}
+
+// identity returns the alignment of a token sequence with itself
+func identity(n int) []int {
+	align := make([]int, n)
+	for i := range align {
+		align[i] = i
+	}
+	return align
+}
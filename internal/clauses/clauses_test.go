@@ -0,0 +1,141 @@
+package clauses
+
+import (
+	"strings"
+	"testing"
+)
+
+const confidentiality = "The parties agree to keep the terms of this agreement confidential and not to disclose them to any third party."
+
+func TestMineExactAndFuzzyParagraphs(t *testing.T) {
+	docs := []Document{
+		{Name: "a.dot", Text: confidentiality + "\n\nPayment of $1,500 is due within 14 days of the date of this letter."},
+		{Name: "b.dot", Text: "Payment of $2,750.00 is due within 30 days of the date of this letter.\r\n" + confidentiality},
+		{Name: "c.dot", Text: "An unrelated memorandum about parking arrangements for the office staff.\n" + strings.ToUpper(confidentiality)},
+	}
+	clauses := NewMiner().Mine(docs)
+	if len(clauses) != 2 {
+		t.Fatalf("mined %d clauses, want 2: %+v", len(clauses), clauses)
+	}
+
+	exact := clauses[0]
+	if exact.Text != confidentiality || exact.Reuse != 3 || !exact.Exact || len(exact.Variables) != 0 {
+		t.Errorf("confidentiality clause = %+v", exact)
+	}
+	for _, occ := range exact.Occurrences {
+		text := docs[0].Text
+		for _, doc := range docs {
+			if doc.Name == occ.Document {
+				text = doc.Text
+			}
+		}
+		if got := text[occ.Offset : occ.Offset+occ.Length]; !strings.EqualFold(got, confidentiality) {
+			t.Errorf("%s offset %d = %q", occ.Document, occ.Offset, got)
+		}
+	}
+
+	fuzzy := clauses[1]
+	if want := "Payment of {{value_1}} is due within {{value_2}} days of the date of this letter."; fuzzy.Text != want {
+		t.Errorf("fuzzy clause text = %q, want %q", fuzzy.Text, want)
+	}
+	if fuzzy.Exact || fuzzy.Reuse != 2 || len(fuzzy.Variables) != 2 {
+		t.Fatalf("fuzzy clause = %+v", fuzzy)
+	}
+	if v := fuzzy.Variables[0]; v.Type != "number" || strings.Join(v.Values, "|") != "$1,500|$2,750.00" {
+		t.Errorf("amount variable = %+v", v)
+	}
+	if got := fuzzy.Occurrences[1].Values["value_2"]; got != "30" {
+		t.Errorf("b.dot value_2 = %q, want 30", got)
+	}
+}
+
+func TestMineRuns(t *testing.T) {
+	closing := "Please do not hesitate to contact { MERGEFIELD Author } if you have any questions about this matter.\n" +
+		"Yours faithfully\n" + "{ MERGEFIELD FirmName } Lawyers and Solicitors of the Supreme Court"
+	docs := []Document{
+		{Name: "letter1.dot", Text: "We enclose the signed contract for your records and retention.\n" + closing},
+		{Name: "letter2.dot", Text: "We enclose our invoice for the work completed this month.\n" + closing},
+		{Name: "letter3.dot", Text: closing + "\nEnclosure: the file note of our meeting."},
+	}
+	clauses := NewMiner().Mine(docs)
+	if len(clauses) != 1 {
+		t.Fatalf("mined %d clauses, want the closing run only: %+v", len(clauses), clauses)
+	}
+	c := clauses[0]
+	if c.Paragraphs != 3 || c.Reuse != 3 || c.Type != "section" {
+		t.Errorf("closing clause = %+v", c)
+	}
+	if len(c.Variables) != 2 || c.Variables[0].Field != "Author" || c.Variables[1].Name != "FirmName" {
+		t.Errorf("closing variables = %+v", c.Variables)
+	}
+	if !strings.HasPrefix(c.Text[c.Variables[1].Offset:], "{{FirmName}}") {
+		t.Errorf("FirmName offset %d in %q", c.Variables[1].Offset, c.Text)
+	}
+	if occ := c.Occurrences[2]; occ.Document != "letter3.dot" || occ.Paragraph != 0 || occ.Offset != 0 {
+		t.Errorf("letter3 occurrence = %+v", occ)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text   string
+		keys   string
+		fields string
+	}{
+		{"Dear { MERGEFIELD Client \\* MERGEFORMAT },", "dear {field}", "Client"},
+		{"Re: «Matter_Description» – {{matter.reference}}", "re {field} {field}", "Matter_Description matter.reference"},
+		{`{ IF { DOCVARIABLE "Gender" } = "M" "Sir" "Madam" } and {unclosed`, "{field} and unclosed", "field"},
+	}
+	for _, tt := range tests {
+		var keys, fields []string
+		for _, tok := range tokenize(tt.text) {
+			keys = append(keys, tok.key)
+			if tok.field != "" {
+				fields = append(fields, tok.field)
+			}
+		}
+		if got := strings.Join(keys, " "); got != tt.keys {
+			t.Errorf("tokenize(%q) keys = %q, want %q", tt.text, got, tt.keys)
+		}
+		if got := strings.Join(fields, " "); got != tt.fields {
+			t.Errorf("tokenize(%q) fields = %q, want %q", tt.text, got, tt.fields)
+		}
+	}
+}
+
+func TestSegment(t *testing.T) {
+	preview := "  We enclose the decision.We thank you.\r\n\r\nYou have two options:accept it; or" +
+		` appeal.Call { DOCVARIABLE "!udSchFilAssist;;1;select;USRDDI" } today.`
+	tests := []struct {
+		text    string
+		preview bool
+		want    []string
+	}{
+		{preview, true, []string{
+			"We enclose the decision.",
+			"We thank you.",
+			"You have two options:",
+			"accept it; or appeal.",
+			`Call { DOCVARIABLE "!udSchFilAssist;;1;select;USRDDI" } today.`,
+		}},
+		{preview, false, []string{
+			"We enclose the decision.We thank you.",
+			`You have two options:accept it; or appeal.Call { DOCVARIABLE "!udSchFilAssist;;1;select;USRDDI" } today.`,
+		}},
+		{"Dear «client.Name», thank you", true, []string{"Dear «client.Name», thank you"}},
+		{"Ms.Smith will write to mailto:john today.Thank you", true, []string{"Ms.Smith will write to mailto:john today.", "Thank you"}},
+	}
+	for _, tt := range tests {
+		paragraphs := segment(0, tt.text, tt.preview)
+		var got []string
+		for i, p := range paragraphs {
+			if p.index != i || tt.text[p.offset:p.offset+p.length] != p.text {
+				t.Errorf("paragraph %d of %q is %q at %d", p.index, tt.text, p.text, p.offset)
+			}
+			got = append(got, p.text)
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("segment(%q, %v) = %q, want %q", tt.text, tt.preview, got, tt.want)
+		}
+	}
+}
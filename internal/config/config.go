@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -18,9 +20,26 @@ type Config struct {
 	MaxFileSize                  int64
 	ConversionTimeout            time.Duration
 	LogLevel                     string
-	EnhancedAccuracy             bool          // Enable enhanced accuracy for legal documents
-	SyncMaxFileSize              int64         // Max file size for synchronous conversion (in bytes)
-	SyncTimeout                  time.Duration // Timeout for synchronous conversions
+	EnhancedAccuracy             bool              // Enable enhanced accuracy for legal documents
+	SyncMaxFileSize              int64             // Max file size for synchronous conversion (in bytes)
+	SyncTimeout                  time.Duration     // Timeout for synchronous conversions
+	NativeKeepMacros             bool              // Keep vbaProject.bin when converting natively (produces macro-enabled .docm/.dotm output)
+	OfficePoolSize               int               // Number of warm LibreOffice instances (0 = spawn per conversion)
+	OfficeMaxConversions         int               // Recycle an office instance after this many conversions
+	OfficeBasePort               int               // First UNO socket port used by the office pool
+	OfficeStartupTimeout         time.Duration     // Maximum time to wait for an office instance to start
+	MaxAttempts                  int               // Attempts per job before it is dead-lettered
+	RetryBaseDelay               time.Duration     // Backoff before the first retry (doubles per attempt)
+	RetryMaxDelay                time.Duration     // Upper bound on retry backoff
+	JobLeaseTimeout              time.Duration     // In-flight lease; jobs without a heartbeat for this long are requeued
+	WebhookSecret                string            // HMAC-SHA256 key used to sign webhook deliveries; webhooks are disabled without it
+	WebhookTenantURLs            map[string]string // Default callback URL per tenant
+	WebhookMaxAttempts           int               // Delivery attempts before a webhook is marked failed
+	ComplexityRulesPath          string            // YAML or JSON complexity rules; empty uses the built-in rules
+	ComplexityRulesDir           string            // Directory of named rule sets selectable per analyze request
+	ComplexityRulesReload        time.Duration     // How often the rules file is checked for changes (0 disables reloading)
+	MatterSphereImportDir        string            // Local directory the import API may read exports from (empty = storage only)
+	AllowedOrigins               []string          // Browser origins besides the server's own allowed to open the live stream (* = any)
 }
 
 // Load loads configuration from environment variables
@@ -37,6 +56,27 @@ func Load() *Config {
 		EnhancedAccuracy:             getEnvAsBool("ENHANCED_ACCURACY", true),                      // Default to true for legal documents
 		SyncMaxFileSize:              getEnvAsInt64("SYNC_MAX_FILE_SIZE", 10) * 1024 * 1024,        // Default 10MB for sync
 		SyncTimeout:                  time.Duration(getEnvAsInt("SYNC_TIMEOUT", 30)) * time.Second, // Default 30s
+		NativeKeepMacros:             getEnvAsBool("NATIVE_KEEP_MACROS", false),
+		OfficePoolSize:               getEnvAsInt("OFFICE_POOL_SIZE", 4),
+		OfficeMaxConversions:         getEnvAsInt("OFFICE_MAX_CONVERSIONS", 200),
+		OfficeBasePort:               getEnvAsInt("OFFICE_BASE_PORT", 2002),
+		OfficeStartupTimeout:         time.Duration(getEnvAsInt("OFFICE_STARTUP_TIMEOUT", 30)) * time.Second,
+		MaxAttempts:                  getEnvAsInt("MAX_ATTEMPTS", 3),
+		RetryBaseDelay:               time.Duration(getEnvAsInt("RETRY_BASE_DELAY", 5)) * time.Second,
+		RetryMaxDelay:                time.Duration(getEnvAsInt("RETRY_MAX_DELAY", 300)) * time.Second,
+		JobLeaseTimeout:              time.Duration(getEnvAsInt("JOB_LEASE_TIMEOUT", 120)) * time.Second,
+		WebhookSecret:                getEnv("WEBHOOK_SECRET", ""),
+		WebhookTenantURLs:            getEnvAsMap("WEBHOOK_TENANT_URLS"), // tenant=url,tenant=url
+		WebhookMaxAttempts:           getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 10),
+		ComplexityRulesPath:          getEnv("COMPLEXITY_RULES_PATH", ""),
+		ComplexityRulesReload:        time.Duration(getEnvAsInt("COMPLEXITY_RULES_RELOAD", 30)) * time.Second,
+		MatterSphereImportDir:        getEnv("MATTERSPHERE_IMPORT_DIR", ""),
+		AllowedOrigins:               getEnvAsList("ALLOWED_ORIGINS"), // https://a.example,https://b.example
+	}
+	// Named rule sets live beside the default rules unless configured otherwise
+	cfg.ComplexityRulesDir = getEnv("COMPLEXITY_RULES_DIR", "")
+	if cfg.ComplexityRulesDir == "" && cfg.ComplexityRulesPath != "" {
+		cfg.ComplexityRulesDir = filepath.Dir(cfg.ComplexityRulesPath)
 	}
 
 	log.WithFields(log.Fields{
@@ -79,3 +119,27 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+// getEnvAsList parses a comma-separated list, skipping empty entries
+func getEnvAsList(key string) []string {
+	var result []string
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// getEnvAsMap parses a comma-separated list of key=value pairs
+func getEnvAsMap(key string) map[string]string {
+	result := make(map[string]string)
+	for _, pair := range strings.Split(getEnv(key, ""), ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || name == "" || value == "" {
+			continue
+		}
+		result[name] = value
+	}
+	return result
+}
@@ -0,0 +1,28 @@
+// Package converter provides document conversion engines that turn Word
+// templates (DOT/DOTX/DOTM) into DOCX documents, or DOTX, PDF and ODT outputs.
+package converter
+
+import (
+	"context"
+	"errors"
+)
+
+// Engine names used for per-job engine selection
+const (
+	EngineLibreOffice = "libreoffice"
+	EngineNative      = "native"
+)
+
+var (
+	// ErrUnsupportedFormat is returned when an engine cannot handle the input format
+	ErrUnsupportedFormat = errors.New("unsupported input format")
+	// ErrCorruptInput is returned when the input cannot be parsed; retrying will not help
+	ErrCorruptInput = errors.New("corrupt input document")
+)
+
+// Converter converts a document at inputPath and writes the result to outputPath.
+// The output format is determined by the extension of outputPath.
+type Converter interface {
+	// Convert performs the conversion, honouring ctx cancellation and deadlines
+	Convert(ctx context.Context, inputPath, outputPath string) error
+}
@@ -0,0 +1,161 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Output format names accepted by the output_format option
+const (
+	OutputDOCX = "docx"
+	OutputDOTX = "dotx"
+	OutputPDF  = "pdf"
+	OutputODT  = "odt"
+)
+
+var (
+	// ErrUnsupportedOutput is returned when an engine or request asks for an unknown output format
+	ErrUnsupportedOutput = errors.New("unsupported output format")
+)
+
+// OutputFormat describes a conversion target
+type OutputFormat struct {
+	Name        string `json:"name"`
+	Extension   string `json:"extension"`
+	ContentType string `json:"content_type"`
+}
+
+// outputOrder is the order formats are listed in discovery responses
+var outputOrder = []string{OutputDOCX, OutputDOTX, OutputPDF, OutputODT}
+
+var outputFormats = map[string]OutputFormat{
+	OutputDOCX: {
+		Name:        OutputDOCX,
+		Extension:   ".docx",
+		ContentType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	},
+	OutputDOTX: {
+		Name:        OutputDOTX,
+		Extension:   ".dotx",
+		ContentType: "application/vnd.openxmlformats-officedocument.wordprocessingml.template",
+	},
+	OutputPDF: {
+		Name:        OutputPDF,
+		Extension:   ".pdf",
+		ContentType: "application/pdf",
+	},
+	OutputODT: {
+		Name:        OutputODT,
+		Extension:   ".odt",
+		ContentType: "application/vnd.oasis.opendocument.text",
+	},
+}
+
+// macroEnabled maps output extensions to their macro-enabled variants and
+// content types, for packages that keep their VBA project
+var macroEnabled = map[string]OutputFormat{
+	".docx": {Name: OutputDOCX, Extension: ".docm", ContentType: "application/vnd.ms-word.document.macroEnabled.12"},
+	".dotx": {Name: OutputDOTX, Extension: ".dotm", ContentType: "application/vnd.ms-word.template.macroEnabled.12"},
+}
+
+// FormatSupporter is implemented by engines that only produce some output formats.
+// Engines that don't implement it are assumed to support every format.
+type FormatSupporter interface {
+	SupportsOutput(format string) bool
+}
+
+// OutputFormats lists the supported output format names
+func OutputFormats() []string {
+	return append([]string(nil), outputOrder...)
+}
+
+// LookupOutputFormat returns the output format for a name such as "pdf" or ".pdf"
+func LookupOutputFormat(name string) (OutputFormat, bool) {
+	format, ok := outputFormats[strings.TrimPrefix(strings.ToLower(strings.TrimSpace(name)), ".")]
+	return format, ok
+}
+
+// ParseOutputFormats parses a comma-separated output_format value, defaulting to DOCX
+func ParseOutputFormats(value string) ([]string, error) {
+	formats := make([]string, 0, 1)
+	seen := make(map[string]bool)
+
+	for _, part := range strings.Split(value, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		format, ok := LookupOutputFormat(part)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q (supported: %s)", ErrUnsupportedOutput, strings.TrimSpace(part), strings.Join(outputOrder, ", "))
+		}
+		if !seen[format.Name] {
+			seen[format.Name] = true
+			formats = append(formats, format.Name)
+		}
+	}
+
+	if len(formats) == 0 {
+		formats = append(formats, OutputDOCX)
+	}
+	return formats, nil
+}
+
+// OutputFormatForPath returns the output format implied by a path's extension, defaulting to DOCX
+func OutputFormatForPath(path string) string {
+	if format, ok := LookupOutputFormat(filepath.Ext(path)); ok {
+		return format.Name
+	}
+	return OutputDOCX
+}
+
+// ContentTypeForPath returns the MIME type for a converted file
+func ContentTypeForPath(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if format, ok := LookupOutputFormat(ext); ok {
+		return format.ContentType
+	}
+	for _, format := range macroEnabled {
+		if format.Extension == ext {
+			return format.ContentType
+		}
+	}
+	return "application/octet-stream"
+}
+
+// WithOutputExtension replaces the extension of path with the extension of format
+func WithOutputExtension(path, format string) string {
+	target, ok := LookupOutputFormat(format)
+	if !ok {
+		target = outputFormats[OutputDOCX]
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path)) + target.Extension
+}
+
+// officeFilter returns the LibreOffice export filter for an output format
+func officeFilter(format string, enhancedAccuracy bool) string {
+	switch format {
+	case OutputDOTX:
+		return "MS Word 2007 XML Template"
+	case OutputPDF:
+		return "writer_pdf_Export"
+	case OutputODT:
+		return "writer8"
+	default:
+		if enhancedAccuracy {
+			return "MS Word 2007 XML"
+		}
+		return "Office Open XML Text"
+	}
+}
+
+// MacroEnabledPath returns path with the extension of its macro-enabled variant,
+// .docm for .docx and .dotm for .dotx; other paths are returned unchanged
+func MacroEnabledPath(path string) string {
+	format, ok := macroEnabled[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return path
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path)) + format.Extension
+}
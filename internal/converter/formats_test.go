@@ -0,0 +1,40 @@
+package converter
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseOutputFormats(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected []string
+		wantErr  bool
+	}{
+		{"empty defaults to docx", "", []string{OutputDOCX}, false},
+		{"single format", "pdf", []string{OutputPDF}, false},
+		{"multiple formats", "docx, PDF,.odt", []string{OutputDOCX, OutputPDF, OutputODT}, false},
+		{"duplicates removed", "pdf,pdf,dotx", []string{OutputPDF, OutputDOTX}, false},
+		{"unknown format", "docx,rtf", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formats, err := ParseOutputFormats(tt.value)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnsupportedOutput) {
+					t.Errorf("expected ErrUnsupportedOutput, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(formats, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, formats)
+			}
+		})
+	}
+}
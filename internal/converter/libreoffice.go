@@ -0,0 +1,138 @@
+package converter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// officeLoadFailure is what LibreOffice reports for input it cannot open, such
+// as a damaged or mislabelled file; retrying gives the same result
+const officeLoadFailure = "source file could not be loaded"
+
+// LibreOfficeConverter implements Converter by running LibreOffice in headless mode
+type LibreOfficeConverter struct {
+	timeout          time.Duration
+	enhancedAccuracy bool
+	binary           string
+	pool             *OfficePool
+}
+
+// NewLibreOfficeConverter creates a new LibreOffice-based converter
+func NewLibreOfficeConverter(timeout time.Duration) *LibreOfficeConverter {
+	return &LibreOfficeConverter{
+		timeout:          timeout,
+		enhancedAccuracy: true,
+		binary:           findOfficeBinary(),
+	}
+}
+
+// SetEnhancedAccuracy toggles the MS Word 2007 XML export filter
+func (c *LibreOfficeConverter) SetEnhancedAccuracy(enabled bool) {
+	c.enhancedAccuracy = enabled
+}
+
+// SetPool routes conversions through a warm office pool instead of spawning
+// a new soffice process per conversion
+func (c *LibreOfficeConverter) SetPool(pool *OfficePool) {
+	c.pool = pool
+}
+
+// Convert converts the input document using LibreOffice; the output format follows
+// the extension of outputPath (DOCX, DOTX, PDF or ODT)
+func (c *LibreOfficeConverter) Convert(ctx context.Context, inputPath, outputPath string) error {
+	if c.pool != nil {
+		return c.pool.Convert(ctx, inputPath, outputPath)
+	}
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	// Each conversion gets its own working directory and user profile so
+	// concurrent LibreOffice processes don't fight over the same lock files
+	workDir, err := os.MkdirTemp("", "libreoffice-"+uuid.New().String())
+	if err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	profileDir := filepath.Join(workDir, "profile")
+	outDir := filepath.Join(workDir, "out")
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// The export filter follows the extension of the requested output path
+	format, _ := LookupOutputFormat(OutputFormatForPath(outputPath))
+	filter := strings.TrimPrefix(format.Extension, ".")
+	if format.Name != OutputDOCX || c.enhancedAccuracy {
+		filter += ":" + officeFilter(format.Name, c.enhancedAccuracy)
+	}
+
+	args := []string{
+		"--headless",
+		"--invisible",
+		"--nologo",
+		"--nodefault",
+		"--nofirststartwizard",
+		"--norestore",
+		"-env:UserInstallation=file://" + filepath.ToSlash(profileDir),
+		"--convert-to", filter,
+		"--outdir", outDir,
+		inputPath,
+	}
+
+	cmd := exec.CommandContext(ctx, c.binary, args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("libreoffice conversion aborted: %w", ctx.Err())
+	}
+	// soffice exits cleanly without output when it cannot load the input
+	if strings.Contains(string(output), officeLoadFailure) {
+		return fmt.Errorf("libreoffice: %w: %s", ErrCorruptInput, strings.TrimSpace(string(output)))
+	}
+	if err != nil {
+		return fmt.Errorf("libreoffice conversion failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	// LibreOffice names the output after the input file
+	base := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+	converted := filepath.Join(outDir, base+format.Extension)
+	if _, err := os.Stat(converted); err != nil {
+		return fmt.Errorf("libreoffice produced no output: %s", strings.TrimSpace(string(output)))
+	}
+
+	if err := moveFile(converted, outputPath); err != nil {
+		return fmt.Errorf("failed to move converted file: %w", err)
+	}
+
+	log.Debugf("LibreOffice converted %s to %s", inputPath, outputPath)
+	return nil
+}
+
+// moveFile moves a file, falling back to copy when rename crosses devices
+func moveFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0644)
+}
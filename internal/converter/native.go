@@ -0,0 +1,298 @@
+package converter
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/analyzer"
+	log "github.com/sirupsen/logrus"
+)
+
+// WordprocessingML content types for the main document part
+const (
+	contentTypeTemplate      = "application/vnd.openxmlformats-officedocument.wordprocessingml.template.main+xml"
+	contentTypeMacroTemplate = "application/vnd.ms-word.template.macroEnabledTemplate.main+xml"
+	contentTypeDocument      = "application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"
+	contentTypeMacroDocument = "application/vnd.ms-word.document.macroEnabled.main+xml"
+)
+
+// Relationship types touched during conversion
+const (
+	relTypeVBAProject       = "http://schemas.microsoft.com/office/2006/relationships/vbaProject"
+	relTypeKeyMapCustomizer = "http://schemas.microsoft.com/office/2006/relationships/keyMapCustomizations"
+	relTypeAttachedTemplate = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/attachedTemplate"
+)
+
+// Parts that only exist to support VBA macros
+var macroParts = map[string]bool{
+	"word/vbaProject.bin":            true,
+	"word/vbaData.xml":               true,
+	"word/_rels/vbaProject.bin.rels": true,
+	"word/customizations.xml":        true,
+}
+
+// NativeOptions controls how templates are rewritten
+type NativeOptions struct {
+	// KeepMacros keeps vbaProject.bin; the output is then a macro-enabled document
+	// or template written with a .docm or .dotm extension
+	KeepMacros bool
+	// AttachedTemplate rewrites settings.xml attachedTemplate to this target; empty removes it
+	AttachedTemplate string
+}
+
+// NativeConverter converts ZIP-based templates (DOTX/DOTM) to DOCX without an external
+// process by rewriting the package parts. OLE and RTF inputs are handed to the fallback.
+type NativeConverter struct {
+	options   NativeOptions
+	fallback  Converter
+	extractor *analyzer.DocumentExtractor
+
+	relPattern        *regexp.Regexp
+	overridePattern   *regexp.Regexp
+	defaultBinPattern *regexp.Regexp
+	attachedPattern   *regexp.Regexp
+	targetPattern     *regexp.Regexp
+}
+
+// NewNativeConverter creates a native converter; fallback may be nil
+func NewNativeConverter(options NativeOptions, fallback Converter) *NativeConverter {
+	return &NativeConverter{
+		options:           options,
+		fallback:          fallback,
+		extractor:         analyzer.NewDocumentExtractor(),
+		relPattern:        regexp.MustCompile(`<Relationship\s[^>]*/>`),
+		overridePattern:   regexp.MustCompile(`<Override\s[^>]*/>`),
+		defaultBinPattern: regexp.MustCompile(`<Default\s[^>]*ContentType="application/vnd\.ms-office\.vbaProject"[^>]*/>`),
+		attachedPattern:   regexp.MustCompile(`<w:attachedTemplate\s[^>]*/>`),
+		targetPattern:     regexp.MustCompile(`Target="[^"]*"`),
+	}
+}
+
+// SupportsOutput reports whether the native converter can produce the format;
+// it only rewrites OOXML packages so PDF and ODT are left to other engines
+func (c *NativeConverter) SupportsOutput(format string) bool {
+	return format == OutputDOCX || format == OutputDOTX
+}
+
+// Convert converts the template at inputPath to a document (or clean template
+// when outputPath ends in .dotx) at outputPath. A package that keeps its macros
+// is written to MacroEnabledPath(outputPath) instead, so its extension matches
+// its content type.
+func (c *NativeConverter) Convert(ctx context.Context, inputPath, outputPath string) error {
+	output := OutputFormatForPath(outputPath)
+	if !c.SupportsOutput(output) {
+		if c.fallback == nil {
+			return fmt.Errorf("native converter: %w: %s", ErrUnsupportedOutput, output)
+		}
+		return c.fallback.Convert(ctx, inputPath, outputPath)
+	}
+
+	content, err := os.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+
+	if c.extractor.DetectFormat(content) != analyzer.FormatZipBased {
+		if c.fallback == nil {
+			return fmt.Errorf("native converter: %w", ErrUnsupportedFormat)
+		}
+		log.Debugf("Native converter falling back for non-ZIP input %s", filepath.Base(inputPath))
+		return c.fallback.Convert(ctx, inputPath, outputPath)
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	converted, macros, err := c.rewritePackage(content, output == OutputDOTX)
+	if err != nil {
+		return err
+	}
+	if macros {
+		outputPath = MacroEnabledPath(outputPath)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	if err := os.WriteFile(outputPath, converted, 0644); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	log.Debugf("Natively converted %s to %s", inputPath, outputPath)
+	return nil
+}
+
+// ConvertBytes rewrites a ZIP-based template package into a document package
+func (c *NativeConverter) ConvertBytes(content []byte) ([]byte, error) {
+	converted, _, err := c.rewritePackage(content, false)
+	return converted, err
+}
+
+// rewritePackage strips macros and template links from a package; when asTemplate
+// is set the main part keeps a template content type so the result is a clean DOTX.
+// It reports whether the macros were kept.
+func (c *NativeConverter) rewritePackage(content []byte, asTemplate bool) ([]byte, bool, error) {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read template package: %w: %v", ErrCorruptInput, err)
+	}
+
+	hasDocument := false
+	hasMacros := false
+	for _, file := range reader.File {
+		switch file.Name {
+		case "word/document.xml":
+			hasDocument = true
+		case "word/vbaProject.bin":
+			hasMacros = true
+		}
+	}
+
+	if !hasDocument {
+		return nil, false, fmt.Errorf("template package has no word/document.xml: %w", ErrUnsupportedFormat)
+	}
+
+	keepMacros := c.options.KeepMacros && hasMacros
+
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+
+	for _, file := range reader.File {
+		if !keepMacros && macroParts[file.Name] {
+			continue
+		}
+
+		data, err := readZipFile(file)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+
+		switch file.Name {
+		case "[Content_Types].xml":
+			data = c.rewriteContentTypes(data, keepMacros, asTemplate)
+		case "word/_rels/document.xml.rels":
+			if !keepMacros {
+				data = c.removeRelationships(data, relTypeVBAProject, relTypeKeyMapCustomizer)
+			}
+		case "word/_rels/settings.xml.rels":
+			data = c.rewriteAttachedTemplateRels(data)
+		case "word/settings.xml":
+			if c.options.AttachedTemplate == "" {
+				data = c.attachedPattern.ReplaceAll(data, nil)
+			}
+		}
+
+		header := &zip.FileHeader{
+			Name:     file.Name,
+			Method:   zip.Deflate,
+			Modified: file.Modified,
+		}
+		w, err := writer.CreateHeader(header)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to write %s: %w", file.Name, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, false, fmt.Errorf("failed to write %s: %w", file.Name, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, false, fmt.Errorf("failed to finalize document package: %w", err)
+	}
+
+	return buf.Bytes(), keepMacros, nil
+}
+
+// rewriteContentTypes switches the main part from template to document content type,
+// or to the macro-free template content type when producing a template
+func (c *NativeConverter) rewriteContentTypes(data []byte, keepMacros, asTemplate bool) []byte {
+	types := string(data)
+
+	if keepMacros {
+		// Written as .dotm, so the main part must be a macro-enabled template
+		if asTemplate {
+			return []byte(strings.ReplaceAll(types, contentTypeTemplate, contentTypeMacroTemplate))
+		}
+		types = strings.ReplaceAll(types, contentTypeMacroTemplate, contentTypeMacroDocument)
+		types = strings.ReplaceAll(types, contentTypeTemplate, contentTypeMacroDocument)
+		return []byte(types)
+	}
+
+	if asTemplate {
+		types = strings.ReplaceAll(types, contentTypeMacroTemplate, contentTypeTemplate)
+	} else {
+		types = strings.ReplaceAll(types, contentTypeMacroTemplate, contentTypeDocument)
+		types = strings.ReplaceAll(types, contentTypeTemplate, contentTypeDocument)
+	}
+
+	// Drop overrides for the macro parts we removed
+	types = c.overridePattern.ReplaceAllStringFunc(types, func(override string) string {
+		for part := range macroParts {
+			if strings.Contains(override, `PartName="/`+part+`"`) {
+				return ""
+			}
+		}
+		return override
+	})
+	types = c.defaultBinPattern.ReplaceAllString(types, "")
+
+	return []byte(types)
+}
+
+// removeRelationships drops relationships of the given types
+func (c *NativeConverter) removeRelationships(data []byte, relTypes ...string) []byte {
+	return c.relPattern.ReplaceAllFunc(data, func(rel []byte) []byte {
+		for _, relType := range relTypes {
+			if bytes.Contains(rel, []byte(`Type="`+relType+`"`)) {
+				return nil
+			}
+		}
+		return rel
+	})
+}
+
+// rewriteAttachedTemplateRels retargets or removes the attachedTemplate relationship
+func (c *NativeConverter) rewriteAttachedTemplateRels(data []byte) []byte {
+	if c.options.AttachedTemplate == "" {
+		return c.removeRelationships(data, relTypeAttachedTemplate)
+	}
+
+	target := []byte(`Target="` + xmlEscape(c.options.AttachedTemplate) + `"`)
+	return c.relPattern.ReplaceAllFunc(data, func(rel []byte) []byte {
+		if !bytes.Contains(rel, []byte(`Type="`+relTypeAttachedTemplate+`"`)) {
+			return rel
+		}
+		return c.targetPattern.ReplaceAll(rel, target)
+	})
+}
+
+// readZipFile reads the full contents of a ZIP entry
+func readZipFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// xmlEscape escapes a string for use inside an XML attribute
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	if err := xml.EscapeText(&buf, []byte(s)); err != nil {
+		return s
+	}
+	return buf.String()
+}
@@ -0,0 +1,235 @@
+package converter
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="bin" ContentType="application/vnd.ms-office.vbaProject"/><Override PartName="/word/document.xml" ContentType="application/vnd.ms-word.template.macroEnabledTemplate.main+xml"/><Override PartName="/word/vbaData.xml" ContentType="application/vnd.ms-word.vbaData+xml"/></Types>`
+
+const testDocumentRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.microsoft.com/office/2006/relationships/vbaProject" Target="vbaProject.bin"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/settings" Target="settings.xml"/></Relationships>`
+
+const testSettingsRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/attachedTemplate" Target="file:///\\server\templates\Normal.dotm" TargetMode="External"/></Relationships>`
+
+const testSettings = `<w:settings xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><w:attachedTemplate r:id="rId1"/><w:defaultTabStop w:val="720"/></w:settings>`
+
+func buildTemplate(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range parts {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func readParts(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("output is not a valid package: %v", err)
+	}
+	parts := make(map[string]string)
+	for _, f := range r.File {
+		content, err := readZipFile(f)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", f.Name, err)
+		}
+		parts[f.Name] = string(content)
+	}
+	return parts
+}
+
+func testTemplate(t *testing.T) []byte {
+	return buildTemplate(t, map[string]string{
+		"[Content_Types].xml":          testContentTypes,
+		"word/document.xml":            `<w:document/>`,
+		"word/_rels/document.xml.rels": testDocumentRels,
+		"word/_rels/settings.xml.rels": testSettingsRels,
+		"word/settings.xml":            testSettings,
+		"word/vbaProject.bin":          "VBA",
+		"word/vbaData.xml":             `<wne:vbaSuppData/>`,
+	})
+}
+
+func TestNativeConvertDropsMacros(t *testing.T) {
+	conv := NewNativeConverter(NativeOptions{}, nil)
+
+	out, err := conv.ConvertBytes(testTemplate(t))
+	if err != nil {
+		t.Fatalf("ConvertBytes failed: %v", err)
+	}
+	parts := readParts(t, out)
+
+	if _, ok := parts["word/vbaProject.bin"]; ok {
+		t.Error("vbaProject.bin should be removed")
+	}
+	if _, ok := parts["word/vbaData.xml"]; ok {
+		t.Error("vbaData.xml should be removed")
+	}
+
+	types := parts["[Content_Types].xml"]
+	if !strings.Contains(types, contentTypeDocument) {
+		t.Errorf("main part should use document content type: %s", types)
+	}
+	if strings.Contains(types, "vbaProject") || strings.Contains(types, "vbaData") {
+		t.Errorf("macro content types should be removed: %s", types)
+	}
+
+	if strings.Contains(parts["word/_rels/document.xml.rels"], relTypeVBAProject) {
+		t.Error("vbaProject relationship should be removed")
+	}
+	if !strings.Contains(parts["word/_rels/document.xml.rels"], "settings.xml") {
+		t.Error("unrelated relationships should be kept")
+	}
+
+	if strings.Contains(parts["word/settings.xml"], "attachedTemplate") {
+		t.Error("attachedTemplate should be removed from settings.xml")
+	}
+	if strings.Contains(parts["word/_rels/settings.xml.rels"], relTypeAttachedTemplate) {
+		t.Error("attachedTemplate relationship should be removed")
+	}
+}
+
+func TestNativeConvertKeepsMacros(t *testing.T) {
+	conv := NewNativeConverter(NativeOptions{KeepMacros: true, AttachedTemplate: "Firm.dotx"}, nil)
+
+	out, err := conv.ConvertBytes(testTemplate(t))
+	if err != nil {
+		t.Fatalf("ConvertBytes failed: %v", err)
+	}
+	parts := readParts(t, out)
+
+	if _, ok := parts["word/vbaProject.bin"]; !ok {
+		t.Error("vbaProject.bin should be kept")
+	}
+	if !strings.Contains(parts["[Content_Types].xml"], contentTypeMacroDocument) {
+		t.Error("main part should use macro-enabled document content type")
+	}
+	if !strings.Contains(parts["word/_rels/settings.xml.rels"], `Target="Firm.dotx"`) {
+		t.Errorf("attachedTemplate should be retargeted: %s", parts["word/_rels/settings.xml.rels"])
+	}
+}
+
+func TestNativeConvertToTemplate(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.dotm")
+	if err := os.WriteFile(input, testTemplate(t), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	conv := NewNativeConverter(NativeOptions{}, nil)
+	output := filepath.Join(dir, "out", "clean.dotx")
+	if err := conv.Convert(context.Background(), input, output); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := readParts(t, data)
+	if !strings.Contains(parts["[Content_Types].xml"], contentTypeTemplate) {
+		t.Errorf("main part should use template content type: %s", parts["[Content_Types].xml"])
+	}
+	if _, ok := parts["word/vbaProject.bin"]; ok {
+		t.Error("vbaProject.bin should be removed")
+	}
+
+	err = conv.Convert(context.Background(), input, filepath.Join(dir, "out", "preview.pdf"))
+	if !errors.Is(err, ErrUnsupportedOutput) {
+		t.Errorf("expected ErrUnsupportedOutput for PDF output, got %v", err)
+	}
+}
+
+func TestNativeConvertMacroExtension(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.dotm")
+	if err := os.WriteFile(input, testTemplate(t), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		keepMacros  bool
+		output      string
+		written     string
+		contentType string
+	}{
+		{false, "letter.docx", "letter.docx", contentTypeDocument},
+		{true, "letter.docx", "letter.docm", contentTypeMacroDocument},
+		{false, "letter.dotx", "letter.dotx", contentTypeTemplate},
+		{true, "letter.dotx", "letter.dotm", contentTypeMacroTemplate},
+	}
+	for _, tt := range tests {
+		conv := NewNativeConverter(NativeOptions{KeepMacros: tt.keepMacros}, nil)
+		out := t.TempDir()
+		if err := conv.Convert(context.Background(), input, filepath.Join(out, tt.output)); err != nil {
+			t.Fatalf("Convert to %s failed: %v", tt.output, err)
+		}
+
+		entries, _ := os.ReadDir(out)
+		if len(entries) != 1 || entries[0].Name() != tt.written {
+			t.Fatalf("keepMacros=%v: converting to %s wrote %v, want %s", tt.keepMacros, tt.output, entries, tt.written)
+		}
+		data, err := os.ReadFile(filepath.Join(out, tt.written))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts := readParts(t, data)
+		if !strings.Contains(parts["[Content_Types].xml"], `PartName="/word/document.xml" ContentType="`+tt.contentType+`"`) {
+			t.Errorf("%s main part content type: %s", tt.written, parts["[Content_Types].xml"])
+		}
+		if _, ok := parts["word/vbaProject.bin"]; ok != tt.keepMacros {
+			t.Errorf("%s has vbaProject.bin = %v, want %v", tt.written, ok, tt.keepMacros)
+		}
+	}
+}
+
+type recordingConverter struct {
+	called bool
+}
+
+func (r *recordingConverter) Convert(ctx context.Context, inputPath, outputPath string) error {
+	r.called = true
+	return nil
+}
+
+func TestNativeConvertFallback(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "legacy.dot")
+	ole := append([]byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}, make([]byte, 512)...)
+	if err := os.WriteFile(input, ole, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fallback := &recordingConverter{}
+	conv := NewNativeConverter(NativeOptions{}, fallback)
+	if err := conv.Convert(context.Background(), input, filepath.Join(dir, "legacy.docx")); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if !fallback.called {
+		t.Error("OLE input should be handed to the fallback converter")
+	}
+
+	conv = NewNativeConverter(NativeOptions{}, nil)
+	err := conv.Convert(context.Background(), input, filepath.Join(dir, "legacy.docx"))
+	if !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat without fallback, got %v", err)
+	}
+}
@@ -0,0 +1,540 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Office instance states reported by the pool health check
+const (
+	instanceStarting = "starting"
+	instanceIdle     = "idle"
+	instanceBusy     = "busy"
+	instanceFailed   = "failed"
+)
+
+var (
+	// ErrPoolClosed is returned when converting through a closed pool
+	ErrPoolClosed = errors.New("office pool is closed")
+)
+
+// unoConvertScript drives a running office instance over the UNO socket bridge.
+// It is executed with LibreOffice's bundled Python so the uno module is available.
+const unoConvertScript = `import sys
+import uno
+from com.sun.star.beans import PropertyValue
+
+
+def prop(name, value):
+    p = PropertyValue()
+    p.Name = name
+    p.Value = value
+    return p
+
+
+port, src, dst, export_filter = sys.argv[1:5]
+local = uno.getComponentContext()
+resolver = local.ServiceManager.createInstanceWithContext("com.sun.star.bridge.UnoUrlResolver", local)
+ctx = resolver.resolve("uno:socket,host=127.0.0.1,port=%s;urp;StarOffice.ComponentContext" % port)
+desktop = ctx.ServiceManager.createInstanceWithContext("com.sun.star.frame.Desktop", ctx)
+doc = desktop.loadComponentFromURL(uno.systemPathToFileUrl(src), "_blank", 0,
+                                   (prop("Hidden", True), prop("ReadOnly", True)))
+if doc is None:
+    sys.stderr.write("Error: source file could not be loaded\n")
+    sys.exit(2)
+try:
+    doc.storeToURL(uno.systemPathToFileUrl(dst), (prop("FilterName", export_filter), prop("Overwrite", True)))
+finally:
+    doc.close(True)
+`
+
+// OfficePoolOptions configures the warm LibreOffice instance pool
+type OfficePoolOptions struct {
+	Size              int           // Number of office instances kept running
+	MaxConversions    int           // Recycle an instance after this many conversions (0 = never)
+	BasePort          int           // First UNO socket port; instance i listens on BasePort+i
+	StartupTimeout    time.Duration // Maximum time to wait for an instance to accept connections
+	ConversionTimeout time.Duration // Conversions exceeding this are treated as hangs
+	EnhancedAccuracy  bool          // Use the MS Word 2007 XML export filter for DOCX output
+	WorkDir           string        // Directory for profiles and helper script (default: temp dir)
+}
+
+// OfficeInstanceHealth describes a single pooled office instance
+type OfficeInstanceHealth struct {
+	ID          int    `json:"id"`
+	Port        int    `json:"port"`
+	PID         int    `json:"pid,omitempty"`
+	State       string `json:"state"`
+	Conversions int    `json:"conversions"`
+	Restarts    int    `json:"restarts"`
+	LastError   string `json:"last_error,omitempty"`
+}
+
+// OfficePoolHealth summarises pool state for readiness checks
+type OfficePoolHealth struct {
+	Size             int                    `json:"size"`
+	Available        int                    `json:"available"`
+	Busy             int                    `json:"busy"`
+	Failed           int                    `json:"failed"`
+	TotalConversions int64                  `json:"total_conversions"`
+	TotalRestarts    int64                  `json:"total_restarts"`
+	Instances        []OfficeInstanceHealth `json:"instances"`
+}
+
+// Ready reports whether at least one instance can take conversions
+func (h OfficePoolHealth) Ready() bool {
+	return h.Available+h.Busy > 0
+}
+
+// officeInstance is a single headless soffice process with its own user profile
+type officeInstance struct {
+	id          int
+	port        int
+	profileDir  string
+	cmd         *exec.Cmd
+	exited      chan struct{}
+	state       string
+	conversions int
+	restarts    int
+	lastError   string
+}
+
+// OfficePool keeps warm headless LibreOffice instances and hands conversions to them
+// over the UNO socket bridge, recycling instances that crash, hang or hit their quota
+type OfficePool struct {
+	options    OfficePoolOptions
+	binary     string
+	python     string
+	scriptPath string
+
+	mu               sync.Mutex
+	instances        []*officeInstance
+	idle             chan *officeInstance
+	closed           chan struct{}
+	closeOnce        sync.Once
+	totalConversions int64
+	totalRestarts    int64
+}
+
+// NewOfficePool creates a pool; call Start to launch the office instances
+func NewOfficePool(options OfficePoolOptions) *OfficePool {
+	if options.Size <= 0 {
+		options.Size = 1
+	}
+	if options.BasePort == 0 {
+		options.BasePort = 2002
+	}
+	if options.StartupTimeout == 0 {
+		options.StartupTimeout = 30 * time.Second
+	}
+	if options.WorkDir == "" {
+		options.WorkDir = filepath.Join(os.TempDir(), "office-pool")
+	}
+
+	return &OfficePool{
+		options: options,
+		binary:  findOfficeBinary(),
+		idle:    make(chan *officeInstance, options.Size),
+		closed:  make(chan struct{}),
+	}
+}
+
+// Start launches all office instances and waits until they accept connections
+func (p *OfficePool) Start(ctx context.Context) error {
+	if err := os.MkdirAll(p.options.WorkDir, 0755); err != nil {
+		return fmt.Errorf("failed to create office pool directory: %w", err)
+	}
+
+	if p.python == "" {
+		python, err := p.findPython()
+		if err != nil {
+			return err
+		}
+		p.python = python
+	}
+
+	p.scriptPath = filepath.Join(p.options.WorkDir, "uno_convert.py")
+	if err := os.WriteFile(p.scriptPath, []byte(unoConvertScript), 0644); err != nil {
+		return fmt.Errorf("failed to write UNO helper script: %w", err)
+	}
+
+	for i := 0; i < p.options.Size; i++ {
+		inst := &officeInstance{
+			id:         i,
+			port:       p.options.BasePort + i,
+			profileDir: filepath.Join(p.options.WorkDir, fmt.Sprintf("profile-%d", i)),
+			state:      instanceStarting,
+		}
+		p.instances = append(p.instances, inst)
+
+		if err := p.launch(ctx, inst); err != nil {
+			p.Close()
+			return fmt.Errorf("failed to start office instance %d: %w", i, err)
+		}
+		p.release(inst)
+	}
+
+	log.Infof("Office pool started with %d instances (ports %d-%d)",
+		p.options.Size, p.options.BasePort, p.options.BasePort+p.options.Size-1)
+	return nil
+}
+
+// Convert converts a document using the next idle office instance
+func (p *OfficePool) Convert(ctx context.Context, inputPath, outputPath string) error {
+	var inst *officeInstance
+	select {
+	case inst = <-p.idle:
+	case <-p.closed:
+		return ErrPoolClosed
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for office instance: %w", ctx.Err())
+	}
+
+	// The process may have died while idle
+	select {
+	case <-inst.exited:
+		p.recycle(inst, "process exited while idle", true)
+		return p.Convert(ctx, inputPath, outputPath)
+	default:
+	}
+
+	p.setState(inst, instanceBusy)
+	err := p.convertWith(ctx, inst, inputPath, outputPath)
+
+	p.mu.Lock()
+	inst.conversions++
+	p.totalConversions++
+	limitReached := p.options.MaxConversions > 0 && inst.conversions >= p.options.MaxConversions
+	p.mu.Unlock()
+
+	select {
+	case <-inst.exited:
+		p.recycle(inst, "process crashed during conversion", true)
+	default:
+		switch {
+		case err != nil && (errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)):
+			p.recycle(inst, "conversion hung", true)
+		case limitReached:
+			p.recycle(inst, "conversion limit reached", false)
+		default:
+			p.release(inst)
+		}
+	}
+
+	return err
+}
+
+// convertWith runs the UNO helper against a specific instance
+func (p *OfficePool) convertWith(ctx context.Context, inst *officeInstance, inputPath, outputPath string) error {
+	if p.options.ConversionTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.options.ConversionTimeout)
+		defer cancel()
+	}
+
+	src, err := filepath.Abs(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve input path: %w", err)
+	}
+	dst, err := filepath.Abs(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve output path: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	filter := officeFilter(OutputFormatForPath(dst), p.options.EnhancedAccuracy)
+
+	cmd := exec.CommandContext(ctx, p.python, p.scriptPath, strconv.Itoa(inst.port), src, dst, filter)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("office instance %d conversion aborted: %w", inst.id, ctx.Err())
+		}
+		p.setError(inst, strings.TrimSpace(string(output)))
+		if strings.Contains(string(output), officeLoadFailure) {
+			return fmt.Errorf("office instance %d: %w: %s", inst.id, ErrCorruptInput, strings.TrimSpace(string(output)))
+		}
+		return fmt.Errorf("office instance %d conversion failed: %w: %s", inst.id, err, strings.TrimSpace(string(output)))
+	}
+
+	if _, err := os.Stat(dst); err != nil {
+		return fmt.Errorf("office instance %d produced no output", inst.id)
+	}
+
+	log.Debugf("Office instance %d converted %s", inst.id, filepath.Base(inputPath))
+	return nil
+}
+
+// launch starts the soffice process for an instance and waits for its socket
+func (p *OfficePool) launch(ctx context.Context, inst *officeInstance) error {
+	accept := fmt.Sprintf("--accept=socket,host=127.0.0.1,port=%d;urp;StarOffice.ComponentContext", inst.port)
+	cmd := exec.Command(p.binary,
+		"--headless",
+		"--invisible",
+		"--nologo",
+		"--nodefault",
+		"--nofirststartwizard",
+		"--norestore",
+		"-env:UserInstallation=file://"+filepath.ToSlash(inst.profileDir),
+		accept,
+	)
+	// soffice is a wrapper around soffice.bin, which holds the UNO port; both are
+	// killed as a group
+	startOwnGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		p.setError(inst, err.Error())
+		return err
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+
+	p.mu.Lock()
+	inst.cmd = cmd
+	inst.exited = exited
+	inst.state = instanceStarting
+	p.mu.Unlock()
+
+	if err := waitForPort(ctx, inst.port, exited, p.options.StartupTimeout); err != nil {
+		p.stop(inst)
+		p.setError(inst, err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// recycle restarts an instance in the background and returns it to the pool
+func (p *OfficePool) recycle(inst *officeInstance, reason string, discardProfile bool) {
+	log.Warnf("Recycling office instance %d: %s", inst.id, reason)
+	p.setState(inst, instanceStarting)
+
+	go func() {
+		p.stop(inst)
+		if discardProfile {
+			os.RemoveAll(inst.profileDir)
+		}
+
+		backoff := time.Second
+		for {
+			select {
+			case <-p.closed:
+				return
+			default:
+			}
+
+			if err := p.launch(context.Background(), inst); err == nil {
+				break
+			}
+
+			log.Errorf("Failed to restart office instance %d, retrying in %v", inst.id, backoff)
+			select {
+			case <-p.closed:
+				return
+			case <-time.After(backoff):
+			}
+			if backoff < time.Minute {
+				backoff *= 2
+			}
+		}
+
+		p.mu.Lock()
+		inst.conversions = 0
+		inst.restarts++
+		p.totalRestarts++
+		p.mu.Unlock()
+
+		p.release(inst)
+	}()
+}
+
+// release marks an instance idle and returns it to the idle channel
+func (p *OfficePool) release(inst *officeInstance) {
+	p.setState(inst, instanceIdle)
+	select {
+	case <-p.closed:
+		p.stop(inst)
+	case p.idle <- inst:
+	}
+}
+
+// stop terminates an instance's process group and waits until its port is
+// released, so a replacement can listen on it
+func (p *OfficePool) stop(inst *officeInstance) {
+	p.mu.Lock()
+	cmd, exited := inst.cmd, inst.exited
+	p.mu.Unlock()
+
+	if cmd == nil || cmd.Process == nil {
+		return
+	}
+
+	// soffice.bin may outlive a wrapper that already exited
+	killGroup(cmd)
+	<-exited
+
+	if err := waitForPortRelease(inst.port, p.options.StartupTimeout); err != nil {
+		log.Warnf("Office instance %d: %v", inst.id, err)
+	}
+}
+
+// Close stops all office instances
+func (p *OfficePool) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.closed)
+		for _, inst := range p.instances {
+			p.stop(inst)
+		}
+		log.Info("Office pool stopped")
+	})
+	return nil
+}
+
+// Health returns a snapshot of the pool state
+func (p *OfficePool) Health() OfficePoolHealth {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	health := OfficePoolHealth{
+		Size:             len(p.instances),
+		TotalConversions: p.totalConversions,
+		TotalRestarts:    p.totalRestarts,
+		Instances:        make([]OfficeInstanceHealth, 0, len(p.instances)),
+	}
+
+	for _, inst := range p.instances {
+		entry := OfficeInstanceHealth{
+			ID:          inst.id,
+			Port:        inst.port,
+			State:       inst.state,
+			Conversions: inst.conversions,
+			Restarts:    inst.restarts,
+			LastError:   inst.lastError,
+		}
+		if inst.cmd != nil && inst.cmd.Process != nil {
+			entry.PID = inst.cmd.Process.Pid
+		}
+
+		switch inst.state {
+		case instanceIdle:
+			health.Available++
+		case instanceBusy:
+			health.Busy++
+		case instanceFailed:
+			health.Failed++
+		}
+
+		health.Instances = append(health.Instances, entry)
+	}
+
+	return health
+}
+
+func (p *OfficePool) setState(inst *officeInstance, state string) {
+	p.mu.Lock()
+	inst.state = state
+	p.mu.Unlock()
+}
+
+func (p *OfficePool) setError(inst *officeInstance, message string) {
+	p.mu.Lock()
+	inst.lastError = message
+	if inst.state == instanceStarting {
+		inst.state = instanceFailed
+	}
+	p.mu.Unlock()
+}
+
+// findPython locates a Python interpreter with the uno module, preferring the one
+// bundled next to the office binary
+func (p *OfficePool) findPython() (string, error) {
+	candidates := make([]string, 0, 3)
+	if resolved, err := exec.LookPath(p.binary); err == nil {
+		if real, err := filepath.EvalSymlinks(resolved); err == nil {
+			candidates = append(candidates, filepath.Join(filepath.Dir(real), "python"))
+		}
+	}
+	candidates = append(candidates, "python3", "python")
+
+	for _, candidate := range candidates {
+		path, err := exec.LookPath(candidate)
+		if err != nil {
+			continue
+		}
+		if err := exec.Command(path, "-c", "import uno").Run(); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", errors.New("no Python interpreter with the uno module found")
+}
+
+// findOfficeBinary returns the soffice executable name available on PATH
+func findOfficeBinary() string {
+	if _, err := exec.LookPath("soffice"); err == nil {
+		return "soffice"
+	}
+	if path, err := exec.LookPath("libreoffice"); err == nil {
+		return path
+	}
+	return "soffice"
+}
+
+// waitForPort polls until the UNO socket accepts connections
+func waitForPort(ctx context.Context, port int, exited <-chan struct{}, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	address := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+
+	for time.Now().Before(deadline) {
+		conn, err := net.DialTimeout("tcp", address, 500*time.Millisecond)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+
+		select {
+		case <-exited:
+			return errors.New("office process exited during startup")
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+
+	return fmt.Errorf("office instance did not accept connections on port %d within %v", port, timeout)
+}
+
+// waitForPortRelease polls until the UNO port can be listened on again
+func waitForPortRelease(port int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	address := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+
+	for {
+		listener, err := net.Listen("tcp", address)
+		if err == nil {
+			listener.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("port %d still in use after %v", port, timeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
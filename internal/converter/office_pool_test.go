@@ -0,0 +1,210 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeOfficeEnv makes the test binary stand in for soffice, the soffice.bin it
+// spawns and the UNO helper script
+const fakeOfficeEnv = "FAKE_OFFICE"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeOfficeEnv) != "" {
+		fakeOffice(os.Args[1:])
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// fakeOffice plays the process named by its arguments. Like soffice, the
+// wrapper leaves listening on the UNO port to a soffice.bin child.
+func fakeOffice(args []string) {
+	switch {
+	case len(args) > 2 && args[len(args)-3] == "--outdir":
+		// A one-off soffice --convert-to, which reports unloadable input on
+		// stdout and exits cleanly
+		data, _ := os.ReadFile(args[len(args)-1])
+		if strings.Contains(string(data), "corrupt") {
+			fmt.Println("Error: source file could not be loaded")
+		}
+	case len(args) > 0 && args[0] == "--headless":
+		port := args[len(args)-1]
+		port = port[strings.Index(port, "port=")+len("port=") : strings.Index(port, ";")]
+		child := exec.Command(os.Args[0], "soffice.bin", port)
+		if err := child.Start(); err != nil {
+			os.Exit(1)
+		}
+		select {}
+	case len(args) == 2 && args[0] == "soffice.bin":
+		listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", args[1]))
+		if err != nil {
+			os.Exit(1)
+		}
+		for {
+			if conn, err := listener.Accept(); err == nil {
+				conn.Close()
+			}
+		}
+	case len(args) == 5:
+		// Script, port, source, destination and filter of the UNO helper
+		data, err := os.ReadFile(args[2])
+		if err != nil {
+			os.Exit(2)
+		}
+		if strings.Contains(string(data), "hang") {
+			select {}
+		}
+		if strings.Contains(string(data), "corrupt") {
+			fmt.Fprintln(os.Stderr, "Error: source file could not be loaded")
+			os.Exit(2)
+		}
+		if err := os.WriteFile(args[3], data, 0644); err != nil {
+			os.Exit(2)
+		}
+	}
+}
+
+// freePort returns a port nothing listens on
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// startFakePool starts a single-instance pool on the fake office
+func startFakePool(t *testing.T, options OfficePoolOptions) *OfficePool {
+	t.Helper()
+	t.Setenv(fakeOfficeEnv, "1")
+	options.Size = 1
+	options.BasePort = freePort(t)
+	options.StartupTimeout = 5 * time.Second
+	options.WorkDir = t.TempDir()
+
+	p := NewOfficePool(options)
+	p.binary, p.python = os.Args[0], os.Args[0]
+	if err := p.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+// convertFake converts a document with the given content through the pool
+func convertFake(t *testing.T, p *OfficePool, content string) error {
+	t.Helper()
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.dot")
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p.Convert(context.Background(), input, filepath.Join(dir, "out.docx"))
+}
+
+// waitForRestarts waits until the pool has recycled an instance n times and
+// it is idle again
+func waitForRestarts(t *testing.T, p *OfficePool, n int64) OfficePoolHealth {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		health := p.Health()
+		if health.TotalRestarts >= n && health.Available == 1 {
+			return health
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("pool not restarted %d times: %+v", n, health)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// portFree reports whether the port can be listened on
+func portFree(port int) bool {
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return false
+	}
+	listener.Close()
+	return true
+}
+
+func TestOfficePoolLaunch(t *testing.T) {
+	p := startFakePool(t, OfficePoolOptions{})
+
+	health := p.Health()
+	if !health.Ready() || health.Available != 1 || health.Instances[0].PID == 0 {
+		t.Fatalf("health after start = %+v", health)
+	}
+	if err := convertFake(t, p, "template"); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	if health := p.Health(); health.TotalConversions != 1 || health.Instances[0].Conversions != 1 {
+		t.Errorf("health after a conversion = %+v", health)
+	}
+	if err := convertFake(t, p, "corrupt"); !errors.Is(err, ErrCorruptInput) {
+		t.Errorf("Convert of an unloadable document = %v, want ErrCorruptInput", err)
+	}
+
+	port := p.options.BasePort
+	p.Close()
+	if !portFree(port) {
+		t.Errorf("port %d still held after Close", port)
+	}
+}
+
+func TestOfficePoolRecycles(t *testing.T) {
+	tests := []struct {
+		name    string
+		options OfficePoolOptions
+		content string
+		failed  bool
+	}{
+		{"conversion limit", OfficePoolOptions{MaxConversions: 1}, "template", false},
+		{"hung conversion", OfficePoolOptions{ConversionTimeout: 200 * time.Millisecond}, "hang", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := startFakePool(t, tt.options)
+			pid := p.Health().Instances[0].PID
+
+			if err := convertFake(t, p, tt.content); (err != nil) != tt.failed {
+				t.Fatalf("Convert error = %v, want failure %v", err, tt.failed)
+			}
+			health := waitForRestarts(t, p, 1)
+			if inst := health.Instances[0]; inst.PID == pid || inst.Conversions != 0 || inst.Restarts != 1 {
+				t.Errorf("recycled instance = %+v, previous pid %d", inst, pid)
+			}
+			if err := convertFake(t, p, "template"); err != nil {
+				t.Errorf("Convert after recycling failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestLibreOfficeLoadFailure(t *testing.T) {
+	t.Setenv(fakeOfficeEnv, "1")
+	c := NewLibreOfficeConverter(5 * time.Second)
+	c.binary = os.Args[0]
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.dot")
+	if err := os.WriteFile(input, []byte("corrupt"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Convert(context.Background(), input, filepath.Join(dir, "out.docx")); !errors.Is(err, ErrCorruptInput) {
+		t.Errorf("Convert of an unloadable document = %v, want ErrCorruptInput", err)
+	}
+}
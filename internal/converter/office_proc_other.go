@@ -0,0 +1,13 @@
+//go:build !unix
+
+package converter
+
+import "os/exec"
+
+// startOwnGroup is a no-op where process groups are not available
+func startOwnGroup(cmd *exec.Cmd) {}
+
+// killGroup kills a started command; processes it spawned are left running
+func killGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
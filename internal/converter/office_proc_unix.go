@@ -0,0 +1,19 @@
+//go:build unix
+
+package converter
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// startOwnGroup makes a command the leader of a new process group, so the
+// processes it spawns can be killed with it
+func startOwnGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killGroup kills the process group led by a started command
+func killGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
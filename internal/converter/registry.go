@@ -0,0 +1,239 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/analyzer"
+	log "github.com/sirupsen/logrus"
+)
+
+// sniffSize is the number of leading bytes read for format detection
+const sniffSize = 1024
+
+var (
+	// ErrNoEngine is returned when no engine is configured for an input
+	ErrNoEngine = errors.New("no conversion engine available")
+)
+
+// EngineInfo describes a registered engine for discovery endpoints
+type EngineInfo struct {
+	Name    string   `json:"name"`
+	Formats []string `json:"formats"`
+	Outputs []string `json:"outputs"`
+}
+
+// Registry routes conversions to engines based on the detected input format,
+// trying each engine of the format's chain in order until one succeeds
+type Registry struct {
+	mu           sync.RWMutex
+	engines      map[string]Converter
+	order        []string
+	chains       map[analyzer.DocumentFormat][]string
+	defaultChain []string
+	extractor    *analyzer.DocumentExtractor
+}
+
+// NewRegistry creates an empty converter registry
+func NewRegistry() *Registry {
+	return &Registry{
+		engines:   make(map[string]Converter),
+		order:     make([]string, 0),
+		chains:    make(map[analyzer.DocumentFormat][]string),
+		extractor: analyzer.NewDocumentExtractor(),
+	}
+}
+
+// Register adds a named engine to the registry
+func (r *Registry) Register(name string, c Converter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.engines[name]; !exists {
+		r.order = append(r.order, name)
+	}
+	r.engines[name] = c
+}
+
+// SetChain sets the ordered fallback chain of engines for a document format
+func (r *Registry) SetChain(format analyzer.DocumentFormat, engines ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.chains[format] = engines
+}
+
+// SetDefaultChain sets the chain used for formats without an explicit chain
+func (r *Registry) SetDefaultChain(engines ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.defaultChain = engines
+}
+
+// Has reports whether an engine with the given name is registered
+func (r *Registry) Has(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.engines[name]
+	return ok
+}
+
+// Chain returns the engines to try for a format, with the preferred engine first
+func (r *Registry) Chain(format analyzer.DocumentFormat, preferred string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	base, ok := r.chains[format]
+	if !ok {
+		base = r.defaultChain
+	}
+
+	chain := make([]string, 0, len(base)+1)
+	if preferred != "" {
+		if _, exists := r.engines[preferred]; exists {
+			chain = append(chain, preferred)
+		} else {
+			log.Warnf("Unknown conversion engine %q requested, using %s routing", preferred, format)
+		}
+	}
+
+	for _, name := range base {
+		if name == preferred {
+			continue
+		}
+		if _, exists := r.engines[name]; exists {
+			chain = append(chain, name)
+		}
+	}
+
+	return chain
+}
+
+// Engines lists registered engines with the formats routed to them
+func (r *Registry) Engines() []EngineInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	infos := make([]EngineInfo, 0, len(r.order))
+	for _, name := range r.order {
+		formats := make([]string, 0)
+		for format, chain := range r.chains {
+			for _, engine := range chain {
+				if engine == name {
+					formats = append(formats, format.String())
+					break
+				}
+			}
+		}
+		sort.Strings(formats)
+
+		outputs := make([]string, 0, len(outputOrder))
+		for _, output := range outputOrder {
+			if supportsOutput(r.engines[name], output) {
+				outputs = append(outputs, output)
+			}
+		}
+
+		infos = append(infos, EngineInfo{Name: name, Formats: formats, Outputs: outputs})
+	}
+
+	return infos
+}
+
+// Routing returns the engine chain configured for each format
+func (r *Registry) Routing() map[string][]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	routing := make(map[string][]string, len(r.chains)+1)
+	for format, chain := range r.chains {
+		routing[format.String()] = append([]string(nil), chain...)
+	}
+	if len(r.defaultChain) > 0 {
+		routing["default"] = append([]string(nil), r.defaultChain...)
+	}
+	return routing
+}
+
+// Convert implements Converter using format-based routing without a preferred engine
+func (r *Registry) Convert(ctx context.Context, inputPath, outputPath string) error {
+	_, err := r.ConvertWith(ctx, inputPath, outputPath, "")
+	return err
+}
+
+// ConvertWith converts the input using the preferred engine (if any) followed by the
+// format's fallback chain, and returns the name of the engine that produced the output
+func (r *Registry) ConvertWith(ctx context.Context, inputPath, outputPath, preferred string) (string, error) {
+	format, err := r.sniff(inputPath)
+	if err != nil {
+		return "", err
+	}
+
+	output := OutputFormatForPath(outputPath)
+	engines := make([]string, 0)
+	converters := make([]Converter, 0)
+
+	chain := r.Chain(format, preferred)
+	r.mu.RLock()
+	for _, name := range chain {
+		if engine := r.engines[name]; supportsOutput(engine, output) {
+			engines = append(engines, name)
+			converters = append(converters, engine)
+		}
+	}
+	r.mu.RUnlock()
+
+	if len(engines) == 0 {
+		return "", fmt.Errorf("%w for %s input and %s output", ErrNoEngine, format, output)
+	}
+
+	var errs []error
+	for i, name := range engines {
+		engine := converters[i]
+
+		err := engine.Convert(ctx, inputPath, outputPath)
+		if err == nil {
+			return name, nil
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		if ctx.Err() != nil {
+			break
+		}
+		log.Warnf("Engine %s failed for %s input, trying next: %v", name, format, err)
+	}
+
+	return "", fmt.Errorf("all engines failed: %w", errors.Join(errs...))
+}
+
+// supportsOutput reports whether an engine can produce the output format
+func supportsOutput(engine Converter, output string) bool {
+	if supporter, ok := engine.(FormatSupporter); ok {
+		return supporter.SupportsOutput(output)
+	}
+	return true
+}
+
+// sniff detects the input format from the leading bytes of the file
+func (r *Registry) sniff(inputPath string) (analyzer.DocumentFormat, error) {
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return analyzer.FormatUnknown, fmt.Errorf("failed to open input: %w", err)
+	}
+	defer file.Close()
+
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return analyzer.FormatUnknown, fmt.Errorf("failed to read input: %w", err)
+	}
+	if n == 0 {
+		return analyzer.FormatUnknown, fmt.Errorf("%w: input is empty", ErrCorruptInput)
+	}
+
+	return r.extractor.DetectFormat(head[:n]), nil
+}
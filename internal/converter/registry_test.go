@@ -0,0 +1,96 @@
+package converter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/analyzer"
+)
+
+type stubConverter struct {
+	err   error
+	calls int
+}
+
+func (s *stubConverter) Convert(ctx context.Context, inputPath, outputPath string) error {
+	s.calls++
+	return s.err
+}
+
+func newTestRegistry(native, office Converter) *Registry {
+	r := NewRegistry()
+	r.Register(EngineNative, native)
+	r.Register(EngineLibreOffice, office)
+	r.SetChain(analyzer.FormatZipBased, EngineNative, EngineLibreOffice)
+	r.SetChain(analyzer.FormatOLEBased, EngineLibreOffice)
+	r.SetDefaultChain(EngineLibreOffice)
+	return r
+}
+
+func writeInput(t *testing.T, content []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "input.dot")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRegistryChain(t *testing.T) {
+	r := newTestRegistry(&stubConverter{}, &stubConverter{})
+
+	tests := []struct {
+		name      string
+		format    analyzer.DocumentFormat
+		preferred string
+		expected  []string
+	}{
+		{"zip routing", analyzer.FormatZipBased, "", []string{EngineNative, EngineLibreOffice}},
+		{"ole routing", analyzer.FormatOLEBased, "", []string{EngineLibreOffice}},
+		{"default routing", analyzer.FormatRTF, "", []string{EngineLibreOffice}},
+		{"preferred first", analyzer.FormatZipBased, EngineLibreOffice, []string{EngineLibreOffice, EngineNative}},
+		{"preferred outside chain", analyzer.FormatOLEBased, EngineNative, []string{EngineNative, EngineLibreOffice}},
+		{"unknown preferred ignored", analyzer.FormatOLEBased, "word", []string{EngineLibreOffice}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := r.Chain(tt.format, tt.preferred)
+			if !reflect.DeepEqual(chain, tt.expected) {
+				t.Errorf("expected chain %v, got %v", tt.expected, chain)
+			}
+		})
+	}
+}
+
+func TestRegistryFallback(t *testing.T) {
+	native := &stubConverter{err: ErrUnsupportedFormat}
+	office := &stubConverter{}
+	r := newTestRegistry(native, office)
+
+	input := writeInput(t, []byte{0x50, 0x4B, 0x03, 0x04, 0, 0, 0, 0, 0, 0})
+	engine, err := r.ConvertWith(context.Background(), input, input+".docx", "")
+	if err != nil {
+		t.Fatalf("ConvertWith failed: %v", err)
+	}
+	if engine != EngineLibreOffice {
+		t.Errorf("expected libreoffice to produce output, got %s", engine)
+	}
+	if native.calls != 1 || office.calls != 1 {
+		t.Errorf("expected each engine to be tried once, got native=%d libreoffice=%d", native.calls, office.calls)
+	}
+}
+
+func TestRegistryAllEnginesFail(t *testing.T) {
+	failure := errors.New("boom")
+	r := newTestRegistry(&stubConverter{err: failure}, &stubConverter{err: failure})
+
+	input := writeInput(t, []byte{0x50, 0x4B, 0x03, 0x04, 0, 0, 0, 0, 0, 0})
+	if _, err := r.ConvertWith(context.Background(), input, input+".docx", ""); !errors.Is(err, failure) {
+		t.Errorf("expected wrapped engine error, got %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+// Package field parses Word field codes into a tree. Fields can be read from
+// brace text ("{ IF { MERGEFIELD Sex } = "M" "Sir" "Madam" }"), from the
+// 0x13/0x14/0x15 markers of Word binary text and from the w:fldChar,
+// w:instrText and w:fldSimple elements of WordprocessingML.
+package field
+
+import "strings"
+
+// Field types with dedicated handling
+const (
+	TypeIF          = "IF"
+	TypeMergeField  = "MERGEFIELD"
+	TypeRef         = "REF"
+	TypeDocVariable = "DOCVARIABLE"
+	TypeDocProperty = "DOCPROPERTY"
+	TypeAsk         = "ASK"
+	TypeFillIn      = "FILLIN"
+	TypeSet         = "SET"
+	TypeFormula     = "=" // Expression fields, e.g. "{ = SUM(ABOVE) }"
+)
+
+// General switches shared by all field types
+const (
+	SwitchFormat        = `\*` // Text formatting, e.g. \* MERGEFORMAT, \* Upper
+	SwitchDatePicture   = `\@` // Date-time picture, e.g. \@ "d MMMM yyyy"
+	SwitchNumberPicture = `\#` // Numeric picture, e.g. \# "#,##0.00"
+)
+
+// Position locates a point in the parsed source
+type Position struct {
+	Offset int `json:"offset"` // Byte offset
+	Line   int `json:"line"`   // 1-based
+	Column int `json:"column"` // 1-based, in bytes
+}
+
+// Arg is a positional argument of a field instruction. Nested fields inside the
+// argument are shown in braces in Text and listed in Fields.
+type Arg struct {
+	Text   string   `json:"text"`
+	Quoted bool     `json:"quoted,omitempty"`
+	Fields []*Field `json:"-"`
+	Pos    Position `json:"pos"`
+}
+
+// Switch is a field switch such as \* MERGEFORMAT or \b "text"
+type Switch struct {
+	Name string   `json:"name"`
+	Arg  string   `json:"arg,omitempty"`
+	Pos  Position `json:"pos"`
+}
+
+// Condition is the comparison of an IF field. Operator is empty when the field
+// only tests a single expression.
+type Condition struct {
+	Left     Arg    `json:"left"`
+	Operator string `json:"operator,omitempty"`
+	Right    Arg    `json:"right"`
+	True     Arg    `json:"true"`
+	False    Arg    `json:"false"`
+}
+
+// Field is a parsed field with its nested fields
+type Field struct {
+	Type     string   `json:"type"` // Upper-cased field type, e.g. "MERGEFIELD"
+	Code     string   `json:"code"` // Instruction text with nested fields in braces
+	Args     []Arg    `json:"args,omitempty"`
+	Switches []Switch `json:"switches,omitempty"`
+	Result   string   `json:"result,omitempty"` // Last calculated result, when stored
+	Children []*Field `json:"children,omitempty"`
+	Depth    int      `json:"depth"` // 1 for top-level fields
+	Pos      Position `json:"pos"`   // Start of the field
+	End      Position `json:"end"`   // Just past the end of the field
+
+	Name      string     `json:"name,omitempty"`      // MERGEFIELD, REF, DOCVARIABLE, DOCPROPERTY, ASK and SET target
+	Prompt    string     `json:"prompt,omitempty"`    // ASK and FILLIN prompt
+	Condition *Condition `json:"condition,omitempty"` // IF comparison
+
+	raw string // Instruction text as written
+}
+
+// String returns the field code in braces
+func (f *Field) String() string {
+	return "{" + f.raw + "}"
+}
+
+// Switch returns the switch with the given name, e.g. SwitchFormat
+func (f *Field) Switch(name string) (Switch, bool) {
+	for _, s := range f.Switches {
+		if strings.EqualFold(s.Name, name) {
+			return s, true
+		}
+	}
+	return Switch{}, false
+}
+
+// Walk visits fields depth first, parents before children. Returning false from
+// fn skips the children of that field.
+func Walk(fields []*Field, fn func(*Field) bool) {
+	for _, f := range fields {
+		if fn(f) {
+			Walk(f.Children, fn)
+		}
+	}
+}
+
+// All returns every field in the tree, parents before children
+func All(fields []*Field) []*Field {
+	var all []*Field
+	Walk(fields, func(f *Field) bool {
+		all = append(all, f)
+		return true
+	})
+	return all
+}
+
+// OfType returns every field of one of the given types
+func OfType(fields []*Field, types ...string) []*Field {
+	var matches []*Field
+	Walk(fields, func(f *Field) bool {
+		for _, t := range types {
+			if f.Type == t {
+				matches = append(matches, f)
+				break
+			}
+		}
+		return true
+	})
+	return matches
+}
+
+// MaxDepth returns the deepest nesting of fields of the given type, e.g. 2 for
+// an IF inside another IF
+func MaxDepth(fields []*Field, fieldType string) int {
+	max := 0
+	for _, f := range fields {
+		depth := MaxDepth(f.Children, fieldType)
+		if f.Type == fieldType {
+			depth++
+		}
+		if depth > max {
+			max = depth
+		}
+	}
+	return max
+}
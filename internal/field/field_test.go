@@ -0,0 +1,153 @@
+package field
+
+import "testing"
+
+func TestParseText(t *testing.T) {
+	text := "Dear {IF {MERGEFIELD Sex}=\"M\" \"Sir\" \"Madam\"},\n" +
+		"Re: { MERGEFIELD Matter \\* Upper } dated { MERGEFIELD Date \\@ \"d MMMM yyyy\" }\n" +
+		"{ ASK Name \"Client name?\" \\d \"Smith\" } { FILLIN \"Reference\" } {=SUM(ABOVE)} }"
+
+	fields := ParseText(text)
+	if len(fields) != 6 {
+		t.Fatalf("got %d top-level fields, want 6", len(fields))
+	}
+
+	ifField := fields[0]
+	if ifField.Type != TypeIF || ifField.String() != `{IF {MERGEFIELD Sex}="M" "Sir" "Madam"}` {
+		t.Errorf("IF field = %s %q", ifField.Type, ifField.String())
+	}
+	if ifField.Pos != (Position{Offset: 5, Line: 1, Column: 6}) {
+		t.Errorf("IF position = %+v", ifField.Pos)
+	}
+	c := ifField.Condition
+	if c == nil || c.Left.Text != "{MERGEFIELD Sex}" || c.Operator != "=" || c.Right.Text != "M" ||
+		c.True.Text != "Sir" || c.False.Text != "Madam" || !c.Right.Quoted {
+		t.Errorf("condition = %+v", c)
+	}
+	if len(ifField.Children) != 1 || ifField.Children[0].Name != "Sex" || ifField.Children[0].Depth != 2 {
+		t.Errorf("nested field not parsed: %+v", ifField.Children)
+	}
+	if len(c.Left.Fields) != 1 || c.Left.Fields[0] != ifField.Children[0] {
+		t.Errorf("left operand should hold the nested field")
+	}
+
+	matter := fields[1]
+	if sw, ok := matter.Switch(SwitchFormat); matter.Name != "Matter" || !ok || sw.Arg != "Upper" {
+		t.Errorf("MERGEFIELD = %+v", matter)
+	}
+	if matter.Pos.Line != 2 || matter.Pos.Column != 5 {
+		t.Errorf("MERGEFIELD position = %+v", matter.Pos)
+	}
+
+	date := fields[2]
+	if sw, ok := date.Switch(SwitchDatePicture); !ok || sw.Arg != "d MMMM yyyy" {
+		t.Errorf("date picture = %+v", date.Switches)
+	}
+
+	ask := fields[3]
+	if sw, ok := ask.Switch(`\d`); ask.Name != "Name" || ask.Prompt != "Client name?" || !ok || sw.Arg != "Smith" {
+		t.Errorf("ASK = %+v", ask)
+	}
+
+	if fields[4].Prompt != "Reference" {
+		t.Errorf("FILLIN prompt = %q", fields[4].Prompt)
+	}
+
+	// The stray closing brace after the formula is ignored
+	formulas := OfType(fields, TypeFormula)
+	if len(formulas) != 1 || len(formulas[0].Args) != 1 || formulas[0].Args[0].Text != "SUM(ABOVE)" {
+		t.Errorf("formula = %+v", formulas)
+	}
+}
+
+func TestParseBinary(t *testing.T) {
+	raw := "\x13 IF \x13 MERGEFIELD Sex \x14M\x15 = \"M\" \"Sir\" \"Madam\" \x14Sir\x15\r" +
+		"\x13 TOC \\o \x14\x13 PAGEREF _Toc1 \x142\x15\x15"
+
+	fields := ParseBinary(raw)
+	if len(fields) != 2 {
+		t.Fatalf("got %d top-level fields, want 2", len(fields))
+	}
+
+	if got := fields[0]; got.Code != `IF { MERGEFIELD Sex } = "M" "Sir" "Madam"` || got.Result != "Sir" {
+		t.Errorf("IF code = %q, result = %q", got.Code, got.Result)
+	}
+	if got := fields[0].Children[0]; got.Name != "Sex" || got.Result != "M" {
+		t.Errorf("nested MERGEFIELD = %+v", got)
+	}
+
+	// Fields inside a result are generated by Word and not part of the tree
+	toc := fields[1]
+	if toc.Pos.Line != 2 || len(toc.Children) != 0 || toc.Result != "2" {
+		t.Errorf("TOC = %+v", toc)
+	}
+	if sw, ok := toc.Switch(`\o`); !ok || sw.Arg != "" {
+		t.Errorf("TOC switches = %+v", toc.Switches)
+	}
+}
+
+func TestParseXML(t *testing.T) {
+	doc := `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body><w:p>
+<w:r><w:t>Dear </w:t></w:r>
+<w:r><w:fldChar w:fldCharType="begin"/></w:r>
+<w:r><w:instrText xml:space="preserve"> IF </w:instrText></w:r>
+<w:r><w:fldChar w:fldCharType="begin"/></w:r>
+<w:r><w:instrText xml:space="preserve"> MERGE</w:instrText></w:r>
+<w:r><w:instrText xml:space="preserve">FIELD Sex </w:instrText></w:r>
+<w:r><w:fldChar w:fldCharType="separate"/></w:r>
+<w:r><w:t>M</w:t></w:r>
+<w:r><w:fldChar w:fldCharType="end"/></w:r>
+<w:r><w:instrText xml:space="preserve"> = "M" "Sir" "Madam" </w:instrText></w:r>
+<w:r><w:fldChar w:fldCharType="separate"/></w:r>
+<w:r><w:t>Sir</w:t></w:r>
+<w:r><w:fldChar w:fldCharType="end"/></w:r>
+<w:fldSimple w:instr=" DOCVARIABLE MatterRef \* MERGEFORMAT "><w:r><w:t>M-1</w:t></w:r></w:fldSimple>
+</w:p></w:body></w:document>`
+
+	fields, err := ParseXML([]byte(doc))
+	if err != nil {
+		t.Fatalf("ParseXML: %v", err)
+	}
+	if len(fields) != 2 {
+		t.Fatalf("got %d top-level fields, want 2", len(fields))
+	}
+
+	ifField := fields[0]
+	if ifField.Code != `IF { MERGEFIELD Sex } = "M" "Sir" "Madam"` || ifField.Result != "Sir" || ifField.Pos.Line != 3 {
+		t.Errorf("IF = %q result %q at %+v", ifField.Code, ifField.Result, ifField.Pos)
+	}
+	if MaxDepth(fields, TypeIF) != 1 || len(OfType(fields, TypeMergeField)) != 1 {
+		t.Errorf("unexpected tree shape: %+v", All(fields))
+	}
+
+	docVar := fields[1]
+	if docVar.Type != TypeDocVariable || docVar.Name != "MatterRef" || docVar.Result != "M-1" {
+		t.Errorf("DOCVARIABLE = %+v", docVar)
+	}
+
+	if _, err := ParseXML([]byte("<w:p><w:r>")); err == nil {
+		t.Error("expected an error for truncated XML")
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected int
+	}{
+		{"no fields", "plain text", 0},
+		{"single", `{IF a "b" "c"}`, 1},
+		{"siblings", `{IF a "b" "c"} {IF d "e" "f"}`, 1},
+		{"nested in branch", `{IF a "{IF b "{IF c "x" "y"}" "z"}" "w"}`, 3},
+		{"through another field", `{IF a "{MERGEFIELD {IF b "x" "y"}}" "z"}`, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MaxDepth(ParseText(tt.text), TypeIF); got != tt.expected {
+				t.Errorf("MaxDepth() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package field
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Field markers of Word binary text
+const (
+	markBegin     = '\x13'
+	markSeparator = '\x14'
+	markEnd       = '\x15'
+)
+
+// ParseText parses fields written as brace text. Unmatched closing braces are
+// ignored and fields still open at the end of the text are closed there.
+func ParseText(text string) []*Field {
+	b := newBuilder(text)
+	start := 0
+	for i, r := range text {
+		switch r {
+		case '{':
+			b.text(text[start:i], start)
+			b.begin(i)
+			start = i + 1
+		case '}':
+			b.text(text[start:i], start)
+			b.end(i + 1)
+			start = i + 1
+		}
+	}
+	b.text(text[start:], start)
+	return b.finish(len(text))
+}
+
+// ParseBinary parses the fields of Word binary text, delimited by the 0x13
+// begin, 0x14 separator and 0x15 end markers. Text between the separator and
+// the end is kept as the field result.
+func ParseBinary(raw string) []*Field {
+	b := newBuilder(raw)
+	start := 0
+	for i, r := range raw {
+		switch r {
+		case markBegin, markSeparator, markEnd:
+			b.text(raw[start:i], start)
+			start = i + 1
+		}
+		switch r {
+		case markBegin:
+			b.begin(i)
+		case markSeparator:
+			b.separate()
+		case markEnd:
+			b.end(i + 1)
+		}
+	}
+	b.text(raw[start:], start)
+	return b.finish(len(raw))
+}
+
+// part is a run of instruction text or a nested field
+type part struct {
+	text   string
+	offset int
+	field  *Field
+}
+
+// frame is a field being built
+type frame struct {
+	field    *Field
+	parts    []part
+	raw      strings.Builder
+	result   strings.Builder
+	inResult bool
+}
+
+// builder assembles fields from begin, text, separator and end events shared by
+// every source format
+type builder struct {
+	lineStarts []int
+	stack      []*frame
+	roots      []*Field
+}
+
+func newBuilder(src string) *builder {
+	b := &builder{lineStarts: []int{0}}
+	for i := 0; i < len(src); i++ {
+		// Word binary text ends paragraphs with a bare carriage return
+		if src[i] == '\n' || (src[i] == '\r' && (i+1 == len(src) || src[i+1] != '\n')) {
+			b.lineStarts = append(b.lineStarts, i+1)
+		}
+	}
+	return b
+}
+
+// position converts a byte offset in the source to a Position
+func (b *builder) position(offset int) Position {
+	line := sort.Search(len(b.lineStarts), func(i int) bool { return b.lineStarts[i] > offset })
+	return Position{Offset: offset, Line: line, Column: offset - b.lineStarts[line-1] + 1}
+}
+
+func (b *builder) begin(offset int) {
+	b.stack = append(b.stack, &frame{field: &Field{Depth: len(b.stack) + 1, Pos: b.position(offset)}})
+}
+
+func (b *builder) text(s string, offset int) {
+	if s == "" || len(b.stack) == 0 {
+		return
+	}
+	top := b.stack[len(b.stack)-1]
+	if top.inResult {
+		top.result.WriteString(s)
+		return
+	}
+	top.parts = append(top.parts, part{text: s, offset: offset})
+	top.raw.WriteString(s)
+}
+
+func (b *builder) separate() {
+	if len(b.stack) > 0 {
+		b.stack[len(b.stack)-1].inResult = true
+	}
+}
+
+// inResult reports whether text currently belongs to a field result
+func (b *builder) inResult() bool {
+	return len(b.stack) > 0 && b.stack[len(b.stack)-1].inResult
+}
+
+func (b *builder) end(offset int) {
+	if len(b.stack) == 0 {
+		return
+	}
+	top := b.stack[len(b.stack)-1]
+	b.stack = b.stack[:len(b.stack)-1]
+
+	f := top.field
+	f.End = b.position(offset)
+	f.raw = top.raw.String()
+	f.Result = top.result.String()
+	b.interpret(f, top.parts)
+
+	if len(b.stack) == 0 {
+		b.roots = append(b.roots, f)
+		return
+	}
+
+	// Fields inside a result are regenerated by Word; only their text is kept
+	parent := b.stack[len(b.stack)-1]
+	if parent.inResult {
+		parent.result.WriteString(f.Result)
+		return
+	}
+	parent.parts = append(parent.parts, part{field: f, offset: f.Pos.Offset})
+	parent.raw.WriteString(f.String())
+	parent.field.Children = append(parent.field.Children, f)
+}
+
+func (b *builder) finish(offset int) []*Field {
+	for len(b.stack) > 0 {
+		b.end(offset)
+	}
+	return b.roots
+}
+
+// token is a word, quoted string or operator of an instruction
+type token struct {
+	text   strings.Builder
+	fields []*Field
+	quoted bool
+	offset int
+}
+
+func (t *token) arg(b *builder) Arg {
+	return Arg{Text: t.text.String(), Quoted: t.quoted, Fields: t.fields, Pos: b.position(t.offset)}
+}
+
+// tokenize splits an instruction into whitespace-separated words and quoted
+// strings. Nested fields belong to the word or string they appear in. With
+// operators set, comparison operators outside quotes are split into their own
+// tokens, as IF allows "{ IF { MERGEFIELD Sex }="M" ... }".
+func tokenize(parts []part, operators bool) []*token {
+	var tokens []*token
+	var cur *token
+	inQuote := false
+
+	flush := func() {
+		if cur != nil {
+			tokens = append(tokens, cur)
+			cur = nil
+		}
+	}
+	start := func(offset int, quoted bool) {
+		if cur == nil {
+			cur = &token{offset: offset, quoted: quoted}
+		}
+	}
+
+	for _, p := range parts {
+		if p.field != nil {
+			start(p.offset, false)
+			cur.text.WriteString(p.field.String())
+			cur.fields = append(cur.fields, p.field)
+			continue
+		}
+
+		s := p.text
+		for i := 0; i < len(s); {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			offset := p.offset + i
+
+			switch {
+			case inQuote && r == '\\' && i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\'):
+				cur.text.WriteByte(s[i+1])
+				size = 2
+			case inQuote && r == '"':
+				inQuote = false
+				flush()
+			case inQuote:
+				cur.text.WriteRune(r)
+			case r == '"':
+				flush()
+				start(offset, true)
+				inQuote = true
+			case unicode.IsSpace(r):
+				flush()
+			case operators && strings.ContainsRune("=<>", r):
+				flush()
+				op := string(r)
+				if i+1 < len(s) && (s[i:i+2] == "<=" || s[i:i+2] == ">=" || s[i:i+2] == "<>") {
+					op = s[i : i+2]
+					size = 2
+				}
+				start(offset, false)
+				cur.text.WriteString(op)
+				flush()
+			default:
+				start(offset, false)
+				cur.text.WriteRune(r)
+			}
+			i += size
+		}
+	}
+	flush()
+	return tokens
+}
+
+// interpret fills in the type, arguments, switches and typed details of a field
+// from its instruction parts
+func (b *builder) interpret(f *Field, parts []part) {
+	f.Code = strings.TrimSpace(f.raw)
+
+	tokens := tokenize(parts, false)
+	if len(tokens) == 0 {
+		return
+	}
+
+	first := tokens[0]
+	typeText := first.text.String()
+	rest := tokens[1:]
+	switch {
+	case first.quoted || len(first.fields) > 0:
+		// The type is computed by a nested field
+	case strings.HasPrefix(typeText, "="):
+		// The expression may follow the equals sign without a space
+		f.Type = TypeFormula
+		if expr := typeText[1:]; expr != "" {
+			first.text.Reset()
+			first.text.WriteString(expr)
+			first.offset++
+			rest = tokens
+		}
+	default:
+		f.Type = strings.ToUpper(typeText)
+		if f.Type == TypeIF {
+			rest = tokenize(parts, true)[1:]
+		}
+	}
+
+	for i := 0; i < len(rest); i++ {
+		t := rest[i]
+		text := t.text.String()
+		if t.quoted || len(t.fields) > 0 || len(text) < 2 || text[0] != '\\' {
+			f.Args = append(f.Args, t.arg(b))
+			continue
+		}
+
+		sw := Switch{Name: text, Pos: b.position(t.offset)}
+		general := strings.ContainsRune("*@#", rune(text[1]))
+		if general && len(text) > 2 {
+			// Written without a space, e.g. \*MERGEFORMAT
+			sw.Name, sw.Arg = text[:2], text[2:]
+		} else if i+1 < len(rest) && (general || rest[i+1].quoted) {
+			i++
+			sw.Arg = rest[i].text.String()
+		}
+		f.Switches = append(f.Switches, sw)
+	}
+
+	switch f.Type {
+	case TypeMergeField, TypeRef, TypeDocVariable, TypeDocProperty, TypeSet:
+		if len(f.Args) > 0 {
+			f.Name = f.Args[0].Text
+		}
+	case TypeAsk:
+		if len(f.Args) > 0 {
+			f.Name = f.Args[0].Text
+		}
+		if len(f.Args) > 1 {
+			f.Prompt = f.Args[1].Text
+		}
+	case TypeFillIn:
+		if len(f.Args) > 0 {
+			f.Prompt = f.Args[0].Text
+		}
+	case TypeIF:
+		f.Condition = condition(f.Args)
+	}
+}
+
+// condition reads the comparison and branches of IF arguments
+func condition(args []Arg) *Condition {
+	if len(args) == 0 {
+		return nil
+	}
+	c := &Condition{Left: args[0]}
+	rest := args[1:]
+	if len(args) >= 3 && !args[1].Quoted && isOperator(args[1].Text) {
+		c.Operator = args[1].Text
+		c.Right = args[2]
+		rest = args[3:]
+	}
+	if len(rest) > 0 {
+		c.True = rest[0]
+	}
+	if len(rest) > 1 {
+		c.False = rest[1]
+	}
+	return c
+}
+
+func isOperator(s string) bool {
+	switch s {
+	case "=", "<>", "<", ">", "<=", ">=":
+		return true
+	}
+	return false
+}
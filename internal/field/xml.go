@@ -0,0 +1,76 @@
+package field
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseXML parses the fields of a WordprocessingML part such as
+// word/document.xml. Complex fields are read from w:fldChar and w:instrText runs,
+// simple fields from w:fldSimple; result text comes from w:t.
+func ParseXML(data []byte) ([]*Field, error) {
+	b := newBuilder(string(data))
+	decoder := xml.NewDecoder(strings.NewReader(string(data)))
+
+	var inInstr, inText bool
+	for {
+		offset := int(decoder.InputOffset())
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse field XML: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "fldChar":
+				switch attr(t, "fldCharType") {
+				case "begin":
+					b.begin(offset)
+				case "separate":
+					b.separate()
+				case "end":
+					b.end(int(decoder.InputOffset()))
+				}
+			case "instrText":
+				inInstr = true
+			case "t":
+				inText = true
+			case "fldSimple":
+				b.begin(offset)
+				b.text(attr(t, "instr"), offset)
+				b.separate()
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "instrText":
+				inInstr = false
+			case "t":
+				inText = false
+			case "fldSimple":
+				b.end(int(decoder.InputOffset()))
+			}
+		case xml.CharData:
+			// Run text only matters as the result of a field
+			if inInstr || (inText && b.inResult()) {
+				b.text(string(t), offset)
+			}
+		}
+	}
+	return b.finish(len(data)), nil
+}
+
+// attr returns the value of the attribute with the given local name
+func attr(e xml.StartElement, local string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,167 @@
+package inventory
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	_ "image/gif"  // Register the GIF decoder for DecodeConfig
+	_ "image/jpeg" // Register the JPEG decoder for DecodeConfig
+	_ "image/png"  // Register the PNG decoder for DecodeConfig
+)
+
+// Image signatures
+var (
+	pngSignature  = []byte("\x89PNG\r\n\x1a\n")
+	jpegSignature = []byte{0xFF, 0xD8, 0xFF}
+	wmfPlaceable  = []byte{0xD7, 0xCD, 0xC6, 0x9A}
+)
+
+// ReadImage identifies a picture file by its signature and reads its dimensions
+func ReadImage(name string, data []byte) (Image, bool) {
+	img := Image{Name: name, Size: len(data)}
+	switch {
+	case bytes.HasPrefix(data, pngSignature):
+		img.Type = "png"
+	case bytes.HasPrefix(data, jpegSignature):
+		img.Type = "jpeg"
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		img.Type = "gif"
+	case bytes.HasPrefix(data, []byte("II*\x00")), bytes.HasPrefix(data, []byte("MM\x00*")):
+		img.Type = "tiff"
+	case isBMP(data):
+		img.Type = "bmp"
+		img.Width = int(int32(binary.LittleEndian.Uint32(data[18:])))
+		img.Height = abs(int(int32(binary.LittleEndian.Uint32(data[22:]))))
+	case len(data) >= 44 && binary.LittleEndian.Uint32(data) == 1 && string(data[40:44]) == " EMF":
+		// rclBounds in device pixels
+		img.Type = "emf"
+		img.Width = int(int32(binary.LittleEndian.Uint32(data[16:]))-int32(binary.LittleEndian.Uint32(data[8:]))) + 1
+		img.Height = int(int32(binary.LittleEndian.Uint32(data[20:]))-int32(binary.LittleEndian.Uint32(data[12:]))) + 1
+	case bytes.HasPrefix(data, wmfPlaceable) && len(data) >= 22:
+		// Bounding box in units of Inch per inch
+		img.Type = "wmf"
+		if inch := int(binary.LittleEndian.Uint16(data[14:])); inch > 0 {
+			left := int(int16(binary.LittleEndian.Uint16(data[6:])))
+			top := int(int16(binary.LittleEndian.Uint16(data[8:])))
+			right := int(int16(binary.LittleEndian.Uint16(data[10:])))
+			bottom := int(int16(binary.LittleEndian.Uint16(data[12:])))
+			img.Width = abs(right-left) * 96 / inch
+			img.Height = abs(bottom-top) * 96 / inch
+		}
+	case len(data) >= 18 && (bytes.HasPrefix(data, []byte{1, 0, 9, 0}) || bytes.HasPrefix(data, []byte{2, 0, 9, 0})):
+		img.Type = "wmf"
+	default:
+		return Image{}, false
+	}
+
+	switch img.Type {
+	case "png", "jpeg", "gif":
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+			img.Width, img.Height = cfg.Width, cfg.Height
+		}
+	}
+	if img.Width < 0 || img.Height < 0 {
+		img.Width, img.Height = 0, 0
+	}
+	return img, true
+}
+
+// isBMP checks the file header and a known DIB header size, as "BM" alone turns
+// up in any binary data
+func isBMP(data []byte) bool {
+	if len(data) < 26 || data[0] != 'B' || data[1] != 'M' {
+		return false
+	}
+	switch binary.LittleEndian.Uint32(data[14:]) {
+	case 12, 40, 52, 56, 108, 124:
+		return int(binary.LittleEndian.Uint32(data[10:])) < len(data)
+	}
+	return false
+}
+
+// ScanImages finds PNG and JPEG pictures stored inside binary data, such as the
+// Data stream of Word binary documents. Only pictures that decode are reported.
+func ScanImages(name string, data []byte) []Image {
+	var images []Image
+	for i := 0; i < len(data); {
+		var end int
+		switch {
+		case bytes.HasPrefix(data[i:], pngSignature):
+			end = pngEnd(data, i)
+		case bytes.HasPrefix(data[i:], jpegSignature):
+			end = jpegEnd(data, i)
+		}
+		if end > i {
+			if img, ok := ReadImage(name, data[i:end]); ok && img.Width > 0 {
+				images = append(images, img)
+				i = end
+				continue
+			}
+		}
+
+		next := -1
+		for _, sig := range [][]byte{pngSignature, jpegSignature} {
+			if j := bytes.Index(data[i+1:], sig); j >= 0 && (next < 0 || i+1+j < next) {
+				next = i + 1 + j
+			}
+		}
+		if next < 0 {
+			break
+		}
+		i = next
+	}
+	return images
+}
+
+// pngEnd walks the chunks of a PNG starting at start and returns the offset past
+// its IEND chunk, or 0 when it is truncated
+func pngEnd(data []byte, start int) int {
+	i := start + len(pngSignature)
+	for i+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		if length < 0 || i+12+length > len(data) {
+			return 0
+		}
+		chunk := string(data[i+4 : i+8])
+		i += 12 + length
+		if chunk == "IEND" {
+			return i
+		}
+	}
+	return 0
+}
+
+// jpegEnd walks the segments of a JPEG starting at start and returns the offset
+// past its end-of-image marker, or 0 when it is truncated
+func jpegEnd(data []byte, start int) int {
+	i := start + 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return 0
+		}
+		marker := data[i+1]
+		if marker == 0xD9 {
+			return i + 2
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		i += 2 + length
+		if i > len(data) {
+			return 0
+		}
+		if marker == 0xDA {
+			// Entropy-coded data runs to the end-of-image marker
+			if j := bytes.Index(data[i:], []byte{0xFF, 0xD9}); j >= 0 {
+				return i + j + 2
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
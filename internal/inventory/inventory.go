@@ -0,0 +1,236 @@
+// Package inventory lists the images, embedded OLE objects and external links of
+// Word documents. Links to network shares and local paths are flagged, as they
+// stop resolving once templates move to another system.
+package inventory
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/field"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/msword"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/ole"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/rtf"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/wordml"
+)
+
+// Link kinds
+const (
+	LinkHyperlink        = "hyperlink"
+	LinkAttachedTemplate = "attached_template"
+	LinkIncludeText      = "include_text"
+	LinkIncludePicture   = "include_picture"
+	LinkObject           = "linked_object" // LINK and DDE fields and linked OLE objects
+	LinkImage            = "linked_image"
+	LinkExternal         = "external" // Other external relationships, e.g. subdocuments
+)
+
+// Object kinds, derived from the ProgID
+const (
+	ObjectSpreadsheet  = "spreadsheet"
+	ObjectEquation     = "equation"
+	ObjectPackage      = "package" // Packager object wrapping an arbitrary file
+	ObjectDocument     = "document"
+	ObjectPresentation = "presentation"
+	ObjectChart        = "chart"
+	ObjectOther        = "other"
+)
+
+// linkFields map field types to the link kind and index of the argument naming
+// their target
+var linkFields = map[string]struct {
+	kind string
+	arg  int
+}{
+	"HYPERLINK":      {LinkHyperlink, 0},
+	"INCLUDETEXT":    {LinkIncludeText, 0},
+	"INCLUDEPICTURE": {LinkIncludePicture, 0},
+	"LINK":           {LinkObject, 1}, // LINK class "path" "item"
+	"DDE":            {LinkObject, 1},
+	"DDEAUTO":        {LinkObject, 1},
+}
+
+// Image is a picture stored in the document
+type Image struct {
+	Name   string `json:"name"` // Part, stream or picture it was read from
+	Type   string `json:"type"` // png, jpeg, gif, bmp, tiff, emf, wmf, ...
+	Size   int    `json:"size"` // Bytes
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"` // Pixels; metafiles at 96 dpi
+}
+
+// Object is an embedded or linked OLE object
+type Object struct {
+	Name   string `json:"name"`              // Storage or part holding the object
+	ProgID string `json:"prog_id,omitempty"` // e.g. "Excel.Sheet.8"
+	Kind   string `json:"kind"`
+	Linked bool   `json:"linked,omitempty"`
+	Source string `json:"source,omitempty"` // Link source, or the file wrapped by a packager object
+	Size   int    `json:"size,omitempty"`
+}
+
+// Link is a reference to something outside the document
+type Link struct {
+	Kind     string `json:"kind"`
+	Target   string `json:"target"`
+	Location string `json:"location,omitempty"`   // Part, stream or field it was found in
+	UNC      bool   `json:"unc,omitempty"`        // Network share, e.g. \\server\share
+	Local    bool   `json:"local_path,omitempty"` // Absolute path on a local drive
+}
+
+// Inventory lists what a document embeds and links to
+type Inventory struct {
+	Images  []Image  `json:"images,omitempty"`
+	Objects []Object `json:"objects,omitempty"`
+	Links   []Link   `json:"links,omitempty"`
+}
+
+// Read inventories a Word binary, ZIP-based or RTF document, including the
+// link targets of its fields; other content is rejected with the RTF parser's
+// error
+func Read(content []byte) (*Inventory, error) {
+	switch {
+	case ole.IsCFB(content):
+		cf, err := ole.Open(content)
+		if err != nil {
+			return nil, err
+		}
+		inv := FromCompoundFile(cf)
+		if doc, err := msword.Open(cf); err == nil {
+			inv.AddLink(LinkAttachedTemplate, doc.AttachedTemplate, "SttbfAssoc")
+			for _, story := range doc.Stories {
+				inv.AddFields(field.ParseBinary(story.Raw))
+			}
+		}
+		return inv, nil
+	case bytes.HasPrefix(content, []byte("PK\x03\x04")):
+		pkg, err := wordml.OpenPackage(content)
+		if err != nil {
+			return nil, err
+		}
+		inv, err := FromPackage(pkg)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range pkg.Parts() {
+			data, err := pkg.ReadPart(part.Name)
+			if err != nil {
+				continue
+			}
+			if fields, err := field.ParseXML(data); err == nil {
+				inv.AddFields(fields)
+			}
+		}
+		return inv, nil
+	default:
+		doc, err := rtf.Parse(content)
+		if err != nil {
+			return nil, err
+		}
+		inv := FromRTF(doc)
+		for _, story := range doc.Stories {
+			inv.AddFields(field.ParseBinary(story.Raw))
+		}
+		return inv, nil
+	}
+}
+
+// Empty reports whether nothing was found
+func (inv *Inventory) Empty() bool {
+	return len(inv.Images) == 0 && len(inv.Objects) == 0 && len(inv.Links) == 0
+}
+
+// AddLink records a link unless the same target is already listed for the kind
+func (inv *Inventory) AddLink(kind, target, location string) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return
+	}
+	for _, l := range inv.Links {
+		if l.Kind == kind && l.Target == target {
+			return
+		}
+	}
+	unc, local := Classify(target)
+	inv.Links = append(inv.Links, Link{Kind: kind, Target: target, Location: location, UNC: unc, Local: local})
+}
+
+// AddFields records the targets of hyperlink, include, LINK and DDE fields
+func (inv *Inventory) AddFields(fields []*field.Field) {
+	for _, f := range field.All(fields) {
+		spec, ok := linkFields[f.Type]
+		if !ok || len(f.Args) <= spec.arg {
+			continue
+		}
+		// Targets built from nested fields cannot be resolved
+		if arg := f.Args[spec.arg]; len(arg.Fields) == 0 {
+			inv.AddLink(spec.kind, arg.Text, f.String())
+		}
+	}
+}
+
+// ExternalPaths returns the links to network shares and local paths
+func (inv *Inventory) ExternalPaths() []Link {
+	var links []Link
+	for _, l := range inv.Links {
+		if l.UNC || l.Local {
+			links = append(links, l)
+		}
+	}
+	return links
+}
+
+// Classify reports whether a link target is a UNC path or an absolute local path.
+// file: URLs are classified by the path they hold; web and mail addresses and
+// relative paths are neither.
+func Classify(target string) (unc, local bool) {
+	t := strings.TrimSpace(target)
+	if len(t) >= 5 && strings.EqualFold(t[:5], "file:") {
+		rest := t[5:]
+		if trimmed := strings.TrimLeft(rest, `/\`); hasDrive(trimmed) {
+			return false, true
+		}
+		if strings.HasPrefix(rest, "//") || strings.HasPrefix(rest, `\\`) {
+			return true, false
+		}
+		return false, true
+	}
+	switch {
+	case strings.HasPrefix(t, `\\`) || strings.HasPrefix(t, "//"):
+		return true, false
+	case hasDrive(t):
+		return false, true
+	case strings.HasPrefix(t, "/"):
+		return false, true
+	}
+	return false, false
+}
+
+// hasDrive reports whether a path starts with a drive letter, e.g. "C:\"
+func hasDrive(path string) bool {
+	if len(path) < 2 || path[1] != ':' {
+		return false
+	}
+	c := path[0] | 0x20
+	return c >= 'a' && c <= 'z' && (len(path) == 2 || path[2] == '\\' || path[2] == '/')
+}
+
+// ObjectKind classifies an OLE object by its ProgID
+func ObjectKind(progID string) string {
+	id := strings.ToLower(progID)
+	switch {
+	case strings.HasPrefix(id, "excel."):
+		return ObjectSpreadsheet
+	case strings.HasPrefix(id, "equation."), strings.HasPrefix(id, "mathtype"):
+		return ObjectEquation
+	case id == "package" || strings.HasPrefix(id, "package."):
+		return ObjectPackage
+	case strings.HasPrefix(id, "word."):
+		return ObjectDocument
+	case strings.HasPrefix(id, "powerpoint."):
+		return ObjectPresentation
+	case strings.HasPrefix(id, "msgraph."):
+		return ObjectChart
+	}
+	return ObjectOther
+}
@@ -0,0 +1,248 @@
+package inventory
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/ole"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/ole/oletest"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		target     string
+		unc, local bool
+	}{
+		{`\\fileserver\templates\Letter.dot`, true, false},
+		{"//fileserver/templates/Letter.dot", true, false},
+		{`C:\Templates\Normal.dotm`, false, true},
+		{"file:///C:/Templates/Normal.dotm", false, true},
+		{"file://fileserver/templates/Letter.dot", true, false},
+		{"/home/user/letter.dot", false, true},
+		{"https://example.com/terms", false, false},
+		{"mailto:office@example.com", false, false},
+		{"Letters/Template.dot", false, false},
+	}
+	for _, tt := range tests {
+		unc, local := Classify(tt.target)
+		if unc != tt.unc || local != tt.local {
+			t.Errorf("Classify(%q) = %v, %v, want %v, %v", tt.target, unc, local, tt.unc, tt.local)
+		}
+	}
+}
+
+// pngImage encodes a blank PNG of the given size
+func pngImage(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// compObj builds a CompObj stream naming a ProgID
+func compObj(progID string) []byte {
+	data := make([]byte, 28)
+	for _, s := range []string{"Microsoft Excel Worksheet", "", progID} {
+		if s == "" {
+			data = binary.LittleEndian.AppendUint32(data, 0) // No clipboard format
+			continue
+		}
+		data = binary.LittleEndian.AppendUint32(data, uint32(len(s)+1))
+		data = append(append(data, s...), 0)
+	}
+	return data
+}
+
+func TestFromCompoundFile(t *testing.T) {
+	picture := pngImage(t, 4, 3)
+	// "BM" and a broken PNG signature in the surrounding data are not pictures
+	dataStream := append([]byte("BM junk \x89PNG\r\n\x1a\n"), picture...)
+
+	linked := make([]byte, 8)
+	linked[4] = 1
+	data, err := oletest.Build(map[string][]byte{
+		"WordDocument":                     make([]byte, 64),
+		"Data":                             dataStream,
+		"ObjectPool/_1234/\x01CompObj":     compObj("Excel.Sheet.8"),
+		"ObjectPool/_1234/Workbook":        make([]byte, 100),
+		"ObjectPool/_5678/\x01Ole":         linked,
+		"ObjectPool/_5678/\x01CompObj":     compObj("Equation.3"),
+		"ObjectPool/_9999/\x01Ole10Native": append([]byte{10, 0, 0, 0, 2, 0}, "report.pdf\x00"...),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf, err := ole.Open(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inv := FromCompoundFile(cf)
+	wantImages := []Image{{Name: "Data", Type: "png", Size: len(picture), Width: 4, Height: 3}}
+	if !reflect.DeepEqual(inv.Images, wantImages) {
+		t.Errorf("images = %+v, want %+v", inv.Images, wantImages)
+	}
+
+	kinds := make(map[string]Object)
+	for _, o := range inv.Objects {
+		kinds[o.Kind] = o
+	}
+	if o := kinds[ObjectSpreadsheet]; o.ProgID != "Excel.Sheet.8" || o.Linked || o.Size == 0 {
+		t.Errorf("spreadsheet = %+v", o)
+	}
+	if o := kinds[ObjectEquation]; !o.Linked {
+		t.Errorf("equation = %+v, want linked", o)
+	}
+	if o := kinds[ObjectPackage]; o.Source != "report.pdf" {
+		t.Errorf("package = %+v, want source report.pdf", o)
+	}
+}
+
+func TestScanImagesTruncated(t *testing.T) {
+	pngHeader := append(append([]byte{}, pngSignature...), 0, 0, 0, 13, 'I', 'H', 'D', 'R')
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"SOS length past the end", []byte{0xFF, 0xD8, 0xFF, 0xDA, 0x00, 0x40}},
+		{"APPn length past the end", []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x40, 'J', 'F', 'I', 'F'}},
+		{"APPn without its length", []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00}},
+		{"PNG chunk past the end", append(append([]byte{}, pngHeader...), 0, 0, 0, 1)},
+		{"PNG chunk length overflowing", append(append([]byte{}, pngSignature...), 0xFF, 0xFF, 0xFF, 0xFF, 'I', 'D', 'A', 'T', 0, 0, 0, 0)},
+	}
+	for _, tt := range tests {
+		if images := ScanImages("Data", tt.data); len(images) != 0 {
+			t.Errorf("%s: found %+v", tt.name, images)
+		}
+		if img, ok := ReadImage("Data", tt.data); ok && (img.Width != 0 || img.Height != 0) {
+			t.Errorf("%s: read %+v", tt.name, img)
+		}
+	}
+
+	// A Word stream holding a truncated picture is inventoried without one
+	data, err := oletest.Build(map[string][]byte{
+		"WordDocument": append(make([]byte, 64), tests[0].data...),
+		"Data":         tests[0].data,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf, err := ole.Open(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inv := FromCompoundFile(cf); len(inv.Images) != 0 {
+		t.Errorf("images = %+v, want none", inv.Images)
+	}
+}
+
+func TestScanImagesRandomTruncation(t *testing.T) {
+	var jpg bytes.Buffer
+	if err := jpeg.Encode(&jpg, image.NewGray(image.Rect(0, 0, 6, 2)), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := append(append([]byte("junk"), pngImage(t, 4, 3)...), jpg.Bytes()...)
+	if images := ScanImages("Data", data); len(images) != 2 {
+		t.Fatalf("found %d images in the whole data, want 2", len(images))
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for n := 0; n < 2000; n++ {
+		start := rng.Intn(len(data))
+		end := start + rng.Intn(len(data)-start+1)
+		ScanImages("Data", data[start:end])
+		ReadImage("Data", data[start:end])
+	}
+}
+
+func TestReadPackage(t *testing.T) {
+	const rel = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/"
+	picture := pngImage(t, 8, 5)
+	embedded, err := oletest.Build(map[string][]byte{"\x01CompObj": compObj("Excel.Sheet.8")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := map[string][]byte{
+		"_rels/.rels": []byte(`<Relationships><Relationship Id="rId1" Type="` + rel + `officeDocument" Target="word/document.xml"/></Relationships>`),
+		"word/_rels/document.xml.rels": []byte(`<Relationships>` +
+			`<Relationship Id="rId2" Type="` + rel + `image" Target="media/image1.png"/>` +
+			`<Relationship Id="rId3" Type="` + rel + `package" Target="embeddings/Sheet.xlsx"/>` +
+			`<Relationship Id="rId4" Type="` + rel + `oleObject" Target="embeddings/oleObject1.bin"/>` +
+			`<Relationship Id="rId5" Type="` + rel + `oleObject" Target="file:///\\fileserver\finance\rates.xls" TargetMode="External"/>` +
+			`<Relationship Id="rId6" Type="` + rel + `hyperlink" Target="https://example.com/terms" TargetMode="External"/>` +
+			`</Relationships>`),
+		"word/_rels/settings.xml.rels": []byte(`<Relationships>` +
+			`<Relationship Id="rId1" Type="` + rel + `attachedTemplate" Target="file:///C:\Templates\Letter.dotm" TargetMode="External"/>` +
+			`</Relationships>`),
+		"word/document.xml": []byte(`<w:document xmlns:w="w" xmlns:o="urn:schemas-microsoft-com:office:office" xmlns:r="r"><w:body>` +
+			`<w:p><w:object><o:OLEObject Type="Embed" ProgID="Excel.Sheet.12" r:id="rId3"/></w:object></w:p>` +
+			`<w:p><w:object><o:OLEObject Type="Link" ProgID="Excel.Sheet.8" r:id="rId5"/></w:object></w:p>` +
+			`<w:p><w:fldSimple w:instr=" INCLUDETEXT &quot;\\\\fileserver\\clauses\\Boilerplate.docx&quot; "/></w:p>` +
+			`</w:body></w:document>`),
+		"word/settings.xml":              []byte(`<w:settings xmlns:w="w"/>`),
+		"word/media/image1.png":          picture,
+		"word/embeddings/Sheet.xlsx":     []byte("PK\x03\x04"),
+		"word/embeddings/oleObject1.bin": embedded,
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	inv, err := Read(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	wantImages := []Image{{Name: "word/media/image1.png", Type: "png", Size: len(picture), Width: 8, Height: 5}}
+	if !reflect.DeepEqual(inv.Images, wantImages) {
+		t.Errorf("images = %+v, want %+v", inv.Images, wantImages)
+	}
+
+	wantObjects := []Object{
+		{Name: "word/document.xml", ProgID: "Excel.Sheet.8", Kind: ObjectSpreadsheet, Linked: true, Source: `file:///\\fileserver\finance\rates.xls`},
+		{Name: "word/embeddings/Sheet.xlsx", ProgID: "Excel.Sheet.12", Kind: ObjectSpreadsheet, Size: 4},
+		{Name: "word/embeddings/oleObject1.bin", ProgID: "Excel.Sheet.8", Kind: ObjectSpreadsheet, Size: len(embedded)},
+	}
+	if !reflect.DeepEqual(inv.Objects, wantObjects) {
+		t.Errorf("objects = %+v, want %+v", inv.Objects, wantObjects)
+	}
+
+	links := make(map[string]Link)
+	for _, l := range inv.Links {
+		links[l.Kind] = l
+	}
+	if l := links[LinkAttachedTemplate]; l.Target != `file:///C:\Templates\Letter.dotm` || !l.Local || l.Location != "word/settings.xml" {
+		t.Errorf("attached template = %+v", l)
+	}
+	if l := links[LinkObject]; !l.UNC {
+		t.Errorf("linked object = %+v, want UNC", l)
+	}
+	if l := links[LinkHyperlink]; l.UNC || l.Local {
+		t.Errorf("hyperlink = %+v, want neither UNC nor local", l)
+	}
+	if l := links[LinkIncludeText]; l.Target != `\\fileserver\clauses\Boilerplate.docx` || !l.UNC {
+		t.Errorf("INCLUDETEXT = %+v", l)
+	}
+	if got := len(inv.ExternalPaths()); got != 3 {
+		t.Errorf("external paths = %d, want 3", got)
+	}
+}
@@ -0,0 +1,309 @@
+package inventory
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/ole"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/rtf"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/wordml"
+)
+
+// Streams and storages describing OLE objects in compound files
+const (
+	objectPoolStorage = "ObjectPool"      // Embedded objects of Word binary documents
+	compObjStream     = "\x01CompObj"     // Class and ProgID of an object
+	oleStream         = "\x01Ole"         // Object flags, including whether it is linked
+	ole10NativeStream = "\x01Ole10Native" // File wrapped by a packager object
+)
+
+// pictureStreams hold the pictures of Word binary documents
+var pictureStreams = []string{"Data", "WordDocument"}
+
+// extensionProgIDs name the ProgID of embedded packages without an OLEObject
+// element
+var extensionProgIDs = map[string]string{
+	".xlsx": "Excel.Sheet.12",
+	".xlsm": "Excel.SheetMacroEnabled.12",
+	".xls":  "Excel.Sheet.8",
+	".docx": "Word.Document.12",
+	".doc":  "Word.Document.8",
+	".pptx": "PowerPoint.Show.12",
+	".ppt":  "PowerPoint.Show.8",
+}
+
+// relationshipLinks map relationship types, by their last path segment, to link kinds
+var relationshipLinks = map[string]string{
+	"hyperlink":        LinkHyperlink,
+	"attachedTemplate": LinkAttachedTemplate,
+	"image":            LinkImage,
+	"oleObject":        LinkObject,
+}
+
+// FromCompoundFile inventories the embedded objects under the ObjectPool of a
+// Word binary document and the PNG and JPEG pictures in its streams
+func FromCompoundFile(cf *ole.File) *Inventory {
+	inv := &Inventory{}
+	if pool, ok := cf.Find(objectPoolStorage); ok {
+		for _, child := range pool.Children {
+			if child.Type == ole.EntryStorage {
+				inv.Objects = append(inv.Objects, readOLEObject(cf, child))
+			}
+		}
+	}
+	for _, name := range pictureStreams {
+		if data, err := cf.ReadStream(name); err == nil {
+			inv.Images = append(inv.Images, ScanImages(name, data)...)
+		}
+	}
+	return inv
+}
+
+// readOLEObject describes the object held in a storage
+func readOLEObject(cf *ole.File, storage *ole.Entry) Object {
+	obj := Object{Name: storage.Path}
+	for _, child := range storage.Children {
+		if !child.IsStream() {
+			continue
+		}
+		obj.Size += int(child.Size)
+		data, err := cf.Read(child)
+		if err != nil {
+			continue
+		}
+		switch child.Name {
+		case compObjStream:
+			obj.ProgID = compObjProgID(data)
+		case oleStream:
+			obj.Linked = len(data) >= 8 && binary.LittleEndian.Uint32(data[4:])&1 != 0
+		case ole10NativeStream:
+			obj.Source = packageLabel(data)
+		}
+	}
+	if obj.ProgID == "" && obj.Source != "" {
+		obj.ProgID = "Package"
+	}
+	obj.Kind = ObjectKind(obj.ProgID)
+	return obj
+}
+
+// compObjProgID reads the ProgID from a CompObj stream: a 28-byte header, the
+// user type, the clipboard format and then the ProgID
+func compObjProgID(data []byte) string {
+	r := &compObjReader{data: data, pos: 28}
+	r.ansiString() // AnsiUserType
+	switch marker := r.uint32(); marker {
+	case 0:
+	case 0xFFFFFFFF, 0xFFFFFFFE:
+		r.uint32() // Standard clipboard format
+	default:
+		r.pos += int(marker)
+	}
+	id := r.ansiString()
+	if r.err {
+		return ""
+	}
+	return id
+}
+
+// compObjReader reads length-prefixed values, noting when data runs out
+type compObjReader struct {
+	data []byte
+	pos  int
+	err  bool
+}
+
+func (r *compObjReader) uint32() uint32 {
+	if r.pos < 0 || r.pos+4 > len(r.data) {
+		r.err = true
+		return 0
+	}
+	v := binary.LittleEndian.Uint32(r.data[r.pos:])
+	r.pos += 4
+	return v
+}
+
+func (r *compObjReader) ansiString() string {
+	n := int(r.uint32())
+	if r.err || n < 0 || n > len(r.data)-r.pos {
+		r.err = true
+		return ""
+	}
+	s := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return ole.DecodeANSI(bytes.TrimRight(s, "\x00"))
+}
+
+// packageLabel reads the label of a packager object, normally the name of the
+// wrapped file, from its Ole10Native stream
+func packageLabel(data []byte) string {
+	// Native data size and a reserved word precede the label
+	if len(data) < 7 {
+		return ""
+	}
+	label := data[6:]
+	if i := bytes.IndexByte(label, 0); i >= 0 {
+		label = label[:i]
+	}
+	return ole.DecodeANSI(label)
+}
+
+// FromPackage inventories the media, embeddings and external relationships of a
+// ZIP-based document
+func FromPackage(pkg *wordml.Package) (*Inventory, error) {
+	inv := &Inventory{}
+
+	// OLEObject elements name the ProgID of embeddings and the source of links
+	progIDs := make(map[string]string)
+	for _, part := range pkg.Parts() {
+		data, err := pkg.ReadPart(part.Name)
+		if err != nil {
+			return nil, err
+		}
+		rels, err := pkg.Relationships(part.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range oleObjects(data) {
+			for _, rel := range rels {
+				if rel.ID != o.relID {
+					continue
+				}
+				if rel.External() {
+					inv.Objects = append(inv.Objects, Object{
+						Name: part.Name, ProgID: o.progID, Kind: ObjectKind(o.progID),
+						Linked: true, Source: rel.Target,
+					})
+				} else {
+					progIDs[rel.Target] = o.progID
+				}
+			}
+		}
+	}
+
+	for _, name := range pkg.Files() {
+		switch {
+		case strings.Contains(name, "/media/"):
+			data, err := pkg.ReadPart(name)
+			if err != nil {
+				return nil, err
+			}
+			img, ok := ReadImage(name, data)
+			if !ok {
+				img = Image{Name: name, Type: strings.TrimPrefix(strings.ToLower(path.Ext(name)), "."), Size: len(data)}
+			}
+			inv.Images = append(inv.Images, img)
+		case strings.Contains(name, "/embeddings/"):
+			data, err := pkg.ReadPart(name)
+			if err != nil {
+				return nil, err
+			}
+			inv.Objects = append(inv.Objects, readEmbedding(name, data, progIDs[name]))
+		case strings.HasSuffix(name, ".rels"):
+			source := relsSource(name)
+			rels, err := pkg.Relationships(source)
+			if err != nil {
+				return nil, err
+			}
+			for _, rel := range rels {
+				if !rel.External() {
+					continue
+				}
+				kind, ok := relationshipLinks[path.Base(rel.Type)]
+				if !ok {
+					kind = LinkExternal
+				}
+				inv.AddLink(kind, rel.Target, source)
+			}
+		}
+	}
+	return inv, nil
+}
+
+// readEmbedding describes an embedded part; embedded compound files carry their
+// own ProgID and packaged files are named by their extension
+func readEmbedding(name string, data []byte, progID string) Object {
+	obj := Object{Name: name, ProgID: progID, Size: len(data)}
+	if ole.IsCFB(data) {
+		if cf, err := ole.Open(data); err == nil {
+			if compObj, err := cf.ReadStream(compObjStream); err == nil && obj.ProgID == "" {
+				obj.ProgID = compObjProgID(compObj)
+			}
+			if native, err := cf.ReadStream(ole10NativeStream); err == nil {
+				obj.Source = packageLabel(native)
+			}
+		}
+	}
+	if obj.ProgID == "" {
+		obj.ProgID = extensionProgIDs[strings.ToLower(path.Ext(name))]
+	}
+	obj.Kind = ObjectKind(obj.ProgID)
+	return obj
+}
+
+// relsSource returns the part whose relationships a .rels file holds, e.g.
+// "word/document.xml" for "word/_rels/document.xml.rels"
+func relsSource(rels string) string {
+	dir := path.Dir(path.Dir(rels))
+	base := strings.TrimSuffix(path.Base(rels), ".rels")
+	if dir == "." {
+		return base
+	}
+	return dir + "/" + base
+}
+
+// oleObject is an o:OLEObject element of a content part
+type oleObject struct {
+	progID string
+	relID  string
+}
+
+// oleObjects returns the OLEObject elements of a content part
+func oleObjects(data []byte) []oleObject {
+	var objects []oleObject
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return objects
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "OLEObject" {
+			continue
+		}
+		var o oleObject
+		for _, a := range start.Attr {
+			switch a.Name.Local {
+			case "ProgID":
+				o.progID = a.Value
+			case "id":
+				o.relID = a.Value
+			}
+		}
+		objects = append(objects, o)
+	}
+}
+
+// FromRTF inventories the pictures, objects and attached template of an RTF
+// document
+func FromRTF(doc *rtf.Document) *Inventory {
+	inv := &Inventory{}
+	for i, pic := range doc.Pictures {
+		inv.Images = append(inv.Images, Image{
+			Name: fmt.Sprintf("picture %d", i+1), Type: pic.Type, Size: pic.Size,
+			Width: pic.Width, Height: pic.Height,
+		})
+	}
+	for i, o := range doc.Objects {
+		inv.Objects = append(inv.Objects, Object{
+			Name: fmt.Sprintf("object %d", i+1), ProgID: o.Class, Kind: ObjectKind(o.Class),
+			Linked: o.Kind == "linked",
+		})
+	}
+	inv.AddLink(LinkAttachedTemplate, doc.Template, `\template`)
+	return inv
+}
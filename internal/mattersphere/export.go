@@ -0,0 +1,436 @@
+// Package mattersphere reads MatterSphere "Export S and I" packages: the
+// ExportSandI.Manifest.xml listing every exported item, the manifest.xml
+// DataSet of each precedent, the precedent documents themselves and the
+// scripts precedents run.
+package mattersphere
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/analyzer"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/cataloger"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/msword"
+)
+
+// Layout of an export directory
+const (
+	manifestSuffix   = ".Manifest.xml" // ExportSandI.Manifest.xml, named after the package code
+	precedentsDir    = "Precedents"    // One directory per precedent, named by its code
+	scriptsDir       = "Scripts"       // One directory per script, named by its code
+	itemManifestName = "manifest.xml"
+)
+
+// Metadata keys set on the documents of an export
+const (
+	MetaPrecedentID = "prec_id"
+	MetaTitle       = "title"
+	MetaDescription = "description"
+	MetaCategory    = "category"
+	MetaSubcategory = "subcategory"
+	MetaType        = "type"
+	MetaLibrary     = "library"
+	MetaLanguage    = "language"
+	MetaAddressee   = "addressee"
+	MetaScripts     = "scripts"           // Comma-separated script codes
+	MetaDifficulty  = "script_difficulty" // Highest migration difficulty of the precedent's scripts
+	MetaSource      = "source"            // "document", or "preview" when only the manifest preview was available
+)
+
+var (
+	// ErrNoManifest is returned when a directory holds no export manifest
+	ErrNoManifest = errors.New("no export manifest found")
+	// ErrNoDocument is returned when neither a precedent's document nor its preview was exported
+	ErrNoDocument = errors.New("precedent document not exported")
+)
+
+// documentExtensions are the extensions precedent documents are stored with, in
+// order of preference
+var documentExtensions = []string{".dot", ".dotx", ".dotm", ".doc", ".docx", ".docm", ".rtf"}
+
+// isDocument reports whether a file name has a precedent document extension
+func isDocument(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range documentExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// Precedent is one precedent of an export, joined from its manifest item, its
+// manifest.xml and its document
+type Precedent struct {
+	Code          string   `json:"code"` // PrecID
+	Title         string   `json:"title"`
+	Description   string   `json:"description,omitempty"`
+	Type          string   `json:"type,omitempty"`
+	Category      string   `json:"category,omitempty"`
+	Subcategory   string   `json:"subcategory,omitempty"`
+	Minorcategory string   `json:"minorcategory,omitempty"`
+	Library       string   `json:"library,omitempty"`
+	Language      string   `json:"language,omitempty"`
+	Addressee     string   `json:"addressee,omitempty"`
+	AssocType     string   `json:"assoc_type,omitempty"`
+	Path          string   `json:"path,omitempty"` // PrecPath as stored by MatterSphere
+	Scripts       []string `json:"scripts,omitempty"`
+	Children      []string `json:"children,omitempty"` // Codes of the precedents of a multi-precedent
+
+	Manifest string `json:"manifest,omitempty"` // Path of the precedent's manifest.xml, if exported
+	File     string `json:"file,omitempty"`     // Path of the precedent document, if exported
+	Preview  string `json:"-"`                  // Document text from the manifest, with Word field characters
+}
+
+// Export is an export directory read from disk
+type Export struct {
+	Root       string                `json:"root"`
+	Code       string                `json:"code"`
+	Precedents []*Precedent          `json:"precedents"`
+	Scripts    map[string]*Script    `json:"scripts,omitempty"` // By code
+	Warnings   []string              `json:"warnings,omitempty"`
+	byCode     map[string]*Precedent // Precedents by code
+}
+
+// Find returns the export directories under root, the directories holding an
+// export manifest, in lexical order
+func Find(root string) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), manifestSuffix) {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search %s: %w", root, err)
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+// Open reads the export in dir: its manifest, the manifest.xml of each precedent
+// and script, and the location of each precedent document. Precedents whose
+// manifest.xml cannot be read keep the details of their manifest item and are
+// noted in Warnings.
+func Open(dir string) (*Export, error) {
+	manifests, err := filepath.Glob(filepath.Join(dir, "*"+manifestSuffix))
+	if err != nil {
+		return nil, err
+	}
+	if len(manifests) == 0 {
+		return nil, fmt.Errorf("%s: %w", dir, ErrNoManifest)
+	}
+
+	f, err := os.Open(manifests[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest: %w", err)
+	}
+	defer f.Close()
+	manifest, err := ParseManifest(f)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Export{
+		Root:    dir,
+		Code:    manifest.Code,
+		Scripts: make(map[string]*Script),
+		byCode:  make(map[string]*Precedent),
+	}
+	e.addItems(manifest.Items)
+	e.findScripts()
+	documents := e.indexDocuments()
+	for _, p := range e.Precedents {
+		e.readPrecedent(p)
+		p.File = e.locateDocument(p, documents)
+		for _, code := range p.Scripts {
+			if s, ok := e.Scripts[code]; ok {
+				s.Precedents = append(s.Precedents, p.Code)
+			}
+		}
+	}
+	return e, nil
+}
+
+// addItems creates a precedent for each precedent item and links the script
+// items listed under them
+func (e *Export) addItems(items []Item) {
+	byID := make(map[int]*Precedent)
+	for _, item := range items {
+		if item.Type != ItemPrecedent || item.Code == "" {
+			continue
+		}
+		if _, ok := e.byCode[item.Code]; ok {
+			continue
+		}
+		d := ParseDescription(item.Description)
+		p := &Precedent{
+			Code: item.Code, Title: item.Name, Description: d.Text,
+			Type: d.Type, Category: d.Category, Subcategory: d.Subcategory,
+			Minorcategory: d.Minorcategory, Library: d.Library,
+		}
+		e.Precedents = append(e.Precedents, p)
+		e.byCode[p.Code] = p
+		byID[item.ID] = p
+	}
+	for _, item := range items {
+		if p, ok := byID[item.ParentID]; ok && item.Type == ItemScript {
+			p.addScript(item.Code)
+		}
+	}
+}
+
+// findScripts reads the manifest of each exported script. Scripts that cannot
+// be read are noted in Warnings.
+func (e *Export) findScripts() {
+	dirs, err := os.ReadDir(filepath.Join(e.Root, scriptsDir))
+	if err != nil {
+		return
+	}
+	for _, d := range dirs {
+		path := filepath.Join(e.Root, scriptsDir, d.Name(), itemManifestName)
+		if _, err := os.Stat(path); !d.IsDir() || err != nil {
+			continue
+		}
+		s, err := readScript(path)
+		if err != nil {
+			e.Warnings = append(e.Warnings, fmt.Sprintf("script %s: %v", d.Name(), err))
+			continue
+		}
+		if s.Code == "" {
+			s.Code = d.Name()
+		}
+		e.Scripts[s.Code] = s
+	}
+}
+
+// readPrecedent fills in a precedent from its manifest.xml, which takes
+// precedence over the summary in the export manifest
+func (e *Export) readPrecedent(p *Precedent) {
+	path := filepath.Join(e.Root, precedentsDir, p.Code, itemManifestName)
+	f, err := os.Open(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			e.Warnings = append(e.Warnings, fmt.Sprintf("precedent %s: %v", p.Code, err))
+		}
+		return
+	}
+	defer f.Close()
+
+	m, err := ParsePrecedentManifest(f)
+	if err != nil {
+		e.Warnings = append(e.Warnings, fmt.Sprintf("precedent %s: %v", p.Code, err))
+		return
+	}
+	p.Manifest = path
+
+	r := m.Precedent
+	set := func(dst *string, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			*dst = value
+		}
+	}
+	set(&p.Title, r.Title)
+	set(&p.Description, r.Description)
+	set(&p.Type, r.Type)
+	set(&p.Category, r.Category)
+	set(&p.Subcategory, r.Subcategory)
+	set(&p.Library, r.Library)
+	set(&p.Language, r.Language)
+	set(&p.Addressee, r.Addressee)
+	set(&p.AssocType, r.AssocType)
+	set(&p.Path, r.Path)
+	p.Preview = r.Preview
+	p.addScript(strings.TrimSpace(r.Script))
+	for _, child := range m.Multi {
+		p.Children = append(p.Children, strings.TrimSpace(child.ChildID))
+	}
+}
+
+// addScript links a script to the precedent once
+func (p *Precedent) addScript(code string) {
+	if code == "" {
+		return
+	}
+	for _, s := range p.Scripts {
+		if s == code {
+			return
+		}
+	}
+	p.Scripts = append(p.Scripts, code)
+}
+
+// indexDocuments lists the precedent documents of the export by lower-case file name
+func (e *Export) indexDocuments() map[string]string {
+	documents := make(map[string]string)
+	filepath.WalkDir(e.Root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		name := strings.ToLower(d.Name())
+		if _, ok := documents[name]; !ok && isDocument(name) {
+			documents[name] = path
+		}
+		return nil
+	})
+	return documents
+}
+
+// locateDocument finds a precedent's document: a document in its own directory,
+// else the file named by PrecPath, else one named after its code
+func (e *Export) locateDocument(p *Precedent, documents map[string]string) string {
+	if entries, err := os.ReadDir(filepath.Join(e.Root, precedentsDir, p.Code)); err == nil {
+		for _, entry := range entries {
+			if !entry.IsDir() && isDocument(entry.Name()) {
+				return filepath.Join(e.Root, precedentsDir, p.Code, entry.Name())
+			}
+		}
+	}
+
+	// PrecPath is a Windows path relative to the precedents share
+	if p.Path != "" {
+		name := p.Path[strings.LastIndexAny(p.Path, `\/`)+1:]
+		if path, ok := documents[strings.ToLower(name)]; ok {
+			return path
+		}
+	}
+	for _, ext := range documentExtensions {
+		if path, ok := documents[strings.ToLower(p.Code)+ext]; ok {
+			return path
+		}
+	}
+	return ""
+}
+
+// Precedent returns the precedent with a code
+func (e *Export) Precedent(code string) (*Precedent, bool) {
+	p, ok := e.byCode[code]
+	return p, ok
+}
+
+// ScriptsOf returns the exported scripts a precedent runs, as dependencies for
+// complexity reports and catalogs
+func (e *Export) ScriptsOf(p *Precedent) []analyzer.ScriptDependency {
+	var scripts []analyzer.ScriptDependency
+	for _, code := range p.Scripts {
+		if s, ok := e.Scripts[code]; ok {
+			scripts = append(scripts, s.Dependency())
+		}
+	}
+	return scripts
+}
+
+// Analyze reports the complexity of a precedent: that of its document, or of
+// its preview text when the document was not exported, with the scripts it runs.
+// It returns ErrNoDocument when there is neither.
+func (e *Export) Analyze(p *Precedent) (*analyzer.ComplexityReport, error) {
+	var report *analyzer.ComplexityReport
+	switch {
+	case p.File != "":
+		content, err := os.ReadFile(p.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read precedent %s: %w", p.Code, err)
+		}
+		report = analyzer.AnalyzeComplexity(content)
+	case p.Preview != "":
+		text, _ := msword.Render(p.Preview)
+		report = analyzer.AnalyzeComplexity([]byte(text))
+	default:
+		return nil, fmt.Errorf("precedent %s: %w", p.Code, ErrNoDocument)
+	}
+	analyzer.AddScripts(report, e.ScriptsOf(p))
+	return report, nil
+}
+
+// Metadata returns the precedent's catalog metadata
+func (p *Precedent) Metadata() map[string]string {
+	metadata := make(map[string]string)
+	for key, value := range map[string]string{
+		MetaPrecedentID: p.Code,
+		MetaTitle:       p.Title,
+		MetaDescription: p.Description,
+		MetaCategory:    p.Category,
+		MetaSubcategory: p.Subcategory,
+		MetaType:        p.Type,
+		MetaLibrary:     p.Library,
+		MetaLanguage:    p.Language,
+		MetaAddressee:   p.Addressee,
+		MetaScripts:     strings.Join(p.Scripts, ","),
+	} {
+		if value != "" {
+			metadata[key] = value
+		}
+	}
+	return metadata
+}
+
+// Documents reads the precedents of the export as catalog documents. Precedents
+// without an exported document are cataloged from the preview text of their
+// manifest; those with neither are skipped.
+func (e *Export) Documents(extractor *analyzer.DocumentExtractor) []cataloger.DocumentData {
+	documents := make([]cataloger.DocumentData, 0, len(e.Precedents))
+	for _, p := range e.Precedents {
+		doc := cataloger.DocumentData{Metadata: p.Metadata(), Scripts: e.ScriptsOf(p)}
+		if difficulty := hardest(doc.Scripts); difficulty != "" {
+			doc.Metadata[MetaDifficulty] = difficulty
+		}
+
+		switch {
+		case p.File != "":
+			content, err := os.ReadFile(p.File)
+			if err != nil {
+				log.Warnf("Failed to read precedent %s: %v", p.Code, err)
+				continue
+			}
+			doc.Filename = filepath.Base(p.File)
+			doc.Content = content
+			doc.Metadata[MetaSource] = "document"
+			if info, err := extractor.AnalyzeDocument(content); err == nil {
+				doc.ExtractedText = info.Text
+			} else {
+				doc.ExtractedText = string(content)
+			}
+		case p.Preview != "":
+			doc.Filename = p.Code + p.extension()
+			doc.ExtractedText, _ = msword.Render(p.Preview)
+			doc.Metadata[MetaSource] = "preview"
+		default:
+			continue
+		}
+		documents = append(documents, doc)
+	}
+	return documents
+}
+
+// difficultyRank orders script difficulty levels
+var difficultyRank = map[string]int{"low": 1, "medium": 2, "high": 3}
+
+// hardest returns the highest difficulty of the scripts, or "" for none
+func hardest(scripts []analyzer.ScriptDependency) string {
+	level := ""
+	for _, s := range scripts {
+		if difficultyRank[s.Difficulty] > difficultyRank[level] {
+			level = s.Difficulty
+		}
+	}
+	return level
+}
+
+// extension returns the extension of the precedent's document, by PrecPath
+func (p *Precedent) extension() string {
+	if ext := filepath.Ext(strings.ReplaceAll(p.Path, `\`, "/")); ext != "" {
+		return strings.ToLower(ext)
+	}
+	return ".dot"
+}
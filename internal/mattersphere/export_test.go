@@ -0,0 +1,128 @@
+package mattersphere
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/analyzer"
+)
+
+func TestParseDescription(t *testing.T) {
+	d := ParseDescription("Precedent [17938] \r\n\r\nForm 43 - Oath of administrator\r\n\r\n" +
+		"Library : ARCHIVE\r\nType : LETTERHEAD2\r\nCategory : Superannuation\r\nSubcategory : \r\nMinorcategory : ")
+	want := ItemDescription{Text: "Form 43 - Oath of administrator", Library: "ARCHIVE", Type: "LETTERHEAD2", Category: "Superannuation"}
+	if d != want {
+		t.Errorf("ParseDescription = %+v, want %+v", d, want)
+	}
+}
+
+// writeFiles creates files under dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	root := t.TempDir()
+	description := func(code, text, typ, category string) string {
+		return "Precedent [" + code + "] &#xD;&#xA;&#xD;&#xA;" + text + "&#xD;&#xA;&#xD;&#xA;Library : &#xD;&#xA;Type : " + typ +
+			"&#xD;&#xA;Category : " + category + "&#xD;&#xA;Subcategory : &#xD;&#xA;Minorcategory : "
+	}
+	writeFiles(t, root, map[string]string{
+		"Export/ExportSandI/ExportSandI.Manifest.xml": "\ufeff" + `<?xml version="1.0" encoding="utf-8"?>` +
+			`<config Code="ExportSandI ">` +
+			`<Items ID="0" Code="ROOT" Name="Export S and I " Type="None" ParentID="-1" />` +
+			`<Items ID="1" Code="2605" Name="Sup039" Description="` + description("2605", "Letter to client", "BLANK", "Superannuation") + `" Type="Precedents" ParentID="0" />` +
+			`<Items ID="2" Code="_2605" Name="_2605" Description="Script [_2605]&#xD;&#xA;Version : 3" Type="Scripts" ParentID="1" />` +
+			`<Items ID="3" Code="3264" Name="Sup422b" Description="` + description("3264", "Affidavit", "VICCOUNTY", "") + `" Type="Precedents" ParentID="0" />` +
+			`<Items ID="4" Code="4000" Name="NoText" Description="` + description("4000", "", "EMAIL", "Employment") + `" Type="Precedents" ParentID="0" />` +
+			`</config>`,
+		"Export/ExportSandI/Precedents/2605/manifest.xml": `<?xml version="1.0" standalone="yes"?><PRECEDENTS>` +
+			`<xs:schema id="PRECEDENTS" xmlns:xs="http://www.w3.org/2001/XMLSchema"/>` +
+			`<PRECEDENT><PrecID>2605</PrecID><PrecTitle>Sup039</PrecTitle><PrecType>BLANK</PrecType>` +
+			`<PrecCategory>Superannuation</PrecCategory><PrecAddressee>CLIENT</PrecAddressee>` +
+			`<PrecLibrary>ARCHIVE</PrecLibrary><PrecScript>_2605</PrecScript><PrecPath>Company\2605.dot</PrecPath></PRECEDENT>` +
+			`<LANGUAGE><langCode>en-au</langCode></LANGUAGE></PRECEDENTS>`,
+		"Export/ExportSandI/Precedents/2605/Sup039.dot": "binary",
+		"Export/ExportSandI/Precedents/3264/manifest.xml": `<PRECEDENTS><PRECEDENT><PrecID>3264</PrecID>` +
+			`<PrecCategory>Personal Injury</PrecCategory><PrecPath>Company\3264.dot</PrecPath>` +
+			`<PrecPreview>IN THE &#x13; DOCVARIABLE "Court" &#x14;Court&#x15; OF VICTORIA&#xD;</PrecPreview>` +
+			`</PRECEDENT><PRECEDENTMULTI><multiChildID>2605</multiChildID></PRECEDENTMULTI></PRECEDENTS>`,
+		"Export/ExportSandI/Scripts/_2605/manifest.xml": scriptManifestXML("_2605", `Wizards.GetWizard("udScrWiz0087Sel", null)`),
+		"Export/ExportSandI/3264.dot.meta.json":         "{}",
+	})
+
+	dirs, err := Find(root)
+	if err != nil {
+		t.Fatalf("Find failed: %v", err)
+	}
+	if len(dirs) != 1 || filepath.Base(dirs[0]) != "ExportSandI" {
+		t.Fatalf("Find = %q, want the export directory", dirs)
+	}
+
+	export, err := Open(dirs[0])
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if export.Code != "ExportSandI" || len(export.Precedents) != 3 || len(export.Warnings) != 0 {
+		t.Fatalf("export = %+v", export)
+	}
+	if s, ok := export.Scripts["_2605"]; !ok || !reflect.DeepEqual(s.Precedents, []string{"2605"}) {
+		t.Errorf("scripts = %v, want _2605 used by 2605", export.Scripts)
+	}
+
+	letter, _ := export.Precedent("2605")
+	wantMetadata := map[string]string{
+		MetaPrecedentID: "2605", MetaTitle: "Sup039", MetaDescription: "Letter to client",
+		MetaCategory: "Superannuation", MetaType: "BLANK", MetaLibrary: "ARCHIVE",
+		MetaLanguage: "en-au", MetaAddressee: "CLIENT", MetaScripts: "_2605",
+	}
+	if got := letter.Metadata(); !reflect.DeepEqual(got, wantMetadata) {
+		t.Errorf("metadata = %v, want %v", got, wantMetadata)
+	}
+	if filepath.Base(letter.File) != "Sup039.dot" {
+		t.Errorf("file = %q, want the document in the precedent directory", letter.File)
+	}
+
+	affidavit, _ := export.Precedent("3264")
+	if affidavit.Category != "Personal Injury" || affidavit.Type != "VICCOUNTY" || affidavit.Title != "Sup422b" {
+		t.Errorf("affidavit = %+v, want manifest.xml values over the item's", affidavit)
+	}
+	if affidavit.File != "" || !reflect.DeepEqual(affidavit.Children, []string{"2605"}) {
+		t.Errorf("affidavit = %+v, want no document and one child", affidavit)
+	}
+
+	documents := export.Documents(analyzer.NewDocumentExtractor())
+	if len(documents) != 2 {
+		t.Fatalf("documents = %d, want 2", len(documents))
+	}
+	if scripts := documents[0].Scripts; len(scripts) != 1 || documents[0].Metadata[MetaDifficulty] != "low" {
+		t.Errorf("document scripts = %+v, metadata = %v, want _2605 rated low", scripts, documents[0].Metadata)
+	}
+	preview := documents[1]
+	if preview.Filename != "3264.dot" || preview.Metadata[MetaSource] != "preview" ||
+		!strings.HasPrefix(preview.ExtractedText, `IN THE { DOCVARIABLE "Court" } OF VICTORIA`) {
+		t.Errorf("preview document = %+v", preview)
+	}
+
+	report, err := export.Analyze(letter)
+	if err != nil || len(report.Scripts) != 1 || report.Detections["precedent_scripts"] != 1 {
+		t.Errorf("Analyze = %+v, %v, want the script in the report", report, err)
+	}
+	empty, _ := export.Precedent("4000")
+	if _, err := export.Analyze(empty); !errors.Is(err, ErrNoDocument) {
+		t.Errorf("Analyze without a document = %v, want ErrNoDocument", err)
+	}
+}
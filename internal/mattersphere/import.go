@@ -0,0 +1,465 @@
+package mattersphere
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/converter"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/queue"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/storage"
+)
+
+// Reconciliation statuses of precedents that were not queued; queued precedents
+// carry the status of their job
+const (
+	StatusSkipped   = "skipped"   // No document was exported for the precedent
+	StatusDuplicate = "duplicate" // Already queued from another export under the root
+	StatusNotQueued = "not_queued"
+	StatusMissing   = "missing" // The job is no longer known to the queue
+)
+
+// Import layout in storage
+const (
+	importsDir         = "imports"       // Documents of local exports are copied to imports/<batch>/
+	uncategorized      = "Uncategorized" // Folder of precedents without a category
+	reconciliationName = "reconciliation-%s.json"
+)
+
+// Batch metadata keys set by an import
+const (
+	MetaBatchRoot           = "mattersphere_root"
+	MetaBatchReconciliation = "reconciliation" // Storage path of the reconciliation report
+)
+
+var (
+	// ErrNoReconciliation is returned for batches that were not created by an import
+	ErrNoReconciliation = errors.New("batch has no reconciliation report")
+
+	// ErrRootNotAllowed is returned for local roots outside the import directory
+	ErrRootNotAllowed = errors.New("export root is outside the import directory")
+
+	// ErrInvalidPrefix is returned for storage prefixes that climb out of their parent
+	ErrInvalidPrefix = errors.New("storage prefix must not contain ..")
+)
+
+// ImportOptions configures the jobs an import queues
+type ImportOptions struct {
+	Destination string   // Storage prefix of the converted documents
+	Formats     []string // Output formats; the first names the output path
+	Priority    int
+	Engine      string            // Conversion engine, or "" for the default
+	Annotate    bool              // Also produce a DOCX with a review comment per complexity issue
+	Metadata    map[string]string // Added to every job and the batch, e.g. callback settings
+	StorageOnly bool              // Read the root from storage even if a local directory has its name
+}
+
+// Reconciliation maps each precedent of an import to its job and output document
+type Reconciliation struct {
+	BatchID     string                `json:"batch_id"`
+	Root        string                `json:"root"`
+	Destination string                `json:"destination"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+	Queued      int                   `json:"queued"`
+	Entries     []ReconciliationEntry `json:"entries"`
+}
+
+// ReconciliationEntry is one precedent of an import
+type ReconciliationEntry struct {
+	PrecID      string `json:"prec_id"`
+	Title       string `json:"title,omitempty"`
+	Category    string `json:"category,omitempty"`
+	Subcategory string `json:"subcategory,omitempty"`
+	Export      string `json:"export"`           // Export directory, relative to the root
+	Source      string `json:"source,omitempty"` // Storage path of the precedent document
+	Output      string `json:"output,omitempty"` // Storage path of the converted document
+	JobID       string `json:"job_id,omitempty"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Importer queues the precedents of MatterSphere exports for conversion
+type Importer struct {
+	queue   queue.Queue
+	storage storage.Storage
+}
+
+// NewImporter creates an importer
+func NewImporter(q queue.Queue, s storage.Storage) *Importer {
+	return &Importer{queue: q, storage: s}
+}
+
+// Import queues one job per precedent document of the exports under root, a
+// local directory or else a storage prefix, as a batch. Converted documents are
+// written under the destination in folders named after the precedent category,
+// subcategory and minor category. The returned reconciliation is saved to
+// storage and recorded on the batch.
+func (im *Importer) Import(ctx context.Context, root string, opts ImportOptions) (*Reconciliation, error) {
+	if len(opts.Formats) == 0 {
+		opts.Formats = []string{converter.OutputDOCX}
+	}
+	staged, err := im.stage(ctx, root, opts.StorageOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer staged.cleanup()
+
+	dirs, err := Find(staged.dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("%s: %w", root, ErrNoManifest)
+	}
+
+	now := time.Now()
+	r := &Reconciliation{
+		BatchID:     uuid.New().String(),
+		Root:        root,
+		Destination: opts.Destination,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	var jobIDs []string
+	queued := make(map[string]string) // Export of each queued precedent
+	for _, dir := range dirs {
+		export, err := Open(dir)
+		if err != nil {
+			im.rollback(ctx, r.BatchID, jobIDs)
+			return nil, err
+		}
+		for _, warning := range export.Warnings {
+			log.Warnf("MatterSphere import %s: %s", r.BatchID, warning)
+		}
+		exportPath, _ := filepath.Rel(staged.dir, dir)
+
+		for _, p := range export.Precedents {
+			entry := ReconciliationEntry{
+				PrecID: p.Code, Title: p.Title, Category: p.Category, Subcategory: p.Subcategory,
+				Export: filepath.ToSlash(exportPath),
+			}
+			switch {
+			case queued[p.Code] != "":
+				entry.Status = StatusDuplicate
+				entry.Error = "already queued from " + queued[p.Code]
+			case p.File == "":
+				entry.Status = StatusSkipped
+				entry.Error = ErrNoDocument.Error()
+			default:
+				job, err := im.enqueue(ctx, r.BatchID, staged, p, opts)
+				if err != nil {
+					entry.Status = StatusNotQueued
+					entry.Error = err.Error()
+					break
+				}
+				entry.Source, entry.Output = job.InputPath, job.OutputPath
+				entry.JobID, entry.Status = job.ID, job.Status
+				jobIDs = append(jobIDs, job.ID)
+				queued[p.Code] = entry.Export
+			}
+			r.Entries = append(r.Entries, entry)
+		}
+	}
+	r.Queued = len(jobIDs)
+
+	batch := &queue.Batch{
+		ID:        r.BatchID,
+		Status:    queue.BatchStatusPending,
+		JobIDs:    jobIDs,
+		Total:     len(jobIDs),
+		Pending:   len(jobIDs),
+		CreatedAt: now,
+		Metadata: map[string]string{
+			"source":                root,
+			"destination":           opts.Destination,
+			MetaBatchRoot:           root,
+			MetaBatchReconciliation: path.Join(opts.Destination, fmt.Sprintf(reconciliationName, r.BatchID)),
+		},
+	}
+	for key, value := range opts.Metadata {
+		batch.Metadata[key] = value
+	}
+	if err := im.queue.CreateBatch(ctx, batch); err != nil {
+		im.rollback(ctx, r.BatchID, jobIDs)
+		return nil, fmt.Errorf("failed to store batch: %w", err)
+	}
+	if err := im.save(batch.Metadata[MetaBatchReconciliation], r); err != nil {
+		im.rollback(ctx, r.BatchID, jobIDs)
+		return nil, err
+	}
+	return r, nil
+}
+
+// rollback cancels the jobs an import queued before it failed, so none of them
+// run outside a batch with a reconciliation report
+func (im *Importer) rollback(ctx context.Context, batchID string, jobIDs []string) {
+	for _, id := range jobIDs {
+		if err := im.queue.CancelJob(ctx, id); err != nil {
+			log.Warnf("MatterSphere import %s: failed to cancel job %s: %v", batchID, id, err)
+		}
+	}
+}
+
+// ResolveRoot checks an export root named by an API caller. A root naming a
+// directory under importDir, the only local directory the API may read, is
+// returned resolved and local; other roots must be storage prefixes. An empty
+// importDir allows storage prefixes only.
+func ResolveRoot(importDir, root string) (resolved string, local bool, err error) {
+	if importDir != "" {
+		candidate := root
+		if !filepath.IsAbs(candidate) {
+			candidate = filepath.Join(importDir, candidate)
+		}
+		if dir, err := filepath.EvalSymlinks(filepath.Clean(candidate)); err == nil {
+			base, err := filepath.EvalSymlinks(importDir)
+			if err != nil {
+				return "", false, fmt.Errorf("failed to resolve import directory: %w", err)
+			}
+			rel, err := filepath.Rel(base, dir)
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return "", false, fmt.Errorf("%s: %w", root, ErrRootNotAllowed)
+			}
+			return dir, true, nil
+		}
+	}
+	if filepath.IsAbs(root) {
+		return "", false, fmt.Errorf("%s: %w", root, ErrRootNotAllowed)
+	}
+	if err := ValidPrefix(root); err != nil {
+		return "", false, err
+	}
+	return root, false, nil
+}
+
+// ValidPrefix checks that a storage prefix stays under its parent
+func ValidPrefix(prefix string) error {
+	for _, part := range strings.FieldsFunc(prefix, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part == ".." {
+			return fmt.Errorf("%s: %w", prefix, ErrInvalidPrefix)
+		}
+	}
+	return nil
+}
+
+// enqueue queues the conversion of a precedent's document
+func (im *Importer) enqueue(ctx context.Context, batchID string, staged *stagedExport, p *Precedent, opts ImportOptions) (*queue.Job, error) {
+	input, err := staged.source(im.storage, batchID, p.File)
+	if err != nil {
+		return nil, err
+	}
+
+	output := OutputPath(p, opts.Formats[0])
+	job := &queue.Job{
+		ID:         uuid.New().String(),
+		InputPath:  input,
+		OutputPath: path.Join(opts.Destination, output),
+		Status:     queue.StatusPending,
+		Priority:   opts.Priority,
+		CreatedAt:  time.Now(),
+		Formats:    opts.Formats,
+		Metadata:   p.Metadata(),
+	}
+	job.Metadata["batch_id"] = batchID
+	// Batch downloads keep the folders of the filename
+	job.Metadata["filename"] = strings.TrimSuffix(output, path.Ext(output)) + filepath.Ext(p.File)
+	if opts.Engine != "" {
+		job.Metadata["engine"] = opts.Engine
+	}
+	if opts.Annotate {
+		job.Metadata["annotate"] = "true"
+	}
+	for key, value := range opts.Metadata {
+		job.Metadata[key] = value
+	}
+
+	if err := im.queue.Enqueue(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to queue job: %w", err)
+	}
+	return job, nil
+}
+
+// OutputPath returns where a precedent's converted document goes, relative to
+// the destination: category folders, then the title and code, e.g.
+// "Superannuation/Claims/Letter to client (2605).docx"
+func OutputPath(p *Precedent, format string) string {
+	var folders []string
+	for _, folder := range []string{p.Category, p.Subcategory, p.Minorcategory} {
+		if folder = safeName(folder); folder != "" {
+			folders = append(folders, folder)
+		}
+	}
+	if len(folders) == 0 {
+		folders = []string{uncategorized}
+	}
+
+	name := p.Code
+	if title := safeName(p.Title); title != "" {
+		name = title + " (" + p.Code + ")"
+	}
+	return path.Join(append(folders, converter.WithOutputExtension(name+p.extension(), format))...)
+}
+
+// safeName makes a category or title usable as a file or folder name
+func safeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	return strings.Trim(strings.Join(strings.Fields(name), " "), ". ")
+}
+
+// save writes a reconciliation to storage
+func (im *Importer) save(reportPath string, r *Reconciliation) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode reconciliation: %w", err)
+	}
+	if err := im.storage.WriteFile(reportPath, data); err != nil {
+		return fmt.Errorf("failed to save reconciliation: %w", err)
+	}
+	return nil
+}
+
+// Reconcile refreshes the reconciliation of an import batch with the current
+// status of its jobs and saves it
+func (im *Importer) Reconcile(ctx context.Context, batchID string) (*Reconciliation, error) {
+	batch, err := im.queue.GetBatch(ctx, batchID)
+	if err != nil {
+		return nil, err
+	}
+	reportPath := batch.Metadata[MetaBatchReconciliation]
+	if reportPath == "" {
+		return nil, fmt.Errorf("batch %s: %w", batchID, ErrNoReconciliation)
+	}
+	data, err := im.storage.ReadFile(ctx, reportPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reconciliation: %w", err)
+	}
+	var r Reconciliation
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse reconciliation: %w", err)
+	}
+
+	for i := range r.Entries {
+		entry := &r.Entries[i]
+		if entry.JobID == "" {
+			continue
+		}
+		job, err := im.queue.GetJob(ctx, entry.JobID)
+		if err != nil {
+			entry.Status, entry.Error = StatusMissing, err.Error()
+			continue
+		}
+		entry.Status, entry.Error = job.Status, job.Error
+		if len(job.Formats) > 0 && job.Outputs[job.Formats[0]] != "" {
+			entry.Output = job.Outputs[job.Formats[0]]
+		}
+	}
+	r.UpdatedAt = time.Now()
+	if err := im.save(reportPath, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// WriteCSV writes the reconciliation entries as CSV with a header row
+func (r *Reconciliation) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"prec_id", "title", "category", "subcategory", "export", "source", "output", "job_id", "status", "error"})
+	for _, e := range r.Entries {
+		out.Write([]string{e.PrecID, e.Title, e.Category, e.Subcategory, e.Export, e.Source, e.Output, e.JobID, e.Status, e.Error})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// stagedExport is an export root readable from the local filesystem
+type stagedExport struct {
+	dir     string
+	remote  map[string]string // Storage path of each local document, for roots in storage
+	cleanup func()
+}
+
+// stage makes an export root readable locally. A local directory is used as is
+// unless storageOnly is set. For a storage prefix the manifests are downloaded to a temporary directory and
+// documents are listed there as empty placeholders, so Open locates them
+// without downloading them; jobs read them from storage.
+func (im *Importer) stage(ctx context.Context, root string, storageOnly bool) (*stagedExport, error) {
+	if info, err := os.Stat(root); !storageOnly && err == nil && info.IsDir() {
+		return &stagedExport{dir: root, cleanup: func() {}}, nil
+	}
+
+	files, err := im.storage.List(ctx, root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", root, err)
+	}
+	dir, err := os.MkdirTemp("", "mattersphere-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	staged := &stagedExport{dir: dir, remote: make(map[string]string), cleanup: func() { os.RemoveAll(dir) }}
+
+	prefix := strings.Trim(filepath.ToSlash(root), "/")
+	for _, file := range files {
+		rel := strings.TrimPrefix(strings.TrimPrefix(filepath.ToSlash(file), prefix), "/")
+		name := path.Base(rel)
+		local := filepath.Join(dir, filepath.FromSlash(rel))
+
+		var data []byte
+		switch {
+		case strings.HasSuffix(name, manifestSuffix) || strings.EqualFold(name, itemManifestName):
+			if data, err = im.storage.ReadFile(ctx, file); err != nil {
+				staged.cleanup()
+				return nil, fmt.Errorf("failed to read %s: %w", file, err)
+			}
+		case isDocument(name):
+			staged.remote[local] = file
+		default:
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(local), 0755); err != nil {
+			staged.cleanup()
+			return nil, err
+		}
+		if err := os.WriteFile(local, data, 0644); err != nil {
+			staged.cleanup()
+			return nil, err
+		}
+	}
+	return staged, nil
+}
+
+// source returns the storage path of a precedent document, copying documents of
+// local exports into storage
+func (s *stagedExport) source(store storage.Storage, batchID, file string) (string, error) {
+	if s.remote != nil {
+		return s.remote[file], nil
+	}
+	rel, err := filepath.Rel(s.dir, file)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read document: %w", err)
+	}
+	remote := path.Join(importsDir, batchID, filepath.ToSlash(rel))
+	if err := store.WriteFile(remote, data); err != nil {
+		return "", fmt.Errorf("failed to copy document to storage: %w", err)
+	}
+	return remote, nil
+}
@@ -0,0 +1,170 @@
+package mattersphere
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/queue"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/storage"
+)
+
+// importFixture is a root holding two exports that both include precedent 2605
+var importFixture = map[string]string{
+	"A/ExportSandI/ExportSandI.Manifest.xml": `<config Code="ExportSandI">` +
+		`<Items ID="1" Code="2605" Name="Sup039" Type="Precedents" ParentID="0" />` +
+		`<Items ID="2" Code="3264" Name="Sup422b" Type="Precedents" ParentID="0" /></config>`,
+	"A/ExportSandI/Precedents/2605/manifest.xml": `<PRECEDENTS><PRECEDENT><PrecID>2605</PrecID><PrecTitle>Letter: to client?</PrecTitle>` +
+		`<PrecCategory>Superannuation</PrecCategory><PrecSubCategory>Claims</PrecSubCategory></PRECEDENT></PRECEDENTS>`,
+	"A/ExportSandI/Precedents/2605/Sup039.dot": "binary",
+	"B/ExportSandI/ExportSandI.Manifest.xml": `<config Code="ExportSandI">` +
+		`<Items ID="1" Code="2605" Name="Sup039" Type="Precedents" ParentID="0" /></config>`,
+	"B/ExportSandI/Precedents/2605/Sup039.dot": "binary",
+}
+
+func TestImport(t *testing.T) {
+	tests := []struct {
+		name    string
+		inStore bool   // Export written to storage rather than a local directory
+		source  string // Expected job input, after the batch ID for local exports
+	}{
+		{"local directory", false, "A/ExportSandI/Precedents/2605/Sup039.dot"},
+		{"storage prefix", true, "exports/A/ExportSandI/Precedents/2605/Sup039.dot"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			base := t.TempDir()
+			s := storage.NewLocalStorage(base)
+			q := queue.NewMemoryQueue()
+
+			root := t.TempDir()
+			if tt.inStore {
+				writeFiles(t, filepath.Join(base, "exports"), importFixture)
+				root = "exports"
+			} else {
+				writeFiles(t, root, importFixture)
+			}
+
+			r, err := NewImporter(q, s).Import(ctx, root, ImportOptions{Destination: "converted", Annotate: true})
+			if err != nil {
+				t.Fatalf("Import failed: %v", err)
+			}
+			if r.Queued != 1 || len(r.Entries) != 3 {
+				t.Fatalf("reconciliation = %+v, want 3 entries with 1 queued", r)
+			}
+			statuses := []string{r.Entries[0].Status, r.Entries[1].Status, r.Entries[2].Status}
+			if strings.Join(statuses, ",") != "pending,skipped,duplicate" {
+				t.Errorf("statuses = %v", statuses)
+			}
+
+			entry := r.Entries[0]
+			if entry.Output != "converted/Superannuation/Claims/Letter_ to client_ (2605).docx" {
+				t.Errorf("output = %q, want the category folders and title", entry.Output)
+			}
+			if want := tt.source; !tt.inStore {
+				want = "imports/" + r.BatchID + "/" + want
+				if _, err := os.Stat(s.GetLocalPath(want)); err != nil {
+					t.Errorf("document not copied to storage: %v", err)
+				}
+				tt.source = want
+			}
+			if filepath.ToSlash(entry.Source) != tt.source {
+				t.Errorf("source = %q, want %q", entry.Source, tt.source)
+			}
+
+			job, err := q.GetJob(ctx, entry.JobID)
+			if err != nil {
+				t.Fatalf("job not queued: %v", err)
+			}
+			if job.Metadata[MetaPrecedentID] != "2605" || job.Metadata[MetaCategory] != "Superannuation" ||
+				job.Metadata["batch_id"] != r.BatchID || job.Metadata["annotate"] != "true" {
+				t.Errorf("job metadata = %v", job.Metadata)
+			}
+
+			job.Status, job.Error = queue.StatusFailed, "conversion failed"
+			if err := q.UpdateJob(job); err != nil {
+				t.Fatal(err)
+			}
+			refreshed, err := NewImporter(q, s).Reconcile(ctx, r.BatchID)
+			if err != nil {
+				t.Fatalf("Reconcile failed: %v", err)
+			}
+			if got := refreshed.Entries[0]; got.Status != queue.StatusFailed || got.Error != "conversion failed" {
+				t.Errorf("reconciled entry = %+v, want the job's failure", got)
+			}
+		})
+	}
+}
+
+// failingBatches is a queue that cannot store batches
+type failingBatches struct {
+	*queue.MemoryQueue
+}
+
+func (failingBatches) CreateBatch(ctx context.Context, batch *queue.Batch) error {
+	return errors.New("store unavailable")
+}
+
+func TestImportRollsBackWithoutBatch(t *testing.T) {
+	ctx := context.Background()
+	q := failingBatches{queue.NewMemoryQueue()}
+	root := t.TempDir()
+	writeFiles(t, root, importFixture)
+
+	if _, err := NewImporter(q, storage.NewLocalStorage(t.TempDir())).Import(ctx, root, ImportOptions{Destination: "converted"}); err == nil {
+		t.Fatal("Import succeeded without storing its batch")
+	}
+	jobs, err := q.ListJobs(ctx, "", 0)
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("queued %d jobs, want 1: %v", len(jobs), err)
+	}
+	for _, job := range jobs {
+		if job.Status != queue.StatusCancelled {
+			t.Errorf("job %s is %s, want it cancelled", job.ID, job.Status)
+		}
+	}
+	if size, _ := q.Size(); size != 0 {
+		t.Errorf("%d jobs still pending", size)
+	}
+}
+
+func TestResolveRoot(t *testing.T) {
+	importDir := t.TempDir()
+	outside := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(importDir, "exports"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(importDir, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	resolvedDir, _ := filepath.EvalSymlinks(importDir)
+
+	tests := []struct {
+		importDir string
+		root      string
+		want      string
+		local     bool
+		err       error
+	}{
+		{importDir, "exports", filepath.Join(resolvedDir, "exports"), true, nil},
+		{importDir, filepath.Join(importDir, "exports"), filepath.Join(resolvedDir, "exports"), true, nil},
+		{importDir, "escape", "", false, ErrRootNotAllowed},
+		{importDir, outside, "", false, ErrRootNotAllowed},
+		{importDir, "/", "", false, ErrRootNotAllowed},
+		{importDir, "exports/../../etc", "", false, ErrInvalidPrefix},
+		{importDir, "mattersphere/2024", "mattersphere/2024", false, nil},
+		{"", importDir, "", false, ErrRootNotAllowed},
+		{"", "exports", "exports", false, nil},
+	}
+	for _, tt := range tests {
+		got, local, err := ResolveRoot(tt.importDir, tt.root)
+		if !errors.Is(err, tt.err) || got != tt.want || local != tt.local {
+			t.Errorf("ResolveRoot(%q, %q) = %q, %v, %v; want %q, %v, %v",
+				tt.importDir, tt.root, got, local, err, tt.want, tt.local, tt.err)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package mattersphere
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Item types of an export manifest
+const (
+	ItemPrecedent = "Precedents"
+	ItemScript    = "Scripts"
+)
+
+// Item is one entry of an export manifest
+type Item struct {
+	ID          int    `xml:"ID,attr"`
+	ParentID    int    `xml:"ParentID,attr"`
+	Code        string `xml:"Code,attr"`
+	Name        string `xml:"Name,attr"`
+	Type        string `xml:"Type,attr"`
+	Description string `xml:"Description,attr"`
+	Active      string `xml:"Active,attr"`
+}
+
+// Manifest is the ExportSandI.Manifest.xml listing every item of an export
+type Manifest struct {
+	Code  string `xml:"Code,attr"`
+	Items []Item `xml:"Items"`
+}
+
+// ItemDescription is the summary MatterSphere writes into the description of
+// precedent items
+type ItemDescription struct {
+	Text          string
+	Library       string
+	Type          string
+	Category      string
+	Subcategory   string
+	Minorcategory string
+}
+
+// descriptionLine matches the "Label : value" lines of an item description
+var descriptionLine = regexp.MustCompile(`^(Library|Type|Category|Subcategory|Minorcategory)\s*:\s*(.*)$`)
+
+// ParseManifest reads an export manifest
+func ParseManifest(r io.Reader) (*Manifest, error) {
+	var m Manifest
+	if err := xml.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	for i := range m.Items {
+		m.Items[i].Code = strings.TrimSpace(m.Items[i].Code)
+		m.Items[i].Name = strings.TrimSpace(m.Items[i].Name)
+	}
+	m.Code = strings.TrimSpace(m.Code)
+	return &m, nil
+}
+
+// ParseDescription splits a precedent item description such as
+// "Precedent [2605] \r\n\r\nLetter to client\r\n\r\nLibrary : \r\nType : BLANK\r\nCategory : Superannuation"
+// into its text and labelled values
+func ParseDescription(description string) ItemDescription {
+	var d ItemDescription
+	var text []string
+	for i, line := range strings.Split(strings.ReplaceAll(description, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if i == 0 && strings.HasPrefix(line, "Precedent [") {
+			continue
+		}
+		m := descriptionLine.FindStringSubmatch(line)
+		if m == nil {
+			if line != "" {
+				text = append(text, line)
+			}
+			continue
+		}
+		value := strings.TrimSpace(m[2])
+		switch m[1] {
+		case "Library":
+			d.Library = value
+		case "Type":
+			d.Type = value
+		case "Category":
+			d.Category = value
+		case "Subcategory":
+			d.Subcategory = value
+		case "Minorcategory":
+			d.Minorcategory = value
+		}
+	}
+	d.Text = strings.Join(text, " ")
+	return d
+}
+
+// PrecedentRecord is the PRECEDENT row of a precedent's manifest.xml DataSet
+type PrecedentRecord struct {
+	ID          string `xml:"PrecID"`
+	Title       string `xml:"PrecTitle"`
+	Type        string `xml:"PrecType"`
+	Category    string `xml:"PrecCategory"`
+	Subcategory string `xml:"PrecSubCategory"`
+	Library     string `xml:"PrecLibrary"`
+	Language    string `xml:"PrecLanguage"`
+	Addressee   string `xml:"PrecAddressee"`
+	AssocType   string `xml:"PrecAssocType"`
+	Description string `xml:"PrecDesc"`
+	Publisher   string `xml:"PrecPubName"`
+	Path        string `xml:"PrecPath"` // Relative to the precedent directory, e.g. "Company\3264.dot"
+	Extension   string `xml:"precExtension"`
+	Script      string `xml:"PrecScript"`
+	Preview     string `xml:"PrecPreview"` // Document text with Word field characters
+	Created     string `xml:"Created"`
+	Updated     string `xml:"Updated"`
+}
+
+// PrecedentManifest is a precedent's manifest.xml: the precedent row and the
+// lookup rows exported with it
+type PrecedentManifest struct {
+	Precedent PrecedentRecord `xml:"PRECEDENT"`
+	Directory struct {
+		Path string `xml:"dirPath"`
+	} `xml:"DIRECTORY"`
+	Language struct {
+		Code        string `xml:"langCode"`
+		Description string `xml:"langDesc"`
+	} `xml:"LANGUAGE"`
+	Multi []struct {
+		ChildID string `xml:"multiChildID"`
+		Order   int    `xml:"multiOrder"`
+	} `xml:"PRECEDENTMULTI"`
+}
+
+// ParsePrecedentManifest reads a precedent's manifest.xml
+func ParsePrecedentManifest(r io.Reader) (*PrecedentManifest, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read precedent manifest: %w", err)
+	}
+	var m PrecedentManifest
+	if err := xml.Unmarshal(escapeControls(data), &m); err != nil {
+		return nil, fmt.Errorf("failed to parse precedent manifest: %w", err)
+	}
+	m.Precedent.Preview = restoreControls(m.Precedent.Preview)
+	if m.Precedent.Language == "" {
+		m.Precedent.Language = m.Language.Code
+	}
+	return &m, nil
+}
+
+// controlBase is where control characters are parked in the private use area
+// while XML 1.0, which forbids them, is decoded
+const controlBase = 0xF000
+
+// controlRef matches character references
+var controlRef = regexp.MustCompile(`&#(x[0-9A-Fa-f]+|[0-9]+);`)
+
+// escapeControls replaces references to control characters, which .NET writes
+// for the field characters of previews, with private use characters
+func escapeControls(data []byte) []byte {
+	return controlRef.ReplaceAllFunc(data, func(ref []byte) []byte {
+		num := string(ref[2 : len(ref)-1])
+		base := 10
+		if strings.HasPrefix(num, "x") {
+			num, base = num[1:], 16
+		}
+		code, err := strconv.ParseInt(num, base, 32)
+		if err != nil || code >= 0x20 || code == '\t' || code == '\n' || code == '\r' {
+			return ref
+		}
+		return []byte(string(rune(controlBase + code)))
+	})
+}
+
+// restoreControls turns the private use characters of escapeControls back into
+// control characters
+func restoreControls(s string) string {
+	if !strings.ContainsFunc(s, isParkedControl) {
+		return s
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if isParkedControl(r) {
+			r -= controlBase
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func isParkedControl(r rune) bool {
+	return r >= controlBase && r < controlBase+0x20
+}
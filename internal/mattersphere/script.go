@@ -0,0 +1,266 @@
+package mattersphere
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/analyzer"
+)
+
+// Script difficulty settings: points per finding and the levels they reach
+const (
+	linesPerPoint     = 25 // Lines of code
+	wizardPoints      = 3  // Per wizard form prompting the user
+	promptPoints      = 2  // Per message box
+	tablePoints       = 4  // Per database table read directly
+	lookupPoints      = 3  // Per MatterSphere lookup API used
+	libraryPoints     = 2  // Per shared script library referenced
+	precedentJobPoint = 5  // Generates further precedents
+
+	mediumDifficulty = 6
+	highDifficulty   = 15
+)
+
+// ScriptUnit is one source file of a script
+type ScriptUnit struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+}
+
+// Script is a MatterSphere precedent script with what its code depends on
+type Script struct {
+	Code       string       `json:"code"`
+	Type       string       `json:"type,omitempty"` // e.g. PRECEDENT
+	Version    int          `json:"version,omitempty"`
+	Path       string       `json:"path,omitempty"` // Manifest the script was read from
+	Units      []ScriptUnit `json:"units,omitempty"`
+	References []string     `json:"references,omitempty"` // Assemblies and shared script libraries
+	Precedents []string     `json:"precedents,omitempty"` // Codes of the precedents using the script
+
+	Lines     int      `json:"lines"`               // Lines of code, without blanks and comments
+	Events    []string `json:"events,omitempty"`    // Precedent events handled, e.g. Merging
+	Wizards   []string `json:"wizards,omitempty"`   // Wizard forms shown to the user
+	Params    []string `json:"params,omitempty"`    // Merge parameters set or read
+	Fields    []string `json:"fields,omitempty"`    // Data read: wizard columns, doc variables, Table.field
+	Tables    []string `json:"tables,omitempty"`    // Tables read by SQL or extended data
+	Lookups   []string `json:"lookups,omitempty"`   // MatterSphere APIs used to look up data
+	Prompts   int      `json:"prompts,omitempty"`   // Message boxes
+	Generates bool     `json:"generates,omitempty"` // Runs further precedent jobs
+	Rating    Rating   `json:"difficulty"`
+}
+
+// Rating is the migration difficulty of a script
+type Rating struct {
+	Level   string   `json:"level"` // low, medium or high
+	Score   int      `json:"score"`
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// scriptManifest is a script's manifest.xml
+type scriptManifest struct {
+	Code    string `xml:"SCRIPTS>scrCode"`
+	Type    string `xml:"SCRIPTS>scrType"`
+	Version int    `xml:"SCRIPTS>scrVersion"`
+	Text    string `xml:"SCRIPTS>scrText"` // Script definition, an XML config document
+}
+
+// scriptConfig is the script definition held in scrText
+type scriptConfig struct {
+	Units []struct {
+		Filename string `xml:"filename,attr"`
+		Source   string `xml:",chardata"` // Base64
+	} `xml:"script>units>units"`
+	References       []string `xml:"script>references>reference"`
+	ScriptReferences []string `xml:"script>scriptReferences>reference"`
+}
+
+// Patterns over script source
+var (
+	lineComment   = regexp.MustCompile(`//[^\n]*`)
+	blockComment  = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	eventOverride = regexp.MustCompile(`override\s+\w+\s+(\w+)\s*\(`)
+	wizardCall    = regexp.MustCompile(`GetWizard\(\s*"([^"]+)"`)
+	paramName     = regexp.MustCompile(`Params(?:\.Add\(\s*|\[\s*)"([^"]+)"\s*[,\]]`)
+	rowColumn     = regexp.MustCompile(`Rows\[\s*\d+\s*\]\[\s*"([^"]+)"\s*\]`)
+	docVariable   = regexp.MustCompile(`DOCVARIABLE\(\s*\w+\s*,\s*"([^"]+)"`)
+	extendedVar   = regexp.MustCompile(`^\$\$\w+\.(\w+)\.`) // Doc variables of extended data, e.g. $$FILE.UDEXTFILE.FIELD
+	extendedData  = regexp.MustCompile(`ExtendedData\[\s*"([^"]+)"\s*\]\.GetExtendedData\(\s*"([^"]+)"`)
+	stringLiteral = regexp.MustCompile(`@"(?:[^"]|"")*"|"(?:[^"\\\n]|\\.)*"`)
+	sqlStatement  = regexp.MustCompile(`(?i)^\W*(select|update|insert|delete|exec|execute|with)\b`)
+	sqlTable      = regexp.MustCompile(`(?i)\b(?:from|join|update|into)\s+\[?([A-Za-z_][\w.]*)`)
+	lookupCall    = regexp.MustCompile(`\b(GetAssociate|AssocContact|SearchList|OMSDataSource|GetPrecedent|GetUser|getCodeLookupValue|ExecuteSQL|GetContact|GetClient)\s*\(`)
+	messageBox    = regexp.MustCompile(`\b(?:MessageBox\.Show|InputBox)\s*\(`)
+	precedentJob  = regexp.MustCompile(`\b(?:PrecedentJob|ProcessJob)\s*\(`)
+)
+
+// ParseScript reads a script's manifest.xml and analyzes its code
+func ParseScript(r io.Reader) (*Script, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script manifest: %w", err)
+	}
+	var m scriptManifest
+	if err := xml.Unmarshal(escapeControls(data), &m); err != nil {
+		return nil, fmt.Errorf("failed to parse script manifest: %w", err)
+	}
+
+	s := &Script{Code: strings.TrimSpace(m.Code), Type: m.Type, Version: m.Version}
+	if strings.TrimSpace(m.Text) != "" {
+		var config scriptConfig
+		if err := xml.Unmarshal([]byte(m.Text), &config); err != nil {
+			return nil, fmt.Errorf("failed to parse script %s definition: %w", s.Code, err)
+		}
+		for _, u := range config.Units {
+			source, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(u.Source), ""))
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode script %s unit %s: %w", s.Code, u.Filename, err)
+			}
+			// Units saved from the script cache carry the full cache path
+			name := u.Filename[strings.LastIndexAny(u.Filename, `\/`)+1:]
+			s.Units = append(s.Units, ScriptUnit{Name: name, Source: strings.TrimPrefix(string(source), "\ufeff")})
+		}
+		s.References = append(config.References, config.ScriptReferences...)
+	}
+	s.analyze()
+	return s, nil
+}
+
+// readScript reads the script manifest at path
+func readScript(path string) (*Script, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s, err := ParseScript(f)
+	if err != nil {
+		return nil, err
+	}
+	s.Path = path
+	return s, nil
+}
+
+// analyze finds what the script's code depends on and rates how hard it is to
+// migrate
+func (s *Script) analyze() {
+	found := make(map[string]map[string]bool)
+	add := func(kind, value string) {
+		if found[kind] == nil {
+			found[kind] = make(map[string]bool)
+		}
+		found[kind][value] = true
+	}
+
+	for _, unit := range s.Units {
+		code := lineComment.ReplaceAllString(blockComment.ReplaceAllString(unit.Source, ""), "")
+		for _, line := range strings.Split(code, "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "using ") && !strings.HasPrefix(line, "[assembly:") {
+				s.Lines++
+			}
+		}
+
+		for _, m := range eventOverride.FindAllStringSubmatch(code, -1) {
+			add("events", m[1])
+		}
+		for _, m := range wizardCall.FindAllStringSubmatch(code, -1) {
+			add("wizards", m[1])
+		}
+		for _, m := range paramName.FindAllStringSubmatch(code, -1) {
+			add("params", m[1])
+		}
+		for _, m := range rowColumn.FindAllStringSubmatch(code, -1) {
+			add("fields", m[1])
+		}
+		for _, m := range docVariable.FindAllStringSubmatch(code, -1) {
+			add("fields", m[1])
+			if t := extendedVar.FindStringSubmatch(m[1]); t != nil {
+				add("tables", t[1])
+			}
+		}
+		for _, m := range extendedData.FindAllStringSubmatch(code, -1) {
+			add("fields", m[1]+"."+m[2])
+			add("tables", m[1])
+		}
+		for _, literal := range stringLiteral.FindAllString(code, -1) {
+			if sqlStatement.MatchString(strings.Trim(literal, `@"`)) {
+				for _, m := range sqlTable.FindAllStringSubmatch(literal, -1) {
+					add("tables", m[1])
+				}
+			}
+		}
+		for _, m := range lookupCall.FindAllStringSubmatch(code, -1) {
+			add("lookups", m[1])
+		}
+		s.Prompts += len(messageBox.FindAllStringIndex(code, -1))
+		s.Generates = s.Generates || precedentJob.MatchString(code)
+	}
+
+	keys := func(kind string) []string {
+		var values []string
+		for v := range found[kind] {
+			values = append(values, v)
+		}
+		sort.Strings(values)
+		return values
+	}
+	s.Events, s.Wizards, s.Params = keys("events"), keys("wizards"), keys("params")
+	s.Fields, s.Tables, s.Lookups = keys("fields"), keys("tables"), keys("lookups")
+	s.Rating = s.rate()
+}
+
+// rate scores the script's migration difficulty
+func (s *Script) rate() Rating {
+	var r Rating
+	add := func(points int, reason string) {
+		if points > 0 {
+			r.Score += points
+			r.Reasons = append(r.Reasons, reason)
+		}
+	}
+	add(s.Lines/linesPerPoint, fmt.Sprintf("%d lines of code", s.Lines))
+	add(len(s.Wizards)*wizardPoints, fmt.Sprintf("prompts with wizard forms %s", strings.Join(s.Wizards, ", ")))
+	add(s.Prompts*promptPoints, fmt.Sprintf("%d message boxes", s.Prompts))
+	add(len(s.Tables)*tablePoints, fmt.Sprintf("reads tables %s", strings.Join(s.Tables, ", ")))
+	add(len(s.Lookups)*lookupPoints, fmt.Sprintf("looks up data with %s", strings.Join(s.Lookups, ", ")))
+	libraries := 0
+	for _, ref := range s.References {
+		if !strings.HasSuffix(strings.ToLower(ref), ".dll") {
+			libraries++
+		}
+	}
+	add(libraries*libraryPoints, "uses shared script libraries")
+	if s.Generates {
+		add(precedentJobPoint, "generates further precedents")
+	}
+
+	switch {
+	case r.Score >= highDifficulty:
+		r.Level = "high"
+	case r.Score >= mediumDifficulty:
+		r.Level = "medium"
+	default:
+		r.Level = "low"
+	}
+	return r
+}
+
+// Dependency summarizes the script for complexity reports and catalogs
+func (s *Script) Dependency() analyzer.ScriptDependency {
+	return analyzer.ScriptDependency{
+		Code:       s.Code,
+		Difficulty: s.Rating.Level,
+		Score:      s.Rating.Score,
+		Reasons:    s.Rating.Reasons,
+		Wizards:    s.Wizards,
+		Fields:     s.Fields,
+		Tables:     s.Tables,
+		Params:     s.Params,
+	}
+}
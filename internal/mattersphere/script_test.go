@@ -0,0 +1,77 @@
+package mattersphere
+
+import (
+	"encoding/base64"
+	"html"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// scriptManifestXML builds a script manifest.xml around C# source
+func scriptManifestXML(code, source string, references ...string) string {
+	config := `<config><script><units><units filename="C:\Cache\` + code + `\Editor.cs">` +
+		base64.StdEncoding.EncodeToString([]byte("\ufeff"+source)) + `</units></units><scriptReferences>`
+	for _, ref := range references {
+		config += "<reference>" + ref + "</reference>"
+	}
+	config += `</scriptReferences></script></config>`
+	return `<?xml version="1.0" standalone="yes"?><SCRIPTS><SCRIPTS><scrCode>` + code +
+		`</scrCode><scrType>PRECEDENT</scrType><scrVersion>3</scrVersion><scrText>` +
+		html.EscapeString(config) + `</scrText></SCRIPTS></SCRIPTS>`
+}
+
+func TestParseScript(t *testing.T) {
+	source := `using System;
+public partial class _2605 : FWBS.OMS.Script.PrecedentScriptType
+{
+	// Ask for the benefit types
+	protected override void Merging(object sender, FWBS.OMS.PrecedentLinkCancelEventArgs e)
+	{
+		DataTable dt = Wizards.GetWizard("udScrWiz0087Sel", null, EnquiryMode.Add, kvc) as DataTable;
+		e.PrecLink.Params.Add("Death", Convert.ToString(dt.Rows[0]["xpDeath"]));
+		e.PrecLink.Params.Add("_" + dc.ColumnName, "");
+		string name = MBCommon.DOCVARIABLE(e, "$$FILE.UDEXTSUPENQINJ.UDROLE");
+		var ds = new FWBS.OMS.SourceEngine.OMSDataSource(@"select c.contName from udFileExtended f
+			join dbContact c on c.contID = f.contID");
+		if (name == "") MessageBox.Show("No role recorded");
+	}
+}`
+	s, err := ParseScript(strings.NewReader(scriptManifestXML("_2605", source, "MBCommon")))
+	if err != nil {
+		t.Fatalf("ParseScript failed: %v", err)
+	}
+
+	if s.Code != "_2605" || s.Version != 3 || len(s.Units) != 1 || s.Units[0].Name != "Editor.cs" ||
+		!strings.HasPrefix(s.Units[0].Source, "using System;") {
+		t.Fatalf("script = %+v", s)
+	}
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{"events", s.Events, []string{"Merging"}},
+		{"wizards", s.Wizards, []string{"udScrWiz0087Sel"}},
+		{"params", s.Params, []string{"Death"}},
+		{"fields", s.Fields, []string{"$$FILE.UDEXTSUPENQINJ.UDROLE", "xpDeath"}},
+		{"tables", s.Tables, []string{"UDEXTSUPENQINJ", "dbContact", "udFileExtended"}},
+		{"lookups", s.Lookups, []string{"OMSDataSource"}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+	if s.Prompts != 1 || s.Lines != 13 {
+		t.Errorf("prompts = %d, lines = %d, want 1 and 13", s.Prompts, s.Lines)
+	}
+
+	// 3 per wizard, 2 per prompt, 4 per table, 3 per lookup, 2 per script library
+	if s.Rating.Score != 3+2+12+3+2 || s.Rating.Level != "high" {
+		t.Errorf("rating = %+v, want high with score 22", s.Rating)
+	}
+	if d := s.Dependency(); d.Code != "_2605" || d.Difficulty != "high" || len(d.Tables) != 3 {
+		t.Errorf("dependency = %+v", d)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/alterspective-engine/dot-to-docx-converter/internal/analyzer"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/field"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/inventory"
 )
 
 type FieldType string
@@ -43,6 +45,7 @@ type TemplateMetadata struct {
 	ConversionNotes []string             `json:"conversionNotes"`
 	Statistics      ConversionStatistics `json:"statistics"`
 	Mappings        map[string]string    `json:"mappings,omitempty"`
+	Inventory       *inventory.Inventory `json:"inventory,omitempty"`
 }
 
 type ComplexityInfo struct {
@@ -76,14 +79,7 @@ type MetadataExtractor struct {
 func NewMetadataExtractor() *MetadataExtractor {
 	return &MetadataExtractor{
 		patterns: map[string]*regexp.Regexp{
-			"mergefield":  regexp.MustCompile(`(?i)«([^»]+)»|{{\s*([^}]+)\s*}}|\bMERGEFIELD\s+(\w+)`),
-			"formula":     regexp.MustCompile(`(?i)=\s*(?:SUM|AVG|COUNT|MAX|MIN|IF)\s*\([^)]+\)`),
-			"ifstatement": regexp.MustCompile(`(?i)(?:IF\s+[^{]+\s*{|{{\s*#if\s+[^}]+}})`),
-			"docproperty": regexp.MustCompile(`(?i)\bDOCPROPERTY\s+"?([^"\s]+)"?`),
-			"docvariable": regexp.MustCompile(`(?i)\bDOCVARIABLE\s+"?([^"\s]+)"?`),
-			"ask":         regexp.MustCompile(`(?i)\bASK\s+(\w+)\s+"([^"]+)"`),
-			"fillin":      regexp.MustCompile(`(?i)\bFILLIN\s+"([^"]+)"`),
-			"ref":         regexp.MustCompile(`(?i)\bREF\s+(\w+)`),
+			"placeholder": regexp.MustCompile(`(?i)«([^»]+)»|{{\s*([^}]+)\s*}}`),
 		},
 	}
 }
@@ -109,11 +105,17 @@ func (m *MetadataExtractor) ExtractMetadata(content []byte, filename string, doc
 		text = string(content)
 	}
 
-	m.extractMergeFields(text, &metadata.Fields)
-	m.extractFormulas(text, &metadata.Fields)
-	m.extractIFStatements(text, &metadata.Fields)
-	m.extractDocProperties(text, &metadata.Fields)
-	m.extractOtherFields(text, &metadata.Fields)
+	// Positions and contexts refer to the text, so its fields are parsed here
+	tree := field.ParseText(text)
+	m.extractMergeFields(text, tree, &metadata.Fields)
+	m.extractFormulas(text, tree, &metadata.Fields)
+	m.extractIFStatements(text, tree, &metadata.Fields)
+	m.extractDocProperties(tree, &metadata.Fields)
+	m.extractOtherFields(tree, &metadata.Fields)
+
+	if docInfo.Inventory != nil && !docInfo.Inventory.Empty() {
+		metadata.Inventory = docInfo.Inventory
+	}
 
 	m.generateSuggestedMappings(metadata)
 	m.calculateStatistics(metadata, docInfo)
@@ -134,138 +136,109 @@ func (m *MetadataExtractor) detectFormat(content []byte) string {
 	return "Unknown"
 }
 
-func (m *MetadataExtractor) extractMergeFields(text string, fields *FieldCollection) {
-	matches := m.patterns["mergefield"].FindAllStringSubmatchIndex(text, -1)
+func (m *MetadataExtractor) extractMergeFields(text string, tree []*field.Field, fields *FieldCollection) {
 	seen := make(map[string]bool)
-
-	for _, match := range matches {
-		if match[0] < 0 || match[1] > len(text) {
-			continue
-		}
-
-		original := text[match[0]:match[1]]
+	add := func(original, fieldName string, position int) {
 		if seen[original] {
-			continue
+			return
 		}
 		seen[original] = true
 
-		fieldName := m.extractFieldName(original)
-		context := m.extractContext(text, match[0], 50)
-
-		field := Field{
+		fields.MergeFields = append(fields.MergeFields, Field{
 			Original:         original,
 			Type:             FieldTypeMergeField,
-			Position:         match[0],
-			Context:          context,
+			Position:         position,
+			Context:          m.extractContext(text, position, 50),
 			ExtractedValue:   fieldName,
 			SuggestedMapping: m.suggestMapping(fieldName, FieldTypeMergeField),
-		}
-		fields.MergeFields = append(fields.MergeFields, field)
+		})
 	}
-}
 
-func (m *MetadataExtractor) extractFormulas(text string, fields *FieldCollection) {
-	matches := m.patterns["formula"].FindAllStringSubmatchIndex(text, -1)
-
-	for _, match := range matches {
-		if match[0] < 0 || match[1] > len(text) {
-			continue
-		}
+	for _, f := range field.OfType(tree, field.TypeMergeField) {
+		add(source(text, f), f.Name, f.Pos.Offset)
+	}
 
+	// Merge results and template placeholders outside Word fields
+	for _, match := range m.patterns["placeholder"].FindAllStringSubmatchIndex(text, -1) {
 		original := text[match[0]:match[1]]
-		context := m.extractContext(text, match[0], 50)
+		add(original, m.extractFieldName(original), match[0])
+	}
+}
 
-		field := Field{
+func (m *MetadataExtractor) extractFormulas(text string, tree []*field.Field, fields *FieldCollection) {
+	for _, f := range field.OfType(tree, field.TypeFormula) {
+		original := source(text, f)
+		fields.Formulas = append(fields.Formulas, Field{
 			Original:         original,
 			Type:             FieldTypeFormula,
-			Position:         match[0],
-			Context:          context,
+			Position:         f.Pos.Offset,
+			Context:          m.extractContext(text, f.Pos.Offset, 50),
 			SuggestedMapping: m.suggestFormulaMapping(original),
-		}
-		fields.Formulas = append(fields.Formulas, field)
+		})
 	}
 }
 
-func (m *MetadataExtractor) extractIFStatements(text string, fields *FieldCollection) {
-	matches := m.patterns["ifstatement"].FindAllStringSubmatchIndex(text, -1)
-
-	for _, match := range matches {
-		if match[0] < 0 || match[1] > len(text) {
-			continue
-		}
-
-		original := text[match[0]:match[1]]
-		context := m.extractContext(text, match[0], 100)
-
-		field := Field{
+func (m *MetadataExtractor) extractIFStatements(text string, tree []*field.Field, fields *FieldCollection) {
+	for _, f := range field.OfType(tree, field.TypeIF) {
+		original := source(text, f)
+		fields.IFStatements = append(fields.IFStatements, Field{
 			Original:         original,
 			Type:             FieldTypeIF,
-			Position:         match[0],
-			Context:          context,
-			SuggestedMapping: m.suggestIFMapping(original),
-		}
-		fields.IFStatements = append(fields.IFStatements, field)
+			Position:         f.Pos.Offset,
+			Context:          m.extractContext(text, f.Pos.Offset, 100),
+			SuggestedMapping: m.suggestIFMapping(f),
+		})
 	}
 }
 
-func (m *MetadataExtractor) extractDocProperties(text string, fields *FieldCollection) {
-	matches := m.patterns["docproperty"].FindAllStringSubmatchIndex(text, -1)
-
-	for _, match := range matches {
-		if match[0] < 0 || match[1] > len(text) {
-			continue
-		}
-
-		original := text[match[0]:match[1]]
-		propName := m.extractPropertyName(original)
-
-		field := Field{
-			Original:         original,
+func (m *MetadataExtractor) extractDocProperties(tree []*field.Field, fields *FieldCollection) {
+	for _, f := range field.OfType(tree, field.TypeDocProperty) {
+		fields.DocProperties = append(fields.DocProperties, Field{
+			Original:         f.String(),
 			Type:             FieldTypeDocProperty,
-			Position:         match[0],
-			ExtractedValue:   propName,
-			SuggestedMapping: fmt.Sprintf("{{document.%s}}", strings.ToLower(propName)),
-		}
-		fields.DocProperties = append(fields.DocProperties, field)
+			Position:         f.Pos.Offset,
+			ExtractedValue:   f.Name,
+			SuggestedMapping: fmt.Sprintf("{{document.%s}}", strings.ToLower(f.Name)),
+		})
 	}
 }
 
-func (m *MetadataExtractor) extractOtherFields(text string, fields *FieldCollection) {
-	for fieldType, pattern := range m.patterns {
-		if fieldType == "mergefield" || fieldType == "formula" || fieldType == "ifstatement" || fieldType == "docproperty" {
-			continue
+func (m *MetadataExtractor) extractOtherFields(tree []*field.Field, fields *FieldCollection) {
+	types := map[string]FieldType{
+		field.TypeDocVariable: FieldTypeDocVariable,
+		field.TypeAsk:         FieldTypeASK,
+		field.TypeFillIn:      FieldTypeFILLIN,
+		field.TypeRef:         FieldTypeREF,
+	}
+
+	field.Walk(tree, func(f *field.Field) bool {
+		fType, ok := types[f.Type]
+		if !ok {
+			return true
 		}
 
-		matches := pattern.FindAllStringSubmatchIndex(text, -1)
-		for _, match := range matches {
-			if match[0] < 0 || match[1] > len(text) {
-				continue
-			}
-
-			original := text[match[0]:match[1]]
-			var fType FieldType
-			switch fieldType {
-			case "docvariable":
-				fType = FieldTypeDocVariable
-			case "ask":
-				fType = FieldTypeASK
-			case "fillin":
-				fType = FieldTypeFILLIN
-			case "ref":
-				fType = FieldTypeREF
-			default:
-				continue
-			}
-
-			field := Field{
-				Original:         original,
-				Type:             fType,
-				Position:         match[0],
-				SuggestedMapping: m.suggestGenericMapping(original, fType),
-			}
-			fields.OtherFields = append(fields.OtherFields, field)
+		extracted := Field{
+			Original:         f.String(),
+			Type:             fType,
+			Position:         f.Pos.Offset,
+			ExtractedValue:   f.Name,
+			SuggestedMapping: m.suggestGenericMapping(f.String(), fType),
+		}
+		if fType == FieldTypeDocVariable {
+			fields.DocVariables = append(fields.DocVariables, extracted)
+		} else {
+			fields.OtherFields = append(fields.OtherFields, extracted)
 		}
+		return true
+	})
+}
+
+// source returns the text a field was parsed from
+func source(text string, f *field.Field) string {
+	if f.Pos.Offset >= 0 && f.End.Offset <= len(text) && f.Pos.Offset < f.End.Offset {
+		return text[f.Pos.Offset:f.End.Offset]
 	}
+	return f.String()
 }
 
 func (m *MetadataExtractor) extractContext(text string, position int, contextSize int) string {
@@ -305,14 +278,6 @@ func (m *MetadataExtractor) extractFieldName(text string) string {
 	return text
 }
 
-func (m *MetadataExtractor) extractPropertyName(text string) string {
-	parts := strings.Fields(text)
-	if len(parts) > 1 {
-		return strings.Trim(parts[1], `"`)
-	}
-	return ""
-}
-
 func (m *MetadataExtractor) suggestMapping(fieldName string, fieldType FieldType) string {
 	fieldName = strings.ToLower(fieldName)
 	fieldName = strings.ReplaceAll(fieldName, " ", "_")
@@ -364,9 +329,16 @@ func (m *MetadataExtractor) suggestFormulaMapping(formula string) string {
 	return "{{calculation.custom}}"
 }
 
-func (m *MetadataExtractor) suggestIFMapping(ifStatement string) string {
-	if strings.Contains(strings.ToLower(ifStatement), "client") {
-		if strings.Contains(strings.ToLower(ifStatement), "type") {
+func (m *MetadataExtractor) suggestIFMapping(f *field.Field) string {
+	// Only the condition decides the mapping, not the text of the branches
+	condition := f.Code
+	if c := f.Condition; c != nil {
+		condition = c.Left.Text + " " + c.Operator + " " + c.Right.Text
+	}
+
+	condition = strings.ToLower(condition)
+	if strings.Contains(condition, "client") {
+		if strings.Contains(condition, "type") {
 			return "{{#if client.type}}"
 		}
 		return "{{#if client}}"
@@ -417,13 +389,18 @@ func (m *MetadataExtractor) calculateStatistics(metadata *TemplateMetadata, docI
 		successRate = 85
 	}
 
+	imageCount := 0
+	if docInfo.Inventory != nil {
+		imageCount = len(docInfo.Inventory.Images)
+	}
+
 	metadata.Statistics = ConversionStatistics{
 		TotalFields:      totalFields,
 		MappableFields:   mappableFields,
 		ComplexFields:    complexFields,
 		ExtractedText:    len(docInfo.Text),
 		TableCount:       docInfo.TableCount,
-		ImageCount:       0, // ImageCount field not yet available in DocumentInfo
+		ImageCount:       imageCount,
 		EstimatedSuccess: successRate,
 	}
 }
@@ -450,6 +427,13 @@ func (m *MetadataExtractor) generateConversionNotes(metadata *TemplateMetadata,
 			"Limited text extracted - document may contain embedded objects")
 	}
 
+	if metadata.Inventory != nil {
+		for _, link := range metadata.Inventory.ExternalPaths() {
+			metadata.ConversionNotes = append(metadata.ConversionNotes,
+				fmt.Sprintf("%s link to %s will not resolve on other systems", strings.ReplaceAll(link.Kind, "_", " "), link.Target))
+		}
+	}
+
 	for _, issue := range report.Issues {
 		if issue.Severity == "high" || issue.Severity == "critical" {
 			metadata.ConversionNotes = append(metadata.ConversionNotes, issue.Description)
@@ -6,15 +6,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
-	"sort"
 	"strings"
 	"time"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/clauses"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/mattersphere"
 )
 
 // ContentBlockGenerator creates reusable Sharedo content blocks
 type ContentBlockGenerator struct {
 	fieldMapper      *FieldMapper
 	variableDetector *VariableDetector
+	miner            *clauses.Miner
 	blockTemplates   map[string]*BlockTemplate
 	generatedBlocks  map[string]*SharedoContentBlock
 }
@@ -32,12 +35,13 @@ type BlockTemplate struct {
 
 // BlockVariable represents a variable within a block
 type BlockVariable struct {
-	Name         string `json:"name"`
-	Type         string `json:"type"`
-	DefaultValue string `json:"defaultValue"`
-	Required     bool   `json:"required"`
-	Position     int    `json:"position"`
-	MappedTo     string `json:"mappedTo"`
+	Name         string   `json:"name"`
+	Type         string   `json:"type"`
+	DefaultValue string   `json:"defaultValue"`
+	Required     bool     `json:"required"`
+	Position     int      `json:"position"`
+	MappedTo     string   `json:"mappedTo"`
+	Values       []string `json:"values,omitempty"` // Values seen in the source documents
 }
 
 // BlockUsageStats tracks block usage
@@ -95,13 +99,17 @@ type ContentAnalysisResult struct {
 
 // CommonBlock represents a commonly occurring content block
 type CommonBlock struct {
-	Hash       string   `json:"hash"`
-	Content    string   `json:"content"`
-	Type       string   `json:"type"`
-	Frequency  int      `json:"frequency"`
-	Documents  []string `json:"documents"`
-	Variables  []string `json:"variables"`
-	Confidence float64  `json:"confidence"`
+	Hash           string               `json:"hash"`
+	Content        string               `json:"content"`
+	Type           string               `json:"type"`
+	Frequency      int                  `json:"frequency"` // Documents using the block
+	Documents      []string             `json:"documents"`
+	Variables      []string             `json:"variables"`
+	Confidence     float64              `json:"confidence"`
+	Paragraphs     int                  `json:"paragraphs"`
+	Exact          bool                 `json:"exact"` // Occurrences only differ in merge fields
+	BlockVariables []BlockVariable      `json:"blockVariables,omitempty"`
+	Occurrences    []clauses.Occurrence `json:"occurrences,omitempty"`
 }
 
 // VariableInfo contains information about detected variables
@@ -126,6 +134,7 @@ func NewContentBlockGenerator() *ContentBlockGenerator {
 	return &ContentBlockGenerator{
 		fieldMapper:      NewFieldMapper(),
 		variableDetector: newVariableDetector(),
+		miner:            clauses.NewMiner(),
 		blockTemplates:   make(map[string]*BlockTemplate),
 		generatedBlocks:  make(map[string]*SharedoContentBlock),
 	}
@@ -140,46 +149,19 @@ func (g *ContentBlockGenerator) AnalyzeContent(documents []DocumentContent) *Con
 		Recommendations: []string{},
 	}
 
-	// Find common blocks across documents
-	blockMap := make(map[string]*CommonBlock)
-
-	for _, doc := range documents {
-		// Extract potential blocks
-		blocks := g.extractPotentialBlocks(doc)
-
-		for _, block := range blocks {
-			hash := g.hashContent(block.Content)
-
-			if existing, exists := blockMap[hash]; exists {
-				existing.Frequency++
-				existing.Documents = append(existing.Documents, doc.Filename)
-			} else {
-				blockMap[hash] = &CommonBlock{
-					Hash:       hash,
-					Content:    block.Content,
-					Type:       block.Type,
-					Frequency:  1,
-					Documents:  []string{doc.Filename},
-					Variables:  g.variableDetector.detect(block.Content),
-					Confidence: 0.0,
-				}
-			}
+	// Mine clauses reused across documents, most reused first
+	sources := make([]clauses.Document, len(documents))
+	for i, doc := range documents {
+		sources[i] = clauses.Document{
+			Name:    doc.Filename,
+			Text:    doc.Content,
+			Preview: doc.Metadata[mattersphere.MetaSource] == "preview",
 		}
 	}
-
-	// Convert map to slice and calculate confidence
-	for _, block := range blockMap {
-		if block.Frequency > 1 {
-			block.Confidence = float64(block.Frequency) / float64(len(documents))
-			result.CommonBlocks = append(result.CommonBlocks, *block)
-		}
+	for _, clause := range g.miner.Mine(sources) {
+		result.CommonBlocks = append(result.CommonBlocks, g.commonBlock(clause, len(documents)))
 	}
 
-	// Sort by frequency
-	sort.Slice(result.CommonBlocks, func(i, j int) bool {
-		return result.CommonBlocks[i].Frequency > result.CommonBlocks[j].Frequency
-	})
-
 	// Detect variables across all documents
 	g.analyzeVariables(documents, result)
 
@@ -189,6 +171,29 @@ func (g *ContentBlockGenerator) AnalyzeContent(documents []DocumentContent) *Con
 	return result
 }
 
+// SetMiningOptions overrides the documents a clause must be reused by and the
+// similarity of paragraph variants; zero values keep the defaults
+func (g *ContentBlockGenerator) SetMiningOptions(minDocuments int, similarity float64) {
+	if minDocuments > 0 {
+		g.miner.MinDocuments = minDocuments
+	}
+	if similarity > 0 {
+		g.miner.Similarity = similarity
+	}
+}
+
+// MineBlocks generates a content block candidate for every clause reused
+// across the documents, most reused first
+func (g *ContentBlockGenerator) MineBlocks(documents []DocumentContent) []*SharedoContentBlock {
+	analysis := g.AnalyzeContent(documents)
+	blocks := make([]*SharedoContentBlock, 0, len(analysis.CommonBlocks))
+	for i, commonBlock := range analysis.CommonBlocks {
+		blockName := fmt.Sprintf("%s_block_%d", commonBlock.Type, i+1)
+		blocks = append(blocks, g.GenerateContentBlock(commonBlock, blockName))
+	}
+	return blocks
+}
+
 // GenerateContentBlock creates a Sharedo content block
 func (g *ContentBlockGenerator) GenerateContentBlock(commonBlock CommonBlock, name string) *SharedoContentBlock {
 	blockID := g.generateBlockID(name)
@@ -203,12 +208,21 @@ func (g *ContentBlockGenerator) GenerateContentBlock(commonBlock CommonBlock, na
 			"blockType": commonBlock.Type,
 		})
 
-		variables[varName] = map[string]interface{}{
+		definition := map[string]interface{}{
 			"mapped":     mappingResult.Mapped,
 			"type":       g.inferVariableType(varName),
 			"required":   true,
 			"confidence": mappingResult.Confidence,
 		}
+		for _, v := range commonBlock.BlockVariables {
+			if v.Name == varName {
+				definition["type"] = v.Type
+				definition["position"] = v.Position
+				definition["default"] = v.DefaultValue
+				definition["values"] = v.Values
+			}
+		}
+		variables[varName] = definition
 	}
 
 	block := &SharedoContentBlock{
@@ -235,6 +249,9 @@ func (g *ContentBlockGenerator) GenerateContentBlock(commonBlock CommonBlock, na
 			"sourceDocuments": commonBlock.Documents,
 			"frequency":       commonBlock.Frequency,
 			"confidence":      commonBlock.Confidence,
+			"paragraphs":      commonBlock.Paragraphs,
+			"exact":           commonBlock.Exact,
+			"occurrences":     commonBlock.Occurrences,
 			"autoGenerated":   true,
 			"generatedAt":     time.Now(),
 		},
@@ -246,36 +263,39 @@ func (g *ContentBlockGenerator) GenerateContentBlock(commonBlock CommonBlock, na
 	return block
 }
 
-// extractPotentialBlocks identifies potential content blocks
-func (g *ContentBlockGenerator) extractPotentialBlocks(doc DocumentContent) []BlockCandidate {
-	candidates := []BlockCandidate{}
-
-	// Extract header
-	if header := g.extractHeader(doc.Content); header != "" {
-		candidates = append(candidates, BlockCandidate{
-			Content: header,
-			Type:    "header",
-		})
-	}
-
-	// Extract footer
-	if footer := g.extractFooter(doc.Content); footer != "" {
-		candidates = append(candidates, BlockCandidate{
-			Content: footer,
-			Type:    "footer",
-		})
+// commonBlock describes a mined clause as a common block of a library of
+// total documents
+func (g *ContentBlockGenerator) commonBlock(clause clauses.Clause, total int) CommonBlock {
+	block := CommonBlock{
+		Hash:        g.hashContent(clause.Text),
+		Content:     clause.Text,
+		Type:        clause.Type,
+		Frequency:   clause.Reuse,
+		Documents:   clause.Documents,
+		Variables:   []string{},
+		Confidence:  float64(clause.Reuse) / float64(total),
+		Paragraphs:  clause.Paragraphs,
+		Exact:       clause.Exact,
+		Occurrences: clause.Occurrences,
 	}
-
-	// Extract clauses and paragraphs
-	clauses := g.extractClauses(doc.Content)
-	for _, clause := range clauses {
-		candidates = append(candidates, BlockCandidate{
-			Content: clause,
-			Type:    "clause",
-		})
+	for _, v := range clause.Variables {
+		variable := BlockVariable{
+			Name:     v.Name,
+			Type:     v.Type,
+			Required: true,
+			Position: v.Offset,
+			Values:   v.Values,
+		}
+		if v.Field != "" {
+			variable.Type = g.inferVariableType(v.Field)
+			variable.MappedTo = g.fieldMapper.MapField(v.Field, map[string]interface{}{"blockType": clause.Type}).Mapped
+		} else if len(v.Values) > 0 {
+			variable.DefaultValue = v.Values[0]
+		}
+		block.Variables = append(block.Variables, v.Name)
+		block.BlockVariables = append(block.BlockVariables, variable)
 	}
-
-	return candidates
+	return block
 }
 
 // convertToSharedo converts content to Sharedo template format
@@ -284,8 +304,11 @@ func (g *ContentBlockGenerator) convertToSharedo(content string, variables []str
 
 	// Replace variable patterns with Sharedo syntax
 	for _, variable := range variables {
-		// Map the field
+		// Map the field; unmapped variables stay block parameters
 		mapping := g.fieldMapper.MapField(variable, nil)
+		if mapping.Mapped == "" {
+			continue
+		}
 
 		// Replace all occurrences
 		patterns := []string{
@@ -312,8 +335,8 @@ func (g *ContentBlockGenerator) convertToSharedo(content string, variables []str
 
 // convertConditionals converts IF statements to Sharedo format
 func (g *ContentBlockGenerator) convertConditionals(content string) string {
-	// Pattern for IF statements
-	ifPattern := regexp.MustCompile(`(?i)IF\s+([^{]+)\s*{([^}]+)}`)
+	// Pattern for IF statements; {{placeholders}} are not bodies
+	ifPattern := regexp.MustCompile(`(?i)\bIF\s+([^{}\n]+?)\s*{([^{}]+)}`)
 
 	return ifPattern.ReplaceAllStringFunc(content, func(match string) string {
 		parts := ifPattern.FindStringSubmatch(match)
@@ -331,7 +354,7 @@ func (g *ContentBlockGenerator) convertConditionals(content string) string {
 // convertLoops converts loop structures to Sharedo format
 func (g *ContentBlockGenerator) convertLoops(content string) string {
 	// Pattern for loops
-	loopPattern := regexp.MustCompile(`(?i)FOREACH\s+([^{]+)\s*{([^}]+)}`)
+	loopPattern := regexp.MustCompile(`(?i)\bFOREACH\s+([^{}\n]+?)\s*{([^{}]+)}`)
 
 	return loopPattern.ReplaceAllStringFunc(content, func(match string) string {
 		parts := loopPattern.FindStringSubmatch(match)
@@ -430,33 +453,6 @@ func (g *ContentBlockGenerator) documentParameters(variables map[string]interfac
 	return params
 }
 
-// Extract methods
-
-func (g *ContentBlockGenerator) extractHeader(content string) string {
-	lines := strings.Split(content, "\n")
-	if len(lines) > 10 {
-		// Take first 10 lines as potential header
-		return strings.Join(lines[:10], "\n")
-	}
-	return ""
-}
-
-func (g *ContentBlockGenerator) extractFooter(content string) string {
-	lines := strings.Split(content, "\n")
-	if len(lines) > 10 {
-		// Take last 10 lines as potential footer
-		return strings.Join(lines[len(lines)-10:], "\n")
-	}
-	return ""
-}
-
-func (g *ContentBlockGenerator) extractClauses(content string) []string {
-	// Simple clause extraction based on numbering
-	clausePattern := regexp.MustCompile(`(?m)^\d+\..*?(?:\n\n|\z)`)
-	matches := clausePattern.FindAllString(content, -1)
-	return matches
-}
-
 func (g *ContentBlockGenerator) analyzeVariables(documents []DocumentContent, result *ContentAnalysisResult) {
 	for _, doc := range documents {
 		variables := g.variableDetector.detect(doc.Content)
@@ -545,11 +541,6 @@ type DocumentContent struct {
 	Metadata map[string]interface{}
 }
 
-type BlockCandidate struct {
-	Content string
-	Type    string
-}
-
 // ExportBlocks exports generated blocks to JSON
 func (g *ContentBlockGenerator) ExportBlocks(filename string) error {
 	blocks := make([]*SharedoContentBlock, 0, len(g.generatedBlocks))
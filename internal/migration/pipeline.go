@@ -14,6 +14,7 @@ import (
 
 	"github.com/alterspective-engine/dot-to-docx-converter/internal/analyzer"
 	"github.com/alterspective-engine/dot-to-docx-converter/internal/cataloger"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/mattersphere"
 )
 
 // ConversionPipeline orchestrates the end-to-end conversion process
@@ -166,7 +167,7 @@ func (p *ConversionPipeline) Execute(ctx context.Context) (*PipelineResult, erro
 
 	// Phase 2: Content Block Generation
 	log.Println("Phase 2: Generating content blocks...")
-	blocks := p.generateContentBlocks(catalog)
+	blocks := p.generateContentBlocks(documents)
 	result.GeneratedBlocks = blocks
 	p.metrics.ContentBlocks = len(blocks)
 
@@ -211,8 +212,26 @@ func (p *ConversionPipeline) initializeStages() {
 	}
 }
 
-// loadDocuments loads all documents from input directory
+// loadDocuments loads all documents from input directory. MatterSphere exports
+// under it are read with their precedent metadata instead.
 func (p *ConversionPipeline) loadDocuments() ([]cataloger.DocumentData, error) {
+	exports, err := mattersphere.Find(p.config.InputDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(exports) > 0 {
+		var documents []cataloger.DocumentData
+		for _, dir := range exports {
+			export, err := mattersphere.Open(dir)
+			if err != nil {
+				log.Printf("Warning: Failed to read MatterSphere export %s: %v", dir, err)
+				continue
+			}
+			documents = append(documents, export.Documents(p.extractor)...)
+		}
+		return documents, nil
+	}
+
 	files, err := filepath.Glob(filepath.Join(p.config.InputDir, "*.dot"))
 	if err != nil {
 		return nil, err
@@ -262,39 +281,24 @@ func (p *ConversionPipeline) analyzeDocuments(ctx context.Context, documents []c
 	return catalog, nil
 }
 
-// generateContentBlocks creates reusable content blocks
-func (p *ConversionPipeline) generateContentBlocks(catalog *cataloger.DocumentCatalog) []*SharedoContentBlock {
-	blocks := []*SharedoContentBlock{}
-
-	// Convert catalog data to format for block generator
-	docContents := make([]DocumentContent, 0, len(catalog.DocumentProfiles))
-	for _, profile := range catalog.DocumentProfiles {
+// generateContentBlocks mines the documents' text for clauses reused across
+// them and creates a content block candidate for each, most reused first
+func (p *ConversionPipeline) generateContentBlocks(documents []cataloger.DocumentData) []*SharedoContentBlock {
+	docContents := make([]DocumentContent, 0, len(documents))
+	for _, doc := range documents {
 		// Convert metadata from map[string]string to map[string]interface{}
 		metadata := make(map[string]interface{})
-		for k, v := range profile.Metadata {
+		for k, v := range doc.Metadata {
 			metadata[k] = v
 		}
-		// Find corresponding document content
 		docContents = append(docContents, DocumentContent{
-			Filename: profile.Filename,
-			Content:  "", // Would be populated from actual content
+			Filename: doc.Filename,
+			Content:  doc.ExtractedText,
 			Metadata: metadata,
 		})
 	}
 
-	// Analyze for block opportunities
-	analysis := p.blockGenerator.AnalyzeContent(docContents)
-
-	// Generate blocks for high-frequency content
-	for i, commonBlock := range analysis.CommonBlocks {
-		if commonBlock.Confidence > 0.5 {
-			blockName := fmt.Sprintf("%s_block_%d", commonBlock.Type, i+1)
-			block := p.blockGenerator.GenerateContentBlock(commonBlock, blockName)
-			blocks = append(blocks, block)
-		}
-	}
-
-	return blocks
+	return p.blockGenerator.MineBlocks(docContents)
 }
 
 // mapFields creates field mappings
@@ -391,9 +395,13 @@ func (p *ConversionPipeline) processSingleDocument(ctx context.Context, doc cata
 	// Apply content blocks
 	blocksUsed := []string{}
 	for _, block := range blocks {
-		// Check if block content matches document
-		if strings.Contains(doc.ExtractedText, block.Name) {
-			blocksUsed = append(blocksUsed, block.ID)
+		// Blocks were mined from the documents that use them
+		sources, _ := block.Metadata["sourceDocuments"].([]string)
+		for _, source := range sources {
+			if source == doc.Filename {
+				blocksUsed = append(blocksUsed, block.ID)
+				break
+			}
 		}
 	}
 	result.BlocksUsed = blocksUsed
@@ -406,6 +414,12 @@ func (p *ConversionPipeline) processSingleDocument(ctx context.Context, doc cata
 		"processedAt":     time.Now(),
 		"pipelineVersion": "2.1.0",
 	}
+	if len(doc.Metadata) > 0 {
+		metadata["sourceMetadata"] = doc.Metadata
+	}
+	if len(doc.Scripts) > 0 {
+		metadata["scripts"] = doc.Scripts
+	}
 
 	// Save metadata
 	if metadataJSON, err := json.MarshalIndent(metadata, "", "  "); err == nil {
@@ -502,6 +516,20 @@ TOP MAPPED FIELDS:
 		fieldCount++
 	}
 
+	// Add template families; one canonical template per family needs converting
+	if catalog != nil && len(catalog.Families) > 0 {
+		report += fmt.Sprintf("\nTEMPLATE FAMILIES\n-----------------\nFamilies: %d (%d variants)\n",
+			catalog.Statistics.TemplateFamilies, catalog.Statistics.FamilyVariants)
+		for i, family := range catalog.Families {
+			if i >= 10 {
+				report += fmt.Sprintf("  ... and %d more families\n", len(catalog.Families)-10)
+				break
+			}
+			report += fmt.Sprintf("  - %s: %d members, %.0f%% average similarity\n",
+				family.Canonical, len(family.Members), family.AverageSimilarity*100)
+		}
+	}
+
 	// Add processing details
 	report += fmt.Sprintf(`
 DOCUMENT PROCESSING
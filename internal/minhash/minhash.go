@@ -0,0 +1,212 @@
+// Package minhash estimates the similarity of texts from MinHash signatures of
+// their word shingles and finds near-duplicate pairs with locality-sensitive
+// hashing (LSH): signatures are split into bands and texts sharing any band are
+// candidates.
+package minhash
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Shingles returns the distinct hashed runs of k consecutive words of a text.
+// Words are lower-cased and stripped of surrounding punctuation; texts shorter
+// than k words yield a single shingle of all their words.
+func Shingles(text string, k int) []uint64 {
+	if k < 1 {
+		k = 1
+	}
+	var words []string
+	for _, w := range strings.Fields(strings.ToLower(text)) {
+		if w = strings.TrimFunc(w, unicode.IsPunct); w != "" {
+			words = append(words, w)
+		}
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	if len(words) < k {
+		k = len(words)
+	}
+
+	seen := make(map[uint64]bool)
+	shingles := make([]uint64, 0, len(words)-k+1)
+	for i := 0; i+k <= len(words); i++ {
+		h := fnv.New64a()
+		for _, w := range words[i : i+k] {
+			h.Write([]byte(w))
+			h.Write([]byte{0})
+		}
+		if s := h.Sum64(); !seen[s] {
+			seen[s] = true
+			shingles = append(shingles, s)
+		}
+	}
+	return shingles
+}
+
+// Jaccard returns the exact Jaccard similarity of two shingle sets
+func Jaccard(a, b []uint64) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	set := make(map[uint64]bool, len(a))
+	for _, s := range a {
+		set[s] = true
+	}
+	shared := 0
+	for _, s := range b {
+		if set[s] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// Signature is the MinHash signature of a shingle set: the minimum of each hash
+// function over the set
+type Signature []uint64
+
+// Similarity estimates the Jaccard similarity of the sets behind two signatures
+// of the same hasher
+func (s Signature) Similarity(t Signature) float64 {
+	if len(s) == 0 || len(s) != len(t) {
+		return 0
+	}
+	equal := 0
+	for i := range s {
+		if s[i] == t[i] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(s))
+}
+
+// Hasher computes signatures with a fixed family of hash functions
+type Hasher struct {
+	seeds []uint64
+}
+
+// NewHasher creates a hasher with n hash functions derived from seed; signatures
+// are only comparable between hashers with the same n and seed
+func NewHasher(n int, seed uint64) *Hasher {
+	h := &Hasher{seeds: make([]uint64, n)}
+	for i := range h.seeds {
+		seed += 0x9e3779b97f4a7c15
+		h.seeds[i] = mix(seed)
+	}
+	return h
+}
+
+// Signature returns the signature of a shingle set; an empty set has the
+// maximum value in every position
+func (h *Hasher) Signature(shingles []uint64) Signature {
+	sig := make(Signature, len(h.seeds))
+	for i := range sig {
+		sig[i] = math.MaxUint64
+	}
+	for _, s := range shingles {
+		for i, seed := range h.seeds {
+			if v := mix(s ^ seed); v < sig[i] {
+				sig[i] = v
+			}
+		}
+	}
+	return sig
+}
+
+// mix is the splitmix64 finalizer, used as a family of hash functions by
+// xoring the input with a per-function seed
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// Bands returns the band count and rows per band that split signatures of n
+// hashes so that pairs become candidates at about the threshold similarity,
+// (1/bands)^(1/rows) ≈ threshold
+func Bands(n int, threshold float64) (bands, rows int) {
+	bands, rows = n, 1
+	best := math.Inf(1)
+	for r := 1; r <= n; r++ {
+		b := n / r
+		if b == 0 {
+			break
+		}
+		if diff := math.Abs(math.Pow(1/float64(b), 1/float64(r)) - threshold); diff < best {
+			best, bands, rows = diff, b, r
+		}
+	}
+	return bands, rows
+}
+
+// Index buckets signatures by band to find candidate near-duplicates
+type Index struct {
+	bands, rows int
+	buckets     []map[uint64][]int // Per band, the IDs by hash of the band's rows
+}
+
+// NewIndex creates an index splitting signatures into bands of rows
+func NewIndex(bands, rows int) *Index {
+	ix := &Index{bands: bands, rows: rows, buckets: make([]map[uint64][]int, bands)}
+	for i := range ix.buckets {
+		ix.buckets[i] = make(map[uint64][]int)
+	}
+	return ix
+}
+
+// Add indexes a signature under an ID. Signatures shorter than bands*rows only
+// use their complete bands.
+func (ix *Index) Add(id int, sig Signature) {
+	buf := make([]byte, 8)
+	for b := 0; b < ix.bands && (b+1)*ix.rows <= len(sig); b++ {
+		h := fnv.New64a()
+		for _, v := range sig[b*ix.rows : (b+1)*ix.rows] {
+			binary.LittleEndian.PutUint64(buf, v)
+			h.Write(buf)
+		}
+		key := h.Sum64()
+		ix.buckets[b][key] = append(ix.buckets[b][key], id)
+	}
+}
+
+// Pair is a candidate pair of IDs, lower ID first
+type Pair [2]int
+
+// Candidates returns the distinct pairs of IDs that share a bucket in any band,
+// in order
+func (ix *Index) Candidates() []Pair {
+	seen := make(map[Pair]bool)
+	var pairs []Pair
+	for _, band := range ix.buckets {
+		for _, ids := range band {
+			for i := 0; i < len(ids); i++ {
+				for j := i + 1; j < len(ids); j++ {
+					p := Pair{ids[i], ids[j]}
+					if p[0] > p[1] {
+						p[0], p[1] = p[1], p[0]
+					}
+					if p[0] != p[1] && !seen[p] {
+						seen[p] = true
+						pairs = append(pairs, p)
+					}
+				}
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	return pairs
+}
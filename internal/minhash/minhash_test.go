@@ -0,0 +1,77 @@
+package minhash
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestShingles(t *testing.T) {
+	tests := []struct {
+		text string
+		k    int
+		want int
+	}{
+		{"", 3, 0},
+		{"Oath", 3, 1},
+		{"I swear by Almighty God", 3, 3},
+		{"I swear, I swear, I swear", 2, 2}, // "i swear" and "swear i"
+	}
+	for _, tt := range tests {
+		if got := Shingles(tt.text, tt.k); len(got) != tt.want {
+			t.Errorf("Shingles(%q, %d) = %d shingles, want %d", tt.text, tt.k, len(got), tt.want)
+		}
+	}
+	if Jaccard(Shingles("The Oath of Administrator.", 2), Shingles("the oath of administrator", 2)) != 1 {
+		t.Error("case and punctuation should not change shingles")
+	}
+}
+
+func TestSignatureSimilarity(t *testing.T) {
+	words := strings.Fields(strings.Repeat("the executor shall administer the estate according to law and ", 4) +
+		"I make oath and say that I am the executor named in the will of the deceased person")
+	original := strings.Join(words, " ")
+	variant := strings.Join(append(words[:len(words)-2], "late", "testator"), " ")
+	other := "Dear Sir, please find enclosed our invoice for professional services rendered this month"
+
+	h := NewHasher(256, 1)
+	a, b, c := Shingles(original, 3), Shingles(variant, 3), Shingles(other, 3)
+	if got, want := h.Signature(a).Similarity(h.Signature(b)), Jaccard(a, b); math.Abs(got-want) > 0.1 {
+		t.Errorf("estimated similarity = %.2f, want about %.2f", got, want)
+	}
+	if got := h.Signature(a).Similarity(h.Signature(c)); got > 0.1 {
+		t.Errorf("similarity of unrelated texts = %.2f", got)
+	}
+}
+
+func TestIndexCandidates(t *testing.T) {
+	bands, rows := Bands(128, 0.8)
+	if bands*rows > 128 || math.Abs(math.Pow(1/float64(bands), 1/float64(rows))-0.8) > 0.05 {
+		t.Fatalf("Bands(128, 0.8) = %d, %d", bands, rows)
+	}
+
+	base := strings.Repeat("this deed of trust is made between the trustee and the beneficiary ", 3)
+	texts := []string{
+		base + "dated the first day of the month",
+		"Dear Sir, please find enclosed our invoice for professional services",
+		base + "dated the second day of the month",
+		base + "dated the first day of the month",
+	}
+	h := NewHasher(128, 7)
+	ix := NewIndex(bands, rows)
+	for id, text := range texts {
+		ix.Add(id, h.Signature(Shingles(text, 3)))
+	}
+
+	got := ix.Candidates()
+	want := []Pair{{0, 2}, {0, 3}, {2, 3}}
+	if len(got) != len(want) {
+		t.Fatalf("candidates = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("candidates = %v, want %v", got, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package msword
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/ole"
+)
+
+// ErrCorrupt is returned when the piece table points outside the streams
+var ErrCorrupt = errors.New("corrupt Word document")
+
+// Story names, in the order their text is stored in the document
+const (
+	StoryMain            = "main"
+	StoryFootnotes       = "footnotes"
+	StoryHeaders         = "headers" // Headers and footers
+	StoryComments        = "comments"
+	StoryEndnotes        = "endnotes"
+	StoryTextboxes       = "textboxes"
+	StoryHeaderTextboxes = "header_textboxes"
+)
+
+// CLX entry types
+const (
+	clxtPrc  = 0x01
+	clxtPcdt = 0x02
+)
+
+const (
+	pcdSize        = 8
+	fcCompressed   = 0x40000000
+	fcOffsetMask   = 0x3FFFFFFF
+	maxStoryLength = 1 << 26 // Sanity limit on character counts from the FIB
+)
+
+// Story is one independently stored part of the document text
+type Story struct {
+	Name string
+	Raw  string // Text including field markers and special characters
+}
+
+// Document is the text of a Word binary document split into stories
+type Document struct {
+	FIB              *FIB
+	Stories          []Story
+	AttachedTemplate string // Path of the template the document is based on, if stored
+}
+
+// ibstAssocDot is the index of the attached template in the SttbfAssoc
+const ibstAssocDot = 1
+
+// Open reads the text of the Word document held in a compound file
+func Open(cf *ole.File) (*Document, error) {
+	wordDocument, err := cf.ReadStream(WordDocumentStream)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotWord, err)
+	}
+
+	fib, err := ParseFIB(wordDocument)
+	if err != nil {
+		return nil, err
+	}
+
+	table, err := cf.ReadStream(fib.TableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s stream: %w", fib.TableName, err)
+	}
+
+	return parse(fib, wordDocument, table)
+}
+
+// Parse reads the text of a Word document from its WordDocument and table streams
+func Parse(wordDocument, table []byte) (*Document, error) {
+	fib, err := ParseFIB(wordDocument)
+	if err != nil {
+		return nil, err
+	}
+	return parse(fib, wordDocument, table)
+}
+
+func parse(fib *FIB, wordDocument, table []byte) (*Document, error) {
+	text, err := readPieces(fib, wordDocument, table)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{FIB: fib}
+	counts := []struct {
+		name string
+		ccp  uint32
+	}{
+		{StoryMain, fib.CcpText},
+		{StoryFootnotes, fib.CcpFtn},
+		{StoryHeaders, fib.CcpHdd},
+		{StoryComments, fib.CcpAtn},
+		{StoryEndnotes, fib.CcpEdn},
+		{StoryTextboxes, fib.CcpTxbx},
+		{StoryHeaderTextboxes, fib.CcpHdrTxbx},
+	}
+
+	cp := 0
+	for _, c := range counts {
+		if c.ccp > maxStoryLength {
+			return nil, fmt.Errorf("%w: %s story length %d", ErrCorrupt, c.name, c.ccp)
+		}
+		end := cp + int(c.ccp)
+		if end > len(text) {
+			end = len(text)
+		}
+		if c.name == StoryMain || end > cp {
+			doc.Stories = append(doc.Stories, Story{Name: c.name, Raw: string(utf16.Decode(text[cp:end]))})
+		}
+		cp = end
+	}
+
+	if start, end := int(fib.FcAssoc), int(fib.FcAssoc)+int(fib.LcbAssoc); fib.LcbAssoc > 0 && end <= len(table) {
+		if assoc := readSttb(table[start:end]); len(assoc) > ibstAssocDot {
+			doc.AttachedTemplate = assoc[ibstAssocDot]
+		}
+	}
+	return doc, nil
+}
+
+// readSttb reads the strings of a string table (STTB). Extended tables hold
+// UTF-16 strings, others single-byte ones; extra data after each string is
+// skipped. A truncated table yields the strings read so far.
+func readSttb(data []byte) []string {
+	le := binary.LittleEndian
+	if len(data) < 4 {
+		return nil
+	}
+	extended := le.Uint16(data) == 0xFFFF
+	offset := 0
+	if extended {
+		offset = 2
+	}
+	if offset+4 > len(data) {
+		return nil
+	}
+	count, cbExtra := int(le.Uint16(data[offset:])), int(le.Uint16(data[offset+2:]))
+	offset += 4
+
+	var strs []string
+	for i := 0; i < count; i++ {
+		if extended {
+			if offset+2 > len(data) {
+				break
+			}
+			n := int(le.Uint16(data[offset:]))
+			offset += 2
+			if offset+n*2 > len(data) {
+				break
+			}
+			units := make([]uint16, n)
+			for j := range units {
+				units[j] = le.Uint16(data[offset+j*2:])
+			}
+			strs = append(strs, string(utf16.Decode(units)))
+			offset += n * 2
+		} else {
+			if offset+1 > len(data) {
+				break
+			}
+			n := int(data[offset])
+			offset++
+			if offset+n > len(data) {
+				break
+			}
+			strs = append(strs, ole.DecodeANSI(data[offset:offset+n]))
+			offset += n
+		}
+		offset += cbExtra
+	}
+	return strs
+}
+
+// readPieces reassembles the document text, as UTF-16 code units in character
+// position order, from the piece table in the CLX
+func readPieces(fib *FIB, wordDocument, table []byte) ([]uint16, error) {
+	le := binary.LittleEndian
+	start, end := int(fib.FcClx), int(fib.FcClx)+int(fib.LcbClx)
+	if fib.LcbClx == 0 || end > len(table) || end < start {
+		return nil, fmt.Errorf("%w: CLX outside the %s stream", ErrCorrupt, fib.TableName)
+	}
+	clx := table[start:end]
+
+	// Skip the Prc entries holding property modifiers; the Pcdt follows them
+	for len(clx) > 0 && clx[0] == clxtPrc {
+		if len(clx) < 3 {
+			return nil, fmt.Errorf("%w: truncated CLX", ErrCorrupt)
+		}
+		size := 3 + int(int16(le.Uint16(clx[1:])))
+		if size < 3 || size > len(clx) {
+			return nil, fmt.Errorf("%w: truncated CLX", ErrCorrupt)
+		}
+		clx = clx[size:]
+	}
+	if len(clx) < 5 || clx[0] != clxtPcdt {
+		return nil, fmt.Errorf("%w: piece table not found", ErrCorrupt)
+	}
+	plc := clx[5:]
+	if lcb := int(le.Uint32(clx[1:])); lcb <= len(plc) {
+		plc = plc[:lcb]
+	}
+
+	// PlcPcd holds n+1 character positions followed by n piece descriptors
+	n := (len(plc) - 4) / (4 + pcdSize)
+	if n <= 0 {
+		return nil, fmt.Errorf("%w: empty piece table", ErrCorrupt)
+	}
+
+	var text []uint16
+	for i := 0; i < n; i++ {
+		cpStart := le.Uint32(plc[i*4:])
+		cpEnd := le.Uint32(plc[(i+1)*4:])
+		if cpEnd < cpStart || cpEnd-cpStart > maxStoryLength {
+			return nil, fmt.Errorf("%w: piece %d has invalid bounds", ErrCorrupt, i)
+		}
+		count := int(cpEnd - cpStart)
+
+		fc := le.Uint32(plc[(n+1)*4+i*pcdSize+2:])
+		if fc&fcCompressed != 0 {
+			offset := int(fc&fcOffsetMask) / 2
+			if offset+count > len(wordDocument) {
+				return nil, fmt.Errorf("%w: piece %d outside the WordDocument stream", ErrCorrupt, i)
+			}
+			for _, r := range ole.DecodeANSI(wordDocument[offset : offset+count]) {
+				text = append(text, uint16(r))
+			}
+			continue
+		}
+
+		offset := int(fc)
+		if offset+count*2 > len(wordDocument) {
+			return nil, fmt.Errorf("%w: piece %d outside the WordDocument stream", ErrCorrupt, i)
+		}
+		for j := 0; j < count; j++ {
+			text = append(text, le.Uint16(wordDocument[offset+j*2:]))
+		}
+	}
+	return text, nil
+}
+
+// Story returns the rendered text of the named story, or "" if the document
+// has none
+func (d *Document) Story(name string) string {
+	for _, s := range d.Stories {
+		if s.Name == name {
+			text, _ := Render(s.Raw)
+			return text
+		}
+	}
+	return ""
+}
+
+// Text returns the rendered text of every story, main document first
+func (d *Document) Text() string {
+	parts := make([]string, 0, len(d.Stories))
+	for _, s := range d.Stories {
+		if text, _ := Render(s.Raw); strings.TrimSpace(text) != "" {
+			parts = append(parts, strings.TrimRight(text, "\n"))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// FieldCodes returns the instruction of every field in the document, with nested
+// fields shown in braces
+func (d *Document) FieldCodes() []string {
+	var codes []string
+	for _, s := range d.Stories {
+		_, fields := Render(s.Raw)
+		codes = append(codes, fields...)
+	}
+	return codes
+}
@@ -0,0 +1,233 @@
+package msword
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/ole"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/ole/oletest"
+)
+
+// piece is a run of text stored either as 8-bit (compressed) or UTF-16 characters
+type piece struct {
+	text       string
+	compressed bool
+}
+
+// buildWord lays out WordDocument and 1Table streams for the given pieces and
+// story lengths (main, footnotes, headers)
+func buildWord(t *testing.T, pieces []piece, ccpText, ccpFtn, ccpHdd uint32) (wordDocument, table []byte) {
+	t.Helper()
+	le := binary.LittleEndian
+
+	const textOffset = 1024
+	wordDocument = make([]byte, textOffset)
+	le.PutUint16(wordDocument, wIdent)
+	le.PutUint16(wordDocument[2:], minNFib)
+	le.PutUint16(wordDocument[0x0A:], flagWhichTblStm)
+
+	offset := fibBaseSize
+	le.PutUint16(wordDocument[offset:], 14)
+	offset += 2 + 14*2
+	le.PutUint16(wordDocument[offset:], 22)
+	rgLw := offset + 2
+	le.PutUint32(wordDocument[rgLw+lwCcpText*4:], ccpText)
+	le.PutUint32(wordDocument[rgLw+lwCcpFtn*4:], ccpFtn)
+	le.PutUint32(wordDocument[rgLw+lwCcpHdd*4:], ccpHdd)
+	offset = rgLw + 22*4
+	le.PutUint16(wordDocument[offset:], 93)
+	rgFcLcb := offset + 2
+
+	// Piece table: n+1 character positions, then n descriptors
+	var cps []uint32
+	var fcs []uint32
+	cp := uint32(0)
+	for _, p := range pieces {
+		cps = append(cps, cp)
+		units := utf16.Encode([]rune(p.text))
+		cp += uint32(len(units))
+		if p.compressed {
+			fcs = append(fcs, uint32(len(wordDocument))*2|fcCompressed)
+			for _, u := range units {
+				wordDocument = append(wordDocument, byte(u))
+			}
+			continue
+		}
+		fcs = append(fcs, uint32(len(wordDocument)))
+		for _, u := range units {
+			wordDocument = le.AppendUint16(wordDocument, u)
+		}
+	}
+	cps = append(cps, cp)
+
+	var plc []byte
+	for _, c := range cps {
+		plc = le.AppendUint32(plc, c)
+	}
+	for _, fc := range fcs {
+		pcd := make([]byte, pcdSize)
+		le.PutUint32(pcd[2:], fc)
+		plc = append(plc, pcd...)
+	}
+
+	// A property modifier precedes the piece table, as Word often writes one
+	table = []byte{0xAA, 0xBB}
+	clx := []byte{clxtPrc, 2, 0, 0x55, 0x55, clxtPcdt}
+	clx = le.AppendUint32(clx, uint32(len(plc)))
+	clx = append(clx, plc...)
+	le.PutUint32(wordDocument[rgFcLcb+fcLcbClx*8:], uint32(len(table)))
+	le.PutUint32(wordDocument[rgFcLcb+fcLcbClx*8+4:], uint32(len(clx)))
+	table = append(table, clx...)
+
+	// SttbfAssoc with an attached template
+	assoc := []byte{0xFF, 0xFF, 18, 0, 0, 0}
+	for i := 0; i < 18; i++ {
+		var units []uint16
+		if i == ibstAssocDot {
+			units = utf16.Encode([]rune(`\\fileserver\templates\Letter.dot`))
+		}
+		assoc = le.AppendUint16(assoc, uint16(len(units)))
+		for _, u := range units {
+			assoc = le.AppendUint16(assoc, u)
+		}
+	}
+	le.PutUint32(wordDocument[rgFcLcb+fcLcbAssoc*8:], uint32(len(table)))
+	le.PutUint32(wordDocument[rgFcLcb+fcLcbAssoc*8+4:], uint32(len(assoc)))
+	table = append(table, assoc...)
+	return wordDocument, table
+}
+
+func TestOpenReconstructsStories(t *testing.T) {
+	body := "Dear \x13 MERGEFIELD Client \x14«Client»\x15,\rRegards\r"
+	footnote := "\x02 See clause 4\r"
+	header := "Página 1\r\r"
+	pieces := []piece{
+		{body[:20], true},
+		{body[20:], false},
+		{footnote, true},
+		{header, false},
+	}
+	wordDocument, table := buildWord(t, pieces, uint32(len([]rune(body))), uint32(len(footnote)), uint32(len([]rune(header))))
+
+	data, err := oletest.Build(map[string][]byte{
+		WordDocumentStream: wordDocument,
+		Table1Stream:       table,
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	cf, err := ole.Open(data)
+	if err != nil {
+		t.Fatalf("ole.Open: %v", err)
+	}
+
+	doc, err := Open(cf)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	var names []string
+	for _, s := range doc.Stories {
+		names = append(names, s.Name)
+	}
+	if want := []string{StoryMain, StoryFootnotes, StoryHeaders}; !reflect.DeepEqual(names, want) {
+		t.Errorf("stories = %v, want %v", names, want)
+	}
+
+	if got, want := doc.Story(StoryMain), "Dear { MERGEFIELD Client },\nRegards\n"; got != want {
+		t.Errorf("main = %q, want %q", got, want)
+	}
+	if got, want := doc.Story(StoryFootnotes), " See clause 4\n"; got != want {
+		t.Errorf("footnotes = %q, want %q", got, want)
+	}
+	if got, want := doc.Story(StoryHeaders), "Página 1\n\n"; got != want {
+		t.Errorf("headers = %q, want %q", got, want)
+	}
+	if got, want := doc.FieldCodes(), []string{"MERGEFIELD Client"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("field codes = %q, want %q", got, want)
+	}
+	if got, want := doc.AttachedTemplate, `\\fileserver\templates\Letter.dot`; got != want {
+		t.Errorf("attached template = %q, want %q", got, want)
+	}
+}
+
+func TestParseRejectsInvalidStreams(t *testing.T) {
+	wordDocument, table := buildWord(t, []piece{{"Text\r", true}}, 5, 0, 0)
+
+	oldVersion := append([]byte(nil), wordDocument...)
+	binary.LittleEndian.PutUint16(oldVersion[2:], 0x0065)
+
+	encrypted := append([]byte(nil), wordDocument...)
+	binary.LittleEndian.PutUint16(encrypted[0x0A:], flagWhichTblStm|flagEncrypted)
+
+	tests := []struct {
+		name         string
+		wordDocument []byte
+		table        []byte
+		want         error
+	}{
+		{"not a FIB", []byte("plain text, not a Word document at all"), table, ErrNotWord},
+		{"Word 95", oldVersion, table, ErrUnsupportedVersion},
+		{"encrypted", encrypted, table, ErrEncrypted},
+		{"truncated table", wordDocument, table[:4], ErrCorrupt},
+		{"truncated text", wordDocument[:1026], table, ErrCorrupt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.wordDocument, tt.table); !errors.Is(err, tt.want) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		text   string
+		fields []string
+	}{
+		{
+			name: "special characters",
+			raw:  "A\x07B\x07\x07\rnon\x1Ebreaking space\x0Bopt\x1Fional\x01\x08",
+			text: "A\tB\t\t\nnon-breaking space\noptional",
+		},
+		{
+			name:   "field without result",
+			raw:    "\x13 DOCVARIABLE Matter \x15",
+			text:   "{ DOCVARIABLE Matter }",
+			fields: []string{"DOCVARIABLE Matter"},
+		},
+		{
+			name:   "nested fields",
+			raw:    "\x13 IF \x13 MERGEFIELD Sex \x14M\x15 = \"M\" \"Sir\" \"Madam\" \x14Sir\x15",
+			text:   "{ IF { MERGEFIELD Sex } = \"M\" \"Sir\" \"Madam\" }",
+			fields: []string{"IF { MERGEFIELD Sex } = \"M\" \"Sir\" \"Madam\"", "MERGEFIELD Sex"},
+		},
+		{
+			name:   "fields in results are skipped",
+			raw:    "\x13 TOC \\o \x14\x13 PAGEREF _Toc1 \x142\x15\x15 end",
+			text:   "{ TOC \\o } end",
+			fields: []string{"TOC \\o"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, fields := Render(tt.raw)
+			if text != tt.text {
+				t.Errorf("text = %q, want %q", text, tt.text)
+			}
+			if len(fields) != 0 || len(tt.fields) != 0 {
+				if !reflect.DeepEqual(fields, tt.fields) {
+					t.Errorf("fields = %q, want %q", fields, tt.fields)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+// Package msword reads the text of Word 97-2003 binary documents (.doc/.dot) from
+// the WordDocument and table streams of their compound file.
+package msword
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Stream names used by binary Word documents
+const (
+	WordDocumentStream = "WordDocument"
+	Table0Stream       = "0Table"
+	Table1Stream       = "1Table"
+)
+
+const (
+	// wIdent identifies a Word binary file in FibBase
+	wIdent = 0xA5EC
+	// minNFib is the oldest file format (Word 97) this package reads
+	minNFib = 0x00C1
+	// fibBaseSize is the fixed size of FibBase
+	fibBaseSize = 32
+)
+
+// FibBase flag bits
+const (
+	flagComplex     = 0x0004
+	flagEncrypted   = 0x0100
+	flagWhichTblStm = 0x0200
+	flagObfuscated  = 0x8000
+)
+
+// Indexes into fibRgLw and fibRgFcLcb
+const (
+	lwCcpText     = 3
+	lwCcpFtn      = 4
+	lwCcpHdd      = 5
+	lwCcpAtn      = 7
+	lwCcpEdn      = 8
+	lwCcpTxbx     = 9
+	lwCcpHdrTxbx  = 10
+	fcLcbAssoc    = 32
+	fcLcbClx      = 33
+	minFcLcbCount = fcLcbClx + 1
+)
+
+var (
+	// ErrNotWord is returned when the WordDocument stream has no valid FIB
+	ErrNotWord = errors.New("not a Word binary document")
+	// ErrUnsupportedVersion is returned for pre-Word 97 files
+	ErrUnsupportedVersion = errors.New("unsupported Word version")
+	// ErrEncrypted is returned for password protected documents
+	ErrEncrypted = errors.New("document is encrypted")
+)
+
+// FIB is the subset of the File Information Block needed to locate the text
+type FIB struct {
+	NFib       uint16
+	Complex    bool   // Saved with fast save; text pieces may be out of order
+	TableName  string // "1Table" or "0Table"
+	CcpText    uint32 // Characters in the main document
+	CcpFtn     uint32 // Characters in footnotes
+	CcpHdd     uint32 // Characters in headers and footers
+	CcpAtn     uint32 // Characters in comments
+	CcpEdn     uint32 // Characters in endnotes
+	CcpTxbx    uint32 // Characters in textboxes of the main document
+	CcpHdrTxbx uint32 // Characters in textboxes of headers
+	FcClx      uint32 // Offset of the CLX in the table stream
+	LcbClx     uint32 // Size of the CLX
+	FcAssoc    uint32 // Offset of the SttbfAssoc in the table stream
+	LcbAssoc   uint32 // Size of the SttbfAssoc
+}
+
+// ParseFIB reads the FIB at the start of the WordDocument stream
+func ParseFIB(stream []byte) (*FIB, error) {
+	le := binary.LittleEndian
+	if len(stream) < fibBaseSize+2 || le.Uint16(stream) != wIdent {
+		return nil, ErrNotWord
+	}
+
+	fib := &FIB{NFib: le.Uint16(stream[2:])}
+	if fib.NFib < minNFib {
+		return nil, fmt.Errorf("%w: nFib %#x", ErrUnsupportedVersion, fib.NFib)
+	}
+
+	flags := le.Uint16(stream[0x0A:])
+	if flags&(flagEncrypted|flagObfuscated) != 0 {
+		return nil, ErrEncrypted
+	}
+	fib.Complex = flags&flagComplex != 0
+	fib.TableName = Table0Stream
+	if flags&flagWhichTblStm != 0 {
+		fib.TableName = Table1Stream
+	}
+
+	// FibBase is followed by three counted arrays: fibRgW, fibRgLw and fibRgFcLcb
+	offset := fibBaseSize
+	csw := int(le.Uint16(stream[offset:]))
+	offset += 2 + csw*2
+
+	if offset+2 > len(stream) {
+		return nil, fmt.Errorf("%w: truncated FIB", ErrNotWord)
+	}
+	cslw := int(le.Uint16(stream[offset:]))
+	rgLw := offset + 2
+	offset = rgLw + cslw*4
+
+	if cslw <= lwCcpHdrTxbx || offset+2 > len(stream) {
+		return nil, fmt.Errorf("%w: truncated FIB", ErrNotWord)
+	}
+	lw := func(i int) uint32 { return le.Uint32(stream[rgLw+i*4:]) }
+	fib.CcpText = lw(lwCcpText)
+	fib.CcpFtn = lw(lwCcpFtn)
+	fib.CcpHdd = lw(lwCcpHdd)
+	fib.CcpAtn = lw(lwCcpAtn)
+	fib.CcpEdn = lw(lwCcpEdn)
+	fib.CcpTxbx = lw(lwCcpTxbx)
+	fib.CcpHdrTxbx = lw(lwCcpHdrTxbx)
+
+	cbRgFcLcb := int(le.Uint16(stream[offset:]))
+	rgFcLcb := offset + 2
+	if cbRgFcLcb < minFcLcbCount || rgFcLcb+cbRgFcLcb*8 > len(stream) {
+		return nil, fmt.Errorf("%w: truncated FIB", ErrNotWord)
+	}
+	fib.FcClx = le.Uint32(stream[rgFcLcb+fcLcbClx*8:])
+	fib.LcbClx = le.Uint32(stream[rgFcLcb+fcLcbClx*8+4:])
+	fib.FcAssoc = le.Uint32(stream[rgFcLcb+fcLcbAssoc*8:])
+	fib.LcbAssoc = le.Uint32(stream[rgFcLcb+fcLcbAssoc*8+4:])
+
+	return fib, nil
+}
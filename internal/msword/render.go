@@ -0,0 +1,107 @@
+package msword
+
+import "strings"
+
+// Special characters used in Word binary text
+const (
+	FieldBegin     = '\x13'
+	FieldSeparator = '\x14'
+	FieldEnd       = '\x15'
+
+	paragraphMark     = '\r'
+	lineBreak         = '\x0B'
+	pageBreak         = '\x0C'
+	cellMark          = '\x07'
+	nonBreakingHyphen = '\x1E'
+	optionalHyphen    = '\x1F'
+	nonBreakingSpace  = '\u00A0'
+)
+
+// field tracks an open field while rendering
+type field struct {
+	index    int  // Position in the returned instructions
+	visible  bool // Whether the field's braces are part of the output
+	inResult bool // Past the separator; result text is not rendered
+	closed   bool // Closing brace already written
+}
+
+// Render converts raw story text to plain text. Fields are shown as their codes
+// in braces, e.g. "{ MERGEFIELD Client }", the way Word displays them with field
+// codes toggled on; field results are dropped. It also returns the instruction
+// of every field, in the order the fields start.
+func Render(raw string) (string, []string) {
+	var out strings.Builder
+	var stack []*field
+	var instructions []*strings.Builder
+
+	visible := func() bool {
+		return len(stack) == 0 || (stack[len(stack)-1].visible && !stack[len(stack)-1].inResult)
+	}
+	write := func(s string) {
+		if !visible() {
+			return
+		}
+		out.WriteString(s)
+		// The text is part of the instruction of every open field
+		for _, f := range stack {
+			if f.visible && !f.inResult {
+				instructions[f.index].WriteString(s)
+			}
+		}
+	}
+
+	for _, r := range raw {
+		switch r {
+		case FieldBegin:
+			// Fields inside another field's result are generated text and skipped
+			if !visible() {
+				stack = append(stack, &field{index: -1})
+				continue
+			}
+			write("{")
+			stack = append(stack, &field{index: len(instructions), visible: true})
+			instructions = append(instructions, &strings.Builder{})
+		case FieldSeparator, FieldEnd:
+			if len(stack) == 0 {
+				continue
+			}
+			top := stack[len(stack)-1]
+			if !top.closed {
+				top.inResult = true
+				top.closed = true
+				if top.visible {
+					out.WriteString("}")
+					for _, f := range stack[:len(stack)-1] {
+						if f.visible && !f.inResult {
+							instructions[f.index].WriteString("}")
+						}
+					}
+				}
+			}
+			if r == FieldEnd {
+				stack = stack[:len(stack)-1]
+			}
+		case paragraphMark, lineBreak, pageBreak:
+			write("\n")
+		case cellMark:
+			write("\t")
+		case nonBreakingHyphen:
+			write("-")
+		case nonBreakingSpace:
+			write(" ")
+		case optionalHyphen:
+		default:
+			// Remaining control characters anchor pictures, footnote references
+			// and similar objects that have no text of their own
+			if r >= 0x20 || r == '\t' {
+				write(string(r))
+			}
+		}
+	}
+
+	codes := make([]string, len(instructions))
+	for i, b := range instructions {
+		codes[i] = strings.TrimSpace(b.String())
+	}
+	return out.String(), codes
+}
@@ -0,0 +1,481 @@
+// Package ole reads Compound File Binary (OLE2 structured storage) files such as
+// legacy Word .doc/.dot documents, exposing their storages and streams.
+package ole
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Signature is the magic number at the start of every compound file
+var Signature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// Special sector numbers
+const (
+	maxRegSect = 0xFFFFFFFA
+	difatSect  = 0xFFFFFFFC
+	fatSect    = 0xFFFFFFFD
+	endOfChain = 0xFFFFFFFE
+	freeSect   = 0xFFFFFFFF
+	noStream   = 0xFFFFFFFF
+)
+
+const (
+	headerSize   = 512
+	dirEntrySize = 128
+	// headerDIFAT is the number of FAT sector locations stored in the header
+	headerDIFAT = 109
+)
+
+var (
+	// ErrNotCFB is returned when the data does not start with the CFB signature
+	ErrNotCFB = errors.New("not a compound file")
+	// ErrCorrupt is returned when the compound file structures are inconsistent
+	ErrCorrupt = errors.New("corrupt compound file")
+	// ErrStreamNotFound is returned when a stream path does not exist
+	ErrStreamNotFound = errors.New("stream not found")
+)
+
+// EntryType is the kind of a directory entry
+type EntryType byte
+
+const (
+	EntryUnknown EntryType = 0
+	EntryStorage EntryType = 1
+	EntryStream  EntryType = 2
+	EntryRoot    EntryType = 5
+)
+
+// Entry is a storage or stream in the compound file directory
+type Entry struct {
+	Name     string
+	Path     string // Slash-separated path from the root, e.g. "Macros/VBA/dir"
+	Type     EntryType
+	Size     int64
+	CLSID    [16]byte
+	Created  time.Time
+	Modified time.Time
+	Children []*Entry // Child entries of a storage, in directory order
+
+	start uint32
+}
+
+// IsStream reports whether the entry holds data
+func (e *Entry) IsStream() bool {
+	return e.Type == EntryStream
+}
+
+// File is a parsed compound file held in memory
+type File struct {
+	data           []byte
+	sectorSize     int
+	miniSectorSize int
+	miniCutoff     int64
+	fat            []uint32
+	miniFAT        []uint32
+	miniStream     []byte
+	root           *Entry
+	entries        []*Entry
+}
+
+// IsCFB reports whether data starts with the compound file signature
+func IsCFB(data []byte) bool {
+	return bytes.HasPrefix(data, Signature)
+}
+
+// Open parses the header, FAT/DIFAT, mini FAT and directory tree of a compound file
+func Open(data []byte) (*File, error) {
+	if len(data) < headerSize || !IsCFB(data) {
+		return nil, ErrNotCFB
+	}
+
+	le := binary.LittleEndian
+	major := le.Uint16(data[26:])
+	sectorShift := le.Uint16(data[30:])
+	miniShift := le.Uint16(data[32:])
+
+	if le.Uint16(data[28:]) != 0xFFFE {
+		return nil, fmt.Errorf("%w: bad byte order mark", ErrCorrupt)
+	}
+	if (major == 3 && sectorShift != 9) || (major == 4 && sectorShift != 12) || (major != 3 && major != 4) {
+		return nil, fmt.Errorf("%w: unsupported version %d with sector shift %d", ErrCorrupt, major, sectorShift)
+	}
+	if miniShift != 6 {
+		return nil, fmt.Errorf("%w: unsupported mini sector shift %d", ErrCorrupt, miniShift)
+	}
+
+	f := &File{
+		data:           data,
+		sectorSize:     1 << sectorShift,
+		miniSectorSize: 1 << miniShift,
+		miniCutoff:     int64(le.Uint32(data[56:])),
+	}
+
+	if err := f.loadFAT(); err != nil {
+		return nil, err
+	}
+
+	dirData, err := f.readChain(le.Uint32(data[48:]), -1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	if err := f.loadDirectory(dirData); err != nil {
+		return nil, err
+	}
+
+	if err := f.loadMiniStream(le.Uint32(data[60:])); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// sectorCount is the number of whole sectors after the header
+func (f *File) sectorCount() int {
+	return (len(f.data) - f.sectorSize) / f.sectorSize
+}
+
+// sector returns the contents of a regular sector
+func (f *File) sector(n uint32) ([]byte, error) {
+	if n > maxRegSect {
+		return nil, fmt.Errorf("%w: invalid sector %#x", ErrCorrupt, n)
+	}
+	offset := (int64(n) + 1) * int64(f.sectorSize)
+	end := offset + int64(f.sectorSize)
+	if end > int64(len(f.data)) {
+		// Some writers truncate the final sector
+		if offset < int64(len(f.data)) {
+			padded := make([]byte, f.sectorSize)
+			copy(padded, f.data[offset:])
+			return padded, nil
+		}
+		return nil, fmt.Errorf("%w: sector %d beyond end of file", ErrCorrupt, n)
+	}
+	return f.data[offset:end], nil
+}
+
+// loadFAT collects the FAT sector locations from the header and DIFAT chain and
+// reads the FAT itself
+func (f *File) loadFAT() error {
+	le := binary.LittleEndian
+	numFAT := int(le.Uint32(f.data[44:]))
+	if numFAT > f.sectorCount()+1 {
+		return fmt.Errorf("%w: %d FAT sectors in a %d sector file", ErrCorrupt, numFAT, f.sectorCount())
+	}
+
+	locations := make([]uint32, 0, numFAT)
+	for i := 0; i < headerDIFAT && len(locations) < numFAT; i++ {
+		locations = append(locations, le.Uint32(f.data[76+i*4:]))
+	}
+
+	// Files with more than 109 FAT sectors chain further DIFAT sectors
+	next := le.Uint32(f.data[68:])
+	perSector := f.sectorSize/4 - 1
+	seen := make(map[uint32]bool)
+	for len(locations) < numFAT && next <= maxRegSect {
+		if seen[next] {
+			return fmt.Errorf("%w: DIFAT chain loops", ErrCorrupt)
+		}
+		seen[next] = true
+
+		sector, err := f.sector(next)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < perSector && len(locations) < numFAT; i++ {
+			locations = append(locations, le.Uint32(sector[i*4:]))
+		}
+		next = le.Uint32(sector[perSector*4:])
+	}
+
+	f.fat = make([]uint32, 0, len(locations)*f.sectorSize/4)
+	for _, loc := range locations {
+		sector, err := f.sector(loc)
+		if err != nil {
+			return fmt.Errorf("failed to read FAT: %w", err)
+		}
+		for i := 0; i < f.sectorSize; i += 4 {
+			f.fat = append(f.fat, le.Uint32(sector[i:]))
+		}
+	}
+	return nil
+}
+
+// readChain concatenates the regular sectors of a chain; size < 0 reads it all
+func (f *File) readChain(start uint32, size int64) ([]byte, error) {
+	var buf bytes.Buffer
+	seen := make(map[uint32]bool)
+
+	for sect := start; sect != endOfChain; {
+		if size >= 0 && int64(buf.Len()) >= size {
+			break
+		}
+		if sect > maxRegSect || int(sect) >= len(f.fat) {
+			if size < 0 && sect == freeSect {
+				break
+			}
+			return nil, fmt.Errorf("%w: chain points to sector %#x", ErrCorrupt, sect)
+		}
+		if seen[sect] {
+			return nil, fmt.Errorf("%w: sector chain loops at %d", ErrCorrupt, sect)
+		}
+		seen[sect] = true
+
+		data, err := f.sector(sect)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+		sect = f.fat[sect]
+	}
+
+	out := buf.Bytes()
+	if size >= 0 {
+		if int64(len(out)) < size {
+			return nil, fmt.Errorf("%w: stream shorter than its declared size", ErrCorrupt)
+		}
+		out = out[:size]
+	}
+	return out, nil
+}
+
+// loadMiniStream reads the mini FAT and the root entry's mini stream
+func (f *File) loadMiniStream(miniFATStart uint32) error {
+	if miniFATStart <= maxRegSect {
+		data, err := f.readChain(miniFATStart, -1)
+		if err != nil {
+			return fmt.Errorf("failed to read mini FAT: %w", err)
+		}
+		f.miniFAT = make([]uint32, len(data)/4)
+		for i := range f.miniFAT {
+			f.miniFAT[i] = binary.LittleEndian.Uint32(data[i*4:])
+		}
+	}
+
+	if f.root.Size > 0 && f.root.start <= maxRegSect {
+		data, err := f.readChain(f.root.start, f.root.Size)
+		if err != nil {
+			return fmt.Errorf("failed to read mini stream: %w", err)
+		}
+		f.miniStream = data
+	}
+	return nil
+}
+
+// readMiniChain concatenates the mini sectors of a small stream
+func (f *File) readMiniChain(start uint32, size int64) ([]byte, error) {
+	out := make([]byte, 0, size)
+	seen := make(map[uint32]bool)
+
+	for sect := start; sect != endOfChain && int64(len(out)) < size; {
+		if int(sect) >= len(f.miniFAT) {
+			return nil, fmt.Errorf("%w: mini chain points to sector %#x", ErrCorrupt, sect)
+		}
+		if seen[sect] {
+			return nil, fmt.Errorf("%w: mini sector chain loops at %d", ErrCorrupt, sect)
+		}
+		seen[sect] = true
+
+		offset := int(sect) * f.miniSectorSize
+		if offset+f.miniSectorSize > len(f.miniStream) {
+			return nil, fmt.Errorf("%w: mini sector %d beyond mini stream", ErrCorrupt, sect)
+		}
+		out = append(out, f.miniStream[offset:offset+f.miniSectorSize]...)
+		sect = f.miniFAT[sect]
+	}
+
+	if int64(len(out)) < size {
+		return nil, fmt.Errorf("%w: stream shorter than its declared size", ErrCorrupt)
+	}
+	return out[:size], nil
+}
+
+// rawEntry is a directory entry before the tree is resolved
+type rawEntry struct {
+	entry              *Entry
+	left, right, child uint32
+}
+
+// loadDirectory parses the directory entries and walks the red-black sibling
+// trees to build the storage hierarchy
+func (f *File) loadDirectory(data []byte) error {
+	le := binary.LittleEndian
+	raws := make([]rawEntry, 0, len(data)/dirEntrySize)
+
+	for off := 0; off+dirEntrySize <= len(data); off += dirEntrySize {
+		d := data[off : off+dirEntrySize]
+		nameLen := int(le.Uint16(d[64:]))
+		if nameLen > 64 {
+			nameLen = 64
+		}
+
+		entry := &Entry{
+			Name:     decodeName(d[:nameLen]),
+			Type:     EntryType(d[66]),
+			Created:  fileTime(le.Uint64(d[100:])),
+			Modified: fileTime(le.Uint64(d[108:])),
+			Size:     int64(le.Uint64(d[120:])),
+			start:    le.Uint32(d[116:]),
+		}
+		copy(entry.CLSID[:], d[80:96])
+
+		// Version 3 files only define the low 32 bits of the size
+		if f.sectorSize == 512 {
+			entry.Size = int64(le.Uint32(d[120:]))
+		}
+
+		raws = append(raws, rawEntry{
+			entry: entry,
+			left:  le.Uint32(d[68:]),
+			right: le.Uint32(d[72:]),
+			child: le.Uint32(d[76:]),
+		})
+	}
+
+	if len(raws) == 0 || raws[0].entry.Type != EntryRoot {
+		return fmt.Errorf("%w: missing root entry", ErrCorrupt)
+	}
+
+	f.root = raws[0].entry
+	f.root.Path = ""
+	visited := map[uint32]bool{0: true}
+
+	// collect walks a sibling tree in order, appending entries to parent
+	var collect func(parent *Entry, id uint32) error
+	collect = func(parent *Entry, id uint32) error {
+		if id == noStream {
+			return nil
+		}
+		if int(id) >= len(raws) || visited[id] {
+			return fmt.Errorf("%w: invalid directory reference %d", ErrCorrupt, id)
+		}
+		visited[id] = true
+		raw := raws[id]
+
+		if err := collect(parent, raw.left); err != nil {
+			return err
+		}
+
+		entry := raw.entry
+		if entry.Type == EntryStorage || entry.Type == EntryStream {
+			entry.Path = entry.Name
+			if parent.Path != "" {
+				entry.Path = parent.Path + "/" + entry.Name
+			}
+			parent.Children = append(parent.Children, entry)
+			f.entries = append(f.entries, entry)
+
+			if entry.Type == EntryStorage {
+				if err := collect(entry, raw.child); err != nil {
+					return err
+				}
+			}
+		}
+
+		return collect(parent, raw.right)
+	}
+
+	return collect(f.root, raws[0].child)
+}
+
+// Root returns the root storage
+func (f *File) Root() *Entry {
+	return f.root
+}
+
+// Entries returns every storage and stream below the root, depth first
+func (f *File) Entries() []*Entry {
+	return f.entries
+}
+
+// Streams returns the paths of all streams, sorted
+func (f *File) Streams() []string {
+	paths := make([]string, 0, len(f.entries))
+	for _, entry := range f.entries {
+		if entry.IsStream() {
+			paths = append(paths, entry.Path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Find looks up an entry by slash-separated path; names compare case-insensitively
+// as they do in compound files
+func (f *File) Find(path string) (*Entry, bool) {
+	current := f.root
+	for _, name := range strings.Split(strings.Trim(path, "/"), "/") {
+		var next *Entry
+		for _, child := range current.Children {
+			if strings.EqualFold(child.Name, name) {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			return nil, false
+		}
+		current = next
+	}
+	return current, current != f.root
+}
+
+// ReadStream returns the contents of the stream at path
+func (f *File) ReadStream(path string) ([]byte, error) {
+	entry, ok := f.Find(path)
+	if !ok || !entry.IsStream() {
+		return nil, fmt.Errorf("%w: %s", ErrStreamNotFound, path)
+	}
+	return f.Read(entry)
+}
+
+// Read returns the contents of a stream entry
+func (f *File) Read(entry *Entry) ([]byte, error) {
+	if !entry.IsStream() {
+		return nil, fmt.Errorf("%s is not a stream", entry.Path)
+	}
+	if entry.Size == 0 {
+		return []byte{}, nil
+	}
+	if entry.Size > int64(len(f.data)) {
+		return nil, fmt.Errorf("%w: stream %s larger than the file", ErrCorrupt, entry.Path)
+	}
+
+	if entry.Size < f.miniCutoff {
+		return f.readMiniChain(entry.start, entry.Size)
+	}
+	return f.readChain(entry.start, entry.Size)
+}
+
+// decodeName converts a UTF-16LE directory entry name, dropping the terminator
+func decodeName(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		u := binary.LittleEndian.Uint16(b[i:])
+		if u == 0 {
+			break
+		}
+		units = append(units, u)
+	}
+	return string(utf16.Decode(units))
+}
+
+// fileTime converts a Windows FILETIME (100ns intervals since 1601) to time.Time
+func fileTime(ft uint64) time.Time {
+	if ft == 0 {
+		return time.Time{}
+	}
+	const epochDiff = 116444736000000000 // 1601-01-01 to 1970-01-01 in 100ns units
+	const maxFileTime = epochDiff + math.MaxInt64/100
+	if ft < epochDiff || ft > maxFileTime {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(ft-epochDiff)*100).UTC()
+}
@@ -0,0 +1,158 @@
+package ole
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/ole/oletest"
+)
+
+func TestBuildAndOpen(t *testing.T) {
+	large := bytes.Repeat([]byte("WordDocument"), 1000) // Above the mini stream cutoff
+	streams := map[string][]byte{
+		"WordDocument":             large,
+		"1Table":                   []byte("table stream"),
+		"Macros/VBA/dir":           []byte{0x01, 0x02, 0x03},
+		"Macros/PROJECT":           []byte("ID=\"{}\""),
+		"ObjectPool/_1234/\x01Ole": make([]byte, 20),
+	}
+
+	data, err := oletest.Build(streams)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	f, err := Open(data)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	want := []string{"1Table", "Macros/PROJECT", "Macros/VBA/dir", "ObjectPool/_1234/\x01Ole", "WordDocument"}
+	if got := f.Streams(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Streams() = %q, want %q", got, want)
+	}
+
+	for path, content := range streams {
+		got, err := f.ReadStream(path)
+		if err != nil {
+			t.Errorf("ReadStream(%q) failed: %v", path, err)
+			continue
+		}
+		if !bytes.Equal(got, content) {
+			t.Errorf("ReadStream(%q) returned %d bytes, want %d", path, len(got), len(content))
+		}
+	}
+
+	if entry, ok := f.Find("macros/vba"); !ok || entry.Type != EntryStorage {
+		t.Errorf("expected case-insensitive lookup of Macros/VBA storage")
+	}
+	if _, err := f.ReadStream("Data"); !errors.Is(err, ErrStreamNotFound) {
+		t.Errorf("expected ErrStreamNotFound, got %v", err)
+	}
+}
+
+func TestOpenRejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name string
+		data func() []byte
+		want error
+	}{
+		{"empty", func() []byte { return nil }, ErrNotCFB},
+		{"zip", func() []byte { return append([]byte("PK\x03\x04"), make([]byte, 600)...) }, ErrNotCFB},
+		{"bad sector shift", func() []byte {
+			data, _ := oletest.Build(map[string][]byte{"a": []byte("x")})
+			binary.LittleEndian.PutUint16(data[30:], 7)
+			return data
+		}, ErrCorrupt},
+		{"looping chain", func() []byte {
+			data, _ := oletest.Build(map[string][]byte{"a": make([]byte, 5000)})
+			// Point the directory's FAT entry back at itself
+			dirStart := binary.LittleEndian.Uint32(data[48:])
+			binary.LittleEndian.PutUint32(data[headerSize+int(dirStart)*4:], dirStart)
+			return data
+		}, ErrCorrupt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Open(tt.data()); !errors.Is(err, tt.want) {
+				t.Errorf("Open() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummaryInformation(t *testing.T) {
+	data, err := oletest.Build(map[string][]byte{
+		SummaryInformationStream: propertySet(map[uint32][]byte{
+			1:  typed(vtI2, []byte{0xE4, 0x04}), // Codepage 1252
+			2:  lpstr("Letter of Advice"),
+			4:  lpstr("J\xf6rg Smith"),
+			7:  lpstr("Normal.dot"),
+			14: typed(vtI4, []byte{3, 0, 0, 0}),
+		}),
+	})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	f, err := Open(data)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	info, err := f.SummaryInformation()
+	if err != nil {
+		t.Fatalf("SummaryInformation failed: %v", err)
+	}
+	if info.Title != "Letter of Advice" || info.Author != "Jörg Smith" || info.Template != "Normal.dot" || info.Pages != 3 {
+		t.Errorf("unexpected summary information: %+v", info)
+	}
+}
+
+// propertySet encodes a single-section property set stream
+func propertySet(props map[uint32][]byte) []byte {
+	le := binary.LittleEndian
+	ids := make([]uint32, 0, len(props))
+	for id := range props {
+		ids = append(ids, id)
+	}
+
+	section := make([]byte, 8+len(ids)*8)
+	for i, id := range ids {
+		le.PutUint32(section[8+i*8:], id)
+		le.PutUint32(section[12+i*8:], uint32(len(section)))
+		section = append(section, pad(props[id], 4)...)
+	}
+	le.PutUint32(section, uint32(len(section)))
+	le.PutUint32(section[4:], uint32(len(ids)))
+
+	header := make([]byte, 48)
+	le.PutUint16(header, 0xFFFE)
+	le.PutUint32(header[24:], 1)
+	le.PutUint32(header[44:], 48)
+	return append(header, section...)
+}
+
+func typed(vt uint32, body []byte) []byte {
+	b := make([]byte, 4, 4+len(body))
+	binary.LittleEndian.PutUint32(b, vt)
+	return append(b, body...)
+}
+
+func lpstr(s string) []byte {
+	body := make([]byte, 4, 5+len(s))
+	binary.LittleEndian.PutUint32(body, uint32(len(s)+1))
+	body = append(body, s...)
+	return typed(vtLPSTR, append(body, 0))
+}
+
+// pad extends data with zeros to a multiple of size
+func pad(data []byte, size int) []byte {
+	padded := make([]byte, (len(data)+size-1)/size*size)
+	copy(padded, data)
+	return padded
+}
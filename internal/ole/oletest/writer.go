@@ -0,0 +1,289 @@
+// Package oletest builds small Compound File Binary files for tests of the
+// packages that read them.
+package oletest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	writerSectorSize     = 512
+	writerMiniSectorSize = 64
+	writerMiniCutoff     = 4096
+)
+
+// Compound file layout, as read by package ole
+const (
+	headerSize   = 512
+	dirEntrySize = 128
+	headerDIFAT  = 109
+
+	fatSect    = 0xFFFFFFFD
+	endOfChain = 0xFFFFFFFE
+	freeSect   = 0xFFFFFFFF
+	noStream   = 0xFFFFFFFF
+)
+
+// entryType is the object type of a directory entry
+type entryType byte
+
+const (
+	entryStorage entryType = 1
+	entryStream  entryType = 2
+	entryRoot    entryType = 5
+)
+
+// signature is the magic number at the start of every compound file
+var signature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// node is a directory entry being laid out by Build
+type node struct {
+	name     string
+	typ      entryType
+	data     []byte
+	children []*node
+	id       uint32
+	right    uint32
+	start    uint32
+	size     uint64
+}
+
+// Build writes a version 3 compound file containing the given streams, keyed by
+// slash-separated path; intermediate storages are created as needed.
+func Build(streams map[string][]byte) ([]byte, error) {
+	root := &node{name: "Root Entry", typ: entryRoot}
+
+	paths := make([]string, 0, len(streams))
+	for path := range streams {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		parts := strings.Split(strings.Trim(path, "/"), "/")
+		parent := root
+		for i, part := range parts {
+			if len(utf16.Encode([]rune(part))) > 31 {
+				return nil, fmt.Errorf("entry name %q is longer than 31 characters", part)
+			}
+			var child *node
+			for _, existing := range parent.children {
+				if strings.EqualFold(existing.name, part) {
+					child = existing
+					break
+				}
+			}
+			if child == nil {
+				child = &node{name: part, typ: entryStorage}
+				parent.children = append(parent.children, child)
+			}
+			if i == len(parts)-1 {
+				if len(child.children) > 0 {
+					return nil, fmt.Errorf("%s is both a storage and a stream", path)
+				}
+				child.typ = entryStream
+				child.data = streams[path]
+			} else if child.typ == entryStream {
+				return nil, fmt.Errorf("%s is both a storage and a stream", path)
+			}
+			parent = child
+		}
+	}
+
+	// Number entries depth first; the root is entry 0
+	var nodes []*node
+	var number func(n *node)
+	number = func(n *node) {
+		n.id = uint32(len(nodes))
+		n.right = noStream
+		nodes = append(nodes, n)
+		sort.Slice(n.children, func(i, j int) bool {
+			return lessName(n.children[i].name, n.children[j].name)
+		})
+		for _, child := range n.children {
+			number(child)
+		}
+	}
+	number(root)
+	for _, n := range nodes {
+		for i := 0; i+1 < len(n.children); i++ {
+			n.children[i].right = n.children[i+1].id
+		}
+	}
+
+	// Small streams go to the mini stream, large ones to regular sectors
+	var miniStream []byte
+	var miniFAT []uint32
+	var large []*node
+	for _, n := range nodes {
+		if n.typ != entryStream {
+			continue
+		}
+		n.size = uint64(len(n.data))
+		if len(n.data) == 0 {
+			n.start = endOfChain
+			continue
+		}
+		if len(n.data) >= writerMiniCutoff {
+			large = append(large, n)
+			continue
+		}
+
+		n.start = uint32(len(miniFAT))
+		count := (len(n.data) + writerMiniSectorSize - 1) / writerMiniSectorSize
+		for i := 0; i < count; i++ {
+			miniFAT = append(miniFAT, n.start+uint32(i)+1)
+		}
+		miniFAT[len(miniFAT)-1] = endOfChain
+		miniStream = append(miniStream, pad(n.data, writerMiniSectorSize)...)
+	}
+
+	// Unused directory slots and mini FAT entries are marked free
+	dirData := make([]byte, len(pad(make([]byte, len(nodes)*dirEntrySize), writerSectorSize)))
+	for off := len(nodes) * dirEntrySize; off < len(dirData); off += dirEntrySize {
+		writeDirEntry(dirData[off:], &node{right: noStream})
+	}
+	for len(miniFAT)%(writerSectorSize/4) != 0 {
+		miniFAT = append(miniFAT, freeSect)
+	}
+	miniFATData := make([]byte, len(miniFAT)*4)
+	for i, v := range miniFAT {
+		binary.LittleEndian.PutUint32(miniFATData[i*4:], v)
+	}
+
+	// Lay out the regular sectors after the FAT: directory, mini FAT, mini stream,
+	// then each large stream
+	type chain struct {
+		data  []byte
+		start *uint32
+	}
+	var dirStart, miniFATStart uint32 = endOfChain, endOfChain
+	root.start = endOfChain
+	chains := []chain{{dirData, &dirStart}, {miniFATData, &miniFATStart}, {miniStream, &root.start}}
+	for _, n := range large {
+		chains = append(chains, chain{n.data, &n.start})
+	}
+	root.size = uint64(len(miniStream))
+
+	dataSectors := 0
+	for _, c := range chains {
+		dataSectors += sectorsFor(len(c.data))
+	}
+	perFAT := writerSectorSize / 4
+	fatSectors := 1
+	for fatSectors*perFAT < dataSectors+fatSectors {
+		fatSectors++
+	}
+	if fatSectors > headerDIFAT {
+		return nil, fmt.Errorf("compound file too large for Build")
+	}
+
+	fat := make([]uint32, fatSectors*perFAT)
+	for i := range fat {
+		fat[i] = freeSect
+	}
+	for i := 0; i < fatSectors; i++ {
+		fat[i] = fatSect
+	}
+
+	next := uint32(fatSectors)
+	for _, c := range chains {
+		count := sectorsFor(len(c.data))
+		if count == 0 {
+			continue
+		}
+		*c.start = next
+		for i := 0; i < count; i++ {
+			fat[next+uint32(i)] = next + uint32(i) + 1
+		}
+		fat[next+uint32(count)-1] = endOfChain
+		next += uint32(count)
+	}
+
+	// Directory entries can only be written once every start sector is known
+	for _, n := range nodes {
+		writeDirEntry(dirData[n.id*dirEntrySize:], n)
+	}
+
+	body := make([]byte, 0, dataSectors*writerSectorSize)
+	for _, c := range chains {
+		body = append(body, pad(c.data, writerSectorSize)...)
+	}
+
+	out := make([]byte, headerSize, headerSize+len(fat)*4+len(body))
+	le := binary.LittleEndian
+	copy(out, signature)
+	le.PutUint16(out[24:], 0x003E)
+	le.PutUint16(out[26:], 3)
+	le.PutUint16(out[28:], 0xFFFE)
+	le.PutUint16(out[30:], 9)
+	le.PutUint16(out[32:], 6)
+	le.PutUint32(out[44:], uint32(fatSectors))
+	le.PutUint32(out[48:], dirStart)
+	le.PutUint32(out[56:], writerMiniCutoff)
+	le.PutUint32(out[60:], miniFATStart)
+	le.PutUint32(out[64:], uint32(sectorsFor(len(miniFATData))))
+	le.PutUint32(out[68:], endOfChain)
+	for i := 0; i < headerDIFAT; i++ {
+		value := uint32(freeSect)
+		if i < fatSectors {
+			value = uint32(i)
+		}
+		le.PutUint32(out[76+i*4:], value)
+	}
+
+	fatData := make([]byte, len(fat)*4)
+	for i, v := range fat {
+		le.PutUint32(fatData[i*4:], v)
+	}
+	out = append(out, fatData...)
+	out = append(out, body...)
+	return out, nil
+}
+
+// writeDirEntry encodes a directory entry; siblings are chained through the
+// right pointer, which is a valid (if unbalanced) all-black tree
+func writeDirEntry(b []byte, n *node) {
+	le := binary.LittleEndian
+	name := utf16.Encode([]rune(n.name))
+	for i, u := range name {
+		le.PutUint16(b[i*2:], u)
+	}
+	le.PutUint16(b[64:], uint16((len(name)+1)*2))
+	b[66] = byte(n.typ)
+	b[67] = 1 // Black
+	le.PutUint32(b[68:], noStream)
+	le.PutUint32(b[72:], n.right)
+	le.PutUint32(b[76:], noStream)
+	if len(n.children) > 0 {
+		le.PutUint32(b[76:], n.children[0].id)
+	}
+	le.PutUint32(b[116:], n.start)
+	le.PutUint64(b[120:], n.size)
+}
+
+// lessName orders sibling names the way compound files do: shorter names first,
+// then by upper-cased code units
+func lessName(a, b string) bool {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	if len(ua) != len(ub) {
+		return len(ua) < len(ub)
+	}
+	return strings.ToUpper(a) < strings.ToUpper(b)
+}
+
+// sectorsFor returns the number of regular sectors needed for n bytes
+func sectorsFor(n int) int {
+	return (n + writerSectorSize - 1) / writerSectorSize
+}
+
+// pad extends data with zeros to a multiple of size
+func pad(data []byte, size int) []byte {
+	padded := make([]byte, (len(data)+size-1)/size*size)
+	copy(padded, data)
+	return padded
+}
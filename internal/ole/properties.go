@@ -0,0 +1,253 @@
+package ole
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Property set stream names
+const (
+	SummaryInformationStream         = "\x05SummaryInformation"
+	DocumentSummaryInformationStream = "\x05DocumentSummaryInformation"
+)
+
+// Property value types (VT_*) understood by the parser
+const (
+	vtI2       = 0x0002
+	vtI4       = 0x0003
+	vtBool     = 0x000B
+	vtUI4      = 0x0013
+	vtLPSTR    = 0x001E
+	vtLPWSTR   = 0x001F
+	vtFileTime = 0x0040
+)
+
+// codepageUTF16 marks property sets whose 8-bit strings are really UTF-16
+const codepageUTF16 = 1200
+
+// SummaryInformation holds the standard document properties
+type SummaryInformation struct {
+	Title      string
+	Subject    string
+	Author     string
+	Keywords   string
+	Comments   string
+	Template   string
+	LastAuthor string
+	Revision   string
+	AppName    string
+	Created    time.Time
+	LastSaved  time.Time
+	Pages      int
+	Words      int
+	Characters int
+}
+
+// DocumentSummaryInformation holds the extended document properties
+type DocumentSummaryInformation struct {
+	Category string
+	Manager  string
+	Company  string
+}
+
+// SummaryInformation parses the \005SummaryInformation stream
+func (f *File) SummaryInformation() (*SummaryInformation, error) {
+	data, err := f.ReadStream(SummaryInformationStream)
+	if err != nil {
+		return nil, err
+	}
+
+	props, err := ParsePropertySet(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SummaryInformation{
+		Title:      propString(props, 2),
+		Subject:    propString(props, 3),
+		Author:     propString(props, 4),
+		Keywords:   propString(props, 5),
+		Comments:   propString(props, 6),
+		Template:   propString(props, 7),
+		LastAuthor: propString(props, 8),
+		Revision:   propString(props, 9),
+		AppName:    propString(props, 18),
+		Created:    propTime(props, 12),
+		LastSaved:  propTime(props, 13),
+		Pages:      propInt(props, 14),
+		Words:      propInt(props, 15),
+		Characters: propInt(props, 16),
+	}, nil
+}
+
+// DocumentSummaryInformation parses the \005DocumentSummaryInformation stream
+func (f *File) DocumentSummaryInformation() (*DocumentSummaryInformation, error) {
+	data, err := f.ReadStream(DocumentSummaryInformationStream)
+	if err != nil {
+		return nil, err
+	}
+
+	props, err := ParsePropertySet(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DocumentSummaryInformation{
+		Category: propString(props, 2),
+		Manager:  propString(props, 14),
+		Company:  propString(props, 15),
+	}, nil
+}
+
+// ParsePropertySet decodes the first property set of a property set stream into
+// values keyed by property ID. Strings, integers, booleans and FILETIMEs are
+// decoded; other types are skipped.
+func ParsePropertySet(data []byte) (map[uint32]interface{}, error) {
+	le := binary.LittleEndian
+	if len(data) < 48 || le.Uint16(data) != 0xFFFE {
+		return nil, fmt.Errorf("%w: invalid property set stream", ErrCorrupt)
+	}
+	if le.Uint32(data[24:]) == 0 {
+		return map[uint32]interface{}{}, nil
+	}
+
+	setOffset := int(le.Uint32(data[44:]))
+	if setOffset < 0 || setOffset+8 > len(data) {
+		return nil, fmt.Errorf("%w: property set offset out of range", ErrCorrupt)
+	}
+	set := data[setOffset:]
+	if size := int(le.Uint32(set)); size >= 8 && size < len(set) {
+		set = set[:size]
+	}
+
+	count := int(le.Uint32(set[4:]))
+	if count < 0 || 8+count*8 > len(set) {
+		return nil, fmt.Errorf("%w: property count out of range", ErrCorrupt)
+	}
+
+	type location struct {
+		id     uint32
+		offset int
+	}
+	locations := make([]location, 0, count)
+	codepage := 0
+	for i := 0; i < count; i++ {
+		id := le.Uint32(set[8+i*8:])
+		offset := int(le.Uint32(set[12+i*8:]))
+		locations = append(locations, location{id, offset})
+
+		// The codepage (property 1) decides how 8-bit strings are decoded
+		if id == 1 && offset+6 <= len(set) && le.Uint32(set[offset:])&0xFFFF == vtI2 {
+			codepage = int(le.Uint16(set[offset+4:]))
+		}
+	}
+
+	props := make(map[uint32]interface{}, count)
+	for _, loc := range locations {
+		if loc.offset < 8 || loc.offset+4 > len(set) {
+			continue
+		}
+		if value, ok := decodeProperty(set[loc.offset:], codepage); ok {
+			props[loc.id] = value
+		}
+	}
+	return props, nil
+}
+
+// decodeProperty reads one typed property value
+func decodeProperty(b []byte, codepage int) (interface{}, bool) {
+	le := binary.LittleEndian
+	vt := le.Uint32(b) & 0xFFFF
+	body := b[4:]
+
+	switch vt {
+	case vtI2:
+		if len(body) >= 2 {
+			return int(int16(le.Uint16(body))), true
+		}
+	case vtI4:
+		if len(body) >= 4 {
+			return int(int32(le.Uint32(body))), true
+		}
+	case vtUI4:
+		if len(body) >= 4 {
+			return int(le.Uint32(body)), true
+		}
+	case vtBool:
+		if len(body) >= 2 {
+			return le.Uint16(body) != 0, true
+		}
+	case vtFileTime:
+		if len(body) >= 8 {
+			return fileTime(le.Uint64(body)), true
+		}
+	case vtLPSTR:
+		if len(body) >= 4 {
+			n := int(le.Uint32(body))
+			if n >= 0 && 4+n <= len(body) {
+				raw := body[4 : 4+n]
+				if codepage == codepageUTF16 {
+					return decodeUTF16(raw), true
+				}
+				return strings.TrimRight(DecodeANSI(raw), "\x00"), true
+			}
+		}
+	case vtLPWSTR:
+		if len(body) >= 4 {
+			n := int(le.Uint32(body)) * 2
+			if n >= 0 && 4+n <= len(body) {
+				return decodeUTF16(body[4 : 4+n]), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// decodeUTF16 converts UTF-16LE bytes, dropping trailing terminators
+func decodeUTF16(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(b[i*2:])
+	}
+	return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+}
+
+// cp1252 maps the 0x80-0x9F range of Windows-1252 to Unicode; the rest of the
+// code page matches Latin-1
+var cp1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+// DecodeANSI converts Windows-1252 bytes, the code page of 8-bit text in legacy
+// Western Office documents, to a string
+func DecodeANSI(b []byte) string {
+	var sb strings.Builder
+	sb.Grow(len(b))
+	for _, c := range b {
+		if c >= 0x80 && c < 0xA0 {
+			sb.WriteRune(cp1252[c-0x80])
+		} else {
+			sb.WriteRune(rune(c))
+		}
+	}
+	return sb.String()
+}
+
+func propString(props map[uint32]interface{}, id uint32) string {
+	s, _ := props[id].(string)
+	return strings.TrimSpace(s)
+}
+
+func propInt(props map[uint32]interface{}, id uint32) int {
+	n, _ := props[id].(int)
+	return n
+}
+
+func propTime(props map[uint32]interface{}, id uint32) time.Time {
+	t, _ := props[id].(time.Time)
+	return t
+}
@@ -0,0 +1,92 @@
+package queue
+
+import (
+	"errors"
+	"time"
+)
+
+// Batch status values derived from the status of its jobs
+const (
+	BatchStatusPending    = "pending"
+	BatchStatusProcessing = "processing"
+	BatchStatusCompleted  = "completed"
+	BatchStatusFailed     = "failed"
+	BatchStatusPartial    = "partial" // Finished with a mix of completed and failed jobs
+	BatchStatusCancelled  = "cancelled"
+)
+
+var (
+	// ErrBatchNotFound is returned when a batch is not found
+	ErrBatchNotFound = errors.New("batch not found")
+)
+
+// Batch groups jobs submitted together; counts are refreshed from the jobs on read
+type Batch struct {
+	ID         string            `json:"id"`
+	Status     string            `json:"status"`
+	JobIDs     []string          `json:"job_ids"`
+	Total      int               `json:"total"`
+	Pending    int               `json:"pending"`
+	Processing int               `json:"processing"`
+	Completed  int               `json:"completed"`
+	Failed     int               `json:"failed"`
+	Cancelled  int               `json:"cancelled"`
+	Progress   float64           `json:"progress"` // Fraction of jobs that reached a final state
+	CreatedAt  time.Time         `json:"created_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+// Tally recomputes the batch counts, status and finish time from its jobs
+func (b *Batch) Tally(jobs []*Job) {
+	b.Total = len(b.JobIDs)
+	b.Pending, b.Processing, b.Completed, b.Failed, b.Cancelled = 0, 0, 0, 0, 0
+	b.FinishedAt = nil
+
+	var finished time.Time
+	for _, job := range jobs {
+		switch job.Status {
+		case StatusPending, StatusRetrying:
+			b.Pending++
+		case StatusProcessing:
+			b.Processing++
+		case StatusCompleted:
+			b.Completed++
+		case StatusFailed, StatusDeadLetter:
+			b.Failed++
+		case StatusCancelled:
+			b.Cancelled++
+		}
+
+		if job.CompletedAt != nil && job.CompletedAt.After(finished) {
+			finished = *job.CompletedAt
+		}
+	}
+
+	// Jobs that disappeared from the backend can never finish; count them as failed
+	b.Failed += b.Total - len(jobs)
+
+	done := b.Completed + b.Failed + b.Cancelled
+	if b.Total > 0 {
+		b.Progress = float64(done) / float64(b.Total)
+	}
+
+	switch {
+	case b.Total == 0 || b.Pending == b.Total:
+		b.Status = BatchStatusPending
+	case done < b.Total:
+		b.Status = BatchStatusProcessing
+	case b.Completed == b.Total:
+		b.Status = BatchStatusCompleted
+	case b.Cancelled == b.Total:
+		b.Status = BatchStatusCancelled
+	case b.Completed == 0 && b.Failed > 0:
+		b.Status = BatchStatusFailed
+	default:
+		b.Status = BatchStatusPartial
+	}
+
+	if done == b.Total && !finished.IsZero() {
+		b.FinishedAt = &finished
+	}
+}
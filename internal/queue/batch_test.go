@@ -0,0 +1,54 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBatchTally(t *testing.T) {
+	done := time.Now()
+
+	job := func(status string) *Job {
+		j := &Job{Status: status}
+		if status == StatusCompleted || status == StatusFailed || status == StatusCancelled || status == StatusDeadLetter {
+			j.CompletedAt = &done
+		}
+		return j
+	}
+
+	tests := []struct {
+		name     string
+		statuses []string
+		expected string
+		finished bool
+	}{
+		{"all pending", []string{StatusPending, StatusRetrying}, BatchStatusPending, false},
+		{"in progress", []string{StatusCompleted, StatusProcessing}, BatchStatusProcessing, false},
+		{"all completed", []string{StatusCompleted, StatusCompleted}, BatchStatusCompleted, true},
+		{"all failed", []string{StatusFailed, StatusDeadLetter}, BatchStatusFailed, true},
+		{"mixed outcome", []string{StatusCompleted, StatusFailed, StatusCancelled}, BatchStatusPartial, true},
+		{"all cancelled", []string{StatusCancelled}, BatchStatusCancelled, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs := make([]*Job, 0, len(tt.statuses))
+			batch := &Batch{}
+			for i, status := range tt.statuses {
+				jobs = append(jobs, job(status))
+				batch.JobIDs = append(batch.JobIDs, string(rune('a'+i)))
+			}
+
+			batch.Tally(jobs)
+			if batch.Status != tt.expected {
+				t.Errorf("expected status %s, got %s", tt.expected, batch.Status)
+			}
+			if (batch.FinishedAt != nil) != tt.finished {
+				t.Errorf("expected finished=%v, got %v", tt.finished, batch.FinishedAt)
+			}
+			if batch.Total != len(tt.statuses) {
+				t.Errorf("expected total %d, got %d", len(tt.statuses), batch.Total)
+			}
+		})
+	}
+}
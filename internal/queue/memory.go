@@ -5,73 +5,254 @@ import (
 	"sort"
 	"sync"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // MemoryQueue implements Queue interface using in-memory storage
 type MemoryQueue struct {
-	mu       sync.RWMutex
-	jobs     map[string]*Job
-	pending  []*Job
-	nextPoll time.Time
+	mu         sync.RWMutex
+	jobs       map[string]*Job
+	pending    []*Job
+	delayed    []*Job           // Retrying jobs waiting for NextAttemptAt
+	deadLetter []*Job           // Jobs that exhausted their attempts, oldest first
+	inFlight   map[string]lease // Lease of each dequeued job
+	batches    map[string]*Batch
+	wake       chan struct{} // Closed and replaced whenever a job becomes pending
+	retry      RetryPolicy
+	lease      time.Duration
+}
+
+// lease is the token and deadline of an in-flight job
+type lease struct {
+	token    string
+	deadline time.Time
 }
 
 // NewMemoryQueue creates a new in-memory queue
 func NewMemoryQueue() *MemoryQueue {
 	return &MemoryQueue{
-		jobs:    make(map[string]*Job),
-		pending: make([]*Job, 0),
+		jobs:       make(map[string]*Job),
+		pending:    make([]*Job, 0),
+		delayed:    make([]*Job, 0),
+		deadLetter: make([]*Job, 0),
+		inFlight:   make(map[string]lease),
+		batches:    make(map[string]*Batch),
+		wake:       make(chan struct{}),
+		retry:      DefaultRetryPolicy,
+		lease:      DefaultLeaseDuration,
 	}
 }
 
+// SetLeaseDuration sets how long a dequeued job stays in flight without a heartbeat
+func (q *MemoryQueue) SetLeaseDuration(d time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.lease = d
+}
+
+// SetRetryPolicy sets the backoff and attempt limit used for failed jobs
+func (q *MemoryQueue) SetRetryPolicy(policy RetryPolicy) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.retry = policy
+}
+
 // Enqueue adds a job to the queue
 func (q *MemoryQueue) Enqueue(ctx context.Context, job *Job) error {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	// Store job
-	q.jobs[job.ID] = job
+	if job.MaxAttempts == 0 {
+		job.MaxAttempts = q.retry.MaxAttempts
+	}
 
-	// Add to pending queue if status is pending
-	if job.Status == StatusPending || job.Status == "" {
+	if job.Status == "" {
 		job.Status = StatusPending
-		q.pending = append(q.pending, job)
+	}
 
-		// Sort by priority (higher priority first) then by creation time
-		sort.Slice(q.pending, func(i, j int) bool {
-			if q.pending[i].Priority != q.pending[j].Priority {
-				return q.pending[i].Priority > q.pending[j].Priority
-			}
-			return q.pending[i].CreatedAt.Before(q.pending[j].CreatedAt)
-		})
+	// Store a copy so callers and workers never share the queue's job
+	stored := *job
+	q.jobs[job.ID] = &stored
+
+	// Add to pending queue if status is pending
+	if stored.Status == StatusPending {
+		q.pushPending(&stored)
 	}
 
 	return nil
 }
 
+// pushPending adds a job to the pending queue; callers must hold the lock
+func (q *MemoryQueue) pushPending(job *Job) {
+	q.pending = append(q.pending, job)
+
+	// Sort by priority (higher priority first) then by creation time
+	sort.Slice(q.pending, func(i, j int) bool {
+		if q.pending[i].Priority != q.pending[j].Priority {
+			return q.pending[i].Priority > q.pending[j].Priority
+		}
+		return q.pending[i].CreatedAt.Before(q.pending[j].CreatedAt)
+	})
+
+	// Wake every blocked DequeueWait caller
+	close(q.wake)
+	q.wake = make(chan struct{})
+}
+
+// promoteDue moves retrying jobs whose backoff has elapsed back to pending;
+// callers must hold the lock
+func (q *MemoryQueue) promoteDue(now time.Time) {
+	remaining := q.delayed[:0]
+	for _, job := range q.delayed {
+		if job.NextAttemptAt == nil || !job.NextAttemptAt.After(now) {
+			job.Status = StatusPending
+			q.pushPending(job)
+			continue
+		}
+		remaining = append(remaining, job)
+	}
+	q.delayed = remaining
+}
+
 // Dequeue retrieves the next job from the queue
 func (q *MemoryQueue) Dequeue(ctx context.Context) (*Job, error) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	// Check if we should wait before polling
-	if time.Now().Before(q.nextPoll) {
+	job, _ := q.dequeueLocked(time.Now())
+	if job == nil {
 		return nil, ErrNoJobs
 	}
+	return job, nil
+}
+
+// DequeueWait blocks until a job is available, the timeout elapses (ErrNoJobs)
+// or ctx is cancelled
+func (q *MemoryQueue) DequeueWait(ctx context.Context, timeout time.Duration) (*Job, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		q.mu.Lock()
+		job, nextDue := q.dequeueLocked(time.Now())
+		wake := q.wake
+		q.mu.Unlock()
+
+		if job != nil {
+			return job, nil
+		}
+
+		// Also wake up when the earliest retrying job becomes due
+		var due <-chan time.Time
+		var dueTimer *time.Timer
+		if !nextDue.IsZero() {
+			dueTimer = time.NewTimer(time.Until(nextDue))
+			due = dueTimer.C
+		}
+
+		var err error
+		select {
+		case <-wake:
+		case <-due:
+		case <-deadline.C:
+			err = ErrNoJobs
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+
+		if dueTimer != nil {
+			dueTimer.Stop()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// dequeueLocked pops and leases the highest priority job, returning the time the
+// next retrying job becomes due when nothing is pending; callers must hold the lock
+func (q *MemoryQueue) dequeueLocked(now time.Time) (*Job, time.Time) {
+	q.promoteDue(now)
 
 	if len(q.pending) == 0 {
-		// Set next poll time to avoid busy waiting
-		q.nextPoll = time.Now().Add(100 * time.Millisecond)
-		return nil, ErrNoJobs
+		var nextDue time.Time
+		for _, job := range q.delayed {
+			if job.NextAttemptAt != nil && (nextDue.IsZero() || job.NextAttemptAt.Before(nextDue)) {
+				nextDue = *job.NextAttemptAt
+			}
+		}
+		return nil, nextDue
 	}
 
-	// Get the first job (highest priority)
-	job := q.pending[0]
+	// Get the first job (highest priority) and lease a copy of it, so the
+	// reaper never changes a job under the worker
+	job := *q.pending[0]
 	q.pending = q.pending[1:]
+	job.Lease = uuid.New().String()
+	q.inFlight[job.ID] = lease{token: job.Lease, deadline: now.Add(q.lease)}
 
-	// Reset poll time on successful dequeue
-	q.nextPoll = time.Time{}
+	return &job, time.Time{}
+}
 
-	return job, nil
+// holdsLease reports whether token is the current lease of an in-flight job;
+// callers must hold the lock
+func (q *MemoryQueue) holdsLease(id, token string) bool {
+	l, leased := q.inFlight[id]
+	return leased && l.token == token
+}
+
+// ExtendLease pushes back the lease deadline of an in-flight job
+func (q *MemoryQueue) ExtendLease(ctx context.Context, id, token string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.holdsLease(id, token) {
+		return ErrLeaseLost
+	}
+	q.inFlight[id] = lease{token: token, deadline: time.Now().Add(q.lease)}
+	return nil
+}
+
+// Ack releases the lease of a finished job
+func (q *MemoryQueue) Ack(ctx context.Context, id, token string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.holdsLease(id, token) {
+		return ErrLeaseLost
+	}
+	delete(q.inFlight, id)
+	return nil
+}
+
+// ReapExpired returns jobs with expired leases to the pending queue
+func (q *MemoryQueue) ReapExpired(ctx context.Context) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	reaped := 0
+	for id, l := range q.inFlight {
+		if l.deadline.After(now) {
+			continue
+		}
+		delete(q.inFlight, id)
+
+		job, exists := q.jobs[id]
+		if !exists {
+			continue
+		}
+
+		if q.retry.expireLease(job) {
+			q.pushPending(job)
+		} else {
+			q.deadLetter = append(q.deadLetter, job)
+		}
+		reaped++
+	}
+
+	return reaped, nil
 }
 
 // GetJob retrieves a job by ID
@@ -94,15 +275,17 @@ func (q *MemoryQueue) UpdateJob(job *Job) error {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	if _, exists := q.jobs[job.ID]; !exists {
+	stored, exists := q.jobs[job.ID]
+	if !exists {
 		return ErrJobNotFound
 	}
 
-	q.jobs[job.ID] = job
+	// Copy into the stored job, which the pending and delayed queues point to
+	*stored = *job
 	return nil
 }
 
-// CancelJob cancels a pending job
+// CancelJob cancels a pending or retrying job
 func (q *MemoryQueue) CancelJob(ctx context.Context, id string) error {
 	q.mu.Lock()
 	defer q.mu.Unlock()
@@ -112,17 +295,13 @@ func (q *MemoryQueue) CancelJob(ctx context.Context, id string) error {
 		return ErrJobNotFound
 	}
 
-	if job.Status != StatusPending {
+	if job.Status != StatusPending && job.Status != StatusRetrying {
 		return ErrJobNotFound
 	}
 
-	// Remove from pending queue
-	for i, pendingJob := range q.pending {
-		if pendingJob.ID == id {
-			q.pending = append(q.pending[:i], q.pending[i+1:]...)
-			break
-		}
-	}
+	// Remove from pending and delayed queues
+	q.pending = removeJob(q.pending, id)
+	q.delayed = removeJob(q.delayed, id)
 
 	// Update job status
 	job.Status = StatusCancelled
@@ -132,6 +311,139 @@ func (q *MemoryQueue) CancelJob(ctx context.Context, id string) error {
 	return nil
 }
 
+// Retry re-schedules a failed job with backoff or dead-letters it
+func (q *MemoryQueue) Retry(ctx context.Context, job *Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stored, exists := q.jobs[job.ID]
+	if !exists {
+		return ErrJobNotFound
+	}
+	if !q.holdsLease(job.ID, job.Lease) {
+		return ErrLeaseLost
+	}
+	delete(q.inFlight, job.ID)
+
+	retry := q.retry.scheduleRetry(job)
+	*stored = *job
+	if retry {
+		q.delayed = append(q.delayed, stored)
+	} else {
+		q.deadLetter = append(q.deadLetter, stored)
+	}
+
+	return nil
+}
+
+// ListDeadLetter lists dead-lettered jobs, newest first
+func (q *MemoryQueue) ListDeadLetter(ctx context.Context, limit int) ([]*Job, error) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	jobs := make([]*Job, 0, len(q.deadLetter))
+	for i := len(q.deadLetter) - 1; i >= 0; i-- {
+		jobCopy := *q.deadLetter[i]
+		jobs = append(jobs, &jobCopy)
+
+		if limit > 0 && len(jobs) >= limit {
+			break
+		}
+	}
+
+	return jobs, nil
+}
+
+// Redrive moves a dead-lettered job back to the pending queue
+func (q *MemoryQueue) Redrive(ctx context.Context, id string) (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, exists := q.jobs[id]
+	if !exists {
+		return nil, ErrJobNotFound
+	}
+	if job.Status != StatusDeadLetter {
+		return nil, ErrNotDeadLettered
+	}
+
+	q.deadLetter = removeJob(q.deadLetter, id)
+	resetForRedrive(job)
+	q.pushPending(job)
+
+	jobCopy := *job
+	return &jobCopy, nil
+}
+
+// removeJob removes the job with the given ID from a slice
+func removeJob(jobs []*Job, id string) []*Job {
+	for i, job := range jobs {
+		if job.ID == id {
+			return append(jobs[:i], jobs[i+1:]...)
+		}
+	}
+	return jobs
+}
+
+// CreateBatch stores a batch
+func (q *MemoryQueue) CreateBatch(ctx context.Context, batch *Batch) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	batchCopy := *batch
+	q.batches[batch.ID] = &batchCopy
+	return nil
+}
+
+// GetBatch retrieves a batch with up-to-date counts
+func (q *MemoryQueue) GetBatch(ctx context.Context, id string) (*Batch, error) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	batch, exists := q.batches[id]
+	if !exists {
+		return nil, ErrBatchNotFound
+	}
+	return q.tallyLocked(batch), nil
+}
+
+// ListBatches lists batches newest first
+func (q *MemoryQueue) ListBatches(ctx context.Context, limit int) ([]*Batch, error) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	batches := make([]*Batch, 0, len(q.batches))
+	for _, batch := range q.batches {
+		batches = append(batches, batch)
+	}
+
+	sort.Slice(batches, func(i, j int) bool {
+		return batches[i].CreatedAt.After(batches[j].CreatedAt)
+	})
+	if limit > 0 && len(batches) > limit {
+		batches = batches[:limit]
+	}
+
+	for i, batch := range batches {
+		batches[i] = q.tallyLocked(batch)
+	}
+	return batches, nil
+}
+
+// tallyLocked returns a copy of the batch with counts from its jobs; callers must hold the lock
+func (q *MemoryQueue) tallyLocked(batch *Batch) *Batch {
+	jobs := make([]*Job, 0, len(batch.JobIDs))
+	for _, id := range batch.JobIDs {
+		if job, exists := q.jobs[id]; exists {
+			jobs = append(jobs, job)
+		}
+	}
+
+	batchCopy := *batch
+	batchCopy.Tally(jobs)
+	return &batchCopy
+}
+
 // ListJobs lists jobs with optional status filter
 func (q *MemoryQueue) ListJobs(ctx context.Context, status string, limit int) ([]*Job, error) {
 	q.mu.RLock()
@@ -174,7 +486,10 @@ func (q *MemoryQueue) Clear() error {
 
 	q.jobs = make(map[string]*Job)
 	q.pending = make([]*Job, 0)
-	q.nextPoll = time.Time{}
+	q.delayed = make([]*Job, 0)
+	q.deadLetter = make([]*Job, 0)
+	q.inFlight = make(map[string]lease)
+	q.batches = make(map[string]*Batch)
 
 	return nil
 }
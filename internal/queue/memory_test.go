@@ -0,0 +1,237 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryQueueRetryAndDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue()
+	q.SetRetryPolicy(RetryPolicy{MaxAttempts: 2})
+
+	if err := q.Enqueue(ctx, &Job{ID: "job-1", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	// First attempt fails and is re-scheduled with (zero) backoff
+	job, err := q.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+	job.Attempts++
+	if err := q.Retry(ctx, job); err != nil {
+		t.Fatalf("Retry failed: %v", err)
+	}
+	if job.Status != StatusRetrying || job.NextAttemptAt == nil {
+		t.Fatalf("expected retrying job with next attempt time, got %s", job.Status)
+	}
+
+	// Second attempt exhausts the limit and lands in the dead-letter set
+	job, err = q.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("expected due retry to be dequeued: %v", err)
+	}
+	job.Attempts++
+	if err := q.Retry(ctx, job); err != nil {
+		t.Fatalf("Retry failed: %v", err)
+	}
+	if job.Status != StatusDeadLetter {
+		t.Fatalf("expected dead-lettered job, got %s", job.Status)
+	}
+
+	dead, _ := q.ListDeadLetter(ctx, 10)
+	if len(dead) != 1 || dead[0].ID != "job-1" {
+		t.Fatalf("expected job-1 in dead-letter set, got %v", dead)
+	}
+
+	redriven, err := q.Redrive(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("Redrive failed: %v", err)
+	}
+	if redriven.Status != StatusPending || redriven.Attempts != 0 {
+		t.Errorf("expected pending job with reset attempts, got %s/%d", redriven.Status, redriven.Attempts)
+	}
+	if _, err := q.Redrive(ctx, "job-1"); err != ErrNotDeadLettered {
+		t.Errorf("expected ErrNotDeadLettered on second redrive, got %v", err)
+	}
+	if size, _ := q.Size(); size != 1 {
+		t.Errorf("expected re-driven job to be pending, queue size %d", size)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{10, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := policy.Backoff(tt.attempts); got != tt.expected {
+			t.Errorf("Backoff(%d) = %v, expected %v", tt.attempts, got, tt.expected)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 20; i++ {
+		if got := policy.Backoff(2); got < time.Second || got > 3*time.Second {
+			t.Errorf("jittered backoff %v outside ±50%% of 2s", got)
+		}
+	}
+}
+
+func TestMemoryQueueLeaseExpiry(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue()
+	q.SetRetryPolicy(RetryPolicy{MaxAttempts: 2})
+	q.SetLeaseDuration(10 * time.Millisecond)
+
+	if err := q.Enqueue(ctx, &Job{ID: "job-1", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	job, err := q.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+
+	// A heartbeat keeps the job in flight
+	if err := q.ExtendLease(ctx, "job-1", job.Lease); err != nil {
+		t.Fatalf("ExtendLease failed: %v", err)
+	}
+	if reaped, _ := q.ReapExpired(ctx); reaped != 0 {
+		t.Fatalf("expected live lease to survive, reaped %d", reaped)
+	}
+
+	// The worker "crashes": the lease expires and the job is requeued with an attempt counted
+	time.Sleep(20 * time.Millisecond)
+	if reaped, _ := q.ReapExpired(ctx); reaped != 1 {
+		t.Fatalf("expected 1 reaped job, got %d", reaped)
+	}
+	if err := q.ExtendLease(ctx, "job-1", job.Lease); err != ErrLeaseLost {
+		t.Errorf("expected ErrLeaseLost after reaping, got %v", err)
+	}
+
+	job, _ = q.GetJob(ctx, "job-1")
+	if job.Status != StatusPending || job.Attempts != 1 {
+		t.Fatalf("expected pending job with 1 attempt, got %s/%d", job.Status, job.Attempts)
+	}
+
+	// A second expiry exhausts the attempts and dead-letters the job
+	if _, err := q.Dequeue(ctx); err != nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	q.ReapExpired(ctx)
+	if job, _ := q.GetJob(ctx, "job-1"); job.Status != StatusDeadLetter {
+		t.Errorf("expected dead-lettered job, got %s", job.Status)
+	}
+
+	// Acknowledged jobs are never reaped
+	q.Enqueue(ctx, &Job{ID: "job-2", CreatedAt: time.Now()})
+	job, _ = q.Dequeue(ctx)
+	q.Ack(ctx, "job-2", job.Lease)
+	time.Sleep(20 * time.Millisecond)
+	if reaped, _ := q.ReapExpired(ctx); reaped != 0 {
+		t.Errorf("expected acknowledged job to be left alone, reaped %d", reaped)
+	}
+}
+
+func TestMemoryQueueDequeueWait(t *testing.T) {
+	q := NewMemoryQueue()
+
+	// Times out with ErrNoJobs when nothing arrives
+	if _, err := q.DequeueWait(context.Background(), 10*time.Millisecond); err != ErrNoJobs {
+		t.Fatalf("expected ErrNoJobs on timeout, got %v", err)
+	}
+
+	// Wakes as soon as a job is enqueued
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		q.Enqueue(context.Background(), &Job{ID: "job-1", CreatedAt: time.Now()})
+	}()
+	start := time.Now()
+	job, err := q.DequeueWait(context.Background(), 5*time.Second)
+	if err != nil {
+		t.Fatalf("DequeueWait failed: %v", err)
+	}
+	if job.ID != "job-1" {
+		t.Errorf("expected job-1, got %s", job.ID)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("DequeueWait took %v to notice the new job", elapsed)
+	}
+
+	// Returns immediately when the context is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := q.DequeueWait(ctx, 5*time.Second); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestMemoryQueueStaleWorker(t *testing.T) {
+	ctx := context.Background()
+	q := NewMemoryQueue()
+	q.SetLeaseDuration(10 * time.Millisecond)
+
+	if err := q.Enqueue(ctx, &Job{ID: "job-1", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	stale, err := q.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+	stale.Status = StatusProcessing
+
+	// The stale worker stalls, its lease is reaped and the job goes to another worker
+	time.Sleep(20 * time.Millisecond)
+	if reaped, _ := q.ReapExpired(ctx); reaped != 1 {
+		t.Fatalf("expected 1 reaped job, got %d", reaped)
+	}
+	if stale.Status != StatusProcessing || stale.Error != "" || stale.Attempts != 0 {
+		t.Errorf("reaping changed the stale worker's job: %+v", stale)
+	}
+	q.SetLeaseDuration(time.Minute)
+	fresh, err := q.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue after reaping failed: %v", err)
+	}
+	if fresh.Lease == stale.Lease {
+		t.Fatal("both workers hold the same lease token")
+	}
+
+	// Nothing the stale worker does touches the new lease
+	if err := q.ExtendLease(ctx, "job-1", stale.Lease); err != ErrLeaseLost {
+		t.Errorf("stale ExtendLease = %v, want ErrLeaseLost", err)
+	}
+	if err := q.Ack(ctx, "job-1", stale.Lease); err != ErrLeaseLost {
+		t.Errorf("stale Ack = %v, want ErrLeaseLost", err)
+	}
+	if err := q.Retry(ctx, stale); err != ErrLeaseLost {
+		t.Errorf("stale Retry = %v, want ErrLeaseLost", err)
+	}
+	if err := q.ExtendLease(ctx, "job-1", fresh.Lease); err != nil {
+		t.Errorf("ExtendLease of the new lease failed: %v", err)
+	}
+	if job, _ := q.GetJob(ctx, "job-1"); job.Status != StatusPending || job.Attempts != 1 {
+		t.Errorf("stale worker changed the job: %s/%d", job.Status, job.Attempts)
+	}
+
+	if err := q.Ack(ctx, "job-1", fresh.Lease); err != nil {
+		t.Errorf("Ack of the new lease failed: %v", err)
+	}
+	if err := q.ExtendLease(ctx, "job-1", fresh.Lease); err != ErrLeaseLost {
+		t.Errorf("ExtendLease after Ack = %v, want ErrLeaseLost", err)
+	}
+}
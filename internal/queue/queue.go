@@ -13,6 +13,14 @@ const (
 	StatusCompleted  = "completed"
 	StatusFailed     = "failed"
 	StatusCancelled  = "cancelled"
+	StatusRetrying   = "retrying"    // Failed with a retryable error, waiting for NextAttemptAt
+	StatusDeadLetter = "dead_letter" // Exhausted its attempts; can be re-driven
+)
+
+// Analysis artifacts stored alongside a job's outputs
+const (
+	ArtifactMacros    = "macros"    // JSON listing of the template's VBA project
+	ArtifactAnnotated = "annotated" // Converted DOCX with a review comment per complexity issue
 )
 
 var (
@@ -20,21 +28,37 @@ var (
 	ErrNoJobs = errors.New("no jobs available")
 	// ErrJobNotFound is returned when a job is not found
 	ErrJobNotFound = errors.New("job not found")
+	// ErrNotDeadLettered is returned when re-driving a job that is not in the dead-letter set
+	ErrNotDeadLettered = errors.New("job is not in the dead-letter set")
+	// ErrLeaseLost is returned when a worker acts on a lease that expired or was
+	// handed to another worker
+	ErrLeaseLost = errors.New("job lease lost")
 )
 
+// DefaultLeaseDuration is how long a dequeued job stays in flight without a heartbeat
+const DefaultLeaseDuration = 2 * time.Minute
+
 // Job represents a conversion job
 type Job struct {
-	ID          string            `json:"id"`
-	InputPath   string            `json:"input_path"`
-	OutputPath  string            `json:"output_path"`
-	Status      string            `json:"status"`
-	Priority    int               `json:"priority"`
-	CreatedAt   time.Time         `json:"created_at"`
-	StartedAt   *time.Time        `json:"started_at,omitempty"`
-	CompletedAt *time.Time        `json:"completed_at,omitempty"`
-	Duration    time.Duration     `json:"duration,omitempty"`
-	Error       string            `json:"error,omitempty"`
-	Metadata    map[string]string `json:"metadata,omitempty"`
+	ID            string            `json:"id"`
+	InputPath     string            `json:"input_path"`
+	OutputPath    string            `json:"output_path"`
+	Status        string            `json:"status"`
+	Priority      int               `json:"priority"`
+	CreatedAt     time.Time         `json:"created_at"`
+	StartedAt     *time.Time        `json:"started_at,omitempty"`
+	CompletedAt   *time.Time        `json:"completed_at,omitempty"`
+	Duration      time.Duration     `json:"duration,omitempty"`
+	Error         string            `json:"error,omitempty"`
+	Attempts      int               `json:"attempts,omitempty"`        // Processing attempts made so far
+	MaxAttempts   int               `json:"max_attempts,omitempty"`    // Attempts allowed before dead-lettering
+	NextAttemptAt *time.Time        `json:"next_attempt_at,omitempty"` // When a retrying job becomes due
+	Engine        string            `json:"engine,omitempty"`          // Conversion engine that produced the output
+	Formats       []string          `json:"output_formats,omitempty"`  // Requested output formats (default: docx)
+	Outputs       map[string]string `json:"outputs,omitempty"`         // Storage path of each produced artifact by format
+	Artifacts     map[string]string `json:"artifacts,omitempty"`       // Storage path of each analysis sidecar by kind
+	Metadata      map[string]string `json:"metadata,omitempty"`
+	Lease         string            `json:"-"` // Token of the lease taken by Dequeue, held by one worker at a time
 }
 
 // Queue interface for job queue operations
@@ -42,21 +66,58 @@ type Queue interface {
 	// Enqueue adds a job to the queue
 	Enqueue(ctx context.Context, job *Job) error
 
-	// Dequeue retrieves and removes the next job from the queue
+	// Dequeue retrieves the next job and leases it to the caller under a fresh
+	// Lease token; the job stays in flight until it is acknowledged or its lease expires
 	Dequeue(ctx context.Context) (*Job, error)
 
+	// DequeueWait is like Dequeue but blocks until a job is available, returning
+	// ErrNoJobs once timeout elapses and ctx.Err() as soon as ctx is cancelled
+	DequeueWait(ctx context.Context, timeout time.Duration) (*Job, error)
+
+	// ExtendLease pushes back the lease deadline of an in-flight job (worker
+	// heartbeat), returning ErrLeaseLost unless the lease token is still current
+	ExtendLease(ctx context.Context, id, lease string) error
+
+	// Ack releases the lease of a job that has finished processing, returning
+	// ErrLeaseLost and leaving the job alone unless the lease token is still current
+	Ack(ctx context.Context, id, lease string) error
+
+	// ReapExpired returns jobs whose lease expired to the pending queue, counting
+	// the abandoned attempt, and reports how many were reaped
+	ReapExpired(ctx context.Context) (int, error)
+
 	// GetJob retrieves a job by ID without removing it
 	GetJob(ctx context.Context, id string) (*Job, error)
 
 	// UpdateJob updates an existing job
 	UpdateJob(job *Job) error
 
-	// CancelJob cancels a pending job
+	// CancelJob cancels a pending or retrying job
 	CancelJob(ctx context.Context, id string) error
 
+	// Retry re-schedules a failed job with exponential backoff, or moves it to the
+	// dead-letter set once it has exhausted its attempts. The job's Lease must
+	// still be current, otherwise ErrLeaseLost is returned and nothing changes.
+	Retry(ctx context.Context, job *Job) error
+
+	// ListDeadLetter lists jobs that exhausted their retries, newest first
+	ListDeadLetter(ctx context.Context, limit int) ([]*Job, error)
+
+	// Redrive moves a dead-lettered job back to the pending queue with fresh attempts
+	Redrive(ctx context.Context, id string) (*Job, error)
+
 	// ListJobs lists all jobs with optional filtering
 	ListJobs(ctx context.Context, status string, limit int) ([]*Job, error)
 
+	// CreateBatch stores a batch of already enqueued jobs
+	CreateBatch(ctx context.Context, batch *Batch) error
+
+	// GetBatch retrieves a batch with counts tallied from its jobs
+	GetBatch(ctx context.Context, id string) (*Batch, error)
+
+	// ListBatches lists batches newest first with counts tallied from their jobs
+	ListBatches(ctx context.Context, limit int) ([]*Batch, error)
+
 	// Size returns the number of pending jobs
 	Size() (int, error)
 
@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	log "github.com/sirupsen/logrus"
 )
@@ -14,11 +17,61 @@ const (
 	queueKey    = "conversion:queue"
 	jobsKey     = "conversion:jobs"
 	priorityKey = "conversion:priority"
+
+	// delayedKey holds retrying jobs scored by NextAttemptAt (unix ms)
+	delayedKey = "conversion:delayed"
+	// deadLetterKey holds jobs that exhausted their retries scored by failure time
+	deadLetterKey = "conversion:deadletter"
+	// inFlightKey holds leased jobs scored by lease deadline (unix ms)
+	inFlightKey = "conversion:inflight"
+	// leasesKey holds the lease token of each in-flight job by ID
+	leasesKey = "conversion:leases"
+	// batchesKey holds batch definitions by ID
+	batchesKey = "conversion:batches"
+	// wakeKey is a list with a token per queued job; waiting workers block on it
+	// and then lease with leaseScript, since blocking pops can't run in scripts
+	wakeKey = "conversion:wake"
 )
 
+// wakeLimit caps the wake tokens kept for workers that are not waiting yet
+const wakeLimit = 1024
+
+// leaseScript atomically pops the highest priority job and records its lease so a
+// job is never only in a worker's memory
+var leaseScript = redis.NewScript(`
+local popped = redis.call('ZPOPMIN', KEYS[1])
+if #popped == 0 then
+	return false
+end
+redis.call('ZADD', KEYS[2], ARGV[1], popped[1])
+redis.call('HSET', KEYS[3], popped[1], ARGV[2])
+return popped[1]
+`)
+
+// extendScript pushes back a lease deadline if the token still holds the lease
+var extendScript = redis.NewScript(`
+if redis.call('HGET', KEYS[2], ARGV[1]) ~= ARGV[2] or not redis.call('ZSCORE', KEYS[1], ARGV[1]) then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+return 1
+`)
+
+// releaseScript removes a lease if the token still holds it, returning 0 when
+// the lease expired or belongs to another worker
+var releaseScript = redis.NewScript(`
+if redis.call('HGET', KEYS[2], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call('HDEL', KEYS[2], ARGV[1])
+return redis.call('ZREM', KEYS[1], ARGV[1])
+`)
+
 // RedisQueue implements Queue interface using Redis
 type RedisQueue struct {
 	client *redis.Client
+	retry  RetryPolicy
+	lease  time.Duration
 }
 
 // NewRedisQueue creates a new Redis-based queue
@@ -42,11 +95,37 @@ func NewRedisQueue(redisURL string) (*RedisQueue, error) {
 
 	return &RedisQueue{
 		client: client,
+		retry:  DefaultRetryPolicy,
+		lease:  DefaultLeaseDuration,
 	}, nil
 }
 
+// SetLeaseDuration sets how long a dequeued job stays in flight without a heartbeat
+func (q *RedisQueue) SetLeaseDuration(d time.Duration) {
+	q.lease = d
+}
+
+// leaseDeadline returns the in-flight score for a lease starting now
+func (q *RedisQueue) leaseDeadline() float64 {
+	return float64(time.Now().Add(q.lease).UnixMilli())
+}
+
+// SetRetryPolicy sets the backoff and attempt limit used for failed jobs
+func (q *RedisQueue) SetRetryPolicy(policy RetryPolicy) {
+	q.retry = policy
+}
+
+// priorityScore orders jobs by priority, then by time of (re)queueing
+func priorityScore(job *Job) float64 {
+	return float64(time.Now().Unix()) - float64(job.Priority*1000)
+}
+
 // Enqueue adds a job to the queue
 func (q *RedisQueue) Enqueue(ctx context.Context, job *Job) error {
+	if job.MaxAttempts == 0 {
+		job.MaxAttempts = q.retry.MaxAttempts
+	}
+
 	// Serialize job
 	jobData, err := json.Marshal(job)
 	if err != nil {
@@ -59,13 +138,13 @@ func (q *RedisQueue) Enqueue(ctx context.Context, job *Job) error {
 	}
 
 	// Add to priority queue
-	score := float64(time.Now().Unix()) - float64(job.Priority*1000)
 	if err := q.client.ZAdd(ctx, priorityKey, redis.Z{
-		Score:  score,
+		Score:  priorityScore(job),
 		Member: job.ID,
 	}).Err(); err != nil {
 		return fmt.Errorf("failed to add job to queue: %w", err)
 	}
+	q.wake(ctx)
 
 	log.Debugf("Enqueued job %s with priority %d", job.ID, job.Priority)
 	return nil
@@ -73,19 +152,20 @@ func (q *RedisQueue) Enqueue(ctx context.Context, job *Job) error {
 
 // Dequeue retrieves the next job from the queue
 func (q *RedisQueue) Dequeue(ctx context.Context) (*Job, error) {
-	// Get highest priority job
-	result := q.client.ZPopMin(ctx, priorityKey, 1)
-	members, err := result.Result()
-	if err != nil {
-		return nil, fmt.Errorf("failed to dequeue: %w", err)
+	if err := q.promoteDue(ctx); err != nil {
+		log.Warnf("Failed to promote retrying jobs: %v", err)
 	}
 
-	if len(members) == 0 {
-		return nil, ErrNoJobs
+	// Pop the highest priority job and lease it in one step
+	token := uuid.New().String()
+	jobID, err := leaseScript.Run(ctx, q.client, []string{priorityKey, inFlightKey, leasesKey}, q.leaseDeadline(), token).Text()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrNoJobs
+		}
+		return nil, fmt.Errorf("failed to dequeue: %w", err)
 	}
 
-	jobID := members[0].Member.(string)
-
 	// Get job data
 	jobData, err := q.client.HGet(ctx, jobsKey, jobID).Result()
 	if err != nil {
@@ -100,11 +180,177 @@ func (q *RedisQueue) Dequeue(ctx context.Context) (*Job, error) {
 	if err := json.Unmarshal([]byte(jobData), &job); err != nil {
 		return nil, fmt.Errorf("failed to deserialize job: %w", err)
 	}
+	job.Lease = token
 
 	log.Debugf("Dequeued job %s", job.ID)
 	return &job, nil
 }
 
+// DequeueWait leases the next job, waiting for a wake token whenever the queue
+// is empty until timeout elapses
+func (q *RedisQueue) DequeueWait(ctx context.Context, timeout time.Duration) (*Job, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		job, err := q.Dequeue(ctx)
+		if err != ErrNoJobs {
+			return job, err
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, ErrNoJobs
+		}
+		// BLPOP waits whole seconds, and zero would block forever
+		if err := q.waitForWake(ctx, (remaining + time.Second - 1).Truncate(time.Second)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// waitForWake blocks on BLPOP until a job is queued or timeout elapses. Another
+// worker may lease the job first, so callers try again after waking.
+func (q *RedisQueue) waitForWake(ctx context.Context, timeout time.Duration) error {
+	// BLPOP can't be interrupted, so it runs detached from ctx and the caller
+	// returns as soon as ctx is cancelled
+	result := make(chan error, 1)
+	go func() {
+		result <- q.client.BLPop(context.Background(), timeout, wakeKey).Err()
+	}()
+
+	select {
+	case <-ctx.Done():
+		// Hand back a token taken after the caller gave up
+		go func() {
+			if err := <-result; err == nil {
+				q.wake(context.Background())
+			}
+		}()
+		return ctx.Err()
+
+	case err := <-result:
+		if err != nil && err != redis.Nil {
+			return fmt.Errorf("failed to wait for jobs: %w", err)
+		}
+		return nil
+	}
+}
+
+// wake leaves a token for a waiting worker after a job is queued; a lost token
+// only delays the job until a worker's wait times out
+func (q *RedisQueue) wake(ctx context.Context) {
+	pipe := q.client.Pipeline()
+	pipe.LPush(ctx, wakeKey, 1)
+	pipe.LTrim(ctx, wakeKey, 0, wakeLimit-1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Warnf("Failed to wake waiting workers: %v", err)
+	}
+}
+
+// promoteDue moves retrying jobs whose backoff has elapsed back to the priority queue
+func (q *RedisQueue) promoteDue(ctx context.Context) error {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	ids, err := q.client.ZRangeByScore(ctx, delayedKey, &redis.ZRangeBy{Min: "-inf", Max: now}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read delayed jobs: %w", err)
+	}
+
+	for _, id := range ids {
+		// Only the worker that removes the entry promotes it
+		removed, err := q.client.ZRem(ctx, delayedKey, id).Result()
+		if err != nil || removed == 0 {
+			continue
+		}
+
+		job, err := q.GetJob(ctx, id)
+		if err != nil {
+			log.Warnf("Dropping delayed job %s: %v", id, err)
+			continue
+		}
+
+		job.Status = StatusPending
+		if err := q.UpdateJob(job); err != nil {
+			return err
+		}
+		if err := q.client.ZAdd(ctx, priorityKey, redis.Z{Score: priorityScore(job), Member: id}).Err(); err != nil {
+			return fmt.Errorf("failed to requeue job %s: %w", id, err)
+		}
+		q.wake(ctx)
+	}
+
+	return nil
+}
+
+// ExtendLease pushes back the lease deadline of an in-flight job
+func (q *RedisQueue) ExtendLease(ctx context.Context, id, token string) error {
+	extended, err := extendScript.Run(ctx, q.client, []string{inFlightKey, leasesKey}, id, token, q.leaseDeadline()).Int()
+	if err != nil {
+		return fmt.Errorf("failed to extend lease: %w", err)
+	}
+	if extended == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Ack releases the lease of a finished job
+func (q *RedisQueue) Ack(ctx context.Context, id, token string) error {
+	released, err := releaseScript.Run(ctx, q.client, []string{inFlightKey, leasesKey}, id, token).Int()
+	if err != nil {
+		return fmt.Errorf("failed to release lease: %w", err)
+	}
+	if released == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// ReapExpired returns jobs with expired leases to the priority queue
+func (q *RedisQueue) ReapExpired(ctx context.Context) (int, error) {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	ids, err := q.client.ZRangeByScore(ctx, inFlightKey, &redis.ZRangeBy{Min: "-inf", Max: now}).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read in-flight jobs: %w", err)
+	}
+
+	reaped := 0
+	for _, id := range ids {
+		// Only the reaper that removes the lease requeues the job
+		removed, err := q.client.ZRem(ctx, inFlightKey, id).Result()
+		if err != nil || removed == 0 {
+			continue
+		}
+		if err := q.client.HDel(ctx, leasesKey, id).Err(); err != nil {
+			log.Warnf("Failed to drop lease token of job %s: %v", id, err)
+		}
+
+		job, err := q.GetJob(ctx, id)
+		if err != nil {
+			log.Warnf("Dropping expired lease for job %s: %v", id, err)
+			continue
+		}
+
+		requeue := q.retry.expireLease(job)
+		if err := q.UpdateJob(job); err != nil {
+			return reaped, err
+		}
+
+		target, score := priorityKey, priorityScore(job)
+		if !requeue {
+			target, score = deadLetterKey, float64(time.Now().UnixMilli())
+		}
+		if err := q.client.ZAdd(ctx, target, redis.Z{Score: score, Member: id}).Err(); err != nil {
+			return reaped, fmt.Errorf("failed to requeue expired job %s: %w", id, err)
+		}
+		if requeue {
+			q.wake(ctx)
+		}
+
+		log.Warnf("Reaped job %s after lease expiry (attempt %d, status %s)", id, job.Attempts, job.Status)
+		reaped++
+	}
+
+	return reaped, nil
+}
+
 // GetJob retrieves a job by ID
 func (q *RedisQueue) GetJob(ctx context.Context, id string) (*Job, error) {
 	jobData, err := q.client.HGet(ctx, jobsKey, id).Result()
@@ -139,7 +385,7 @@ func (q *RedisQueue) UpdateJob(job *Job) error {
 	return nil
 }
 
-// CancelJob cancels a pending job
+// CancelJob cancels a pending or retrying job
 func (q *RedisQueue) CancelJob(ctx context.Context, id string) error {
 	// Get the job
 	job, err := q.GetJob(ctx, id)
@@ -147,15 +393,18 @@ func (q *RedisQueue) CancelJob(ctx context.Context, id string) error {
 		return err
 	}
 
-	// Only cancel if pending
-	if job.Status != StatusPending {
+	// Only cancel if pending or waiting to retry
+	if job.Status != StatusPending && job.Status != StatusRetrying {
 		return fmt.Errorf("can only cancel pending jobs, current status: %s", job.Status)
 	}
 
-	// Remove from priority queue
+	// Remove from priority and delayed queues
 	if err := q.client.ZRem(ctx, priorityKey, id).Err(); err != nil {
 		log.Warnf("Failed to remove job %s from priority queue: %v", id, err)
 	}
+	if err := q.client.ZRem(ctx, delayedKey, id).Err(); err != nil {
+		log.Warnf("Failed to remove job %s from delayed queue: %v", id, err)
+	}
 
 	// Update status
 	job.Status = StatusCancelled
@@ -165,6 +414,184 @@ func (q *RedisQueue) CancelJob(ctx context.Context, id string) error {
 	return q.UpdateJob(job)
 }
 
+// Retry re-schedules a failed job with backoff or dead-letters it
+func (q *RedisQueue) Retry(ctx context.Context, job *Job) error {
+	// Releasing the lease first keeps the reaper from scheduling the job a second
+	// time, and a worker whose lease was reaped from scheduling it at all
+	if err := q.Ack(ctx, job.ID, job.Lease); err != nil {
+		return err
+	}
+
+	retry := q.retry.scheduleRetry(job)
+	if err := q.UpdateJob(job); err != nil {
+		return err
+	}
+
+	if retry {
+		if err := q.client.ZAdd(ctx, delayedKey, redis.Z{
+			Score:  float64(job.NextAttemptAt.UnixMilli()),
+			Member: job.ID,
+		}).Err(); err != nil {
+			return fmt.Errorf("failed to schedule retry: %w", err)
+		}
+		return nil
+	}
+
+	if err := q.client.ZAdd(ctx, deadLetterKey, redis.Z{
+		Score:  float64(time.Now().UnixMilli()),
+		Member: job.ID,
+	}).Err(); err != nil {
+		return fmt.Errorf("failed to dead-letter job: %w", err)
+	}
+	return nil
+}
+
+// ListDeadLetter lists dead-lettered jobs, newest first
+func (q *RedisQueue) ListDeadLetter(ctx context.Context, limit int) ([]*Job, error) {
+	stop := int64(-1)
+	if limit > 0 {
+		stop = int64(limit - 1)
+	}
+
+	ids, err := q.client.ZRevRange(ctx, deadLetterKey, 0, stop).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead-letter jobs: %w", err)
+	}
+
+	jobs := make([]*Job, 0, len(ids))
+	for _, id := range ids {
+		job, err := q.GetJob(ctx, id)
+		if err != nil {
+			log.Warnf("Failed to load dead-letter job %s: %v", id, err)
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// Redrive moves a dead-lettered job back to the priority queue
+func (q *RedisQueue) Redrive(ctx context.Context, id string) (*Job, error) {
+	job, err := q.GetJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	removed, err := q.client.ZRem(ctx, deadLetterKey, id).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove job from dead-letter set: %w", err)
+	}
+	if removed == 0 {
+		return nil, ErrNotDeadLettered
+	}
+
+	resetForRedrive(job)
+	if err := q.UpdateJob(job); err != nil {
+		return nil, err
+	}
+	if err := q.client.ZAdd(ctx, priorityKey, redis.Z{Score: priorityScore(job), Member: id}).Err(); err != nil {
+		return nil, fmt.Errorf("failed to requeue job: %w", err)
+	}
+	q.wake(ctx)
+
+	log.Infof("Re-drove dead-lettered job %s", id)
+	return job, nil
+}
+
+// CreateBatch stores a batch
+func (q *RedisQueue) CreateBatch(ctx context.Context, batch *Batch) error {
+	batchData, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("failed to serialize batch: %w", err)
+	}
+
+	if err := q.client.HSet(ctx, batchesKey, batch.ID, batchData).Err(); err != nil {
+		return fmt.Errorf("failed to store batch: %w", err)
+	}
+	return nil
+}
+
+// GetBatch retrieves a batch with up-to-date counts
+func (q *RedisQueue) GetBatch(ctx context.Context, id string) (*Batch, error) {
+	batchData, err := q.client.HGet(ctx, batchesKey, id).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrBatchNotFound
+		}
+		return nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+
+	var batch Batch
+	if err := json.Unmarshal([]byte(batchData), &batch); err != nil {
+		return nil, fmt.Errorf("failed to deserialize batch: %w", err)
+	}
+
+	if err := q.tally(ctx, &batch); err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// ListBatches lists batches newest first
+func (q *RedisQueue) ListBatches(ctx context.Context, limit int) ([]*Batch, error) {
+	batchMap, err := q.client.HGetAll(ctx, batchesKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list batches: %w", err)
+	}
+
+	batches := make([]*Batch, 0, len(batchMap))
+	for _, batchData := range batchMap {
+		var batch Batch
+		if err := json.Unmarshal([]byte(batchData), &batch); err != nil {
+			log.Warnf("Failed to deserialize batch: %v", err)
+			continue
+		}
+		batches = append(batches, &batch)
+	}
+
+	sort.Slice(batches, func(i, j int) bool {
+		return batches[i].CreatedAt.After(batches[j].CreatedAt)
+	})
+	if limit > 0 && len(batches) > limit {
+		batches = batches[:limit]
+	}
+
+	for _, batch := range batches {
+		if err := q.tally(ctx, batch); err != nil {
+			return nil, err
+		}
+	}
+	return batches, nil
+}
+
+// tally loads a batch's jobs and refreshes its counts
+func (q *RedisQueue) tally(ctx context.Context, batch *Batch) error {
+	jobs := make([]*Job, 0, len(batch.JobIDs))
+	if len(batch.JobIDs) > 0 {
+		values, err := q.client.HMGet(ctx, jobsKey, batch.JobIDs...).Result()
+		if err != nil {
+			return fmt.Errorf("failed to load batch jobs: %w", err)
+		}
+
+		for _, value := range values {
+			jobData, ok := value.(string)
+			if !ok {
+				continue
+			}
+			var job Job
+			if err := json.Unmarshal([]byte(jobData), &job); err != nil {
+				log.Warnf("Failed to deserialize job: %v", err)
+				continue
+			}
+			jobs = append(jobs, &job)
+		}
+	}
+
+	batch.Tally(jobs)
+	return nil
+}
+
 // ListJobs lists jobs with optional status filter
 func (q *RedisQueue) ListJobs(ctx context.Context, status string, limit int) ([]*Job, error) {
 	// Get all job IDs
@@ -212,7 +639,7 @@ func (q *RedisQueue) Clear() error {
 	ctx := context.Background()
 
 	// Clear all keys
-	if err := q.client.Del(ctx, queueKey, jobsKey, priorityKey).Err(); err != nil {
+	if err := q.client.Del(ctx, queueKey, jobsKey, priorityKey, delayedKey, deadLetterKey, inFlightKey, leasesKey, batchesKey, wakeKey).Err(); err != nil {
 		return fmt.Errorf("failed to clear queue: %w", err)
 	}
 
@@ -0,0 +1,119 @@
+//go:build redis
+
+package queue
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+// newTestRedisQueue connects to REDIS_TEST_URL, by default database 15 of a
+// local Redis, and clears the queue keys there. Run with -tags redis.
+func newTestRedisQueue(t *testing.T) *RedisQueue {
+	t.Helper()
+	url := os.Getenv("REDIS_TEST_URL")
+	if url == "" {
+		url = "redis://localhost:6379/15"
+	}
+	q, err := NewRedisQueue(url)
+	if err != nil {
+		t.Skipf("Redis not available: %v", err)
+	}
+	if err := q.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { q.Clear() })
+	return q
+}
+
+func TestRedisQueueDequeueWaitLeases(t *testing.T) {
+	ctx := context.Background()
+	q := newTestRedisQueue(t)
+
+	if _, err := q.DequeueWait(ctx, time.Second); err != ErrNoJobs {
+		t.Fatalf("DequeueWait on an empty queue = %v, want ErrNoJobs", err)
+	}
+
+	// A waiting worker wakes as soon as a job is queued
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		q.Enqueue(ctx, &Job{ID: "job-1", CreatedAt: time.Now()})
+	}()
+	start := time.Now()
+	job, err := q.DequeueWait(ctx, 5*time.Second)
+	if err != nil || job.ID != "job-1" {
+		t.Fatalf("DequeueWait = %v, %v", job, err)
+	}
+	if waited := time.Since(start); waited > 2*time.Second {
+		t.Errorf("waited %v for a queued job", waited)
+	}
+	if err := q.client.ZScore(ctx, inFlightKey, "job-1").Err(); err != nil {
+		t.Errorf("dequeued job is not leased: %v", err)
+	}
+
+	// A cancelled wait leaves the next job queued
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := q.DequeueWait(cancelled, time.Second); err != context.Canceled {
+		t.Errorf("DequeueWait with a cancelled context = %v", err)
+	}
+}
+
+func TestRedisQueueLeaseAndReap(t *testing.T) {
+	ctx := context.Background()
+	q := newTestRedisQueue(t)
+	q.SetLeaseDuration(100 * time.Millisecond)
+
+	if err := q.Enqueue(ctx, &Job{ID: "job-1", CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	stale, err := q.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue failed: %v", err)
+	}
+
+	// Heartbeats keep the job in flight past its first deadline
+	for i := 0; i < 3; i++ {
+		time.Sleep(50 * time.Millisecond)
+		if err := q.ExtendLease(ctx, "job-1", stale.Lease); err != nil {
+			t.Fatalf("ExtendLease failed: %v", err)
+		}
+		if reaped, err := q.ReapExpired(ctx); err != nil || reaped != 0 {
+			t.Fatalf("ReapExpired with a live lease = %d, %v", reaped, err)
+		}
+	}
+
+	// Without heartbeats the lease expires and the job is requeued
+	time.Sleep(150 * time.Millisecond)
+	if reaped, err := q.ReapExpired(ctx); err != nil || reaped != 1 {
+		t.Fatalf("ReapExpired = %d, %v, want 1", reaped, err)
+	}
+	if err := q.ExtendLease(ctx, "job-1", stale.Lease); err != ErrLeaseLost {
+		t.Errorf("ExtendLease after reaping = %v, want ErrLeaseLost", err)
+	}
+	q.SetLeaseDuration(time.Minute)
+	job, err := q.DequeueWait(ctx, time.Second)
+	if err != nil || job.ID != "job-1" || job.Attempts != 1 {
+		t.Fatalf("DequeueWait after reaping = %+v, %v", job, err)
+	}
+
+	// The stale worker can neither extend, release nor retry the new lease
+	if err := q.ExtendLease(ctx, "job-1", stale.Lease); err != ErrLeaseLost {
+		t.Errorf("stale ExtendLease = %v, want ErrLeaseLost", err)
+	}
+	if err := q.Ack(ctx, "job-1", stale.Lease); err != ErrLeaseLost {
+		t.Errorf("stale Ack = %v, want ErrLeaseLost", err)
+	}
+	if err := q.Retry(ctx, stale); err != ErrLeaseLost {
+		t.Errorf("stale Retry = %v, want ErrLeaseLost", err)
+	}
+
+	if err := q.Ack(ctx, "job-1", job.Lease); err != nil {
+		t.Fatalf("Ack failed: %v", err)
+	}
+	if err := q.ExtendLease(ctx, "job-1", job.Lease); err != ErrLeaseLost {
+		t.Errorf("ExtendLease after Ack = %v, want ErrLeaseLost", err)
+	}
+}
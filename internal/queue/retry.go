@@ -0,0 +1,100 @@
+package queue
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy controls how failed jobs are re-scheduled
+type RetryPolicy struct {
+	MaxAttempts int           // Attempts before a job is dead-lettered (including the first)
+	BaseDelay   time.Duration // Delay before the first retry
+	MaxDelay    time.Duration // Upper bound for the backoff delay
+	Jitter      float64       // Random spread applied to each delay (0.2 = ±20%)
+}
+
+// DefaultRetryPolicy is used by queues unless SetRetryPolicy is called
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   5 * time.Second,
+	MaxDelay:    5 * time.Minute,
+	Jitter:      0.2,
+}
+
+// Backoff returns the delay before the next attempt after the given number of
+// attempts, doubling each time and capped at MaxDelay
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	delay := float64(p.BaseDelay) * math.Pow(2, float64(attempts-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(delay)
+}
+
+// maxAttemptsFor returns the attempt limit for a job, falling back to the policy
+func (p RetryPolicy) maxAttemptsFor(job *Job) int {
+	if job.MaxAttempts > 0 {
+		return job.MaxAttempts
+	}
+	return p.MaxAttempts
+}
+
+// scheduleRetry updates a failed job for its next attempt and reports whether it
+// should be retried (true) or dead-lettered (false)
+func (p RetryPolicy) scheduleRetry(job *Job) bool {
+	if job.MaxAttempts == 0 {
+		job.MaxAttempts = p.MaxAttempts
+	}
+
+	now := time.Now()
+	if job.Attempts >= p.maxAttemptsFor(job) {
+		job.Status = StatusDeadLetter
+		job.NextAttemptAt = nil
+		job.CompletedAt = &now
+		return false
+	}
+
+	next := now.Add(p.Backoff(job.Attempts))
+	job.Status = StatusRetrying
+	job.NextAttemptAt = &next
+	return true
+}
+
+// resetForRedrive clears retry state so a dead-lettered job starts over
+func resetForRedrive(job *Job) {
+	job.Status = StatusPending
+	job.Attempts = 0
+	job.Error = ""
+	job.NextAttemptAt = nil
+	job.StartedAt = nil
+	job.CompletedAt = nil
+	job.Duration = 0
+}
+
+// expireLease counts the attempt abandoned by a crashed worker and reports whether
+// the job should return to the pending queue (true) or be dead-lettered (false)
+func (p RetryPolicy) expireLease(job *Job) bool {
+	job.Attempts++
+	job.Error = "lease expired: worker stopped before finishing the job"
+
+	if job.Attempts >= p.maxAttemptsFor(job) {
+		now := time.Now()
+		job.Status = StatusDeadLetter
+		job.CompletedAt = &now
+		return false
+	}
+
+	job.Status = StatusPending
+	job.StartedAt = nil
+	return true
+}
@@ -0,0 +1,500 @@
+// Package rtf reads Rich Text Format documents. The tokenizer handles groups,
+// control words, destinations, \'hh escapes and \uN characters; the parser
+// splits the text into stories and keeps fields, tables and embedded objects.
+//
+// Story text uses the conventions of Word binary text: fields are delimited by
+// 0x13, 0x14 and 0x15, paragraphs end with \r and table cells with 0x07, so it
+// can be rendered with msword.Render and parsed with field.ParseBinary.
+package rtf
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/msword"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/ole"
+)
+
+// ErrNotRTF is returned when data does not start with an RTF header
+var ErrNotRTF = errors.New("not an RTF document")
+
+// Story names; they match the stories of Word binary documents
+const (
+	StoryMain      = msword.StoryMain
+	StoryFootnotes = msword.StoryFootnotes
+	StoryHeaders   = msword.StoryHeaders // Headers and footers
+	StoryComments  = msword.StoryComments
+	StoryTextboxes = msword.StoryTextboxes
+)
+
+// storyOrder is the order stories are returned in
+var storyOrder = []string{StoryMain, StoryFootnotes, StoryHeaders, StoryComments, StoryTextboxes}
+
+// storyDestinations map destinations holding text to the story they belong to
+var storyDestinations = map[string]string{
+	"header":     StoryHeaders,
+	"headerl":    StoryHeaders,
+	"headerr":    StoryHeaders,
+	"headerf":    StoryHeaders,
+	"footer":     StoryHeaders,
+	"footerl":    StoryHeaders,
+	"footerr":    StoryHeaders,
+	"footerf":    StoryHeaders,
+	"footnote":   StoryFootnotes,
+	"annotation": StoryComments,
+	"shptxt":     StoryTextboxes,
+}
+
+// ignoredDestinations hold no document text
+var ignoredDestinations = map[string]bool{
+	"fonttbl":    true,
+	"colortbl":   true,
+	"stylesheet": true,
+	"info":       true,
+	"pict":       true,
+	"objdata":    true,
+	"listtext":   true,
+	"pntext":     true,
+	"xe":         true,
+	"tc":         true,
+}
+
+// readDestinations are the ignorable {\* destinations whose content is read
+var readDestinations = map[string]bool{
+	"fldinst":  true,
+	"objclass": true,
+	"shptxt":   true,
+	"shppict":  true,
+	"template": true,
+}
+
+// specialCharacters map control words to the text they stand for
+var specialCharacters = map[string]string{
+	"par":       "\r",
+	"sect":      "\r",
+	"line":      "\v",
+	"page":      "\f",
+	"column":    "\f",
+	"tab":       "\t",
+	"cell":      "\x07",
+	"nestcell":  "\x07",
+	"row":       "\r",
+	"nestrow":   "\r",
+	"emdash":    "—",
+	"endash":    "–",
+	"emspace":   " ",
+	"enspace":   " ",
+	"qmspace":   " ",
+	"bullet":    "•",
+	"lquote":    "‘",
+	"rquote":    "’",
+	"ldblquote": "“",
+	"rdblquote": "”",
+}
+
+// objectKinds map the control words following \object to the object kind
+var objectKinds = map[string]string{
+	"objemb":     "embedded",
+	"objlink":    "linked",
+	"objautlink": "linked",
+	"objsub":     "subscriber",
+	"objpub":     "publisher",
+	"objicemb":   "embedded",
+	"objhtml":    "html",
+	"objocx":     "control",
+}
+
+// pictureTypes map the control words naming a picture's format to its type
+var pictureTypes = map[string]string{
+	"pngblip":   "png",
+	"jpegblip":  "jpeg",
+	"emfblip":   "emf",
+	"wmetafile": "wmf",
+	"dibitmap":  "bmp",
+	"wbitmap":   "bmp",
+	"macpict":   "pict",
+}
+
+// twipsPerPixel converts the goal size of pictures to pixels at 96 dpi
+const twipsPerPixel = 15
+
+// Story is one part of the document text
+type Story struct {
+	Name string
+	Raw  string // Text including field markers and special characters
+}
+
+// Object is an OLE object embedded in or linked from the document
+type Object struct {
+	Class string `json:"class,omitempty"` // e.g. "Excel.Sheet.8"
+	Kind  string `json:"kind"`            // embedded, linked, control, ...
+}
+
+// Picture is a picture stored in the document
+type Picture struct {
+	Type   string // png, jpeg, emf, wmf, bmp or pict
+	Size   int    // Bytes of picture data
+	Width  int    // Pixels, or the display size at 96 dpi for metafiles
+	Height int
+
+	goalWidth, goalHeight int // Display size in twips
+}
+
+// Document is the text and structure of an RTF document
+type Document struct {
+	CodePage   int
+	Stories    []Story
+	Tables     int // Runs of consecutive table rows
+	Rows       int
+	Cells      int
+	NestedRows int // Rows of tables nested in a cell
+	Objects    []Object
+	Pictures   []Picture
+	Template   string // Attached template named by \template
+}
+
+// group is the state of an open group
+type group struct {
+	story       string // Story receiving text; empty when the text is ignored
+	uc          int    // Fallback characters after \uN
+	inTable     bool   // \intbl paragraph property
+	field       bool   // Group of a \field; ends the field when closed
+	object      int    // Index of the object started by this group, or -1
+	objectClass bool   // Text is the class of the enclosing object
+	picture     int    // Index of the picture whose data this group holds, or -1
+	template    bool   // Text is the attached template path
+}
+
+// parser builds a Document from tokens
+type parser struct {
+	doc       *Document
+	stack     []*group
+	stories   map[string]*strings.Builder
+	skip      int  // Fallback characters still to skip after \uN
+	inTable   bool // Last paragraph was part of a table
+	surrogate rune // High surrogate of a \uN pair waiting for its low half
+}
+
+// Parse reads an RTF document
+func Parse(data []byte) (*Document, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \r\n\t"), []byte(`{\rtf`)) {
+		return nil, ErrNotRTF
+	}
+
+	p := &parser{
+		doc:     &Document{CodePage: 1252},
+		stack:   []*group{{story: StoryMain, uc: 1, object: -1, picture: -1}},
+		stories: make(map[string]*strings.Builder),
+	}
+	l := &lexer{data: data}
+
+	// Destinations are named by the first control word of a group
+	groupStart := false
+	for {
+		t, ok := l.next()
+		if !ok {
+			break
+		}
+		first := groupStart
+		groupStart = false
+
+		switch t.kind {
+		case tokenGroupStart:
+			top := *p.top()
+			top.field, top.object, top.picture = false, -1, -1
+			p.stack = append(p.stack, &top)
+			p.skip = 0
+			groupStart = true
+		case tokenGroupEnd:
+			p.endGroup()
+			p.skip = 0
+		case tokenControlSymbol:
+			if p.skipped() {
+				continue
+			}
+			p.symbol(t, first, l)
+		case tokenControlWord:
+			if p.skipped() {
+				continue
+			}
+			p.word(t, first)
+		case tokenHex:
+			if p.skipped() {
+				continue
+			}
+			p.write(ole.DecodeANSI([]byte(t.text)))
+		case tokenText:
+			text := t.text
+			for p.skip > 0 && text != "" {
+				text = text[1:]
+				p.skip--
+			}
+			if i := p.top().picture; i >= 0 {
+				p.doc.Pictures[i].Size += len(strings.Join(strings.Fields(text), "")) / 2
+				continue
+			}
+			p.write(text)
+		case tokenBinary:
+			// Binary object and picture data is never text
+			if i := p.top().picture; i >= 0 {
+				p.doc.Pictures[i].Size += len(t.text)
+			}
+		}
+	}
+	for i := range p.doc.Pictures {
+		p.doc.Pictures[i].finish()
+	}
+	for len(p.stack) > 1 {
+		p.endGroup()
+	}
+
+	for _, name := range storyOrder {
+		if b, ok := p.stories[name]; ok && (name == StoryMain || strings.TrimSpace(b.String()) != "") {
+			p.doc.Stories = append(p.doc.Stories, Story{Name: name, Raw: b.String()})
+		}
+	}
+	if len(p.doc.Stories) == 0 || p.doc.Stories[0].Name != StoryMain {
+		p.doc.Stories = append([]Story{{Name: StoryMain}}, p.doc.Stories...)
+	}
+	return p.doc, nil
+}
+
+func (p *parser) top() *group {
+	return p.stack[len(p.stack)-1]
+}
+
+// skipped consumes one fallback character of a \uN, reporting whether the
+// current token was skipped
+func (p *parser) skipped() bool {
+	if p.skip > 0 {
+		p.skip--
+		return true
+	}
+	return false
+}
+
+func (p *parser) write(s string) {
+	g := p.top()
+	if s == "" || g.story == "" {
+		return
+	}
+	if g.template {
+		p.doc.Template += s
+		return
+	}
+	if g.objectClass {
+		if i := p.enclosingObject(); i >= 0 {
+			p.doc.Objects[i].Class += s
+		}
+		return
+	}
+	b, ok := p.stories[g.story]
+	if !ok {
+		b = &strings.Builder{}
+		p.stories[g.story] = b
+	}
+	b.WriteString(s)
+}
+
+func (p *parser) endGroup() {
+	if len(p.stack) <= 1 {
+		return
+	}
+	if p.top().field {
+		p.write(string(msword.FieldEnd))
+	}
+	p.stack = p.stack[:len(p.stack)-1]
+}
+
+func (p *parser) symbol(t token, first bool, l *lexer) {
+	switch t.name {
+	case "*":
+		// An ignorable destination: skip it unless it is one we read
+		if !first {
+			return
+		}
+		next, ok := l.next()
+		if !ok {
+			return
+		}
+		if next.kind == tokenControlWord && readDestinations[next.name] {
+			p.word(next, true)
+			return
+		}
+		p.top().story = ""
+	case "~":
+		p.write("\u00A0")
+	case "_":
+		p.write("\x1E")
+	case "-":
+		p.write("\x1F")
+	case "|", ":":
+		// Formula and index subentry characters
+	}
+}
+
+func (p *parser) word(t token, first bool) {
+	g := p.top()
+
+	if first {
+		if story, ok := storyDestinations[t.name]; ok {
+			g.story = story
+			g.inTable = false
+			return
+		}
+		if ignoredDestinations[t.name] {
+			// Pictures inside ignored groups are fallbacks for older readers
+			if t.name == "pict" && g.story != "" {
+				g.picture = len(p.doc.Pictures)
+				p.doc.Pictures = append(p.doc.Pictures, Picture{})
+			}
+			g.story = ""
+			return
+		}
+	}
+
+	if text, ok := specialCharacters[t.name]; ok {
+		p.write(text)
+		p.special(t.name, g)
+		return
+	}
+
+	if g.picture >= 0 {
+		p.pictureWord(t, &p.doc.Pictures[g.picture])
+		return
+	}
+
+	switch t.name {
+	case "ansicpg":
+		p.doc.CodePage = t.param
+	case "uc":
+		g.uc = t.param
+	case "u":
+		r := rune(t.param)
+		if r < 0 {
+			r += 65536
+		}
+		switch {
+		case r >= 0xD800 && r < 0xDC00:
+			p.surrogate = r
+		case utf16.IsSurrogate(r):
+			p.write(string(utf16.DecodeRune(p.surrogate, r)))
+			p.surrogate = 0
+		default:
+			p.write(string(r))
+		}
+		p.skip = g.uc
+	case "field":
+		g.field = true
+		p.write(string(msword.FieldBegin))
+	case "fldinst":
+		// Instruction text belongs to the field in the enclosing story
+	case "fldrslt":
+		p.write(string(msword.FieldSeparator))
+	case "object":
+		g.object = len(p.doc.Objects)
+		p.doc.Objects = append(p.doc.Objects, Object{Kind: "embedded"})
+	case "objclass":
+		g.objectClass = p.enclosingObject() >= 0
+		if !g.objectClass {
+			g.story = ""
+		}
+	case "result":
+		// The last rendering of an object is shown in place of it
+	case "template":
+		g.template = true
+	case "trowd":
+		if !p.inTable {
+			p.doc.Tables++
+			p.inTable = true
+		}
+	case "intbl", "itap":
+		g.inTable = t.name == "intbl" || t.param > 0
+	case "pard":
+		g.inTable = false
+	default:
+		if kind, ok := objectKinds[t.name]; ok {
+			if i := p.enclosingObject(); i >= 0 {
+				p.doc.Objects[i].Kind = kind
+			}
+		}
+	}
+}
+
+// pictureWord reads the format and size of a picture
+func (p *parser) pictureWord(t token, picture *Picture) {
+	if kind, ok := pictureTypes[t.name]; ok {
+		picture.Type = kind
+		return
+	}
+	switch t.name {
+	case "picw":
+		picture.Width = t.param
+	case "pich":
+		picture.Height = t.param
+	case "picwgoal":
+		picture.goalWidth = t.param
+	case "pichgoal":
+		picture.goalHeight = t.param
+	}
+}
+
+// finish settles the pixel size of a picture. The \picw and \pich of bitmaps
+// are pixels, but those of metafiles are in their own units, so the display size
+// is used instead.
+func (pic *Picture) finish() {
+	if pic.Type == "emf" || pic.Type == "wmf" || pic.Type == "pict" || pic.Width <= 0 || pic.Height <= 0 {
+		pic.Width, pic.Height = pic.goalWidth/twipsPerPixel, pic.goalHeight/twipsPerPixel
+	}
+}
+
+// special updates table counts after a paragraph, cell or row mark
+func (p *parser) special(name string, g *group) {
+	switch name {
+	case "cell":
+		p.doc.Cells++
+	case "row":
+		p.doc.Rows++
+	case "nestrow":
+		p.doc.NestedRows++
+	case "par":
+		// A paragraph outside the table ends the run of rows
+		if !g.inTable {
+			p.inTable = false
+		}
+	}
+}
+
+// enclosingObject returns the index of the innermost open \object, or -1
+func (p *parser) enclosingObject() int {
+	for i := len(p.stack) - 1; i >= 0; i-- {
+		if p.stack[i].object >= 0 {
+			return p.stack[i].object
+		}
+	}
+	return -1
+}
+
+// Story returns the rendered text of a story, or "" if the document has none
+func (d *Document) Story(name string) string {
+	for _, s := range d.Stories {
+		if s.Name == name {
+			text, _ := msword.Render(s.Raw)
+			return text
+		}
+	}
+	return ""
+}
+
+// Text returns the rendered text of every story, main document first
+func (d *Document) Text() string {
+	parts := make([]string, 0, len(d.Stories))
+	for _, s := range d.Stories {
+		if text, _ := msword.Render(s.Raw); strings.TrimSpace(text) != "" {
+			parts = append(parts, strings.TrimRight(text, "\n"))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
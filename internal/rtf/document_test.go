@@ -0,0 +1,88 @@
+package rtf
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/field"
+)
+
+func TestParse(t *testing.T) {
+	data := `{\rtf1\ansi\ansicpg1252\deff0{\fonttbl{\f0 Times New Roman;}}{\colortbl;\red0\green0\blue0;}
+{\*\generator Msftedit 5.41;}{\info{\title Letter}}{\*\template \\\\fileserver\\templates\\Letter.dot}
+{\header\pard Ref: {\field{\*\fldinst { MERGEFIELD MatterRef }}{\fldrslt M-1}}\par}
+\pard Dear {\field{\*\fldinst IF {\field{\*\fldinst MERGEFIELD Sex}{\fldrslt M}} = "M" "Sir" "Madam"}{\fldrslt Sir}},\par
+Caf\'e9 \u8364?10 \uc2\u8212--and\~more\par
+\trowd\cellx1000\cellx2000\pard\intbl A\cell B\cell\row
+\trowd\cellx1000\pard\intbl C\cell\row
+\pard\par
+{\object\objemb{\*\objclass Excel.Sheet.8}{\*\objdata 0105}{\result {\pict\wmetafile8\picw2000\pich1000\picwgoal1500\pichgoal750 0000}}}
+{\*\shppict{\pict{\*\blipuid 0123456789abcdef}\pngblip\picw20\pich10 89504E47
+0D0A}}{\*\nonshppict{\pict\wmetafile8 0000}}
+{\*\bkmkstart Client}End\par}`
+
+	doc, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	wantMain := "Dear {IF {MERGEFIELD Sex} = \"M\" \"Sir\" \"Madam\"},\nCafé €10 —and more\nA\tB\t\nC\t\n\nEnd\n"
+	if got := doc.Story(StoryMain); got != wantMain {
+		t.Errorf("main story = %q, want %q", got, wantMain)
+	}
+	if got := doc.Story(StoryHeaders); got != "Ref: { MERGEFIELD MatterRef }\n" {
+		t.Errorf("header story = %q", got)
+	}
+
+	fields := field.ParseBinary(doc.Stories[0].Raw)
+	if len(fields) != 1 || fields[0].Result != "Sir" || fields[0].Children[0].Name != "Sex" {
+		t.Errorf("fields = %+v", fields)
+	}
+
+	if doc.Tables != 1 || doc.Rows != 2 || doc.Cells != 3 {
+		t.Errorf("tables = %d, rows = %d, cells = %d", doc.Tables, doc.Rows, doc.Cells)
+	}
+	if want := []Object{{Class: "Excel.Sheet.8", Kind: "embedded"}}; !reflect.DeepEqual(doc.Objects, want) {
+		t.Errorf("objects = %+v, want %+v", doc.Objects, want)
+	}
+	// The object result and the shape picture count; the fallback picture does not
+	wantPictures := []Picture{{Type: "wmf", Size: 2, Width: 100, Height: 50, goalWidth: 1500, goalHeight: 750}, {Type: "png", Size: 6, Width: 20, Height: 10}}
+	if !reflect.DeepEqual(doc.Pictures, wantPictures) {
+		t.Errorf("pictures = %+v, want %+v", doc.Pictures, wantPictures)
+	}
+	if doc.Template != `\\fileserver\templates\Letter.dot` {
+		t.Errorf("template = %q", doc.Template)
+	}
+
+	if _, err := Parse([]byte("plain text")); err != ErrNotRTF {
+		t.Errorf("expected ErrNotRTF, got %v", err)
+	}
+}
+
+func TestLexer(t *testing.T) {
+	l := &lexer{data: []byte(`{\b1 bold\'41\par\-x\bin3 {}}\u-3913 }`)}
+	var got []token
+	for {
+		tok, ok := l.next()
+		if !ok {
+			break
+		}
+		got = append(got, tok)
+	}
+
+	want := []token{
+		{kind: tokenGroupStart},
+		{kind: tokenControlWord, name: "b", param: 1, hasParam: true},
+		{kind: tokenText, text: "bold"},
+		{kind: tokenHex, text: "A"},
+		{kind: tokenControlWord, name: "par"},
+		{kind: tokenControlSymbol, name: "-"},
+		{kind: tokenText, text: "x"},
+		{kind: tokenBinary, text: "{}}"},
+		{kind: tokenControlWord, name: "u", param: -3913, hasParam: true},
+		{kind: tokenGroupEnd},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tokens = %+v\nwant %+v", got, want)
+	}
+}
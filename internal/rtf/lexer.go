@@ -0,0 +1,133 @@
+package rtf
+
+import (
+	"strconv"
+)
+
+// tokenKind identifies the kind of an RTF token
+type tokenKind int
+
+const (
+	tokenGroupStart tokenKind = iota
+	tokenGroupEnd
+	tokenControlWord   // \name or \nameN
+	tokenControlSymbol // \ followed by one non-letter, e.g. \~ or \*
+	tokenHex           // \'hh
+	tokenText          // Run of plain text
+	tokenBinary        // Data of a \binN control word
+)
+
+// token is one lexical element of an RTF stream
+type token struct {
+	kind     tokenKind
+	name     string // Control word name or control symbol
+	param    int
+	hasParam bool
+	text     string // Plain text, or the byte of a \'hh escape
+}
+
+// lexer splits RTF into tokens
+type lexer struct {
+	data []byte
+	pos  int
+}
+
+// next returns the next token; ok is false at the end of the data
+func (l *lexer) next() (t token, ok bool) {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch c {
+		case '{':
+			l.pos++
+			return token{kind: tokenGroupStart}, true
+		case '}':
+			l.pos++
+			return token{kind: tokenGroupEnd}, true
+		case '\\':
+			return l.control(), true
+		case '\r', '\n':
+			// Line breaks in the file are not part of the text
+			l.pos++
+		default:
+			start := l.pos
+			for l.pos < len(l.data) && !isSpecial(l.data[l.pos]) {
+				l.pos++
+			}
+			return token{kind: tokenText, text: string(l.data[start:l.pos])}, true
+		}
+	}
+	return token{}, false
+}
+
+func isSpecial(c byte) bool {
+	return c == '{' || c == '}' || c == '\\' || c == '\r' || c == '\n'
+}
+
+// control reads the control word or symbol at the current backslash
+func (l *lexer) control() token {
+	l.pos++ // Backslash
+	if l.pos >= len(l.data) {
+		return token{kind: tokenControlSymbol, name: "\\"}
+	}
+
+	c := l.data[l.pos]
+	if !isLetter(c) {
+		l.pos++
+		switch c {
+		case '\'':
+			if l.pos+2 <= len(l.data) {
+				if b, err := strconv.ParseUint(string(l.data[l.pos:l.pos+2]), 16, 8); err == nil {
+					l.pos += 2
+					return token{kind: tokenHex, text: string([]byte{byte(b)})}
+				}
+			}
+			return token{kind: tokenControlSymbol, name: "'"}
+		case '\\', '{', '}':
+			return token{kind: tokenText, text: string(c)}
+		case '\r', '\n':
+			// A backslash before a line break is a paragraph mark
+			return token{kind: tokenControlWord, name: "par"}
+		}
+		return token{kind: tokenControlSymbol, name: string(c)}
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && isLetter(l.data[l.pos]) {
+		l.pos++
+	}
+	t := token{kind: tokenControlWord, name: string(l.data[start:l.pos])}
+
+	paramStart := l.pos
+	if l.pos < len(l.data) && l.data[l.pos] == '-' {
+		l.pos++
+	}
+	digits := l.pos
+	for l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '9' {
+		l.pos++
+	}
+	if l.pos > digits {
+		t.param, _ = strconv.Atoi(string(l.data[paramStart:l.pos]))
+		t.hasParam = true
+	} else {
+		l.pos = paramStart
+	}
+
+	// A single space delimits the control word and is not text
+	if l.pos < len(l.data) && l.data[l.pos] == ' ' {
+		l.pos++
+	}
+
+	if t.name == "bin" && t.param > 0 {
+		end := l.pos + t.param
+		if end > len(l.data) {
+			end = len(l.data)
+		}
+		t = token{kind: tokenBinary, text: string(l.data[l.pos:end])}
+		l.pos = end
+	}
+	return t
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -92,6 +93,10 @@ func (s *AzureStorage) Download(ctx context.Context, remotePath string) (string,
 	get, err := blobClient.DownloadStream(ctx, nil)
 	if err != nil {
 		os.Remove(localPath)
+		if bloberror.HasCode(err, bloberror.BlobNotFound, bloberror.ContainerNotFound) {
+			// Surface missing blobs like missing local files so callers can treat them as permanent
+			return "", fmt.Errorf("failed to download from Azure: %w: %v", os.ErrNotExist, err)
+		}
 		return "", fmt.Errorf("failed to download from Azure: %w", err)
 	}
 
@@ -0,0 +1,107 @@
+package vba
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	// containerSignature starts every CompressedContainer
+	containerSignature = 0x01
+	// chunkSize is the decompressed size of every chunk but the last
+	chunkSize = 4096
+	// chunkSignature occupies bits 12-14 of a chunk header
+	chunkSignature = 0x3
+)
+
+// Decompress expands an MS-OVBA CompressedContainer, the format of the dir
+// stream and of the source code part of module streams
+func Decompress(data []byte) ([]byte, error) {
+	if len(data) == 0 || data[0] != containerSignature {
+		return nil, fmt.Errorf("%w: missing compressed container signature", ErrCorrupt)
+	}
+
+	out := make([]byte, 0, len(data)*2)
+	pos := 1
+	for pos < len(data) {
+		if pos+2 > len(data) {
+			return nil, fmt.Errorf("%w: truncated chunk header", ErrCorrupt)
+		}
+		header := binary.LittleEndian.Uint16(data[pos:])
+		size := int(header&0x0FFF) + 3
+		if (header>>12)&0x7 != chunkSignature {
+			return nil, fmt.Errorf("%w: bad chunk signature at %d", ErrCorrupt, pos)
+		}
+
+		end := pos + size
+		if end > len(data) {
+			// Some writers leave the last chunk header claiming more than was stored
+			end = len(data)
+		}
+		chunk := data[pos+2 : end]
+		pos = end
+
+		if header&0x8000 == 0 {
+			// Uncompressed chunks hold 4096 raw bytes
+			out = append(out, chunk...)
+			continue
+		}
+
+		var err error
+		if out, err = decompressChunk(out, chunk); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// decompressChunk appends the decompressed tokens of one chunk to out
+func decompressChunk(out, chunk []byte) ([]byte, error) {
+	start := len(out)
+	for i := 0; i < len(chunk); {
+		flags := chunk[i]
+		i++
+		for bit := 0; bit < 8 && i < len(chunk); bit++ {
+			if flags&(1<<bit) == 0 {
+				out = append(out, chunk[i])
+				i++
+				continue
+			}
+
+			if i+2 > len(chunk) {
+				return nil, fmt.Errorf("%w: truncated copy token", ErrCorrupt)
+			}
+			token := binary.LittleEndian.Uint16(chunk[i:])
+			i += 2
+
+			// The split between offset and length bits grows with the chunk position
+			bitCount := copyTokenBits(len(out) - start)
+			lengthMask := uint16(0xFFFF) >> bitCount
+			length := int(token&lengthMask) + 3
+			offset := int(token>>(16-bitCount)) + 1
+
+			src := len(out) - offset
+			if src < start {
+				return nil, fmt.Errorf("%w: copy token points before the chunk", ErrCorrupt)
+			}
+			// Copies may overlap the bytes they produce, so go one byte at a time
+			for n := 0; n < length; n++ {
+				out = append(out, out[src+n])
+			}
+		}
+	}
+	if len(out)-start > chunkSize {
+		return nil, fmt.Errorf("%w: chunk decompresses past %d bytes", ErrCorrupt, chunkSize)
+	}
+	return out, nil
+}
+
+// copyTokenBits returns the number of offset bits of a copy token decoded at the
+// given position within its chunk
+func copyTokenBits(position int) uint {
+	bits := uint(4)
+	for 1<<bits < position {
+		bits++
+	}
+	return bits
+}
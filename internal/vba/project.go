@@ -0,0 +1,255 @@
+// Package vba reads the VBA project of Office documents: the dir stream that
+// describes the project and the MS-OVBA compressed source of every module.
+package vba
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/ole"
+)
+
+var (
+	// ErrNoProject is returned when a compound file has no VBA storage
+	ErrNoProject = errors.New("no VBA project found")
+	// ErrCorrupt is returned when the dir stream or a module stream cannot be read
+	ErrCorrupt = errors.New("corrupt VBA project")
+)
+
+// projectStorages are the storages holding the VBA streams: Word binary
+// documents, Excel and PowerPoint binaries, and the vbaProject.bin part of
+// macro-enabled OOXML packages
+var projectStorages = []string{"Macros/VBA", "_VBA_PROJECT_CUR/VBA", "VBA"}
+
+// dirStream is the stream describing the project and its modules
+const dirStream = "dir"
+
+// dir stream record identifiers
+const (
+	recCodePage            = 0x0003
+	recName                = 0x0004
+	recVersion             = 0x0009
+	recTerminator          = 0x0010
+	recReferenceName       = 0x0016
+	recReferenceRegistered = 0x000D
+	recReferenceProject    = 0x000E
+	recReferenceControl    = 0x002F
+	recReferenceOriginal   = 0x0033
+	recModuleName          = 0x0019
+	recModuleStreamName    = 0x001A
+	recModuleOffset        = 0x0031
+	recModuleProcedural    = 0x0021
+	recModuleDocument      = 0x0022
+	recModuleNameUnicode   = 0x0047
+	recModuleTerminator    = 0x002B
+)
+
+// codePageUTF8 is the only non-ANSI code page written by current Office versions
+const codePageUTF8 = 65001
+
+// Module types
+const (
+	ModuleProcedural = "procedural" // Standard modules
+	ModuleDocument   = "document"   // Document, class and form modules, e.g. ThisDocument
+)
+
+// Reference types
+const (
+	ReferenceRegistered = "registered" // Type library such as stdole or Office
+	ReferenceProject    = "project"    // Another VBA project, usually a global template
+	ReferenceControl    = "control"    // ActiveX control type library
+)
+
+// Project is a VBA project with the source of its modules
+type Project struct {
+	Name       string      `json:"name"`
+	CodePage   int         `json:"code_page"`
+	Modules    []Module    `json:"modules"`
+	References []Reference `json:"references,omitempty"`
+}
+
+// Module is one code module of a project
+type Module struct {
+	Name       string   `json:"name"`
+	StreamName string   `json:"stream_name"`
+	Type       string   `json:"type"`
+	Source     string   `json:"source"`
+	Procedures []string `json:"procedures,omitempty"`
+	AutoExec   []string `json:"auto_exec,omitempty"` // Procedures Word runs on its own
+	Objects    []string `json:"objects,omitempty"`   // COM ProgIDs and DLLs the code uses
+}
+
+// Reference is a library referenced by the project
+type Reference struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Libid string `json:"libid,omitempty"`
+}
+
+// Open reads the VBA project of a compound file
+func Open(cf *ole.File) (*Project, error) {
+	for _, storage := range projectStorages {
+		dir, err := cf.ReadStream(storage + "/" + dirStream)
+		if err != nil {
+			continue
+		}
+		return parse(dir, func(name string) ([]byte, error) {
+			return cf.ReadStream(storage + "/" + name)
+		})
+	}
+	return nil, ErrNoProject
+}
+
+// parse decompresses the dir stream and reads each module it lists
+func parse(compressedDir []byte, readStream func(name string) ([]byte, error)) (*Project, error) {
+	dir, err := Decompress(compressedDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress dir stream: %w", err)
+	}
+
+	p, offsets, err := parseDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range p.Modules {
+		m := &p.Modules[i]
+		stream, err := readStream(m.StreamName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read module %s: %w", m.Name, err)
+		}
+		if offsets[i] > len(stream) {
+			return nil, fmt.Errorf("%w: module %s source offset past the stream", ErrCorrupt, m.Name)
+		}
+		source, err := Decompress(stream[offsets[i]:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress module %s: %w", m.Name, err)
+		}
+
+		m.Source = strings.ReplaceAll(decode(source, p.CodePage), "\r\n", "\n")
+		analyzeSource(m)
+	}
+	return p, nil
+}
+
+// parseDir reads the records of a decompressed dir stream, returning the
+// project and the source offset of each module
+func parseDir(dir []byte) (*Project, []int, error) {
+	p := &Project{}
+	var offsets []int
+	var module *Module
+	var refName string
+
+	for pos := 0; pos+6 <= len(dir); {
+		id := binary.LittleEndian.Uint16(dir[pos:])
+		size := int(binary.LittleEndian.Uint32(dir[pos+2:]))
+		if id == recVersion {
+			// PROJECTVERSION declares 4 bytes but stores 6
+			size += 2
+		}
+		pos += 6
+		if size < 0 || pos+size > len(dir) {
+			return nil, nil, fmt.Errorf("%w: record 0x%04X overruns the dir stream", ErrCorrupt, id)
+		}
+		data := dir[pos : pos+size]
+		pos += size
+
+		switch id {
+		case recCodePage:
+			if len(data) >= 2 {
+				p.CodePage = int(binary.LittleEndian.Uint16(data))
+			}
+		case recName:
+			p.Name = decode(data, p.CodePage)
+		case recReferenceName:
+			refName = decode(data, p.CodePage)
+		case recReferenceRegistered, recReferenceProject, recReferenceControl:
+			p.References = append(p.References, Reference{
+				Name:  refName,
+				Type:  referenceType(id),
+				Libid: libid(data, id, p.CodePage),
+			})
+			refName = ""
+		case recReferenceOriginal:
+			// Followed by the REFERENCECONTROL that carries the name
+		case recModuleName:
+			p.Modules = append(p.Modules, Module{Name: decode(data, p.CodePage), Type: ModuleProcedural})
+			offsets = append(offsets, 0)
+			module = &p.Modules[len(p.Modules)-1]
+		case recModuleNameUnicode:
+			if module != nil && len(data) > 0 {
+				module.Name = decodeUTF16(data)
+			}
+		case recModuleStreamName:
+			if module != nil {
+				module.StreamName = decode(data, p.CodePage)
+			}
+		case recModuleOffset:
+			if module != nil && len(data) >= 4 {
+				offsets[len(offsets)-1] = int(binary.LittleEndian.Uint32(data))
+			}
+		case recModuleDocument:
+			if module != nil {
+				module.Type = ModuleDocument
+			}
+		case recModuleTerminator:
+			if module != nil && module.StreamName == "" {
+				module.StreamName = module.Name
+			}
+			module = nil
+		case recTerminator:
+			return p, offsets, nil
+		}
+	}
+	return p, offsets, nil
+}
+
+func referenceType(id uint16) string {
+	switch id {
+	case recReferenceProject:
+		return ReferenceProject
+	case recReferenceControl:
+		return ReferenceControl
+	default:
+		return ReferenceRegistered
+	}
+}
+
+// libid reads the library identifier at the start of a reference record, e.g.
+// "*\G{00020905-0000-0000-C000-000000000046}#8.7#0#...#Microsoft Word 16.0 Object Library"
+func libid(data []byte, id uint16, codePage int) string {
+	if id == recReferenceControl {
+		// SizeOfLibidTwiddled follows the record size
+		if len(data) < 4 {
+			return ""
+		}
+		data = data[4:]
+	}
+	if len(data) < 4 {
+		return ""
+	}
+	n := int(binary.LittleEndian.Uint32(data))
+	if n < 0 || 4+n > len(data) {
+		return ""
+	}
+	return decode(data[4:4+n], codePage)
+}
+
+// decode converts MBCS text of the project code page to a string
+func decode(b []byte, codePage int) string {
+	if codePage == codePageUTF8 {
+		return string(b)
+	}
+	return ole.DecodeANSI(b)
+}
+
+func decodeUTF16(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(b[i*2:])
+	}
+	return string(utf16.Decode(units))
+}
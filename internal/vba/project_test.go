@@ -0,0 +1,153 @@
+package vba
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/ole"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/ole/oletest"
+)
+
+func TestDecompress(t *testing.T) {
+	// Example from MS-OVBA section 3.2.3
+	compressed := []byte{
+		0x01, 0x2F, 0xB0, 0x00, 0x23, 0x61, 0x61, 0x61, 0x62, 0x63, 0x64, 0x65, 0x82, 0x66, 0x00, 0x70,
+		0x61, 0x67, 0x68, 0x69, 0x6A, 0x01, 0x38, 0x08, 0x61, 0x6B, 0x6C, 0x00, 0x30, 0x6D, 0x6E, 0x6F,
+		0x70, 0x06, 0x71, 0x02, 0x70, 0x04, 0x10, 0x72, 0x73, 0x74, 0x75, 0x76, 0x10, 0x77, 0x78, 0x79,
+		0x7A, 0x00, 0x3C,
+	}
+	want := "#aaabcdefaaaaghijaaaaaklaaamnopqaaaaaaaaaaaarstuvwxyzaaa"
+
+	got, err := Decompress(compressed)
+	if err != nil {
+		t.Fatalf("Decompress failed: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("Decompress() = %q, want %q", got, want)
+	}
+
+	for _, bad := range [][]byte{nil, {0x02, 0x00, 0xB0}, {0x01, 0x00, 0x00}, {0x01, 0x02, 0xB0, 0x01, 0x01, 0x00}} {
+		if _, err := Decompress(bad); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("Decompress(% X) error = %v, want ErrCorrupt", bad, err)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	thisDocument := "Attribute VB_Name = \"ThisDocument\"\r\n" +
+		"Private Sub Document_Open()\r\n" +
+		"    Set fso = CreateObject(\"Scripting.FileSystemObject\")\r\n" +
+		"End Sub\r\n"
+	module1 := "Attribute VB_Name = \"Module1\"\r\n" +
+		"Private Declare PtrSafe Function GetTickCount Lib \"kernel32\" () As Long\r\n" +
+		"Sub AutoNew()\r\n" +
+		"    FillLetter\r\n" +
+		"End Sub\r\n" +
+		"Public Function FillLetter() As Boolean\r\n" +
+		"    Set app = GetObject(, \"Outlook.Application\")\r\n" +
+		"End Function\r\n"
+
+	var dir bytes.Buffer
+	record(&dir, recCodePage, []byte{0xE4, 0x04})
+	record(&dir, recName, []byte("Project"))
+	record(&dir, recVersion, make([]byte, 4), 0x01, 0x00)
+	record(&dir, recReferenceName, []byte("stdole"))
+	record(&dir, recReferenceRegistered, libidData("*\\G{00020430-0000-0000-C000-000000000046}#2.0#0#stdole2.tlb#OLE Automation"))
+	for _, m := range []struct {
+		name   string
+		record uint16
+	}{{"ThisDocument", recModuleDocument}, {"Module1", recModuleProcedural}} {
+		record(&dir, recModuleName, []byte(m.name))
+		record(&dir, recModuleStreamName, []byte(m.name))
+		record(&dir, recModuleOffset, []byte{0x10, 0x00, 0x00, 0x00})
+		record(&dir, m.record, nil)
+		record(&dir, recModuleTerminator, nil)
+	}
+	record(&dir, recTerminator, nil)
+
+	data, err := oletest.Build(map[string][]byte{
+		"Macros/VBA/dir":          compress(dir.Bytes()),
+		"Macros/VBA/ThisDocument": append(make([]byte, 16), compress([]byte(thisDocument))...),
+		"Macros/VBA/Module1":      append(make([]byte, 16), compress([]byte(module1))...),
+		"WordDocument":            make([]byte, 64),
+	})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	cf, err := ole.Open(data)
+	if err != nil {
+		t.Fatalf("ole.Open failed: %v", err)
+	}
+
+	p, err := Open(cf)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if p.Name != "Project" || p.CodePage != 1252 || len(p.Modules) != 2 {
+		t.Fatalf("project = %+v", p)
+	}
+
+	doc := p.Modules[0]
+	if doc.Type != ModuleDocument || doc.Source != "Attribute VB_Name = \"ThisDocument\"\nPrivate Sub Document_Open()\n"+
+		"    Set fso = CreateObject(\"Scripting.FileSystemObject\")\nEnd Sub\n" {
+		t.Errorf("ThisDocument = %+v", doc)
+	}
+	if got := p.Modules[1].Procedures; !reflect.DeepEqual(got, []string{"AutoNew", "FillLetter"}) {
+		t.Errorf("Module1 procedures = %q", got)
+	}
+	if got := p.AutoExec(); !reflect.DeepEqual(got, []string{"ThisDocument.Document_Open", "Module1.AutoNew"}) {
+		t.Errorf("AutoExec() = %q", got)
+	}
+	want := []string{"Outlook.Application", "Scripting.FileSystemObject", "kernel32", "stdole"}
+	if got := p.Objects(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Objects() = %q, want %q", got, want)
+	}
+	if len(p.References) != 1 || p.References[0].Type != ReferenceRegistered ||
+		p.References[0].Libid != "*\\G{00020430-0000-0000-C000-000000000046}#2.0#0#stdole2.tlb#OLE Automation" {
+		t.Errorf("references = %+v", p.References)
+	}
+
+	empty, _ := oletest.Build(map[string][]byte{"WordDocument": make([]byte, 64)})
+	cf, _ = ole.Open(empty)
+	if _, err := Open(cf); !errors.Is(err, ErrNoProject) {
+		t.Errorf("expected ErrNoProject, got %v", err)
+	}
+}
+
+// record appends a dir stream record; extra bytes are written past the declared size
+func record(b *bytes.Buffer, id uint16, data []byte, extra ...byte) {
+	binary.Write(b, binary.LittleEndian, id)
+	binary.Write(b, binary.LittleEndian, uint32(len(data)))
+	b.Write(data)
+	b.Write(extra)
+}
+
+func libidData(libid string) []byte {
+	data := binary.LittleEndian.AppendUint32(nil, uint32(len(libid)))
+	data = append(data, libid...)
+	return append(data, make([]byte, 6)...)
+}
+
+// compress wraps data in a compressed container made only of literal tokens
+func compress(data []byte) []byte {
+	out := []byte{containerSignature}
+	for len(data) > 0 {
+		n := len(data)
+		if n > 3500 {
+			n = 3500
+		}
+		var chunk []byte
+		for i := 0; i < n; i += 8 {
+			chunk = append(chunk, 0x00)
+			chunk = append(chunk, data[i:min(i+8, n)]...)
+		}
+		header := uint16(len(chunk)+2-3) | chunkSignature<<12 | 0x8000
+		out = binary.LittleEndian.AppendUint16(out, header)
+		out = append(out, chunk...)
+		data = data[n:]
+	}
+	return out
+}
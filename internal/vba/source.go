@@ -0,0 +1,84 @@
+package vba
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	procedurePattern = regexp.MustCompile(`(?im)^[ \t]*(?:(?:Public|Private|Friend)[ \t]+)?(?:Static[ \t]+)?(?:Sub|Function|Property[ \t]+(?:Get|Let|Set))[ \t]+(\w+)`)
+	progIDPattern    = regexp.MustCompile(`(?i)\b(?:CreateObject|GetObject)\s*\(\s*(?:"[^"]*")?\s*,?\s*"([A-Za-z]\w*\.[\w.]+)"`)
+	declarePattern   = regexp.MustCompile(`(?im)^[ \t]*(?:(?:Public|Private)[ \t]+)?Declare[ \t]+(?:PtrSafe[ \t]+)?(?:Sub|Function)[ \t]+\w+[ \t]+Lib[ \t]+"([^"]+)"`)
+)
+
+// autoExecNames are the procedures Word runs when a document or template is
+// opened, created or closed, or when Word starts and exits
+var autoExecNames = map[string]bool{
+	"autoopen":       true,
+	"autonew":        true,
+	"autoclose":      true,
+	"autoexec":       true,
+	"autoexit":       true,
+	"document_open":  true,
+	"document_new":   true,
+	"document_close": true,
+}
+
+// analyzeSource lists the procedures, auto-exec entry points and external
+// objects of a module from its source
+func analyzeSource(m *Module) {
+	// A module named after an auto macro runs its Main procedure
+	autoModule := autoExecNames[strings.ToLower(m.Name)] && !strings.Contains(m.Name, "_")
+
+	for _, match := range procedurePattern.FindAllStringSubmatch(m.Source, -1) {
+		name := match[1]
+		m.Procedures = append(m.Procedures, name)
+		if autoExecNames[strings.ToLower(name)] || (autoModule && strings.EqualFold(name, "Main")) {
+			m.AutoExec = append(m.AutoExec, m.Name+"."+name)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, pattern := range []*regexp.Regexp{progIDPattern, declarePattern} {
+		for _, match := range pattern.FindAllStringSubmatch(m.Source, -1) {
+			key := strings.ToLower(match[1])
+			if !seen[key] {
+				seen[key] = true
+				m.Objects = append(m.Objects, match[1])
+			}
+		}
+	}
+}
+
+// AutoExec returns the auto-exec entry points of every module as Module.Procedure
+func (p *Project) AutoExec() []string {
+	var names []string
+	for _, m := range p.Modules {
+		names = append(names, m.AutoExec...)
+	}
+	return names
+}
+
+// Objects returns the COM classes and DLLs used by the code together with the
+// names of referenced libraries, sorted and without duplicates
+func (p *Project) Objects() []string {
+	seen := make(map[string]bool)
+	var objects []string
+	add := func(name string) {
+		if name != "" && !seen[strings.ToLower(name)] {
+			seen[strings.ToLower(name)] = true
+			objects = append(objects, name)
+		}
+	}
+	for _, m := range p.Modules {
+		for _, o := range m.Objects {
+			add(o)
+		}
+	}
+	for _, r := range p.References {
+		add(r.Name)
+	}
+	sort.Strings(objects)
+	return objects
+}
@@ -0,0 +1,396 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/queue"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// pollInterval is how often the dispatcher looks for due retries
+	pollInterval = time.Second
+	// dueBatchSize caps how many deliveries are attempted per round
+	dueBatchSize = 20
+	// requestTimeout bounds a single delivery attempt
+	requestTimeout = 10 * time.Second
+	// maxErrorBody is how much of a failed response body is kept in the log
+	maxErrorBody = 512
+)
+
+var deliveriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "webhook_deliveries_total",
+	Help: "Total webhook delivery attempts by outcome",
+}, []string{"outcome"})
+
+// DefaultRetryPolicy retries failed deliveries for roughly a day before giving up
+var DefaultRetryPolicy = queue.RetryPolicy{
+	MaxAttempts: 10,
+	BaseDelay:   10 * time.Second,
+	MaxDelay:    4 * time.Hour,
+	Jitter:      0.2,
+}
+
+// Dispatcher turns job and batch state changes into signed webhook deliveries
+type Dispatcher struct {
+	store      Store
+	queue      queue.Queue
+	client     *http.Client
+	secret     []byte
+	retry      queue.RetryPolicy
+	tenantURLs map[string]string
+	wake       chan struct{}
+}
+
+// NewDispatcher creates a dispatcher; the queue is used to detect batch
+// completion. Without a secret the dispatcher is disabled, as receivers could
+// not verify unsigned callbacks.
+func NewDispatcher(store Store, q queue.Queue, secret string) *Dispatcher {
+	if secret == "" {
+		log.Warn("WEBHOOK_SECRET is not set; webhook delivery is disabled")
+	}
+
+	return &Dispatcher{
+		store:      store,
+		queue:      q,
+		client:     &http.Client{Timeout: requestTimeout},
+		secret:     []byte(secret),
+		retry:      DefaultRetryPolicy,
+		tenantURLs: make(map[string]string),
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// Enabled reports whether the dispatcher has a secret to sign deliveries with
+func (d *Dispatcher) Enabled() bool {
+	return d != nil && len(d.secret) > 0
+}
+
+// SetRetryPolicy sets the backoff and attempt limit for failed deliveries
+func (d *Dispatcher) SetRetryPolicy(policy queue.RetryPolicy) {
+	d.retry = policy
+}
+
+// SetTenantURLs sets the callback URL used for jobs of a tenant that did not
+// specify one
+func (d *Dispatcher) SetTenantURLs(urls map[string]string) {
+	d.tenantURLs = urls
+}
+
+// CallbackURL resolves the callback URL for a job or batch from its metadata
+func (d *Dispatcher) CallbackURL(metadata map[string]string) string {
+	if metadata == nil {
+		return ""
+	}
+	if target := metadata[MetadataCallbackURL]; target != "" {
+		return target
+	}
+	return d.tenantURLs[metadata[MetadataTenant]]
+}
+
+// ValidateURL checks that a callback URL is an absolute http(s) URL
+func ValidateURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid callback_url: %w", err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid callback_url: must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// JobFinished records events for a job that reached a final state, and for its
+// batch when this was the batch's last unfinished job. A nil or disabled
+// dispatcher is a no-op.
+func (d *Dispatcher) JobFinished(ctx context.Context, job *queue.Job) {
+	if !d.Enabled() {
+		return
+	}
+
+	var eventType string
+	switch job.Status {
+	case queue.StatusCompleted:
+		eventType = EventJobCompleted
+	case queue.StatusFailed, queue.StatusDeadLetter:
+		eventType = EventJobFailed
+	case queue.StatusCancelled:
+		eventType = EventJobCancelled
+	default:
+		return
+	}
+
+	if target := d.CallbackURL(job.Metadata); target != "" {
+		event := &Event{
+			ID:        uuid.New().String(),
+			Type:      eventType,
+			CreatedAt: time.Now(),
+			Job:       jobPayload(job),
+		}
+		if err := d.enqueue(ctx, event.ID, target, event); err != nil {
+			log.Errorf("Failed to record %s webhook for job %s: %v", eventType, job.ID, err)
+		}
+	}
+
+	if batchID := job.Metadata["batch_id"]; batchID != "" {
+		d.checkBatch(ctx, batchID)
+	}
+}
+
+// checkBatch records a batch.completed event once every job of the batch finished
+func (d *Dispatcher) checkBatch(ctx context.Context, batchID string) {
+	batch, err := d.queue.GetBatch(ctx, batchID)
+	if err != nil {
+		if err != queue.ErrBatchNotFound {
+			log.Warnf("Failed to load batch %s for webhook: %v", batchID, err)
+		}
+		return
+	}
+	if batch.FinishedAt == nil {
+		return
+	}
+
+	target := d.CallbackURL(batch.Metadata)
+	if target == "" {
+		return
+	}
+
+	// Several workers can finish the batch's last jobs at once; a delivery ID
+	// derived from the finish time makes the event idempotent, while a batch that
+	// finishes again after a redrive still gets a new event
+	id := fmt.Sprintf("%s:%s:%d", EventBatchCompleted, batch.ID, batch.FinishedAt.UnixNano())
+	event := &Event{
+		ID:        id,
+		Type:      EventBatchCompleted,
+		CreatedAt: time.Now(),
+		Batch:     batch,
+	}
+	if err := d.enqueue(ctx, id, target, event); err != nil {
+		log.Errorf("Failed to record batch webhook for %s: %v", batch.ID, err)
+	}
+}
+
+// enqueue stores a delivery for the event and wakes the delivery loop
+func (d *Dispatcher) enqueue(ctx context.Context, id, target string, event *Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	delivery := &Delivery{
+		ID:        id,
+		EventType: event.Type,
+		URL:       target,
+		Payload:   payload,
+		Status:    DeliveryPending,
+		CreatedAt: time.Now(),
+	}
+	if event.Job != nil {
+		delivery.JobID = event.Job.ID
+		delivery.BatchID = event.Job.BatchID
+	}
+	if event.Batch != nil {
+		delivery.BatchID = event.Batch.ID
+	}
+
+	created, err := d.store.Create(ctx, delivery)
+	if err != nil {
+		return err
+	}
+	if created {
+		log.Debugf("Recorded %s webhook %s for %s", event.Type, id, target)
+		d.notify()
+	}
+	return nil
+}
+
+// notify wakes the delivery loop without blocking
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start delivers pending webhooks until the context is cancelled
+func (d *Dispatcher) Start(ctx context.Context) {
+	if !d.Enabled() {
+		return
+	}
+	log.Info("Webhook dispatcher started")
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.DeliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Info("Webhook dispatcher stopped")
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// DeliverDue attempts every delivery that is currently due and returns how many
+// were attempted
+func (d *Dispatcher) DeliverDue(ctx context.Context) int {
+	if !d.Enabled() {
+		return 0
+	}
+
+	due, err := d.store.Due(ctx, time.Now(), dueBatchSize)
+	if err != nil {
+		log.Errorf("Failed to load due webhook deliveries: %v", err)
+		return 0
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range due {
+		wg.Add(1)
+		go func(delivery *Delivery) {
+			defer wg.Done()
+			d.attempt(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+
+	return len(due)
+}
+
+// attempt POSTs a delivery once and records the outcome
+func (d *Dispatcher) attempt(ctx context.Context, delivery *Delivery) {
+	delivery.Attempts++
+	statusCode, err := d.send(ctx, delivery)
+	delivery.LastStatusCode = statusCode
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		delivery.Status = DeliveryDelivered
+		delivery.LastError = ""
+		delivery.NextAttemptAt = nil
+		delivery.DeliveredAt = &now
+		deliveriesTotal.WithLabelValues("delivered").Inc()
+	case delivery.Attempts >= d.retry.MaxAttempts:
+		delivery.Status = DeliveryFailed
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = nil
+		deliveriesTotal.WithLabelValues("failed").Inc()
+		log.Warnf("Webhook %s to %s failed permanently after %d attempts: %v",
+			delivery.ID, delivery.URL, delivery.Attempts, err)
+	default:
+		next := now.Add(d.retry.Backoff(delivery.Attempts))
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = &next
+		deliveriesTotal.WithLabelValues("retry").Inc()
+		log.Infof("Webhook %s to %s failed (attempt %d), retrying at %s: %v",
+			delivery.ID, delivery.URL, delivery.Attempts, next.Format(time.RFC3339), err)
+	}
+
+	if err := d.store.Update(ctx, delivery); err != nil {
+		log.Errorf("Failed to update webhook delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// send performs the signed HTTP request; any non-2xx response is an error
+func (d *Dispatcher) send(ctx context.Context, delivery *Delivery) (int, error) {
+	if len(d.secret) == 0 {
+		return 0, ErrDisabled
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "dot-to-docx-converter-webhook")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(d.secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return resp.StatusCode, fmt.Errorf("receiver returned %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// Deliveries lists the delivery log, newest first
+func (d *Dispatcher) Deliveries(ctx context.Context, filter Filter, limit int) ([]*Delivery, error) {
+	return d.store.List(ctx, filter, limit)
+}
+
+// Delivery returns one delivery from the log
+func (d *Dispatcher) Delivery(ctx context.Context, id string) (*Delivery, error) {
+	return d.store.Get(ctx, id)
+}
+
+// Redeliver schedules a delivery for immediate resend with a fresh attempt budget
+func (d *Dispatcher) Redeliver(ctx context.Context, id string) (*Delivery, error) {
+	if !d.Enabled() {
+		return nil, ErrDisabled
+	}
+
+	delivery, err := d.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.Status = DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = nil
+	if err := d.store.Update(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	d.notify()
+	return delivery, nil
+}
+
+// jobPayload builds the event body for a job
+func jobPayload(job *queue.Job) *JobPayload {
+	payload := &JobPayload{
+		ID:          job.ID,
+		Status:      job.Status,
+		Error:       job.Error,
+		Engine:      job.Engine,
+		Attempts:    job.Attempts,
+		BatchID:     job.Metadata["batch_id"],
+		Filename:    job.Metadata["filename"],
+		CompletedAt: job.CompletedAt,
+	}
+
+	if job.Status == queue.StatusCompleted && len(job.Outputs) > 0 {
+		payload.Downloads = make(map[string]string, len(job.Outputs))
+		for format := range job.Outputs {
+			payload.Downloads[format] = fmt.Sprintf("/api/v1/download/%s?format=%s", job.ID, format)
+		}
+	}
+	return payload
+}
@@ -0,0 +1,205 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/queue"
+)
+
+// receiver is a local webhook endpoint that fails the first N requests
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	events   []Event
+	bad      int
+}
+
+func (r *receiver) handler(secret []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		if !Verify(secret, req.Header.Get(HeaderTimestamp), body, req.Header.Get(HeaderSignature)) {
+			r.bad++
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.failures > 0 {
+			r.failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var event Event
+		json.Unmarshal(body, &event)
+		r.events = append(r.events, event)
+	}
+}
+
+func TestDispatcherRetriesUntilDelivered(t *testing.T) {
+	ctx := context.Background()
+	secret := []byte("test-secret")
+	recv := &receiver{failures: 1}
+	server := httptest.NewServer(recv.handler(secret))
+	defer server.Close()
+
+	d := NewDispatcher(NewMemoryStore(), queue.NewMemoryQueue(), string(secret))
+	d.SetRetryPolicy(queue.RetryPolicy{MaxAttempts: 3})
+
+	now := time.Now()
+	d.JobFinished(ctx, &queue.Job{
+		ID:          "job-1",
+		Status:      queue.StatusCompleted,
+		CompletedAt: &now,
+		Outputs:     map[string]string{"docx": "outputs/job-1/a.docx"},
+		Metadata:    map[string]string{MetadataCallbackURL: server.URL},
+	})
+
+	// First attempt gets a 503 and is re-scheduled with (zero) backoff
+	if n := d.DeliverDue(ctx); n != 1 {
+		t.Fatalf("expected 1 attempted delivery, got %d", n)
+	}
+	deliveries, _ := d.Deliveries(ctx, Filter{JobID: "job-1"}, 10)
+	if len(deliveries) != 1 || deliveries[0].Status != DeliveryPending || deliveries[0].LastStatusCode != 503 {
+		t.Fatalf("expected pending delivery after 503, got %+v", deliveries)
+	}
+
+	d.DeliverDue(ctx)
+	delivery, err := d.Delivery(ctx, deliveries[0].ID)
+	if err != nil {
+		t.Fatalf("Delivery failed: %v", err)
+	}
+	if delivery.Status != DeliveryDelivered || delivery.Attempts != 2 {
+		t.Errorf("expected delivered on attempt 2, got %s/%d", delivery.Status, delivery.Attempts)
+	}
+
+	if recv.bad != 0 {
+		t.Errorf("receiver rejected %d signatures", recv.bad)
+	}
+	if len(recv.events) != 1 || recv.events[0].Type != EventJobCompleted || recv.events[0].Job.Downloads["docx"] == "" {
+		t.Errorf("unexpected events received: %+v", recv.events)
+	}
+}
+
+func TestDispatcherGivesUpAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	recv := &receiver{failures: 10}
+	server := httptest.NewServer(recv.handler([]byte("s")))
+	defer server.Close()
+
+	d := NewDispatcher(NewMemoryStore(), queue.NewMemoryQueue(), "s")
+	d.SetRetryPolicy(queue.RetryPolicy{MaxAttempts: 2})
+	d.JobFinished(ctx, &queue.Job{
+		ID:       "job-1",
+		Status:   queue.StatusFailed,
+		Metadata: map[string]string{MetadataCallbackURL: server.URL},
+	})
+
+	d.DeliverDue(ctx)
+	d.DeliverDue(ctx)
+	if n := d.DeliverDue(ctx); n != 0 {
+		t.Errorf("expected no further attempts, got %d", n)
+	}
+
+	deliveries, _ := d.Deliveries(ctx, Filter{Status: DeliveryFailed}, 10)
+	if len(deliveries) != 1 || deliveries[0].Attempts != 2 {
+		t.Fatalf("expected one failed delivery after 2 attempts, got %+v", deliveries)
+	}
+
+	// Manual redelivery starts a fresh attempt budget
+	recv.failures = 0
+	if _, err := d.Redeliver(ctx, deliveries[0].ID); err != nil {
+		t.Fatalf("Redeliver failed: %v", err)
+	}
+	d.DeliverDue(ctx)
+	if delivery, _ := d.Delivery(ctx, deliveries[0].ID); delivery.Status != DeliveryDelivered {
+		t.Errorf("expected redelivered webhook, got %s", delivery.Status)
+	}
+}
+
+func TestDispatcherBatchCompletedOnce(t *testing.T) {
+	ctx := context.Background()
+	q := queue.NewMemoryQueue()
+	d := NewDispatcher(NewMemoryStore(), q, "s")
+	d.SetTenantURLs(map[string]string{"acme": "http://acme.example/hook"})
+
+	now := time.Now()
+	meta := map[string]string{"batch_id": "batch-1", MetadataTenant: "acme"}
+	jobs := []*queue.Job{
+		{ID: "a", Status: queue.StatusCompleted, CompletedAt: &now, Metadata: meta},
+		{ID: "b", Status: queue.StatusCancelled, CompletedAt: &now, Metadata: meta},
+	}
+	for _, job := range jobs {
+		q.Enqueue(ctx, job)
+		q.UpdateJob(job)
+	}
+	q.CreateBatch(ctx, &queue.Batch{
+		ID:       "batch-1",
+		JobIDs:   []string{"a", "b"},
+		Metadata: map[string]string{MetadataTenant: "acme"},
+	})
+
+	// Both jobs report the finished batch; only one batch event is recorded
+	for _, job := range jobs {
+		d.JobFinished(ctx, job)
+	}
+
+	batchEvents, _ := d.Deliveries(ctx, Filter{BatchID: "batch-1"}, 10)
+	count := 0
+	for _, delivery := range batchEvents {
+		if delivery.EventType == EventBatchCompleted {
+			count++
+			if delivery.URL != "http://acme.example/hook" {
+				t.Errorf("expected tenant default URL, got %s", delivery.URL)
+			}
+		}
+	}
+	if count != 1 {
+		t.Errorf("expected 1 batch.completed delivery, got %d", count)
+	}
+	if len(batchEvents) != 3 {
+		t.Errorf("expected 2 job deliveries and 1 batch delivery, got %d", len(batchEvents))
+	}
+}
+
+func TestDispatcherDisabledWithoutSecret(t *testing.T) {
+	ctx := context.Background()
+	recv := &receiver{}
+	server := httptest.NewServer(recv.handler(nil))
+	defer server.Close()
+
+	store := NewMemoryStore()
+	d := NewDispatcher(store, queue.NewMemoryQueue(), "")
+	if d.Enabled() {
+		t.Fatal("dispatcher without a secret is enabled")
+	}
+	d.JobFinished(ctx, &queue.Job{
+		ID:       "job-1",
+		Status:   queue.StatusCompleted,
+		Metadata: map[string]string{MetadataCallbackURL: server.URL},
+	})
+	if deliveries, _ := d.Deliveries(ctx, Filter{}, 10); len(deliveries) != 0 {
+		t.Errorf("recorded %d deliveries without a secret", len(deliveries))
+	}
+
+	// Deliveries recorded before the secret was removed are left pending
+	store.Create(ctx, &Delivery{ID: "d-1", URL: server.URL, Payload: []byte("{}"), Status: DeliveryPending, CreatedAt: time.Now()})
+	if n := d.DeliverDue(ctx); n != 0 {
+		t.Errorf("attempted %d deliveries without a secret", n)
+	}
+	if _, err := d.Redeliver(ctx, "d-1"); err != ErrDisabled {
+		t.Errorf("Redeliver = %v, want ErrDisabled", err)
+	}
+	if len(recv.events) != 0 || recv.bad != 0 {
+		t.Errorf("receiver got %d requests", len(recv.events)+recv.bad)
+	}
+}
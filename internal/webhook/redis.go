@@ -0,0 +1,185 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// deliveriesKey holds deliveries by ID
+	deliveriesKey = "webhook:deliveries"
+	// dueKey holds pending delivery IDs scored by next attempt time (unix ms)
+	dueKey = "webhook:due"
+
+	// claimTimeout is how long a claimed delivery is hidden from other dispatchers;
+	// a dispatcher that dies mid-delivery leaves it to be retried after this
+	claimTimeout = 2 * time.Minute
+)
+
+// claimScript atomically claims due deliveries by pushing their score forward by
+// the claim timeout, so they survive a crash instead of being popped and lost
+var claimScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[3])
+for _, id in ipairs(ids) do
+	redis.call('ZADD', KEYS[1], ARGV[2], id)
+end
+return ids
+`)
+
+// RedisStore implements Store using Redis
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore creates a new Redis-based delivery store
+func NewRedisStore(redisURL string) (*RedisStore, error) {
+	opt, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+	}
+
+	client := redis.NewClient(opt)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	return &RedisStore{client: client}, nil
+}
+
+// Create stores a new delivery unless one with the same ID exists
+func (s *RedisStore) Create(ctx context.Context, d *Delivery) (bool, error) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal delivery: %w", err)
+	}
+
+	created, err := s.client.HSetNX(ctx, deliveriesKey, d.ID, data).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to store delivery: %w", err)
+	}
+	if !created {
+		return false, nil
+	}
+
+	if err := s.schedule(ctx, d); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// Update stores a delivery and reschedules or retires it
+func (s *RedisStore) Update(ctx context.Context, d *Delivery) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("failed to marshal delivery: %w", err)
+	}
+
+	if err := s.client.HSet(ctx, deliveriesKey, d.ID, data).Err(); err != nil {
+		return fmt.Errorf("failed to update delivery: %w", err)
+	}
+	return s.schedule(ctx, d)
+}
+
+// schedule keeps the due set in step with a delivery's status
+func (s *RedisStore) schedule(ctx context.Context, d *Delivery) error {
+	if d.Status != DeliveryPending {
+		if err := s.client.ZRem(ctx, dueKey, d.ID).Err(); err != nil {
+			return fmt.Errorf("failed to unschedule delivery: %w", err)
+		}
+		return nil
+	}
+
+	next := time.Now()
+	if d.NextAttemptAt != nil {
+		next = *d.NextAttemptAt
+	}
+	if err := s.client.ZAdd(ctx, dueKey, redis.Z{
+		Score:  float64(next.UnixMilli()),
+		Member: d.ID,
+	}).Err(); err != nil {
+		return fmt.Errorf("failed to schedule delivery: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves a delivery by ID
+func (s *RedisStore) Get(ctx context.Context, id string) (*Delivery, error) {
+	data, err := s.client.HGet(ctx, deliveriesKey, id).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, fmt.Errorf("failed to get delivery: %w", err)
+	}
+
+	var d Delivery
+	if err := json.Unmarshal([]byte(data), &d); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal delivery: %w", err)
+	}
+	return &d, nil
+}
+
+// List returns matching deliveries, newest first
+func (s *RedisStore) List(ctx context.Context, filter Filter, limit int) ([]*Delivery, error) {
+	all, err := s.client.HGetAll(ctx, deliveriesKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %w", err)
+	}
+
+	result := make([]*Delivery, 0)
+	for _, data := range all {
+		var d Delivery
+		if err := json.Unmarshal([]byte(data), &d); err != nil {
+			log.Warnf("Failed to deserialize delivery: %v", err)
+			continue
+		}
+		if filter.matches(&d) {
+			result = append(result, &d)
+		}
+	}
+	sortNewestFirst(result)
+
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// Due claims pending deliveries whose next attempt is due
+func (s *RedisStore) Due(ctx context.Context, now time.Time, limit int) ([]*Delivery, error) {
+	ids, err := claimScript.Run(ctx, s.client, []string{dueKey},
+		now.UnixMilli(), now.Add(claimTimeout).UnixMilli(), limit).StringSlice()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+
+	result := make([]*Delivery, 0, len(ids))
+	for _, id := range ids {
+		d, err := s.Get(ctx, id)
+		if err != nil {
+			if err == ErrDeliveryNotFound {
+				s.client.ZRem(ctx, dueKey, id)
+				continue
+			}
+			return nil, err
+		}
+		if d.Status != DeliveryPending {
+			s.client.ZRem(ctx, dueKey, id)
+			continue
+		}
+		result = append(result, d)
+	}
+	return result, nil
+}
@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Filter narrows a delivery log listing; empty fields match everything
+type Filter struct {
+	JobID   string
+	BatchID string
+	Status  string
+}
+
+// matches reports whether a delivery passes the filter
+func (f Filter) matches(d *Delivery) bool {
+	return (f.JobID == "" || d.JobID == f.JobID) &&
+		(f.BatchID == "" || d.BatchID == f.BatchID) &&
+		(f.Status == "" || d.Status == f.Status)
+}
+
+// Store persists deliveries and their retry schedule
+type Store interface {
+	// Create stores a new delivery; it reports false if the ID already exists
+	Create(ctx context.Context, d *Delivery) (bool, error)
+	Update(ctx context.Context, d *Delivery) error
+	Get(ctx context.Context, id string) (*Delivery, error)
+	List(ctx context.Context, filter Filter, limit int) ([]*Delivery, error)
+	// Due claims pending deliveries whose next attempt is at or before now
+	Due(ctx context.Context, now time.Time, limit int) ([]*Delivery, error)
+}
+
+// MemoryStore implements an in-memory delivery log for development/testing
+type MemoryStore struct {
+	mu         sync.Mutex
+	deliveries map[string]*Delivery
+	claimed    map[string]bool
+}
+
+// NewMemoryStore creates a new in-memory delivery store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		deliveries: make(map[string]*Delivery),
+		claimed:    make(map[string]bool),
+	}
+}
+
+// Create stores a new delivery
+func (s *MemoryStore) Create(ctx context.Context, d *Delivery) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.deliveries[d.ID]; exists {
+		return false, nil
+	}
+	copied := *d
+	s.deliveries[d.ID] = &copied
+	return true, nil
+}
+
+// Update replaces a delivery and releases its claim
+func (s *MemoryStore) Update(ctx context.Context, d *Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.deliveries[d.ID]; !exists {
+		return ErrDeliveryNotFound
+	}
+	copied := *d
+	s.deliveries[d.ID] = &copied
+	delete(s.claimed, d.ID)
+	return nil
+}
+
+// Get retrieves a delivery by ID
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, exists := s.deliveries[id]
+	if !exists {
+		return nil, ErrDeliveryNotFound
+	}
+	copied := *d
+	return &copied, nil
+}
+
+// List returns matching deliveries, newest first
+func (s *MemoryStore) List(ctx context.Context, filter Filter, limit int) ([]*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]*Delivery, 0)
+	for _, d := range s.deliveries {
+		if filter.matches(d) {
+			copied := *d
+			result = append(result, &copied)
+		}
+	}
+	sortNewestFirst(result)
+
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// Due claims pending deliveries whose next attempt is due
+func (s *MemoryStore) Due(ctx context.Context, now time.Time, limit int) ([]*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]*Delivery, 0)
+	for id, d := range s.deliveries {
+		if d.Status != DeliveryPending || s.claimed[id] {
+			continue
+		}
+		if d.NextAttemptAt != nil && d.NextAttemptAt.After(now) {
+			continue
+		}
+		copied := *d
+		result = append(result, &copied)
+	}
+
+	// Oldest first so a backlog drains in order
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	for _, d := range result {
+		s.claimed[d.ID] = true
+	}
+	return result, nil
+}
+
+// sortNewestFirst orders deliveries by creation time, newest first
+func sortNewestFirst(deliveries []*Delivery) {
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+	})
+}
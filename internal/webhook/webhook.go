@@ -0,0 +1,104 @@
+// Package webhook delivers signed job and batch completion events to callback
+// URLs, retrying failed deliveries with backoff and keeping a delivery log.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/queue"
+)
+
+// Event types sent to callback URLs
+const (
+	EventJobCompleted   = "job.completed"
+	EventJobFailed      = "job.failed"
+	EventJobCancelled   = "job.cancelled"
+	EventBatchCompleted = "batch.completed"
+)
+
+// Delivery status values
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Headers set on every webhook request
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+)
+
+// Metadata keys used to carry callback settings on jobs and batches
+const (
+	MetadataCallbackURL = "callback_url"
+	MetadataTenant      = "tenant"
+)
+
+var (
+	// ErrDeliveryNotFound is returned when a delivery is not found
+	ErrDeliveryNotFound = errors.New("delivery not found")
+	// ErrDisabled is returned when delivering without a signing secret
+	ErrDisabled = errors.New("webhook delivery is disabled: WEBHOOK_SECRET is not set")
+)
+
+// Event is the JSON body POSTed to callback URLs
+type Event struct {
+	ID        string       `json:"id"`
+	Type      string       `json:"type"`
+	CreatedAt time.Time    `json:"created_at"`
+	Job       *JobPayload  `json:"job,omitempty"`
+	Batch     *queue.Batch `json:"batch,omitempty"`
+}
+
+// JobPayload describes the job an event refers to
+type JobPayload struct {
+	ID          string            `json:"id"`
+	Status      string            `json:"status"`
+	Error       string            `json:"error,omitempty"`
+	Engine      string            `json:"engine,omitempty"`
+	Attempts    int               `json:"attempts,omitempty"`
+	BatchID     string            `json:"batch_id,omitempty"`
+	Filename    string            `json:"filename,omitempty"`
+	Downloads   map[string]string `json:"downloads,omitempty"`
+	CompletedAt *time.Time        `json:"completed_at,omitempty"`
+}
+
+// Delivery is one event sent to one URL, with its retry state
+type Delivery struct {
+	ID             string          `json:"id"`
+	EventType      string          `json:"event_type"`
+	URL            string          `json:"url"`
+	JobID          string          `json:"job_id,omitempty"`
+	BatchID        string          `json:"batch_id,omitempty"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// Sign returns the signature header value for a payload: the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the shared secret, prefixed with "sha256="
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature header produced by Sign
+func Verify(secret []byte, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,409 @@
+package wordml
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoParagraphs is returned when a document has no paragraph to comment on
+var ErrNoParagraphs = errors.New("document has no paragraphs to comment on")
+
+// Namespaces, relationship and content types of comment parts
+const (
+	wordprocessingMLNS  = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+	commentsRelType     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/comments"
+	commentsContentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.comments+xml"
+	relationshipsNS     = "http://schemas.openxmlformats.org/package/2006/relationships"
+	contentTypesPart    = "[Content_Types].xml"
+)
+
+// Comment is a review comment attached to a paragraph of the main document
+type Comment struct {
+	Author    string
+	Initials  string
+	Text      string
+	Find      string // Text of the paragraph to comment on, when known
+	Paragraph int    // 1-based paragraph to comment on when Find matches none
+}
+
+// bodyParagraph is a paragraph of the main document that is not nested in
+// another one, as text box paragraphs are
+type bodyParagraph struct {
+	start, tagEnd int // The start tag
+	content       int // Where the paragraph content begins, after its properties
+	end           int // Start of the end tag
+	selfClosing   bool
+	prefix        string // Namespace prefix the document uses, e.g. "w"
+	text          string
+}
+
+// Annotate returns a copy of a DOCX package with the comments added, each
+// spanning the paragraph it is attached to. Comments already in the document
+// are kept.
+func Annotate(content []byte, comments []Comment) ([]byte, error) {
+	if len(comments) == 0 {
+		return content, nil
+	}
+	pkg, err := OpenPackage(content)
+	if err != nil {
+		return nil, err
+	}
+	main := pkg.Parts()[0].Name
+	document, err := pkg.ReadPart(main)
+	if err != nil {
+		return nil, err
+	}
+	paragraphs, err := bodyParagraphs(document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", main, err)
+	}
+	if len(paragraphs) == 0 {
+		return nil, ErrNoParagraphs
+	}
+
+	// New comments extend the comments part the document already has, if any
+	rels, err := pkg.Relationships(main)
+	if err != nil {
+		return nil, err
+	}
+	commentsPart := ""
+	for _, rel := range rels {
+		if !rel.External() && rel.Type == commentsRelType {
+			commentsPart = rel.Target
+		}
+	}
+	linked := commentsPart != ""
+	if !linked {
+		commentsPart = path.Join(path.Dir(main), "comments.xml")
+	}
+	var existing []byte
+	if pkg.Has(commentsPart) {
+		if existing, err = pkg.ReadPart(commentsPart); err != nil {
+			return nil, err
+		}
+	}
+	firstID := nextCommentID(existing)
+
+	// Comments on the same paragraph share its range markers
+	byParagraph := make(map[int][]int)
+	for i, c := range comments {
+		p := pickParagraph(paragraphs, c)
+		byParagraph[p] = append(byParagraph[p], firstID+i)
+	}
+	changed := map[string][]byte{
+		main:         markParagraphs(document, paragraphs, byParagraph),
+		commentsPart: commentsXML(existing, comments, firstID),
+	}
+
+	if !linked {
+		relsName := relsPath(main)
+		var relsData []byte
+		if pkg.Has(relsName) {
+			if relsData, err = pkg.ReadPart(relsName); err != nil {
+				return nil, err
+			}
+		}
+		id := 1
+		for used := relIDs(rels); used["rId"+strconv.Itoa(id)]; id++ {
+		}
+		target := strings.TrimPrefix(commentsPart, path.Dir(main)+"/")
+		changed[relsName] = appendRelationship(relsData, "rId"+strconv.Itoa(id), commentsRelType, target)
+	}
+
+	types, err := pkg.ReadPart(contentTypesPart)
+	if err != nil {
+		return nil, err
+	}
+	if partName := `PartName="/` + commentsPart + `"`; !bytes.Contains(types, []byte(partName)) {
+		override := `<Override ` + partName + ` ContentType="` + commentsContentType + `"/>`
+		changed[contentTypesPart] = insertBeforeClose(types, override)
+	}
+
+	return rewritePackage(content, changed)
+}
+
+// bodyParagraphs finds the top-level paragraphs of a document part
+func bodyParagraphs(data []byte) ([]bodyParagraph, error) {
+	var paragraphs []bodyParagraph
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	depth, paragraphDepth := 0, 0
+	var inText bool
+	var text strings.Builder
+	for {
+		offset := int(decoder.InputOffset())
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return paragraphs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case t.Name.Local == "p" && t.Name.Space == wordprocessingMLNS:
+				if paragraphDepth == 0 {
+					paragraphDepth = depth
+					tagEnd := int(decoder.InputOffset())
+					tag := data[offset:tagEnd]
+					paragraphs = append(paragraphs, bodyParagraph{
+						start: offset, tagEnd: tagEnd, content: tagEnd,
+						selfClosing: bytes.HasSuffix(tag, []byte("/>")),
+						prefix:      tagPrefix(tag),
+					})
+					text.Reset()
+				}
+			case t.Name.Local == "t" && paragraphDepth > 0:
+				inText = true
+			}
+		case xml.EndElement:
+			switch {
+			case paragraphDepth == 0:
+			case depth == paragraphDepth:
+				p := &paragraphs[len(paragraphs)-1]
+				p.end = offset
+				p.text = text.String()
+				paragraphDepth = 0
+			case depth == paragraphDepth+1 && t.Name.Local == "pPr":
+				paragraphs[len(paragraphs)-1].content = int(decoder.InputOffset())
+			case t.Name.Local == "t":
+				inText = false
+			}
+			depth--
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		}
+	}
+}
+
+// tagPrefix returns the namespace prefix of a tag such as "<w:p w14:paraId=...>"
+func tagPrefix(tag []byte) string {
+	name := bytes.TrimPrefix(tag, []byte("<"))
+	if i := bytes.IndexAny(name, " \t\r\n/>"); i >= 0 {
+		name = name[:i]
+	}
+	if i := bytes.IndexByte(name, ':'); i >= 0 {
+		return string(name[:i])
+	}
+	return ""
+}
+
+// pickParagraph returns the index of the paragraph a comment belongs to: the
+// first containing its text, else its paragraph number, else the first
+func pickParagraph(paragraphs []bodyParagraph, c Comment) int {
+	if find := strings.Join(strings.Fields(c.Find), " "); find != "" {
+		for i, p := range paragraphs {
+			if strings.Contains(strings.Join(strings.Fields(p.text), " "), find) {
+				return i
+			}
+		}
+	}
+	switch {
+	case c.Paragraph > len(paragraphs):
+		return len(paragraphs) - 1
+	case c.Paragraph >= 1:
+		return c.Paragraph - 1
+	}
+	return 0
+}
+
+// markParagraphs inserts comment range markers and references into the paragraphs
+func markParagraphs(data []byte, paragraphs []bodyParagraph, byParagraph map[int][]int) []byte {
+	indexes := make([]int, 0, len(byParagraph))
+	for i := range byParagraph {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	var out bytes.Buffer
+	last := 0
+	for _, i := range indexes {
+		p := paragraphs[i]
+		q := p.prefix
+		if q != "" {
+			q += ":"
+		}
+		var starts, ends strings.Builder
+		for _, id := range byParagraph[i] {
+			fmt.Fprintf(&starts, `<%scommentRangeStart %sid="%d"/>`, q, q, id)
+			fmt.Fprintf(&ends, `<%scommentRangeEnd %sid="%d"/><%sr><%scommentReference %sid="%d"/></%sr>`, q, q, id, q, q, q, id, q)
+		}
+
+		if p.selfClosing {
+			out.Write(data[last:p.start])
+			tag := bytes.TrimSuffix(data[p.start:p.tagEnd], []byte("/>"))
+			out.Write(bytes.TrimRight(tag, " "))
+			fmt.Fprintf(&out, ">%s%s</%sp>", starts.String(), ends.String(), q)
+			last = p.tagEnd
+			continue
+		}
+		out.Write(data[last:p.content])
+		out.WriteString(starts.String())
+		out.Write(data[p.content:p.end])
+		out.WriteString(ends.String())
+		last = p.end
+	}
+	out.Write(data[last:])
+	return out.Bytes()
+}
+
+// nextCommentID returns an id above those of the existing comments
+func nextCommentID(existing []byte) int {
+	next := 0
+	decoder := xml.NewDecoder(bytes.NewReader(existing))
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return next
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == "comment" {
+			for _, a := range start.Attr {
+				if id, err := strconv.Atoi(a.Value); err == nil && a.Name.Local == "id" && id >= next {
+					next = id + 1
+				}
+			}
+		}
+	}
+}
+
+// commentsXML adds the comments to an existing comments part, or creates one
+func commentsXML(existing []byte, comments []Comment, firstID int) []byte {
+	date := time.Now().UTC().Format(time.RFC3339)
+	prefix := "w"
+	if i := bytes.LastIndex(existing, []byte("</")); i >= 0 {
+		prefix = tagPrefix(existing[i+2:])
+	}
+	q := prefix
+	if q != "" {
+		q += ":"
+	}
+
+	var elements bytes.Buffer
+	for i, c := range comments {
+		fmt.Fprintf(&elements, `<%scomment %sid="%d" %sauthor="%s" %sdate="%s" %sinitials="%s">`,
+			q, q, firstID+i, q, escape(c.Author), q, date, q, escape(c.Initials))
+		fmt.Fprintf(&elements, `<%sp><%sr><%st xml:space="preserve">%s</%st></%sr></%sp></%scomment>`,
+			q, q, q, escape(c.Text), q, q, q, q)
+	}
+
+	if len(bytes.TrimSpace(existing)) == 0 {
+		return []byte(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+			`<w:comments xmlns:w="` + wordprocessingMLNS + `">` + elements.String() + `</w:comments>`)
+	}
+	return insertBeforeClose(existing, elements.String())
+}
+
+// appendRelationship adds a relationship to a .rels part, or creates one
+func appendRelationship(rels []byte, id, relType, target string) []byte {
+	rel := `<Relationship Id="` + id + `" Type="` + relType + `" Target="` + escape(target) + `"/>`
+	if len(bytes.TrimSpace(rels)) == 0 {
+		return []byte(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+			`<Relationships xmlns="` + relationshipsNS + `">` + rel + `</Relationships>`)
+	}
+	return insertBeforeClose(rels, rel)
+}
+
+// relIDs returns the ids in use by relationships
+func relIDs(rels []Relationship) map[string]bool {
+	ids := make(map[string]bool, len(rels))
+	for _, rel := range rels {
+		ids[rel.ID] = true
+	}
+	return ids
+}
+
+// insertBeforeClose inserts markup before the end tag of the root element,
+// expanding a self-closing root
+func insertBeforeClose(data []byte, markup string) []byte {
+	trimmed := bytes.TrimRight(data, " \t\r\n")
+	if bytes.HasSuffix(trimmed, []byte("/>")) {
+		// <root .../> becomes <root ...>markup</root>
+		open := bytes.LastIndexByte(trimmed, '<')
+		name := trimmed[open+1:]
+		if i := bytes.IndexAny(name, " \t\r\n/"); i >= 0 {
+			name = name[:i]
+		}
+		out := append([]byte{}, trimmed[:len(trimmed)-2]...)
+		return append(out, []byte(">"+markup+"</"+string(name)+">")...)
+	}
+	i := bytes.LastIndex(trimmed, []byte("</"))
+	if i < 0 {
+		return data
+	}
+	out := append([]byte{}, data[:i]...)
+	out = append(out, markup...)
+	return append(out, data[i:]...)
+}
+
+// escape escapes text for use in XML content and attribute values
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// rewritePackage copies a ZIP package, replacing or adding the changed files
+func rewritePackage(content []byte, changed map[string][]byte) ([]byte, error) {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ZIP archive: %w", err)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	write := func(header *zip.FileHeader, data []byte) error {
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	written := make(map[string]bool, len(changed))
+	for _, file := range reader.File {
+		data, ok := changed[file.Name]
+		if !ok {
+			if err := zw.Copy(file); err != nil {
+				return nil, fmt.Errorf("failed to copy %s: %w", file.Name, err)
+			}
+			continue
+		}
+		header := file.FileHeader
+		if err := write(&zip.FileHeader{Name: header.Name, Method: zip.Deflate, Modified: header.Modified}, data); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", file.Name, err)
+		}
+		written[file.Name] = true
+	}
+
+	names := make([]string, 0, len(changed))
+	for name := range changed {
+		if !written[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := write(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()}, changed[name]); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write ZIP archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,106 @@
+package wordml
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func readZip(t *testing.T, content []byte) map[string]string {
+	t.Helper()
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[file.Name] = string(data)
+	}
+	return files
+}
+
+func TestAnnotate(t *testing.T) {
+	content := buildPackage(t, map[string]string{
+		"[Content_Types].xml": `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Override PartName="/word/document.xml" ContentType="document"/></Types>`,
+		"_rels/.rels": `<Relationships><Relationship Id="rId1" ` +
+			`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/></Relationships>`,
+		"word/_rels/document.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+			`</Relationships>`,
+		"word/document.xml": `<w:document ` + wNS + `><w:body>` +
+			`<w:p><w:pPr><w:jc w:val="center"/></w:pPr><w:r><w:t>Dear client,</w:t></w:r></w:p>` +
+			`<w:p/>` +
+			`<w:p><w:r><w:t>Your   matter number</w:t></w:r><w:r><w:t> is below.</w:t></w:r>` +
+			`<w:r><w:txbxContent><w:p><w:r><w:t>Box</w:t></w:r></w:p></w:txbxContent></w:r></w:p>` +
+			`</w:body></w:document>`,
+	})
+
+	// Comments go on the paragraph containing their text, else the numbered one
+	annotated, err := Annotate(content, []Comment{
+		{Author: "Reviewer", Text: "Nested IF & merge fields", Find: "matter number is below"},
+		{Author: "Reviewer", Text: "Empty paragraph", Paragraph: 2},
+		{Author: "Reviewer", Text: "Unplaced", Find: "not in the document", Paragraph: 99},
+	})
+	if err != nil {
+		t.Fatalf("Annotate failed: %v", err)
+	}
+	files := readZip(t, annotated)
+
+	document := files["word/document.xml"]
+	for _, want := range []string{
+		`<w:p><w:commentRangeStart w:id="1"/><w:commentRangeEnd w:id="1"/><w:r><w:commentReference w:id="1"/></w:r></w:p>`,
+		`<w:p><w:commentRangeStart w:id="0"/><w:commentRangeStart w:id="2"/><w:r><w:t>Your`,
+		`</w:txbxContent></w:r><w:commentRangeEnd w:id="0"/><w:r><w:commentReference w:id="0"/></w:r>` +
+			`<w:commentRangeEnd w:id="2"/><w:r><w:commentReference w:id="2"/></w:r></w:p></w:body>`,
+	} {
+		if !strings.Contains(document, want) {
+			t.Errorf("document.xml lacks %s:\n%s", want, document)
+		}
+	}
+
+	pkg, err := OpenPackage(annotated)
+	if err != nil {
+		t.Fatalf("annotated package does not open: %v", err)
+	}
+	if parts := pkg.Parts(); len(parts) != 2 || parts[1].Name != "word/comments.xml" || parts[1].Kind != PartComments {
+		t.Errorf("parts = %+v, want the comments part linked", parts)
+	}
+	if comments := files["word/comments.xml"]; strings.Count(comments, "<w:comment ") != 3 ||
+		!strings.Contains(comments, "Nested IF &amp; merge fields") {
+		t.Errorf("comments.xml = %s", comments)
+	}
+	if !strings.Contains(files["word/_rels/document.xml.rels"], `Id="rId2"`) {
+		t.Errorf("comments relationship does not take a free id: %s", files["word/_rels/document.xml.rels"])
+	}
+	if !strings.Contains(files["[Content_Types].xml"], `PartName="/word/comments.xml" ContentType="`+commentsContentType+`"`) {
+		t.Errorf("content types lack the comments part: %s", files["[Content_Types].xml"])
+	}
+
+	// Annotating again continues the numbering of the existing comments
+	again, err := Annotate(annotated, []Comment{{Text: "Second pass", Find: "Dear client"}})
+	if err != nil {
+		t.Fatalf("second Annotate failed: %v", err)
+	}
+	files = readZip(t, again)
+	if comments := files["word/comments.xml"]; strings.Count(comments, "<w:comment ") != 4 || !strings.Contains(comments, `<w:comment w:id="3"`) {
+		t.Errorf("comments.xml after second pass = %s", comments)
+	}
+	if document := files["word/document.xml"]; !strings.Contains(document, `</w:pPr><w:commentRangeStart w:id="3"/>`) {
+		t.Errorf("document.xml after second pass = %s", document)
+	}
+	if rels := files["word/_rels/document.xml.rels"]; strings.Count(rels, "relationships/comments") != 1 {
+		t.Errorf("comments relationship duplicated: %s", rels)
+	}
+}
@@ -0,0 +1,226 @@
+// Package wordml reads the structure of ZIP-based Word documents (DOCX, DOTX,
+// DOCM, DOTM). Parts are discovered through the package relationships and walked
+// as a stream of WordprocessingML elements.
+package wordml
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// ErrNoDocument is returned when a package has no main document part
+var ErrNoDocument = errors.New("no main document part found")
+
+// Part kinds, named after the relationship types that reference them
+const (
+	PartDocument  = "document"
+	PartHeader    = "header"
+	PartFooter    = "footer"
+	PartFootnotes = "footnotes"
+	PartEndnotes  = "endnotes"
+	PartComments  = "comments"
+)
+
+// defaultDocumentPart is used when the package relationships are missing
+const defaultDocumentPart = "word/document.xml"
+
+// Part is a content part of the package
+type Part struct {
+	Name string `json:"name"` // Path inside the package, e.g. "word/header2.xml"
+	Kind string `json:"kind"`
+}
+
+// Package is an opened DOCX/DOTX package
+type Package struct {
+	files map[string]*zip.File
+	parts []Part
+}
+
+// Relationship links a part to another part or to an external resource
+type Relationship struct {
+	ID         string `xml:"Id,attr"`
+	Type       string `xml:"Type,attr"`   // Relationship type URI
+	Target     string `xml:"Target,attr"` // Part name for internal targets
+	TargetMode string `xml:"TargetMode,attr"`
+}
+
+// External reports whether the target lies outside the package
+func (r Relationship) External() bool {
+	return r.TargetMode == "External"
+}
+
+// relationships is the root element of a .rels part
+type relationships struct {
+	Relationships []Relationship `xml:"Relationship"`
+}
+
+// OpenPackage opens a ZIP-based Word document and discovers its content parts
+func OpenPackage(content []byte) (*Package, error) {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ZIP archive: %w", err)
+	}
+
+	p := &Package{files: make(map[string]*zip.File, len(reader.File))}
+	for _, file := range reader.File {
+		p.files[file.Name] = file
+	}
+
+	main := defaultDocumentPart
+	if targets := p.related("", "officeDocument"); len(targets) > 0 {
+		main = targets[0]
+	}
+	if _, ok := p.files[main]; !ok {
+		return nil, ErrNoDocument
+	}
+	p.parts = append(p.parts, Part{Name: main, Kind: PartDocument})
+
+	if _, ok := p.files[relsPath(main)]; ok {
+		for _, kind := range []string{PartHeader, PartFooter, PartFootnotes, PartEndnotes, PartComments} {
+			for _, target := range p.related(main, kind) {
+				if _, ok := p.files[target]; ok {
+					p.parts = append(p.parts, Part{Name: target, Kind: kind})
+				}
+			}
+		}
+		return p, nil
+	}
+
+	// Without relationships fall back to the conventional part names
+	var names []string
+	for name := range p.files {
+		if kind := conventionalKind(name); kind != "" && name != main {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p.parts = append(p.parts, Part{Name: name, Kind: conventionalKind(name)})
+	}
+	return p, nil
+}
+
+// Parts returns the content parts, main document first
+func (p *Package) Parts() []Part {
+	return p.parts
+}
+
+// Has reports whether the package contains a part
+func (p *Package) Has(name string) bool {
+	_, ok := p.files[name]
+	return ok
+}
+
+// ReadPart returns the contents of a part
+func (p *Package) ReadPart(name string) ([]byte, error) {
+	file, ok := p.files[name]
+	if !ok {
+		return nil, fmt.Errorf("part %s not found", name)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open part %s: %w", name, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read part %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// Files returns the names of every file in the package, sorted
+func (p *Package) Files() []string {
+	names := make([]string, 0, len(p.files))
+	for name := range p.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Relationships returns the relationships of source, with internal targets
+// resolved to part names; an empty source reads the package relationships.
+// A part without relationships has none.
+func (p *Package) Relationships(source string) ([]Relationship, error) {
+	if !p.Has(relsPath(source)) {
+		return nil, nil
+	}
+	data, err := p.ReadPart(relsPath(source))
+	if err != nil {
+		return nil, err
+	}
+	var rels relationships
+	if err := xml.Unmarshal(data, &rels); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", relsPath(source), err)
+	}
+
+	for i, rel := range rels.Relationships {
+		if rel.External() {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			rels.Relationships[i].Target = strings.TrimPrefix(rel.Target, "/")
+		} else {
+			rels.Relationships[i].Target = path.Join(path.Dir(source), rel.Target)
+		}
+	}
+	return rels.Relationships, nil
+}
+
+// related returns the internal targets of the relationships of source whose type
+// ends in relType
+func (p *Package) related(source, relType string) []string {
+	rels, err := p.Relationships(source)
+	if err != nil {
+		return nil
+	}
+
+	var targets []string
+	for _, rel := range rels {
+		if !rel.External() && strings.HasSuffix(rel.Type, "/"+relType) {
+			targets = append(targets, rel.Target)
+		}
+	}
+	return targets
+}
+
+// relsPath returns the relationships part of source, e.g.
+// "word/_rels/document.xml.rels" for "word/document.xml"
+func relsPath(source string) string {
+	if source == "" {
+		return "_rels/.rels"
+	}
+	return path.Join(path.Dir(source), "_rels", path.Base(source)+".rels")
+}
+
+// conventionalKind returns the kind of a part from the names Word gives them
+func conventionalKind(name string) string {
+	if path.Dir(name) != "word" || path.Ext(name) != ".xml" {
+		return ""
+	}
+	base := strings.TrimSuffix(path.Base(name), ".xml")
+	switch {
+	case base == "document":
+		return PartDocument
+	case strings.HasPrefix(base, "header"):
+		return PartHeader
+	case strings.HasPrefix(base, "footer"):
+		return PartFooter
+	case base == "footnotes":
+		return PartFootnotes
+	case base == "endnotes":
+		return PartEndnotes
+	case base == "comments":
+		return PartComments
+	}
+	return ""
+}
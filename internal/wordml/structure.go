@@ -0,0 +1,327 @@
+package wordml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Element kinds reported by Analyze
+const (
+	KindTable          = "table"           // Top-level table
+	KindNestedTable    = "nested_table"    // Table inside a table cell
+	KindContentControl = "content_control" // w:sdt structured document tag
+	KindFormField      = "form_field"      // Legacy text, check box or drop-down form field
+	KindRevision       = "revision"        // Tracked insertion, deletion, move or formatting change
+	KindComment        = "comment"         // Comment anchored in the text
+	KindTextBox        = "text_box"        // Text box or shape with text
+	KindSection        = "section"         // Section properties; see Structure.Sections
+)
+
+// markupCompatibilityNS is the namespace of mc:AlternateContent
+const markupCompatibilityNS = "http://schemas.openxmlformats.org/markup-compatibility/2006"
+
+// revisionElements are the WordprocessingML elements recording tracked changes
+var revisionElements = map[string]bool{
+	"ins":             true,
+	"del":             true,
+	"moveFrom":        true,
+	"moveTo":          true,
+	"rPrChange":       true,
+	"pPrChange":       true,
+	"tblPrChange":     true,
+	"trPrChange":      true,
+	"tcPrChange":      true,
+	"sectPrChange":    true,
+	"numberingChange": true,
+}
+
+// contentControlTypes are the sdtPr children that give a content control its type;
+// controls without one hold rich text
+var contentControlTypes = map[string]bool{
+	"text":         true,
+	"date":         true,
+	"dropDownList": true,
+	"comboBox":     true,
+	"checkbox":     true,
+	"picture":      true,
+	"docPartObj":   true,
+	"group":        true,
+	"richText":     true,
+}
+
+// Location is the position of an element in a part
+type Location struct {
+	Part   string `json:"part"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// String formats the location as part:line:column
+func (l Location) String() string {
+	return fmt.Sprintf("%s:%d:%d", l.Part, l.Line, l.Column)
+}
+
+// Element is a structural element found in a part
+type Element struct {
+	Kind     string   `json:"kind"`
+	Detail   string   `json:"detail,omitempty"` // e.g. the content control type and tag, or the revision author
+	Depth    int      `json:"depth,omitempty"`  // Table nesting depth
+	Location Location `json:"location"`
+}
+
+// Section holds the page settings of one document section
+type Section struct {
+	Location    Location `json:"location"`
+	Break       string   `json:"break"`                 // nextPage, continuous, evenPage, oddPage or nextColumn
+	PageWidth   int      `json:"page_width,omitempty"`  // Twentieths of a point
+	PageHeight  int      `json:"page_height,omitempty"` // Twentieths of a point
+	Orientation string   `json:"orientation"`
+	Columns     int      `json:"columns,omitempty"`
+	TitlePage   bool     `json:"title_page,omitempty"` // Different first page header and footer
+	Headers     int      `json:"headers,omitempty"`    // Header and footer references
+}
+
+// Structure summarises the structural elements of a package
+type Structure struct {
+	Parts         []Part    `json:"parts"`
+	Elements      []Element `json:"elements,omitempty"`
+	Sections      []Section `json:"sections,omitempty"`
+	MaxTableDepth int       `json:"max_table_depth"`
+}
+
+// Count returns the number of elements of a kind
+func (s *Structure) Count(kind string) int {
+	n := 0
+	for _, e := range s.Elements {
+		if e.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// OfKind returns the elements of a kind in document order
+func (s *Structure) OfKind(kind string) []Element {
+	var elements []Element
+	for _, e := range s.Elements {
+		if e.Kind == kind {
+			elements = append(elements, e)
+		}
+	}
+	return elements
+}
+
+// Analyze walks every content part of the package
+func Analyze(p *Package) (*Structure, error) {
+	s := &Structure{Parts: p.Parts()}
+	for _, part := range p.Parts() {
+		data, err := p.ReadPart(part.Name)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.walk(part.Name, data); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// walker tracks the open elements of interest while a part is decoded
+type walker struct {
+	s          *Structure
+	part       string
+	decoder    *xml.Decoder
+	depth      int
+	tableDepth int
+	sdt        []*Element // Open content controls
+	inSdtPr    bool
+	formField  *Element // Legacy form field whose ffData is being read
+	fieldName  string
+	section    *Section // Open sectPr and its depth
+	sectionAt  int
+}
+
+// walk streams the elements of one part into the structure
+func (s *Structure) walk(part string, data []byte) error {
+	w := &walker{s: s, part: part, decoder: xml.NewDecoder(strings.NewReader(string(data)))}
+
+	for {
+		tok, err := w.decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", part, err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			// Fallback content repeats the preferred choice for older readers
+			if t.Name.Space == markupCompatibilityNS && t.Name.Local == "Fallback" {
+				if err := w.decoder.Skip(); err != nil {
+					return fmt.Errorf("failed to parse %s: %w", part, err)
+				}
+				continue
+			}
+			w.depth++
+			w.start(t)
+		case xml.EndElement:
+			w.end(t)
+			w.depth--
+		}
+	}
+}
+
+// location returns the position just read by the decoder
+func (w *walker) location() Location {
+	line, column := w.decoder.InputPos()
+	return Location{Part: w.part, Line: line, Column: column}
+}
+
+func (w *walker) add(e Element) {
+	w.s.Elements = append(w.s.Elements, e)
+}
+
+func (w *walker) start(t xml.StartElement) {
+	if w.section != nil {
+		w.sectionChild(t)
+		return
+	}
+
+	local := t.Name.Local
+	switch {
+	case local == "tbl":
+		w.tableDepth++
+		kind := KindTable
+		if w.tableDepth > 1 {
+			kind = KindNestedTable
+		}
+		w.add(Element{Kind: kind, Depth: w.tableDepth, Location: w.location()})
+		if w.tableDepth > w.s.MaxTableDepth {
+			w.s.MaxTableDepth = w.tableDepth
+		}
+	case local == "sdt":
+		w.sdt = append(w.sdt, &Element{Kind: KindContentControl, Detail: "richText", Location: w.location()})
+	case local == "sdtPr" && len(w.sdt) > 0:
+		w.inSdtPr = true
+	case w.inSdtPr && contentControlTypes[local]:
+		w.sdt[len(w.sdt)-1].Detail = local
+	case w.inSdtPr && (local == "tag" || local == "alias"):
+		// Prefer the tag, which templates use to bind data, over the display alias
+		if val := attr(t, "val"); val != "" && (local == "tag" || w.fieldName == "") {
+			w.fieldName = val
+		}
+	case local == "ffData":
+		w.formField = &Element{Kind: KindFormField, Detail: "text", Location: w.location()}
+		w.fieldName = ""
+	case w.formField != nil && (local == "checkBox" || local == "ddList"):
+		w.formField.Detail = formFieldType(local)
+	case w.formField != nil && local == "name":
+		w.fieldName = attr(t, "val")
+	case revisionElements[local]:
+		w.addRevision(t)
+	case local == "commentReference":
+		w.add(Element{Kind: KindComment, Detail: "id " + attr(t, "id"), Location: w.location()})
+	case local == "txbxContent":
+		w.add(Element{Kind: KindTextBox, Location: w.location()})
+	case local == "sectPr":
+		w.section = &Section{Location: w.location(), Break: "nextPage", Orientation: "portrait"}
+		w.sectionAt = w.depth
+	}
+}
+
+func (w *walker) addRevision(t xml.StartElement) {
+	detail := t.Name.Local
+	if author := attr(t, "author"); author != "" {
+		detail += " by " + author
+	}
+	w.add(Element{Kind: KindRevision, Detail: detail, Location: w.location()})
+}
+
+// sectionChild reads the page settings inside a sectPr
+func (w *walker) sectionChild(t xml.StartElement) {
+	switch t.Name.Local {
+	case "type":
+		if val := attr(t, "val"); val != "" {
+			w.section.Break = val
+		}
+	case "pgSz":
+		w.section.PageWidth, _ = strconv.Atoi(attr(t, "w"))
+		w.section.PageHeight, _ = strconv.Atoi(attr(t, "h"))
+		if orient := attr(t, "orient"); orient != "" {
+			w.section.Orientation = orient
+		} else if w.section.PageWidth > w.section.PageHeight {
+			w.section.Orientation = "landscape"
+		}
+	case "cols":
+		w.section.Columns, _ = strconv.Atoi(attr(t, "num"))
+	case "titlePg":
+		w.section.TitlePage = attr(t, "val") != "0" && attr(t, "val") != "false"
+	case "headerReference", "footerReference":
+		w.section.Headers++
+	case "sectPrChange":
+		w.addRevision(t)
+	}
+}
+
+func (w *walker) end(t xml.EndElement) {
+	switch t.Name.Local {
+	case "tbl":
+		if w.tableDepth > 0 {
+			w.tableDepth--
+		}
+	case "sdtPr":
+		if w.inSdtPr {
+			w.inSdtPr = false
+			control := w.sdt[len(w.sdt)-1]
+			if w.fieldName != "" {
+				control.Detail += ": " + w.fieldName
+			}
+			w.fieldName = ""
+			w.add(*control)
+		}
+	case "sdt":
+		if len(w.sdt) > 0 {
+			w.sdt = w.sdt[:len(w.sdt)-1]
+		}
+	case "ffData":
+		if w.formField != nil {
+			if w.fieldName != "" {
+				w.formField.Detail += ": " + w.fieldName
+			}
+			w.fieldName = ""
+			w.add(*w.formField)
+			w.formField = nil
+		}
+	case "sectPr":
+		if w.section != nil && w.depth == w.sectionAt {
+			w.add(Element{Kind: KindSection, Detail: w.section.Break, Location: w.section.Location})
+			w.s.Sections = append(w.s.Sections, *w.section)
+			w.section = nil
+		}
+	}
+}
+
+func formFieldType(local string) string {
+	switch local {
+	case "checkBox":
+		return "checkBox"
+	case "ddList":
+		return "dropDown"
+	default:
+		return "text"
+	}
+}
+
+// attr returns the value of the attribute with the given local name
+func attr(e xml.StartElement, local string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
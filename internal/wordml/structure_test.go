@@ -0,0 +1,128 @@
+package wordml
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+const wNS = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" ` +
+	`xmlns:mc="http://schemas.openxmlformats.org/markup-compatibility/2006"`
+
+func buildPackage(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestAnalyze(t *testing.T) {
+	document := `<w:document ` + wNS + `><w:body>
+<w:tbl><w:tr><w:tc>
+  <w:tbl><w:tr><w:tc><w:p/></w:tc></w:tr></w:tbl>
+</w:tc></w:tr></w:tbl>
+<w:sdt><w:sdtPr><w:alias w:val="Client name"/><w:tag w:val="ClientName"/><w:dropDownList/></w:sdtPr>
+  <w:sdtContent><w:r><w:t>Choose</w:t></w:r></w:sdtContent></w:sdt>
+<w:p><w:r><w:fldChar w:fldCharType="begin"><w:ffData><w:name w:val="Check1"/><w:checkBox/></w:ffData></w:fldChar></w:r>
+  <w:ins w:id="1" w:author="Jane"><w:r><w:t>added</w:t></w:r></w:ins>
+  <w:r><w:commentReference w:id="0"/></w:r>
+  <mc:AlternateContent><mc:Choice Requires="wps"><w:txbxContent><w:p/></w:txbxContent></mc:Choice>
+  <mc:Fallback><w:txbxContent><w:p/></w:txbxContent></mc:Fallback></mc:AlternateContent>
+  <w:pPr><w:sectPr><w:pgSz w:w="16838" w:h="11906" w:orient="landscape"/></w:sectPr></w:pPr></w:p>
+<w:sectPr><w:headerReference w:type="default" r:id="rId7" xmlns:r="r"/><w:type w:val="continuous"/><w:pgSz w:w="11906" w:h="16838"/><w:titlePg/></w:sectPr>
+</w:body></w:document>`
+
+	content := buildPackage(t, map[string]string{
+		"_rels/.rels": `<Relationships><Relationship Id="rId1" ` +
+			`Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/></Relationships>`,
+		"word/_rels/document.xml.rels": `<Relationships>` +
+			`<Relationship Id="rId7" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/header" Target="header7.xml"/>` +
+			`<Relationship Id="rId8" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="http://example.com" TargetMode="External"/>` +
+			`</Relationships>`,
+		"word/document.xml": document,
+		"word/header7.xml":  `<w:hdr ` + wNS + `><w:p><w:del w:id="2" w:author="Sam"/></w:p></w:hdr>`,
+		"word/header1.xml":  `<w:hdr ` + wNS + `><w:tbl/></w:hdr>`, // Not referenced
+	})
+
+	pkg, err := OpenPackage(content)
+	if err != nil {
+		t.Fatalf("OpenPackage failed: %v", err)
+	}
+	want := []Part{{Name: "word/document.xml", Kind: PartDocument}, {Name: "word/header7.xml", Kind: PartHeader}}
+	if !reflect.DeepEqual(pkg.Parts(), want) {
+		t.Errorf("Parts() = %+v, want %+v", pkg.Parts(), want)
+	}
+
+	s, err := Analyze(pkg)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	counts := map[string]int{
+		KindTable: 1, KindNestedTable: 1, KindContentControl: 1, KindFormField: 1,
+		KindRevision: 2, KindComment: 1, KindTextBox: 1, KindSection: 2,
+	}
+	for kind, n := range counts {
+		if got := s.Count(kind); got != n {
+			t.Errorf("Count(%s) = %d, want %d", kind, got, n)
+		}
+	}
+	if s.MaxTableDepth != 2 {
+		t.Errorf("MaxTableDepth = %d, want 2", s.MaxTableDepth)
+	}
+
+	if got := s.OfKind(KindContentControl)[0].Detail; got != "dropDownList: ClientName" {
+		t.Errorf("content control = %q", got)
+	}
+	if got := s.OfKind(KindFormField)[0].Detail; got != "checkBox: Check1" {
+		t.Errorf("form field = %q", got)
+	}
+	if got := s.OfKind(KindRevision)[1]; got.Detail != "del by Sam" || got.Location.Part != "word/header7.xml" {
+		t.Errorf("header revision = %+v", got)
+	}
+	if got := s.OfKind(KindNestedTable)[0].Location; got.Part != "word/document.xml" || got.Line != 3 {
+		t.Errorf("nested table location = %+v", got)
+	}
+
+	if len(s.Sections) != 2 {
+		t.Fatalf("Sections = %+v", s.Sections)
+	}
+	if first := s.Sections[0]; first.Orientation != "landscape" || first.Break != "nextPage" {
+		t.Errorf("first section = %+v", first)
+	}
+	if last := s.Sections[1]; last.Orientation != "portrait" || last.Break != "continuous" || !last.TitlePage || last.Headers != 1 {
+		t.Errorf("last section = %+v", last)
+	}
+}
+
+func TestOpenPackageWithoutRelationships(t *testing.T) {
+	content := buildPackage(t, map[string]string{
+		"word/document.xml": `<w:document ` + wNS + `/>`,
+		"word/footer2.xml":  `<w:ftr ` + wNS + `/>`,
+		"word/styles.xml":   `<w:styles ` + wNS + `/>`,
+	})
+
+	pkg, err := OpenPackage(content)
+	if err != nil {
+		t.Fatalf("OpenPackage failed: %v", err)
+	}
+	want := []Part{{Name: "word/document.xml", Kind: PartDocument}, {Name: "word/footer2.xml", Kind: PartFooter}}
+	if !reflect.DeepEqual(pkg.Parts(), want) {
+		t.Errorf("Parts() = %+v, want %+v", pkg.Parts(), want)
+	}
+
+	if _, err := OpenPackage(buildPackage(t, map[string]string{"content.xml": "<a/>"})); err != ErrNoDocument {
+		t.Errorf("expected ErrNoDocument, got %v", err)
+	}
+}
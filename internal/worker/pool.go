@@ -2,14 +2,23 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/analyzer"
 	"github.com/alterspective-engine/dot-to-docx-converter/internal/api"
 	"github.com/alterspective-engine/dot-to-docx-converter/internal/converter"
 	"github.com/alterspective-engine/dot-to-docx-converter/internal/queue"
 	"github.com/alterspective-engine/dot-to-docx-converter/internal/storage"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/vba"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/webhook"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	log "github.com/sirupsen/logrus"
@@ -47,29 +56,76 @@ var (
 			Help: "Number of jobs in queue",
 		},
 	)
+
+	jobRetries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "converter_job_retries_total",
+			Help: "Failed jobs re-scheduled for retry or moved to the dead-letter set",
+		},
+		[]string{"outcome"},
+	)
+
+	leasesReaped = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "converter_leases_reaped_total",
+			Help: "Jobs returned to the queue after their worker's lease expired",
+		},
+	)
 )
 
+// dequeueTimeout bounds each blocking dequeue so workers periodically re-check
+// for shutdown and Redis promotes due retries
+const dequeueTimeout = 5 * time.Second
+
+// artifactsDir holds sidecar documents of jobs outside their outputs/<job>/
+// folders, so downloads never mistake them for a converted file
+const artifactsDir = "artifacts"
+
+// permanentErrors are failures that retrying cannot fix
+var permanentErrors = []error{
+	converter.ErrCorruptInput,
+	converter.ErrUnsupportedFormat,
+	converter.ErrUnsupportedOutput,
+	converter.ErrNoEngine,
+	os.ErrNotExist,
+}
+
 // Pool manages a pool of workers
 type Pool struct {
-	workerCount int
-	queue       queue.Queue
-	converter   converter.Converter
-	storage     storage.Storage
-	wg          sync.WaitGroup
-	stopChan    chan struct{}
+	workerCount   int
+	queue         queue.Queue
+	registry      *converter.Registry
+	storage       storage.Storage
+	leaseDuration time.Duration
+	webhooks      *webhook.Dispatcher
+	wg            sync.WaitGroup
+	stopChan      chan struct{}
 }
 
 // NewPool creates a new worker pool
-func NewPool(workerCount int, q queue.Queue, c converter.Converter, s storage.Storage) *Pool {
+func NewPool(workerCount int, q queue.Queue, r *converter.Registry, s storage.Storage) *Pool {
 	return &Pool{
-		workerCount: workerCount,
-		queue:       q,
-		converter:   c,
-		storage:     s,
-		stopChan:    make(chan struct{}),
+		workerCount:   workerCount,
+		queue:         q,
+		registry:      r,
+		storage:       s,
+		leaseDuration: queue.DefaultLeaseDuration,
+		stopChan:      make(chan struct{}),
 	}
 }
 
+// SetLeaseDuration matches the heartbeat and reaper cadence to the queue's lease duration
+func (p *Pool) SetLeaseDuration(d time.Duration) {
+	if d > 0 {
+		p.leaseDuration = d
+	}
+}
+
+// SetWebhooks sends completion events for finished jobs through the dispatcher
+func (p *Pool) SetWebhooks(d *webhook.Dispatcher) {
+	p.webhooks = d
+}
+
 // Start begins processing jobs with the worker pool
 func (p *Pool) Start(ctx context.Context) {
 	log.Infof("Starting worker pool with %d workers", p.workerCount)
@@ -82,6 +138,9 @@ func (p *Pool) Start(ctx context.Context) {
 	// Monitor queue size
 	go p.monitorQueue(ctx)
 
+	// Return jobs abandoned by crashed workers to the queue
+	go p.reapLeases(ctx)
+
 	// Wait for all workers to finish
 	p.wg.Wait()
 	log.Info("Worker pool stopped")
@@ -101,6 +160,17 @@ func (p *Pool) worker(ctx context.Context, id int) {
 	activeWorkers.Inc()
 	defer activeWorkers.Dec()
 
+	// Stop interrupts a blocked dequeue without cancelling a job already in progress
+	dequeueCtx, cancelDequeue := context.WithCancel(ctx)
+	defer cancelDequeue()
+	go func() {
+		select {
+		case <-p.stopChan:
+			cancelDequeue()
+		case <-dequeueCtx.Done():
+		}
+	}()
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -110,22 +180,25 @@ func (p *Pool) worker(ctx context.Context, id int) {
 			log.Infof("Worker %d stopping (stop signal)", id)
 			return
 		default:
-			// Try to get a job from the queue
-			job, err := p.queue.Dequeue(ctx)
-			if err != nil {
-				if err == queue.ErrNoJobs {
-					// No jobs available, wait a bit
-					time.Sleep(1 * time.Second)
-					continue
-				}
-				log.Errorf("Worker %d: Failed to dequeue job: %v", id, err)
-				time.Sleep(5 * time.Second)
+		}
+
+		// Block until a job arrives instead of polling
+		job, err := p.queue.DequeueWait(dequeueCtx, dequeueTimeout)
+		if err != nil {
+			if err == queue.ErrNoJobs || dequeueCtx.Err() != nil {
 				continue
 			}
+			log.Errorf("Worker %d: Failed to dequeue job: %v", id, err)
 
-			// Process the job
-			p.processJob(ctx, id, job)
+			select {
+			case <-dequeueCtx.Done():
+			case <-time.After(5 * time.Second):
+			}
+			continue
 		}
+
+		// Process the job
+		p.processJob(ctx, id, job)
 	}
 }
 
@@ -137,38 +210,93 @@ func (p *Pool) processJob(ctx context.Context, workerID int, job *queue.Job) {
 	// Record metrics for active processing
 	api.RecordProcessingStart()
 
+	// Keep the lease alive while we work; release it once the job is settled
+	jobCtx, cancelJob := context.WithCancel(ctx)
+	defer cancelJob()
+	defer p.queue.Ack(context.Background(), job.ID, job.Lease)
+
+	var leaseLost atomic.Bool
+	go p.heartbeat(jobCtx, cancelJob, job.ID, job.Lease, &leaseLost)
+
+	// A job whose lease was reaped has already been requeued; leave it alone
+	fail := func(err error) {
+		if leaseLost.Load() || !p.holdsLease(job) {
+			log.Warnf("Worker %d: Abandoning job %s after losing its lease: %v", workerID, job.ID, err)
+			return
+		}
+		p.failJob(job, err)
+	}
+
 	// Update job status
 	job.Status = queue.StatusProcessing
 	job.StartedAt = &start
+	job.NextAttemptAt = nil
 	if err := p.queue.UpdateJob(job); err != nil {
 		log.Errorf("Failed to update job status: %v", err)
 	}
+	api.PublishJobEvent(api.JobEventStarted, job)
 
 	// Download input file from storage if needed
-	localInput, err := p.storage.Download(ctx, job.InputPath)
+	localInput, err := p.storage.Download(jobCtx, job.InputPath)
 	if err != nil {
-		p.failJob(job, fmt.Errorf("failed to download input: %w", err))
+		fail(fmt.Errorf("failed to download input: %w", err))
 		api.RecordProcessingFailed()
 		return
 	}
 	defer p.storage.Cleanup(localInput)
 
-	// Prepare output path
-	localOutput := p.storage.GetLocalPath(job.OutputPath)
+	// Jobs queued before output formats existed produce a single DOCX
+	formats := job.Formats
+	if len(formats) == 0 {
+		formats = []string{converter.OutputFormatForPath(job.OutputPath)}
+	}
 
-	// Perform conversion
-	err = p.converter.Convert(ctx, localInput, localOutput)
-	if err != nil {
-		p.failJob(job, fmt.Errorf("conversion failed: %w", err))
-		api.RecordProcessingFailed()
-		jobsProcessed.WithLabelValues("failed").Inc()
-		jobDuration.WithLabelValues("failed").Observe(time.Since(start).Seconds())
-		return
+	// Produce one artifact per requested format, honouring the job's preferred engine if any
+	job.Outputs = make(map[string]string, len(formats))
+	engines := make([]string, 0, len(formats))
+	for _, format := range formats {
+		outputPath := converter.WithOutputExtension(job.OutputPath, format)
+		localOutput := p.storage.GetLocalPath(outputPath)
+
+		engine, err := p.registry.ConvertWith(jobCtx, localInput, localOutput, job.Metadata["engine"])
+		if err != nil {
+			fail(fmt.Errorf("%s conversion failed: %w", format, err))
+			api.RecordProcessingFailed()
+			jobsProcessed.WithLabelValues("failed").Inc()
+			jobDuration.WithLabelValues("failed").Observe(time.Since(start).Seconds())
+			return
+		}
+
+		// Packages that keep their macros are written with a macro-enabled extension
+		if _, err := os.Stat(localOutput); err != nil {
+			macroOutput := converter.MacroEnabledPath(outputPath)
+			if _, err := os.Stat(p.storage.GetLocalPath(macroOutput)); err == nil {
+				outputPath, localOutput = macroOutput, p.storage.GetLocalPath(macroOutput)
+			}
+		}
+
+		// Upload output file to storage
+		if err := p.storage.Upload(jobCtx, localOutput, outputPath); err != nil {
+			fail(fmt.Errorf("failed to upload %s output: %w", format, err))
+			api.RecordProcessingFailed()
+			return
+		}
+
+		job.Outputs[format] = outputPath
+		if !contains(engines, engine) {
+			engines = append(engines, engine)
+		}
+	}
+
+	// Macro source is kept with the job's artifacts so it can be ported by hand
+	p.storeMacros(job, localInput)
+	if job.Metadata["annotate"] == "true" {
+		p.storeAnnotated(job, localInput)
 	}
 
-	// Upload output file to storage
-	if err := p.storage.Upload(ctx, localOutput, job.OutputPath); err != nil {
-		p.failJob(job, fmt.Errorf("failed to upload output: %w", err))
+	// Another worker owns the job once its lease is reaped; don't overwrite its state
+	if leaseLost.Load() || !p.holdsLease(job) {
+		log.Warnf("Worker %d: Abandoning finished job %s after losing its lease", workerID, job.ID)
 		api.RecordProcessingFailed()
 		return
 	}
@@ -178,37 +306,223 @@ func (p *Pool) processJob(ctx context.Context, workerID int, job *queue.Job) {
 	job.Status = queue.StatusCompleted
 	job.CompletedAt = &now
 	job.Duration = time.Since(start)
+	job.Engine = strings.Join(engines, ",")
+	job.Error = ""
 
 	if err := p.queue.UpdateJob(job); err != nil {
 		log.Errorf("Failed to update completed job: %v", err)
 	}
+	api.PublishJobEvent(api.JobEventCompleted, job)
+	p.webhooks.JobFinished(context.Background(), job)
 
 	// Record metrics for completed job
 	api.RecordProcessingComplete(job.Duration)
 	jobsProcessed.WithLabelValues("success").Inc()
 	jobDuration.WithLabelValues("success").Observe(time.Since(start).Seconds())
 
-	log.Infof("Worker %d: Completed job %s in %v", workerID, job.ID, job.Duration)
+	log.Infof("Worker %d: Completed job %s in %v (engine: %s, formats: %s)",
+		workerID, job.ID, job.Duration, job.Engine, strings.Join(formats, ","))
+}
+
+// storeMacros writes the VBA project of the input template as a JSON sidecar
+// under artifacts/<job>/ and records it in the job's artifacts. Templates
+// without macros produce nothing; a sidecar that cannot be written does not
+// fail the conversion.
+func (p *Pool) storeMacros(job *queue.Job, localInput string) {
+	content, err := os.ReadFile(localInput)
+	if err != nil {
+		log.Warnf("Failed to read input of job %s for macro extraction: %v", job.ID, err)
+		return
+	}
+
+	project, err := analyzer.NewDocumentExtractor().ExtractVBA(content)
+	if err != nil {
+		if !errors.Is(err, vba.ErrNoProject) {
+			log.Warnf("Failed to extract macros of job %s: %v", job.ID, err)
+		}
+		return
+	}
+
+	data, err := json.MarshalIndent(project, "", "  ")
+	if err != nil {
+		log.Warnf("Failed to encode macros of job %s: %v", job.ID, err)
+		return
+	}
+
+	name := strings.TrimSuffix(filepath.Base(job.OutputPath), filepath.Ext(job.OutputPath)) + ".macros.json"
+	path := filepath.ToSlash(filepath.Join(artifactsDir, job.ID, name))
+	if err := p.storage.WriteFile(path, data); err != nil {
+		log.Warnf("Failed to store macros of job %s: %v", job.ID, err)
+		return
+	}
+
+	if job.Artifacts == nil {
+		job.Artifacts = make(map[string]string)
+	}
+	job.Artifacts[queue.ArtifactMacros] = path
 }
 
-// failJob marks a job as failed
+// storeAnnotated writes a copy of the DOCX output with a review comment on each
+// complexity issue of the template under artifacts/<job>/, away from the
+// outputs, and records it in the job's artifacts. A copy that cannot be made
+// does not fail the conversion.
+func (p *Pool) storeAnnotated(job *queue.Job, localInput string) {
+	output, ok := job.Outputs[converter.OutputDOCX]
+	if !ok {
+		log.Warnf("Job %s asked for an annotated document without a DOCX output", job.ID)
+		return
+	}
+
+	content, err := os.ReadFile(localInput)
+	if err != nil {
+		log.Warnf("Failed to read input of job %s for annotation: %v", job.ID, err)
+		return
+	}
+	docx, err := os.ReadFile(p.storage.GetLocalPath(output))
+	if err != nil {
+		log.Warnf("Failed to read DOCX output of job %s for annotation: %v", job.ID, err)
+		return
+	}
+
+	annotated, err := analyzer.Annotate(docx, analyzer.AnalyzeComplexity(content))
+	if err != nil {
+		log.Warnf("Failed to annotate output of job %s: %v", job.ID, err)
+		return
+	}
+
+	name := strings.TrimSuffix(filepath.Base(output), filepath.Ext(output)) + ".annotated.docx"
+	path := filepath.ToSlash(filepath.Join(artifactsDir, job.ID, name))
+	if err := p.storage.WriteFile(path, annotated); err != nil {
+		log.Warnf("Failed to store annotated output of job %s: %v", job.ID, err)
+		return
+	}
+
+	if job.Artifacts == nil {
+		job.Artifacts = make(map[string]string)
+	}
+	job.Artifacts[queue.ArtifactAnnotated] = path
+}
+
+// failJob records a job failure. Retryable errors are re-scheduled with backoff
+// (and dead-lettered once attempts run out); permanent errors fail the job outright.
 func (p *Pool) failJob(job *queue.Job, err error) {
 	now := time.Now()
-	job.Status = queue.StatusFailed
 	job.Error = err.Error()
-	job.CompletedAt = &now
+	job.Attempts++
 
 	if job.StartedAt != nil {
 		job.Duration = now.Sub(*job.StartedAt)
 	}
 
+	if isRetryable(err) {
+		retryErr := p.queue.Retry(context.Background(), job)
+		if errors.Is(retryErr, queue.ErrLeaseLost) {
+			log.Warnf("Abandoning job %s after losing its lease: %v", job.ID, err)
+			return
+		}
+		if retryErr == nil {
+			if job.Status == queue.StatusRetrying {
+				api.PublishJobEvent(api.JobEventRetrying, job)
+				jobRetries.WithLabelValues("retry").Inc()
+				log.Warnf("Job %s failed on attempt %d/%d, retrying at %s: %v",
+					job.ID, job.Attempts, job.MaxAttempts, job.NextAttemptAt.Format(time.RFC3339), err)
+			} else {
+				jobRetries.WithLabelValues("dead_letter").Inc()
+				log.Errorf("Job %s moved to dead-letter set after %d attempts: %v", job.ID, job.Attempts, err)
+				api.PublishJobEvent(api.JobEventFailed, job)
+				p.webhooks.JobFinished(context.Background(), job)
+			}
+			return
+		}
+		log.Errorf("Failed to schedule retry for job %s: %v", job.ID, retryErr)
+	}
+
+	job.Status = queue.StatusFailed
+	job.CompletedAt = &now
+
 	if err := p.queue.UpdateJob(job); err != nil {
 		log.Errorf("Failed to update failed job: %v", err)
 	}
+	api.PublishJobEvent(api.JobEventFailed, job)
+	p.webhooks.JobFinished(context.Background(), job)
 
 	log.Errorf("Job %s failed: %v", job.ID, err)
 }
 
+// isRetryable reports whether a failure may succeed on a later attempt
+func isRetryable(err error) bool {
+	for _, permanent := range permanentErrors {
+		if errors.Is(err, permanent) {
+			return false
+		}
+	}
+	return true
+}
+
+// holdsLease extends the job's lease to check that no other worker has taken the
+// job over before its state is settled. Errors other than a lost lease count as
+// held, as the heartbeat would not have given up on them either.
+func (p *Pool) holdsLease(job *queue.Job) bool {
+	return !errors.Is(p.queue.ExtendLease(context.Background(), job.ID, job.Lease), queue.ErrLeaseLost)
+}
+
+// heartbeat extends the job's lease until ctx is done. If the lease was lost to the
+// reaper the job context is cancelled so the worker stops duplicating requeued work.
+func (p *Pool) heartbeat(ctx context.Context, cancel context.CancelFunc, jobID, lease string, lost *atomic.Bool) {
+	ticker := time.NewTicker(p.leaseDuration / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.queue.ExtendLease(ctx, jobID, lease); err != nil {
+				if errors.Is(err, queue.ErrLeaseLost) {
+					log.Warnf("Lease lost for job %s, cancelling conversion", jobID)
+					lost.Store(true)
+					cancel()
+					return
+				}
+				log.Warnf("Failed to extend lease for job %s: %v", jobID, err)
+			}
+		}
+	}
+}
+
+// reapLeases periodically returns jobs with expired leases to the queue
+func (p *Pool) reapLeases(ctx context.Context) {
+	ticker := time.NewTicker(p.leaseDuration / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reaped, err := p.queue.ReapExpired(ctx)
+			if err != nil {
+				log.Errorf("Failed to reap expired leases: %v", err)
+				continue
+			}
+			if reaped > 0 {
+				leasesReaped.Add(float64(reaped))
+				log.Warnf("Requeued %d jobs with expired leases", reaped)
+			}
+		}
+	}
+}
+
+// contains reports whether values includes value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 // monitorQueue periodically updates queue size metric
 func (p *Pool) monitorQueue(ctx context.Context) {
 	ticker := time.NewTicker(5 * time.Second)
@@ -10,11 +10,13 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/analyzer"
 	"github.com/alterspective-engine/dot-to-docx-converter/internal/api"
 	"github.com/alterspective-engine/dot-to-docx-converter/internal/config"
 	"github.com/alterspective-engine/dot-to-docx-converter/internal/converter"
 	"github.com/alterspective-engine/dot-to-docx-converter/internal/queue"
 	"github.com/alterspective-engine/dot-to-docx-converter/internal/storage"
+	"github.com/alterspective-engine/dot-to-docx-converter/internal/webhook"
 	"github.com/alterspective-engine/dot-to-docx-converter/internal/worker"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -53,28 +55,106 @@ func main() {
 	}
 
 	// Initialize queue
+	retryPolicy := queue.DefaultRetryPolicy
+	retryPolicy.MaxAttempts = cfg.MaxAttempts
+	retryPolicy.BaseDelay = cfg.RetryBaseDelay
+	retryPolicy.MaxDelay = cfg.RetryMaxDelay
+
 	var queueClient queue.Queue
 	if cfg.RedisURL != "" {
 		redisQueue, err := queue.NewRedisQueue(cfg.RedisURL)
 		if err != nil {
 			log.Warnf("Failed to initialize Redis queue, using in-memory queue: %v", err)
-			queueClient = queue.NewMemoryQueue()
+			memoryQueue := queue.NewMemoryQueue()
+			memoryQueue.SetRetryPolicy(retryPolicy)
+			memoryQueue.SetLeaseDuration(cfg.JobLeaseTimeout)
+			queueClient = memoryQueue
 		} else {
+			redisQueue.SetRetryPolicy(retryPolicy)
+			redisQueue.SetLeaseDuration(cfg.JobLeaseTimeout)
 			queueClient = redisQueue
 		}
 	} else {
-		queueClient = queue.NewMemoryQueue()
+		memoryQueue := queue.NewMemoryQueue()
+		memoryQueue.SetRetryPolicy(retryPolicy)
+		memoryQueue.SetLeaseDuration(cfg.JobLeaseTimeout)
+		queueClient = memoryQueue
+	}
+
+	// Initialize webhook delivery; the log lives in Redis when the queue does
+	var webhookStore webhook.Store = webhook.NewMemoryStore()
+	if _, ok := queueClient.(*queue.RedisQueue); ok {
+		redisStore, err := webhook.NewRedisStore(cfg.RedisURL)
+		if err != nil {
+			log.Warnf("Failed to initialize Redis webhook store, using in-memory store: %v", err)
+		} else {
+			webhookStore = redisStore
+		}
+	}
+
+	webhookPolicy := webhook.DefaultRetryPolicy
+	webhookPolicy.MaxAttempts = cfg.WebhookMaxAttempts
+	webhooks := webhook.NewDispatcher(webhookStore, queueClient, cfg.WebhookSecret)
+	webhooks.SetRetryPolicy(webhookPolicy)
+	webhooks.SetTenantURLs(cfg.WebhookTenantURLs)
+	go webhooks.Start(ctx)
+
+	// Load complexity rules; an invalid rules file stops startup
+	if cfg.ComplexityRulesPath != "" {
+		rulesWatcher, err := analyzer.NewRulesWatcher(cfg.ComplexityRulesPath, cfg.ComplexityRulesReload)
+		if err != nil {
+			log.Fatalf("Failed to load complexity rules: %v", err)
+		}
+		go rulesWatcher.Start(ctx)
+		log.Infof("Complexity rules loaded from %s", cfg.ComplexityRulesPath)
 	}
 
 	// Initialize converter
-	conv := converter.NewLibreOfficeConverter(cfg.ConversionTimeout)
+	libreOffice := converter.NewLibreOfficeConverter(cfg.ConversionTimeout)
+	libreOffice.SetEnhancedAccuracy(cfg.EnhancedAccuracy)
+
+	// Keep warm office instances so conversions don't pay LibreOffice startup cost
+	var officePool *converter.OfficePool
+	if cfg.OfficePoolSize > 0 {
+		officePool = converter.NewOfficePool(converter.OfficePoolOptions{
+			Size:              cfg.OfficePoolSize,
+			MaxConversions:    cfg.OfficeMaxConversions,
+			BasePort:          cfg.OfficeBasePort,
+			StartupTimeout:    cfg.OfficeStartupTimeout,
+			ConversionTimeout: cfg.ConversionTimeout,
+			EnhancedAccuracy:  cfg.EnhancedAccuracy,
+		})
+		if err := officePool.Start(ctx); err != nil {
+			log.Warnf("Failed to start office pool, spawning LibreOffice per conversion: %v", err)
+			officePool = nil
+		} else {
+			libreOffice.SetPool(officePool)
+			defer officePool.Close()
+		}
+	}
+
+	// Native converter handles ZIP-based templates in-process; the registry
+	// routes everything else (and native failures) to LibreOffice
+	nativeConv := converter.NewNativeConverter(converter.NativeOptions{
+		KeepMacros: cfg.NativeKeepMacros,
+	}, nil)
+
+	registry := converter.NewRegistry()
+	registry.Register(converter.EngineNative, nativeConv)
+	registry.Register(converter.EngineLibreOffice, libreOffice)
+	registry.SetChain(analyzer.FormatZipBased, converter.EngineNative, converter.EngineLibreOffice)
+	registry.SetChain(analyzer.FormatOLEBased, converter.EngineLibreOffice)
+	registry.SetChain(analyzer.FormatRTF, converter.EngineLibreOffice)
+	registry.SetDefaultChain(converter.EngineLibreOffice)
 
 	// Start worker pool
-	workerPool := worker.NewPool(cfg.WorkerCount, queueClient, conv, storageClient)
+	workerPool := worker.NewPool(cfg.WorkerCount, queueClient, registry, storageClient)
+	workerPool.SetLeaseDuration(cfg.JobLeaseTimeout)
+	workerPool.SetWebhooks(webhooks)
 	go workerPool.Start(ctx)
 
 	// Setup HTTP server with converter for sync endpoints
-	router := setupRouter(cfg, queueClient, storageClient, conv)
+	router := setupRouter(cfg, queueClient, storageClient, registry, officePool, webhooks)
 
 	// Setup graceful shutdown
 	srv := &api.Server{
@@ -107,7 +187,7 @@ func main() {
 	log.Info("Service stopped")
 }
 
-func setupRouter(cfg *config.Config, queue queue.Queue, storage storage.Storage, conv converter.Converter) *gin.Engine {
+func setupRouter(cfg *config.Config, queue queue.Queue, storage storage.Storage, registry *converter.Registry, officePool *converter.OfficePool, webhooks *webhook.Dispatcher) *gin.Engine {
 	if cfg.LogLevel != "debug" {
 		gin.SetMode(gin.ReleaseMode)
 	}
@@ -152,35 +232,61 @@ func setupRouter(cfg *config.Config, queue queue.Queue, storage storage.Storage,
 	// Health checks
 	router.GET("/health", api.HealthCheck())
 	router.GET("/health/live", api.LivenessCheck())
-	router.GET("/health/ready", api.ReadinessCheck(queue))
+	router.GET("/health/ready", api.ReadinessCheck(queue, officePool))
 
 	// Metrics
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 	router.GET("/api/v1/metrics", api.MetricsHandler(queue))
-	router.GET("/ws/metrics", api.WebSocketMetricsHandler())
+	router.GET("/ws/metrics", api.WebSocketMetricsHandler(queue, cfg.AllowedOrigins))
 
 	// API routes
 	v1 := router.Group("/api/v1")
 	{
 		// Asynchronous conversion (queue-based)
-		v1.POST("/convert", api.ConvertHandler(queue, storage))
-		v1.POST("/batch", api.BatchConvertHandler(queue, storage))
+		v1.POST("/convert", api.ConvertHandler(queue, storage, registry))
+		v1.POST("/batch", api.BatchConvertHandler(queue, storage, registry))
+
+		// Batch status, cancellation and bundled download
+		v1.GET("/batches", api.ListBatches(queue))
+		v1.GET("/batches/:id", api.GetBatch(queue))
+		v1.DELETE("/batches/:id", api.CancelBatch(queue, webhooks))
+		v1.GET("/batches/:id/download", api.DownloadBatch(queue, storage))
+		v1.GET("/batches/:id/reconciliation", api.GetBatchReconciliation(queue, storage))
+
+		// MatterSphere exports queued as a batch, one job per precedent
+		v1.POST("/mattersphere/import", api.ImportMatterSphereHandler(queue, storage, registry, cfg.MatterSphereImportDir))
 
 		// Synchronous conversion (immediate response)
-		v1.POST("/convert/sync", api.ConvertSyncHandler(conv, cfg.SyncMaxFileSize, cfg.SyncTimeout))
-		v1.POST("/convert/sync/json", api.ConvertSyncJSONHandler(conv, cfg.SyncMaxFileSize, cfg.SyncTimeout))
+		v1.POST("/convert/sync", api.ConvertSyncHandler(registry, cfg.SyncMaxFileSize, cfg.SyncTimeout))
+		v1.POST("/convert/sync/json", api.ConvertSyncJSONHandler(registry, cfg.SyncMaxFileSize, cfg.SyncTimeout))
+
+		// Conversion engines and format routing
+		v1.GET("/engines", api.ListEngines(registry))
 
 		// Complexity analysis endpoints (no conversion)
-		v1.POST("/analyze", api.AnalyzeHandler())
-		v1.POST("/analyze/batch", api.AnalyzeBatchHandler())
+		v1.POST("/analyze", api.AnalyzeHandler(cfg.ComplexityRulesDir))
+		v1.POST("/analyze/batch", api.AnalyzeBatchHandler(cfg.ComplexityRulesDir))
+
+		// Live metrics and job events as Server-Sent Events (same stream as /ws/metrics)
+		v1.GET("/events", api.WebSocketMetricsHandler(queue, cfg.AllowedOrigins))
 
 		// Job management (for async)
 		v1.GET("/jobs/:id", api.GetJobStatus(queue))
+		v1.GET("/jobs/:id/macros", api.GetJobMacros(queue, storage))
+		v1.GET("/jobs/:id/annotated", api.GetJobAnnotated(queue, storage))
 		v1.GET("/jobs", api.ListJobs(queue))
-		v1.DELETE("/jobs/:id", api.CancelJob(queue))
+		v1.GET("/jobs/dead-letter", api.ListDeadLetterJobs(queue))
+		v1.POST("/jobs/dead-letter/redrive", api.RedriveAllJobs(queue))
+		v1.POST("/jobs/dead-letter/:id/redrive", api.RedriveJob(queue))
+		v1.DELETE("/jobs/:id", api.CancelJob(queue, webhooks))
+
+		// Webhook delivery log
+		v1.GET("/webhooks/deliveries", api.ListWebhookDeliveries(webhooks))
+		v1.GET("/webhooks/deliveries/:id", api.GetWebhookDelivery(webhooks))
+		v1.POST("/webhooks/deliveries/:id/redeliver", api.RedeliverWebhook(webhooks))
 
 		// Download converted file
-		v1.GET("/download/:id", api.DownloadFile(storage))
+		v1.GET("/download/:id", api.DownloadFile(queue, storage))
 
 		// NEW: Sharedo Migration System Endpoints
 		migration := v1.Group("/migration")